@@ -1,9 +1,20 @@
 package advmath
 
 import (
+	"bytes"
+	"context"
+	"encoding/gob"
+	"encoding/json"
 	"fmt"
 	"math"
+	"math/big"
+	"math/cmplx"
+	"math/rand"
+	"reflect"
+	"sort"
+	"strings"
 	"testing"
+	"time"
 )
 
 /*
@@ -331,3 +342,4446 @@ func TestTranspose(t *testing.T) {
 
 	fmt.Println(tr)
 }
+
+func TestMatrix32(t *testing.T) {
+	testMatrix := NewMatrix32(2, 2)
+	testMatrix.SetRow(0, []float32{4, 7})
+	testMatrix.SetRow(1, []float32{2, 6})
+
+	det, err := testMatrix.Determinant()
+	if err != nil || det != 10.0 {
+		t.Errorf("Matrix32.Determinant() = %g, want %g, error %v", det, 10.0, err)
+	}
+
+	inv, err := testMatrix.Inverse()
+	if err != nil {
+		t.Fatalf("Matrix32.Inverse() returned error %v", err)
+	}
+	id, _ := testMatrix.Multiply(inv)
+	var i, j uint
+	for i = 0; i < 2; i++ {
+		for j = 0; j < 2; j++ {
+			if !soclose(float64(id.Get(i, j)), NewIdentity(2).Get(i, j), 1e-6) && math.Abs(float64(id.Get(i, j))) > 1e-6 {
+				t.Errorf("Matrix32 A*A^-1 = %v, want identity", id.M)
+			}
+		}
+	}
+
+	back := testMatrix.ToMatrix().ToMatrix32()
+	if back.Get(1, 0) != 2 {
+		t.Errorf("Matrix32 conversion = %v, want %v", back.M, testMatrix.M)
+	}
+}
+
+func TestComplexMatrix(t *testing.T) {
+	testMatrix := NewComplexMatrix(2, 2)
+	testMatrix.SetRow(0, []complex128{1 + 1i, 2})
+	testMatrix.SetRow(1, []complex128{3i, 4 - 1i})
+
+	//(1+i)(4-i) - 2*3i = 5+3i-6i = 5-3i
+	det, err := testMatrix.Determinant()
+	if err != nil || cmplx.Abs(det-(5-3i)) > 1e-12 {
+		t.Errorf("ComplexMatrix.Determinant() = %v, want %v, error %v", det, 5-3i, err)
+	}
+
+	inv, err := testMatrix.Inverse()
+	if err != nil {
+		t.Fatalf("ComplexMatrix.Inverse() returned error %v", err)
+	}
+	id, _ := testMatrix.Multiply(inv)
+	for i := range id.M {
+		if cmplx.Abs(id.M[i]-NewComplexIdentity(2).M[i]) > 1e-12 {
+			t.Errorf("ComplexMatrix A*A^-1 = %v, want identity", id.M)
+		}
+	}
+
+	h := testMatrix.ConjugateTranspose()
+	if h.Get(0, 1) != -3i || h.Get(0, 0) != 1-1i {
+		t.Errorf("ComplexMatrix.ConjugateTranspose() = %v", h.M)
+	}
+}
+
+func TestBigMatrix(t *testing.T) {
+	//Hilbert matrix of order 8, determinant is 1/365356847125734485878112256000000
+	n := uint(8)
+	testMatrix := NewBigMatrix(n, n, 256)
+	var i, j uint
+	for i = 0; i < n; i++ {
+		for j = 0; j < n; j++ {
+			testMatrix.Set(i, j, new(big.Float).SetPrec(256).Quo(big.NewFloat(1), big.NewFloat(float64(i+j+1))))
+		}
+	}
+
+	det, err := testMatrix.Determinant()
+	if err != nil {
+		t.Fatalf("BigMatrix.Determinant() returned error %v", err)
+	}
+	result := 1.0 / 3.65356847125734485878112256e32
+	calc, _ := det.Float64()
+	if !soclose(calc, result, 1e-12) {
+		t.Errorf("BigMatrix.Determinant() = %g, want %g", calc, result)
+	}
+
+	inv, err := testMatrix.Inverse()
+	if err != nil {
+		t.Fatalf("BigMatrix.Inverse() returned error %v", err)
+	}
+	//Inverse of a Hilbert matrix only has integers, first element is n^2
+	first, _ := inv.Get(0, 0).Float64()
+	if !soclose(first, 64, 1e-12) {
+		t.Errorf("BigMatrix.Inverse()[0][0] = %g, want %g", first, 64.0)
+	}
+
+	l, u, err := testMatrix.LUDecomposition()
+	if err != nil {
+		t.Fatalf("BigMatrix.LUDecomposition() returned error %v", err)
+	}
+	lu, _ := l.Multiply(u)
+	if !soclose(lu.ToMatrix().Get(7, 6), testMatrix.ToMatrix().Get(7, 6), 1e-15) {
+		t.Errorf("BigMatrix L*U = %v, want %v", lu.ToMatrix().M, testMatrix.ToMatrix().M)
+	}
+}
+
+func TestRatMatrix(t *testing.T) {
+	testMatrix := NewRatMatrix(3, 3)
+	testMatrix.SetRow(0, []int64{2, -1, 0})
+	testMatrix.SetRow(1, []int64{-1, 2, -1})
+	testMatrix.SetRow(2, []int64{0, -1, 2})
+
+	det, err := testMatrix.Determinant()
+	if err != nil || det.Cmp(big.NewRat(4, 1)) != 0 {
+		t.Errorf("RatMatrix.Determinant() = %v, want 4, error %v", det, err)
+	}
+
+	inv, err := testMatrix.Inverse()
+	if err != nil {
+		t.Fatalf("RatMatrix.Inverse() returned error %v", err)
+	}
+	if inv.Get(0, 0).Cmp(big.NewRat(3, 4)) != 0 || inv.Get(1, 1).Cmp(big.NewRat(1, 1)) != 0 {
+		t.Errorf("RatMatrix.Inverse() = %v", inv.M)
+	}
+
+	singular := NewRatMatrix(2, 3)
+	singular.SetRow(0, []int64{1, 2, 3})
+	singular.SetRow(1, []int64{2, 4, 6})
+	r, rank := singular.RREF()
+	if rank != 1 || r.Get(0, 2).Cmp(big.NewRat(3, 1)) != 0 || r.Get(1, 2).Sign() != 0 {
+		t.Errorf("RatMatrix.RREF() = %v with rank %d, want rank 1", r.M, rank)
+	}
+
+	floats := NewMatrix(1, 2)
+	floats.M = []float64{0.5, -3}
+	exact, err := floats.ToRatMatrix()
+	if err != nil || exact.Get(0, 0).Cmp(big.NewRat(1, 2)) != 0 || exact.Get(0, 1).Cmp(big.NewRat(-3, 1)) != 0 {
+		t.Errorf("ToRatMatrix() = %v, error %v", exact, err)
+	}
+	for _, v := range []float64{math.Inf(1), math.NaN()} {
+		floats.M[1] = v
+		if _, err := floats.ToRatMatrix(); err == nil {
+			t.Errorf("ToRatMatrix() with %g should return an error", v)
+		}
+	}
+}
+
+func TestMatrixJSON(t *testing.T) {
+	testMatrix := NewMatrix(2, 3)
+	testMatrix.SetRow(0, []float64{1, 2, 3})
+	testMatrix.SetRow(1, []float64{4, 5, 6})
+
+	obj, err := json.Marshal(testMatrix)
+	if err != nil || string(obj) != `{"rows":2,"cols":3,"data":[1,2,3,4,5,6]}` {
+		t.Errorf("json.Marshal(Matrix) = %s, error %v", obj, err)
+	}
+
+	nested, err := testMatrix.MarshalJSONFormat(JSONNestedArray)
+	if err != nil || string(nested) != `[[1,2,3],[4,5,6]]` {
+		t.Errorf("MarshalJSONFormat(JSONNestedArray) = %s, error %v", nested, err)
+	}
+
+	wrapped, err := json.Marshal(struct {
+		M NestedJSON `json:"m"`
+	}{NestedJSON(*testMatrix)})
+	if err != nil || string(wrapped) != `{"m":[[1,2,3],[4,5,6]]}` {
+		t.Errorf("json.Marshal(NestedJSON) = %s, error %v", wrapped, err)
+	}
+	var unwrapped NestedJSON
+	if err := json.Unmarshal(nested, &unwrapped); err != nil || !alikeslices(unwrapped.M, testMatrix.M) {
+		t.Errorf("json.Unmarshal(%s) into NestedJSON = %v, error %v", nested, unwrapped, err)
+	}
+
+	for _, in := range [][]byte{obj, nested} {
+		var back Matrix
+		if err := json.Unmarshal(in, &back); err != nil {
+			t.Fatalf("json.Unmarshal(%s) returned error %v", in, err)
+		}
+		if back.NumberOfRows != 2 || back.NumberOfColumns != 3 || !alikeslices(back.M, testMatrix.M) {
+			t.Errorf("json.Unmarshal(%s) = %v, want %v", in, back, *testMatrix)
+		}
+	}
+
+	var overflow Matrix
+	if err := json.Unmarshal([]byte(`{"rows":3,"cols":12297829382473034411,"data":[1]}`), &overflow); err == nil {
+		t.Errorf("json.Unmarshal with rows*cols overflowing should return an error")
+	}
+
+	var bad Matrix
+	if err := json.Unmarshal([]byte(`[[1,2],[3]]`), &bad); err == nil {
+		t.Errorf("json.Unmarshal of ragged rows should return an error")
+	}
+	if err := json.Unmarshal([]byte(`{"rows":2,"cols":2,"data":[1]}`), &bad); err == nil {
+		t.Errorf("json.Unmarshal of short data should return an error")
+	}
+}
+
+func TestMatrixCSV(t *testing.T) {
+	m, err := ReadCSV(strings.NewReader("1,2,3\n4, 5.5,6\n"))
+	if err != nil {
+		t.Fatalf("ReadCSV() returned error %v", err)
+	}
+	if m.NumberOfRows != 2 || m.NumberOfColumns != 3 || m.Get(1, 1) != 5.5 {
+		t.Errorf("ReadCSV() = %v", m)
+	}
+
+	var buf bytes.Buffer
+	options := CSVOptions{Delimiter: ';', Header: true, Columns: []string{"a", "b", "c"}}
+	if err := m.WriteCSVWithOptions(&buf, options); err != nil {
+		t.Fatalf("WriteCSVWithOptions() returned error %v", err)
+	}
+	if buf.String() != "a;b;c\n1;2;3\n4;5.5;6\n" {
+		t.Errorf("WriteCSVWithOptions() = %q", buf.String())
+	}
+
+	back, header, err := ReadCSVWithOptions(&buf, options)
+	if err != nil || len(header) != 3 || !alikeslices(back.M, m.M) {
+		t.Errorf("ReadCSVWithOptions() = %v, %v, error %v", back, header, err)
+	}
+
+	if _, err := ReadCSV(strings.NewReader("1,x\n")); err == nil {
+		t.Errorf("ReadCSV() with a non numeric field should return an error")
+	}
+}
+
+func TestMatrixNpy(t *testing.T) {
+	testMatrix := NewMatrix(2, 3)
+	testMatrix.SetRow(0, []float64{1, 2, 3})
+	testMatrix.SetRow(1, []float64{4, 5, -6.25})
+
+	var buf bytes.Buffer
+	if err := testMatrix.SaveNpy(&buf); err != nil {
+		t.Fatalf("SaveNpy() returned error %v", err)
+	}
+	if buf.Len() != 128+6*8 {
+		t.Errorf("SaveNpy() wrote %d bytes, want %d", buf.Len(), 128+6*8)
+	}
+
+	back, err := LoadNpy(&buf)
+	if err != nil {
+		t.Fatalf("LoadNpy() returned error %v", err)
+	}
+	if back.NumberOfRows != 2 || back.NumberOfColumns != 3 || !alikeslices(back.M, testMatrix.M) {
+		t.Errorf("LoadNpy() = %v, want %v", back, testMatrix)
+	}
+
+	if _, err := LoadNpy(strings.NewReader("not a npy file")); err == nil {
+		t.Errorf("LoadNpy() of invalid data should return an error")
+	}
+
+	//Headers with a shape which overflows, too large, or larger than the data
+	for _, shape := range []string{"(4294967296, 4294967296)", "(1000000, 1000000)", "(1000, 1000)"} {
+		header := "{'descr': '<f8', 'fortran_order': False, 'shape': " + shape + ", }\n"
+		raw := append([]byte("\x93NUMPY\x01\x00"), byte(len(header)), 0)
+		raw = append(raw, header...)
+		raw = append(raw, make([]byte, 64)...)
+		if m, err := LoadNpy(bytes.NewReader(raw)); err == nil {
+			t.Errorf("LoadNpy() with the shape %s = %dx%d, should fail", shape, m.NumberOfRows, m.NumberOfColumns)
+		}
+	}
+	//A version 2 header claiming 4 GiB
+	if _, err := LoadNpy(strings.NewReader("\x93NUMPY\x02\x00\xff\xff\xff\xff")); err == nil {
+		t.Errorf("LoadNpy() with a header of 4 GiB should fail")
+	}
+}
+
+func TestMatrixBinary(t *testing.T) {
+	testMatrix := NewMatrix(3, 2)
+	testMatrix.SetRow(0, []float64{1, 2})
+	testMatrix.SetRow(1, []float64{math.Pi, -0.5})
+	testMatrix.SetRow(2, []float64{math.Inf(1), 1e-300})
+
+	data, err := testMatrix.MarshalBinary()
+	if err != nil || len(data) != 20+6*8 {
+		t.Fatalf("MarshalBinary() = %d bytes, error %v", len(data), err)
+	}
+	var back Matrix
+	if err := back.UnmarshalBinary(data); err != nil || back.NumberOfRows != 3 || !alikeslices(back.M, testMatrix.M) {
+		t.Errorf("UnmarshalBinary() = %v, error %v", back, err)
+	}
+	if err := back.UnmarshalBinary(data[:len(data)-1]); err == nil {
+		t.Errorf("UnmarshalBinary() of truncated data should return an error")
+	}
+
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(testMatrix); err != nil {
+		t.Fatalf("gob Encode returned error %v", err)
+	}
+	var decoded Matrix
+	if err := gob.NewDecoder(&buf).Decode(&decoded); err != nil || !alikeslices(decoded.M, testMatrix.M) {
+		t.Errorf("gob Decode = %v, error %v", decoded, err)
+	}
+}
+
+func TestGonum(t *testing.T) {
+	testMatrix := NewMatrix(2, 3)
+	testMatrix.SetRow(0, []float64{1, 2, 3})
+	testMatrix.SetRow(1, []float64{4, 5, 6})
+
+	r, c, data := testMatrix.ToGonum()
+	data[0] = 42
+	if r != 2 || c != 3 || testMatrix.Get(0, 0) != 1 {
+		t.Errorf("ToGonum() = %d, %d, %v", r, c, data)
+	}
+
+	back := FromGonum(testMatrix)
+	if back.NumberOfRows != 2 || back.NumberOfColumns != 3 || !alikeslices(back.M, testMatrix.M) {
+		t.Errorf("FromGonum() = %v, want %v", back, testMatrix)
+	}
+}
+
+func TestCrossOuter(t *testing.T) {
+	c, err := Cross([]float64{1, 0, 0}, []float64{0, 1, 0})
+	if err != nil || !alikeslices(c, []float64{0, 0, 1}) {
+		t.Errorf("Cross() = %v, want [0 0 1], error %v", c, err)
+	}
+	c, _ = Cross([]float64{2, 3, 4}, []float64{5, 6, 7})
+	if !alikeslices(c, []float64{-3, 6, -3}) {
+		t.Errorf("Cross() = %v, want [-3 6 -3]", c)
+	}
+	if _, err := Cross([]float64{1, 2}, []float64{3, 4}); err == nil {
+		t.Errorf("Cross() of 2-D vectors should return an error")
+	}
+
+	o := Outer([]float64{1, 2}, []float64{3, 4, 5})
+	if o.NumberOfRows != 2 || o.NumberOfColumns != 3 || !alikeslices(o.M, []float64{3, 4, 5, 6, 8, 10}) {
+		t.Errorf("Outer() = %v", o)
+	}
+
+	d, _ := Dot([]float64{1, 2, 3}, []float64{4, 5, 6})
+	if d != 32 || Norm([]float64{3, 4}) != 5 {
+		t.Errorf("Dot() = %g, Norm() = %g", d, Norm([]float64{3, 4}))
+	}
+}
+
+func TestTransforms(t *testing.T) {
+	p := NewMatrix(2, 1)
+	p.M = []float64{1, 0}
+	r, _ := NewRotation2D(math.Pi / 2).Multiply(p)
+	if math.Abs(r.M[0]) > 1e-15 || !close(r.M[1], 1) {
+		t.Errorf("NewRotation2D(pi/2)*(1,0) = %v, want (0,1)", r.M)
+	}
+
+	//Rotating y around z by 90 degrees gives -x
+	q := NewMatrix(3, 1)
+	q.M = []float64{0, 1, 0}
+	r, _ = NewRotation3DZ(math.Pi / 2).Multiply(q)
+	if !close(r.M[0], -1) || math.Abs(r.M[1]) > 1e-15 {
+		t.Errorf("NewRotation3DZ(pi/2)*(0,1,0) = %v, want (-1,0,0)", r.M)
+	}
+	for _, rot := range []*Matrix{NewRotation3DX(0.3), NewRotation3DY(0.3), NewRotation3DZ(0.3)} {
+		det, _ := rot.Determinant()
+		if !soclose(det, 1, 1e-14) {
+			t.Errorf("Rotation determinant = %g, want 1", det)
+		}
+	}
+
+	//Scale by (2, 3) then translate by (1, -1)
+	s, _ := NewScaling(2, 3).Homogeneous()
+	transform, _ := NewTranslation(1, -1).Multiply(s)
+	h := NewMatrix(3, 1)
+	h.M = []float64{1, 1, 1}
+	res, _ := transform.Multiply(h)
+	if !alikeslices(res.M, []float64{3, 2, 1}) {
+		t.Errorf("Translation*Scaling*(1,1) = %v, want (3,2,1)", res.M)
+	}
+}
+
+func TestQuaternion(t *testing.T) {
+	q, err := NewQuaternionFromAxisAngle([]float64{0, 0, 2}, math.Pi/2)
+	if err != nil {
+		t.Fatalf("NewQuaternionFromAxisAngle() returned error %v", err)
+	}
+	rot := q.ToRotationMatrix()
+	expected := NewRotation3DZ(math.Pi / 2)
+	for i := range rot.M {
+		if math.Abs(rot.M[i]-expected.M[i]) > 1e-15 {
+			t.Errorf("ToRotationMatrix() = %v, want %v", rot.M, expected.M)
+			break
+		}
+	}
+
+	v, _ := q.Rotate([]float64{1, 0, 0})
+	if math.Abs(v[0]) > 1e-15 || !close(v[1], 1) {
+		t.Errorf("Rotate(1,0,0) = %v, want (0,1,0)", v)
+	}
+
+	for _, m := range []*Matrix{NewRotation3DX(2.5), NewRotation3DY(-3), NewRotation3DZ(0.1)} {
+		back, _ := FromRotationMatrix(m)
+		r := back.ToRotationMatrix()
+		for i := range r.M {
+			if math.Abs(r.M[i]-m.M[i]) > 1e-14 {
+				t.Errorf("FromRotationMatrix() round trip = %v, want %v", r.M, m.M)
+				break
+			}
+		}
+	}
+
+	//Half way between no rotation and 90 degrees around z is 45 degrees
+	half := Slerp(Quaternion{W: 1}, q, 0.5)
+	want, _ := NewQuaternionFromAxisAngle([]float64{0, 0, 1}, math.Pi/4)
+	if !close(half.W, want.W) || !close(half.Z, want.Z) {
+		t.Errorf("Slerp() = %v, want %v", half, want)
+	}
+	combined := q.Multiply(q)
+	if math.Abs(combined.W) > 1e-15 || !close(combined.Z, 1) {
+		t.Errorf("Multiply() = %v, want 180 degrees around z", combined)
+	}
+}
+
+func TestAffineTransform(t *testing.T) {
+	tr := NewAffineTransform(2)
+	tr, _ = tr.Scale(2, 2)
+	tr, _ = tr.Rotate2D(math.Pi / 2)
+	tr, err := tr.Translate(1, 0)
+	if err != nil {
+		t.Fatalf("AffineTransform returned error %v", err)
+	}
+
+	points := NewMatrix(2, 2)
+	points.SetRow(0, []float64{1, 0})
+	points.SetRow(1, []float64{0, 1})
+	res, _ := tr.Apply(points)
+	//(1,0) -> (2,0) -> (0,2) -> (1,2) and (0,1) -> (0,2) -> (-2,0) -> (-1,0)
+	expected := []float64{1, 2, -1, 0}
+	for i := range expected {
+		if math.Abs(res.M[i]-expected[i]) > 1e-14 {
+			t.Errorf("AffineTransform.Apply() = %v, want %v", res.M, expected)
+			break
+		}
+	}
+
+	inv, err := tr.Inverse()
+	if err != nil {
+		t.Fatalf("AffineTransform.Inverse() returned error %v", err)
+	}
+	back, _ := inv.Apply(res)
+	for i := range back.M {
+		if math.Abs(back.M[i]-points.M[i]) > 1e-14 {
+			t.Errorf("Inverse().Apply() = %v, want %v", back.M, points.M)
+			break
+		}
+	}
+
+	sh, _ := NewAffineTransform(2).Shear(0, 1, 3)
+	res, _ = sh.Apply(points)
+	if !alikeslices(res.M, []float64{1, 0, 3, 1}) {
+		t.Errorf("Shear(0, 1, 3).Apply() = %v", res.M)
+	}
+	if _, err := NewAffineTransform(3).Translate(1, 2); err == nil {
+		t.Errorf("Translate() with a wrong dimension should return an error")
+	}
+}
+
+func TestPermutation(t *testing.T) {
+	testMatrix := NewMatrix(3, 2)
+	testMatrix.SetRow(0, []float64{1, 2})
+	testMatrix.SetRow(1, []float64{3, 4})
+	testMatrix.SetRow(2, []float64{5, 6})
+
+	p := Permutation{2, 0, 1}
+	if !p.IsValid() || (Permutation{0, 0, 1}).IsValid() {
+		t.Errorf("IsValid() is wrong")
+	}
+	rows, _ := p.ApplyRows(testMatrix)
+	if !alikeslices(rows.M, []float64{5, 6, 1, 2, 3, 4}) {
+		t.Errorf("ApplyRows() = %v", rows.M)
+	}
+	prod, _ := p.Matrix().Multiply(testMatrix)
+	if !alikeslices(prod.M, rows.M) {
+		t.Errorf("Matrix()*A = %v, want %v", prod.M, rows.M)
+	}
+	back, _ := p.Inverse().ApplyRows(rows)
+	if !alikeslices(back.M, testMatrix.M) {
+		t.Errorf("Inverse().ApplyRows() = %v, want %v", back.M, testMatrix.M)
+	}
+
+	q := Permutation{1, 0}
+	cols, _ := q.ApplyColumns(testMatrix)
+	if !alikeslices(cols.M, []float64{2, 1, 4, 3, 6, 5}) {
+		t.Errorf("ApplyColumns() = %v", cols.M)
+	}
+
+	r := Permutation{0, 2, 1}
+	composed, _ := p.Compose(r)
+	twice, _ := p.ApplyRows(testMatrix)
+	twice, _ = r.ApplyRows(twice)
+	once, _ := composed.ApplyRows(testMatrix)
+	if !alikeslices(once.M, twice.M) {
+		t.Errorf("Compose().ApplyRows() = %v, want %v", once.M, twice.M)
+	}
+
+	if p.Sign() != 1 || r.Sign() != -1 || NewPermutation(4).Sign() != 1 {
+		t.Errorf("Sign() = %d, %d, want 1, -1", p.Sign(), r.Sign())
+	}
+}
+
+func TestGivensHouseholder(t *testing.T) {
+	v := []float64{3, 1, 4}
+	g, r := NewGivens(0, 2, v[0], v[2])
+	g.ApplyVector(v)
+	if !close(r, 5) || !close(v[0], 5) || math.Abs(v[2]) > 1e-15 || v[1] != 1 {
+		t.Errorf("Givens ApplyVector() = %v with r = %g, want [5 1 0]", v, r)
+	}
+
+	testMatrix := NewMatrix(3, 3)
+	testMatrix.SetRow(0, []float64{2, -1, 0})
+	testMatrix.SetRow(1, []float64{-1, 2, -1})
+	testMatrix.SetRow(2, []float64{0, -1, 2})
+	full, _ := g.Matrix(3).Multiply(testMatrix)
+	g.ApplyLeft(testMatrix)
+	for i := range full.M {
+		if !soclose(full.M[i], testMatrix.M[i], 1e-15) {
+			t.Errorf("Givens ApplyLeft() = %v, want %v", testMatrix.M, full.M)
+			break
+		}
+	}
+
+	x := []float64{0, 3, 0, 4}
+	h, alpha := NewHouseholder(1, x[1:])
+	h.ApplyVector(x)
+	if !close(math.Abs(alpha), 5) || !close(x[1], alpha) || math.Abs(x[3]) > 1e-15 || x[0] != 0 {
+		t.Errorf("Householder ApplyVector() = %v with alpha = %g", x, alpha)
+	}
+	//A reflector is orthogonal and symmetric, H*H = I
+	hm := h.Matrix(4)
+	h.ApplyRight(hm)
+	id := NewIdentity(4)
+	for i := range hm.M {
+		if math.Abs(hm.M[i]-id.M[i]) > 1e-15 {
+			t.Errorf("Householder H*H = %v, want identity", hm.M)
+			break
+		}
+	}
+}
+
+func TestOrthonormalize(t *testing.T) {
+	testMatrix := NewMatrix(3, 3)
+	testMatrix.SetRow(0, []float64{1, 2, 1})
+	testMatrix.SetRow(1, []float64{1, 2, 0})
+	testMatrix.SetRow(2, []float64{0, 0, 1})
+
+	q, rank := testMatrix.Orthonormalize()
+	if rank != 2 || q.NumberOfColumns != 2 {
+		t.Errorf("Orthonormalize() rank = %d, want 2", rank)
+	}
+	qt, _ := q.Transpose()
+	qtq, _ := qt.Multiply(q)
+	id := NewIdentity(2)
+	for i := range qtq.M {
+		if math.Abs(qtq.M[i]-id.M[i]) > 1e-15 {
+			t.Errorf("Q^T*Q = %v, want identity", qtq.M)
+			break
+		}
+	}
+}
+
+func TestInverseConditioning(t *testing.T) {
+	//Needs pivoting, the first element is zero
+	testMatrix := NewMatrix(3, 3)
+	testMatrix.SetRow(0, []float64{0, 1, 2})
+	testMatrix.SetRow(1, []float64{1, 0, 3})
+	testMatrix.SetRow(2, []float64{4, -3, 8})
+
+	det, _ := testMatrix.Determinant()
+	if !soclose(det, -2, 1e-14) {
+		t.Errorf("Determinant() = %g, want -2", det)
+	}
+	inv, err := testMatrix.Inverse()
+	if err != nil {
+		t.Fatalf("Inverse() returned error %v", err)
+	}
+	id, _ := testMatrix.Multiply(inv)
+	for i := range id.M {
+		if math.Abs(id.M[i]-NewIdentity(3).M[i]) > 1e-14 {
+			t.Errorf("A*A^-1 = %v, want identity", id.M)
+			break
+		}
+	}
+
+	singular := NewMatrix(2, 2)
+	singular.SetRow(0, []float64{1, 2})
+	singular.SetRow(1, []float64{2, 4})
+	if _, err := singular.Inverse(); err == nil {
+		t.Errorf("Inverse() of a singular matrix should return an error")
+	}
+	if rcond, _ := singular.RCond(); rcond != 0 {
+		t.Errorf("RCond() of a singular matrix = %g, want 0", rcond)
+	}
+
+	nearlySingular := NewMatrix(2, 2)
+	nearlySingular.SetRow(0, []float64{1, 1})
+	nearlySingular.SetRow(1, []float64{1, math.Nextafter(1, 2)})
+	if _, err := nearlySingular.Inverse(); err == nil {
+		t.Errorf("Inverse() of a nearly singular matrix should return an error")
+	}
+	if rcond, _ := NewIdentity(3).RCond(); rcond != 1 {
+		t.Errorf("RCond() of the identity = %g, want 1", rcond)
+	}
+}
+
+func TestDiagonal(t *testing.T) {
+	testMatrix := NewMatrix(3, 3)
+	testMatrix.SetRow(0, []float64{1, 2, 3})
+	testMatrix.SetRow(1, []float64{4, 5, 6})
+	testMatrix.SetRow(2, []float64{7, 8, 9})
+
+	if !alikeslices(testMatrix.Diagonal(0), []float64{1, 5, 9}) ||
+		!alikeslices(testMatrix.Diagonal(1), []float64{2, 6}) ||
+		!alikeslices(testMatrix.Diagonal(-2), []float64{7}) ||
+		len(testMatrix.Diagonal(3)) != 0 {
+		t.Errorf("Diagonal() = %v, %v, %v", testMatrix.Diagonal(0), testMatrix.Diagonal(1), testMatrix.Diagonal(-2))
+	}
+
+	if err := testMatrix.SetDiagonal(-1, []float64{0, 0}); err != nil || testMatrix.Get(1, 0) != 0 || testMatrix.Get(2, 1) != 0 {
+		t.Errorf("SetDiagonal(-1) = %v, error %v", testMatrix.M, err)
+	}
+	if err := testMatrix.SetDiagonal(1, []float64{0}); err == nil {
+		t.Errorf("SetDiagonal() with a wrong length should return an error")
+	}
+
+	anti, _ := testMatrix.AntiTrace()
+	if anti != 3+5+7 {
+		t.Errorf("AntiTrace() = %g, want %g", anti, 15.0)
+	}
+}
+
+func TestCovarianceCorrelation(t *testing.T) {
+	data := NewMatrix(4, 3)
+	data.SetRow(0, []float64{1, 2, 5})
+	data.SetRow(1, []float64{2, 4, 3})
+	data.SetRow(2, []float64{3, 6, 4})
+	data.SetRow(3, []float64{4, 8, 0})
+
+	cov, err := data.Covariance(true)
+	if err != nil {
+		t.Fatalf("Covariance() returned error %v", err)
+	}
+	//var(x) = 5/3, cov(x, 2x) = 10/3
+	if !close(cov.Get(0, 0), 5.0/3.0) || !close(cov.Get(0, 1), 10.0/3.0) || cov.Get(1, 0) != cov.Get(0, 1) {
+		t.Errorf("Covariance(true) = %v", cov.M)
+	}
+	biased, _ := data.Covariance(false)
+	if !close(biased.Get(0, 0), 5.0/4.0) {
+		t.Errorf("Covariance(false)[0][0] = %g, want %g", biased.Get(0, 0), 5.0/4.0)
+	}
+
+	corr, err := data.Correlation()
+	if err != nil {
+		t.Fatalf("Correlation() returned error %v", err)
+	}
+	if !close(corr.Get(0, 1), 1) || corr.Get(2, 2) != 1 || corr.Get(0, 2) >= 0 {
+		t.Errorf("Correlation() = %v", corr.M)
+	}
+
+	constant := NewMatrix(2, 2)
+	constant.SetRow(0, []float64{1, 1})
+	constant.SetRow(1, []float64{2, 1})
+	if _, err := constant.Correlation(); err == nil {
+		t.Errorf("Correlation() with a constant column should return an error")
+	}
+	//A constant which is not representable, its mean must still be exact
+	inexact := NewMatrix(3, 2)
+	inexact.M = []float64{0.1, 1, 0.1, 2, 0.1, 4}
+	if c, err := inexact.Correlation(); err == nil {
+		t.Errorf("Correlation() with a column of 0.1 = %v, want an error", c.M)
+	}
+	if c, err := inexact.Covariance(true); err != nil || c.Get(0, 0) != 0 || c.Get(0, 1) != 0 {
+		t.Errorf("Covariance() with a column of 0.1 = %v, error %v", c, err)
+	}
+}
+
+func TestToeplitzCirculant(t *testing.T) {
+	firstRow := []float64{4, 1, 0.5, 0.2}
+	firstCol := []float64{4, 2, -1, 0.3}
+	tm, err := NewToeplitz(firstRow, firstCol)
+	if err != nil || tm.Get(2, 1) != 2 || tm.Get(1, 3) != 0.5 || tm.Get(3, 0) != 0.3 {
+		t.Fatalf("NewToeplitz() = %v, error %v", tm, err)
+	}
+
+	b := []float64{1, 2, 3, 4}
+	x, err := SolveToeplitz(firstRow, firstCol, b)
+	if err != nil {
+		t.Fatalf("SolveToeplitz() returned error %v", err)
+	}
+	xm := NewMatrix(4, 1)
+	xm.M = x
+	check, _ := tm.Multiply(xm)
+	for i := range b {
+		if !soclose(check.M[i], b[i], 1e-13) {
+			t.Errorf("T*SolveToeplitz() = %v, want %v", check.M, b)
+			break
+		}
+	}
+
+	c := []float64{5, 1, 2}
+	cm := NewCirculant(c)
+	if !alikeslices(cm.GetRow(0), []float64{5, 2, 1}) || !alikeslices(cm.GetColumn(1), []float64{2, 5, 1}) {
+		t.Errorf("NewCirculant() = %v", cm.M)
+	}
+	x, err = SolveCirculant(c, []float64{1, 0, 0})
+	if err != nil {
+		t.Fatalf("SolveCirculant() returned error %v", err)
+	}
+	xm = NewMatrix(3, 1)
+	xm.M = x
+	check, _ = cm.Multiply(xm)
+	if !soclose(check.M[0], 1, 1e-14) || math.Abs(check.M[1]) > 1e-14 || math.Abs(check.M[2]) > 1e-14 {
+		t.Errorf("C*SolveCirculant() = %v, want [1 0 0]", check.M)
+	}
+	//A cyclic shift has a zero leading element, Levinson's method can't solve it
+	x, err = SolveCirculant([]float64{0, 1, 0}, []float64{1, 2, 3})
+	if err != nil || !soclose(x[0], 2, 1e-14) || !soclose(x[1], 3, 1e-14) || !soclose(x[2], 1, 1e-14) {
+		t.Errorf("SolveCirculant() of a shift = %v, error %v", x, err)
+	}
+	//The eigenvalue for k = 0 is the sum of c
+	if _, err := SolveCirculant([]float64{1, -1, 0, 0}, []float64{1, 2, 3, 4}); err == nil {
+		t.Errorf("SolveCirculant() of a singular matrix should fail")
+	}
+}
+
+func TestVandermondeHilbert(t *testing.T) {
+	v := NewVandermonde([]float64{1, 2, 3}, 3)
+	if v.NumberOfRows != 3 || v.NumberOfColumns != 4 || !alikeslices(v.GetRow(2), []float64{1, 3, 9, 27}) {
+		t.Errorf("NewVandermonde() = %v", v)
+	}
+
+	h := NewHilbert(4)
+	if h.Get(0, 0) != 1 || h.Get(3, 3) != 1.0/7.0 || h.Get(1, 2) != h.Get(2, 1) {
+		t.Errorf("NewHilbert() = %v", h.M)
+	}
+	//Hilbert of order 4 has a condition number of about 28375 in the 1-norm
+	rcond, _ := h.RCond()
+	if !soclose(1/rcond, 28375, 1e-6) {
+		t.Errorf("NewHilbert(4).RCond() = %g, want %g", rcond, 1/28375.0)
+	}
+	if _, err := NewHilbert(13).Inverse(); err == nil {
+		t.Errorf("NewHilbert(13).Inverse() should be flagged as unreliable")
+	}
+}
+
+func TestAdaptiveSimpson(t *testing.T) {
+	sup := 4.59
+	inf := 2.87
+	//New function
+	x := func(w float64) float64 {
+		return math.Log(w) / w
+	}
+	prim := func(j float64) float64 {
+		return math.Log(j) * math.Log(j) / 2
+	}
+	result := prim(sup) - prim(inf)
+	z, err := AdaptiveSimpson(inf, sup, x, 1e-12)
+	fmt.Printf("AdaptiveSimpson(%g, %g) = %g, want %g\n", inf, sup, z, result)
+	if err != nil || !soclose(z, result, 1e-12) {
+		t.Errorf("AdaptiveSimpson(%g, %g) = %g, want %g, error %v", inf, sup, z, result, err)
+	}
+
+	//A sharp peak in a flat region
+	peak := func(w float64) float64 {
+		return 1 / (1e-4 + w*w)
+	}
+	z, err = AdaptiveSimpson(-1, 1, peak, 1e-10)
+	result = 2 * 100 * math.Atan(100)
+	if err != nil || !soclose(z, result, 1e-10) {
+		t.Errorf("AdaptiveSimpson(peak) = %g, want %g, error %v", z, result, err)
+	}
+}
+
+func TestGaussKronrod(t *testing.T) {
+	sup := 4.59
+	inf := 2.87
+	//New function
+	x := func(w float64) float64 {
+		return math.Log(w) / w
+	}
+	prim := func(j float64) float64 {
+		return math.Log(j) * math.Log(j) / 2
+	}
+	result := prim(sup) - prim(inf)
+	z, e := GaussKronrod(inf, sup, x)
+	fmt.Printf("GaussKronrod(%g, %g) = %g, want %g, estimated error %g\n", inf, sup, z, result, e)
+	if !soclose(z, result, 1e-13) || e > 1e-10 {
+		t.Errorf("GaussKronrod(%g, %g) = %g, want %g, estimated error %g", inf, sup, z, result, e)
+	}
+
+	//Integrable singularity at 0, needs many subdivisions
+	sing := func(w float64) float64 {
+		return math.Log(w) / math.Sqrt(w)
+	}
+	z, e, err := AdaptiveGaussKronrod(0, 1, sing, 1e-10, 0)
+	if err != nil || !soclose(z, -4, 1e-10) || e > 1e-10 {
+		t.Errorf("AdaptiveGaussKronrod() = %g, want -4, estimated error %g, error %v", z, e, err)
+	}
+
+	_, _, err = AdaptiveGaussKronrod(0, 1, sing, 1e-14, 3)
+	if err == nil {
+		t.Errorf("AdaptiveGaussKronrod() with too few intervals should return an error")
+	}
+}
+
+func TestImproperIntegral(t *testing.T) {
+	gauss := func(w float64) float64 {
+		return math.Exp(-w * w)
+	}
+	z, _, err := ImproperIntegral(math.Inf(-1), math.Inf(1), gauss, 1e-12)
+	if err != nil || !soclose(z, math.Sqrt(math.Pi), 1e-12) {
+		t.Errorf("ImproperIntegral(-inf, inf) = %g, want %g, error %v", z, math.Sqrt(math.Pi), err)
+	}
+
+	expo := func(w float64) float64 {
+		return math.Exp(-w)
+	}
+	z, _, err = ImproperIntegral(1, math.Inf(1), expo, 1e-12)
+	if err != nil || !soclose(z, math.Exp(-1), 1e-12) {
+		t.Errorf("ImproperIntegral(1, inf) = %g, want %g, error %v", z, math.Exp(-1), err)
+	}
+
+	lorentz := func(w float64) float64 {
+		return 1 / (1 + w*w)
+	}
+	z, _, err = ImproperIntegral(math.Inf(-1), 0, lorentz, 1e-12)
+	if err != nil || !soclose(z, math.Pi/2, 1e-12) {
+		t.Errorf("ImproperIntegral(-inf, 0) = %g, want %g, error %v", z, math.Pi/2, err)
+	}
+}
+
+func TestClenshawCurtis(t *testing.T) {
+	sup := 4.59
+	inf := 2.87
+	//New function
+	x := func(w float64) float64 {
+		return math.Log(w) / w
+	}
+	prim := func(j float64) float64 {
+		return math.Log(j) * math.Log(j) / 2
+	}
+	result := prim(sup) - prim(inf)
+	calls := 0
+	counted := func(w float64) float64 {
+		calls++
+		return x(w)
+	}
+	z, e, err := ClenshawCurtis(inf, sup, counted, 1e-13)
+	fmt.Printf("ClenshawCurtis(%g, %g) = %g, want %g, %d evaluations\n", inf, sup, z, result, calls)
+	if err != nil || !soclose(z, result, 1e-13) || e > 1e-13 {
+		t.Errorf("ClenshawCurtis(%g, %g) = %g, want %g, error %v", inf, sup, z, result, err)
+	}
+	//Nested points: every level only adds new points, n+1 evaluations for the last level
+	if (calls-1)&(calls-2) != 0 {
+		t.Errorf("ClenshawCurtis() used %d evaluations, want 2^k+1", calls)
+	}
+}
+
+func TestCubature(t *testing.T) {
+	//Integral of exp(x+y+z) over the unit cube is (e-1)^3
+	f := func(x []float64) float64 {
+		return math.Exp(x[0] + x[1] + x[2])
+	}
+	result := math.Pow(math.E-1, 3)
+	z, e, err := Cubature(f, []float64{0, 0, 0}, []float64{1, 1, 1}, 1e-10)
+	fmt.Printf("Cubature() = %g, want %g, estimated error %g\n", z, result, e)
+	if err != nil || !soclose(z, result, 1e-10) {
+		t.Errorf("Cubature() = %g, want %g, error %v", z, result, err)
+	}
+
+	//Gaussian peak in 2-D
+	g := func(x []float64) float64 {
+		return math.Exp(-100 * (x[0]*x[0] + x[1]*x[1]))
+	}
+	z, _, err = Cubature(g, []float64{-1, -1}, []float64{1, 1}, 1e-9)
+	result = math.Pi / 100 * math.Pow(math.Erf(10), 2)
+	if err != nil || !soclose(z, result, 1e-8) {
+		t.Errorf("Cubature(gaussian) = %g, want %g, error %v", z, result, err)
+	}
+
+	if _, _, err := Cubature(g, []float64{0}, []float64{1}, 1e-9); err == nil {
+		t.Errorf("Cubature() in 1-D should return an error")
+	}
+
+	//An impossible tolerance stops after a million evaluations
+	var calls int
+	counted := func(x []float64) float64 {
+		calls++
+		return math.Abs(x[0] - x[1])
+	}
+	if _, _, err := Cubature(counted, []float64{0, 0}, []float64{1, 1}, 0); err == nil || calls > 1000000 || calls < 900000 {
+		t.Errorf("Cubature() with a tolerance of 0 = %v after %d evaluations", err, calls)
+	}
+}
+
+func TestDouble(t *testing.T) {
+	f := func(x, y float64) float64 {
+		return x * y * y
+	}
+	z, err := Double(f, 0, 2, 1, 3, 1e-10)
+	//x^2/2 * y^3/3 = 2 * 26/3
+	if err != nil || !soclose(z, 52.0/3.0, 1e-10) {
+		t.Errorf("Double() = %g, want %g, error %v", z, 52.0/3.0, err)
+	}
+
+	//Area of the unit disk
+	one := func(x, y float64) float64 {
+		return 1
+	}
+	lower := func(x float64) float64 {
+		return -math.Sqrt(1 - x*x)
+	}
+	upper := func(x float64) float64 {
+		return math.Sqrt(1 - x*x)
+	}
+	z, err = DoubleRegion(one, -1, 1, lower, upper, 1e-9)
+	if err != nil || !soclose(z, math.Pi, 1e-9) {
+		t.Errorf("DoubleRegion(disk) = %g, want %g, error %v", z, math.Pi, err)
+	}
+}
+
+func TestCumulativeIntegral(t *testing.T) {
+	x, values := CumulativeIntegral(0, math.Pi, math.Sin, 100)
+	if len(x) != 101 || values[0] != 0 {
+		t.Fatalf("CumulativeIntegral() returned %d points", len(x))
+	}
+	for i := range x {
+		if !soclose(values[i], 1-math.Cos(x[i]), 1e-8) && math.Abs(values[i]-(1-math.Cos(x[i]))) > 1e-12 {
+			t.Errorf("CumulativeIntegral()[%d] = %g, want %g", i, values[i], 1-math.Cos(x[i]))
+		}
+	}
+
+	cdf := Antiderivative(0, math.Pi, math.Sin, 50)
+	for _, p := range []float64{0.1234, 1, 2.5, math.Pi} {
+		if !soclose(cdf(p), 1-math.Cos(p), 1e-8) {
+			t.Errorf("Antiderivative()(%g) = %g, want %g", p, cdf(p), 1-math.Cos(p))
+		}
+	}
+}
+
+func TestIntegrationResult(t *testing.T) {
+	sup := 4.59
+	inf := 2.87
+	//New function
+	x := func(w float64) float64 {
+		return math.Log(w) / w
+	}
+	prim := func(j float64) float64 {
+		return math.Log(j) * math.Log(j) / 2
+	}
+	result := prim(sup) - prim(inf)
+
+	r := RombergWithResult(inf, sup, x, 0, 1e-12)
+	if !r.Converged || r.Evaluations == 0 || !soclose(r.Value, result, 1e-12) {
+		t.Errorf("RombergWithResult() = %+v, want %g", r, result)
+	}
+	r = RombergWithResult(inf, sup, x, 3, 1e-12)
+	if r.Converged || r.Evaluations != 5 {
+		t.Errorf("RombergWithResult() with 3 steps = %+v, should not converge", r)
+	}
+
+	r = TrapezoidalWithResult(inf, sup, x, 1000, 1e-6)
+	if !r.Converged || r.Evaluations != 1001 || math.Abs(r.Value-result) > 2*r.Error {
+		t.Errorf("TrapezoidalWithResult() = %+v, want %g", r, result)
+	}
+
+	r, err := SimpsonWithResult(inf, sup, x, 100)
+	if err != nil || !r.Converged || math.Abs(r.Value-result) > 2*r.Error {
+		t.Errorf("SimpsonWithResult() = %+v, want %g", r, result)
+	}
+
+	for name, r := range map[string]IntegrationResult{
+		"AdaptiveSimpson":      AdaptiveSimpsonWithResult(inf, sup, x, 1e-12),
+		"AdaptiveGaussKronrod": AdaptiveGaussKronrodWithResult(inf, sup, x, 1e-12, 0),
+		"ClenshawCurtis":       ClenshawCurtisWithResult(inf, sup, x, 1e-12),
+		"ImproperIntegral":     ImproperIntegralWithResult(inf, sup, x, 1e-12),
+	} {
+		if !r.Converged || r.Evaluations == 0 || !soclose(r.Value, result, 1e-12) {
+			t.Errorf("%sWithResult() = %+v, want %g", name, r, result)
+		}
+	}
+}
+
+func TestFilon(t *testing.T) {
+	omega := 1000.0
+	f := func(w float64) float64 {
+		return math.Exp(w)
+	}
+	//Integral of e^x*sin(wx) is e^x*(sin(wx) - w*cos(wx))/(1+w^2)
+	prim := func(w float64) float64 {
+		return math.Exp(w) * (math.Sin(omega*w) - omega*math.Cos(omega*w)) / (1 + omega*omega)
+	}
+	result := prim(1) - prim(0)
+	z, err := FilonSin(0, 1, f, omega, 40)
+	fmt.Printf("FilonSin() = %g, want %g\n", z, result)
+	if err != nil || !soclose(z, result, 1e-6) {
+		t.Errorf("FilonSin() = %g, want %g, error %v", z, result, err)
+	}
+
+	primCos := func(w float64) float64 {
+		return math.Exp(w) * (math.Cos(omega*w) + omega*math.Sin(omega*w)) / (1 + omega*omega)
+	}
+	result = primCos(1) - primCos(0)
+	z, err = FilonCos(0, 1, f, omega, 40)
+	if err != nil || !soclose(z, result, 1e-6) {
+		t.Errorf("FilonCos() = %g, want %g, error %v", z, result, err)
+	}
+
+	//Small omega uses the series
+	z, _ = FilonCos(0, math.Pi/2, func(float64) float64 { return 1 }, 1, 200)
+	if !soclose(z, 1, 1e-10) {
+		t.Errorf("FilonCos(1, omega=1) = %g, want 1", z)
+	}
+	if _, err := FilonSin(0, 1, f, omega, 41); err == nil {
+		t.Errorf("FilonSin() with an odd number of intervals should return an error")
+	}
+}
+
+func TestPrincipalValue(t *testing.T) {
+	//PV of 1/(x-c) between 0 and 3 with c = 1 is ln(2)
+	inv := func(w float64) float64 {
+		return 1 / (w - 1)
+	}
+	z, err := PrincipalValue(0, 3, inv, 1, 1e-10)
+	if err != nil || !soclose(z, math.Log(2), 1e-9) {
+		t.Errorf("PrincipalValue(1/(x-1)) = %g, want %g, error %v", z, math.Log(2), err)
+	}
+
+	//PV of e^x/x between -1 and 1 is 2*Shi(1)
+	ex := func(w float64) float64 {
+		return math.Exp(w) / w
+	}
+	result := 2 * 1.0572508753757285
+	z, err = PrincipalValue(-1, 1, ex, 0, 1e-10)
+	if err != nil || !soclose(z, result, 1e-9) {
+		t.Errorf("PrincipalValue(e^x/x) = %g, want %g, error %v", z, result, err)
+	}
+
+	if _, err := PrincipalValue(0, 1, inv, 2, 1e-10); err == nil {
+		t.Errorf("PrincipalValue() with a pole outside should return an error")
+	}
+}
+
+func TestParallelIntegration(t *testing.T) {
+	sup := 4.59
+	inf := 2.87
+	//New function
+	x := func(w float64) float64 {
+		return math.Log(w) / w
+	}
+	prim := func(j float64) float64 {
+		return math.Log(j) * math.Log(j) / 2
+	}
+	result := prim(sup) - prim(inf)
+
+	sequential, _ := Simpson(inf, sup, x, 1000)
+	z, err := ParallelSimpson(inf, sup, x, 1000, 3)
+	if err != nil || !soclose(z, sequential, 1e-13) {
+		t.Errorf("ParallelSimpson() = %g, want %g, error %v", z, sequential, err)
+	}
+	z, _ = ParallelSimpson(inf, sup, x, 2, 0)
+	single, _ := Simpson(inf, sup, x, 2)
+	if z != single {
+		t.Errorf("ParallelSimpson() with 2 intervals = %g, want %g", z, single)
+	}
+
+	z, e := ParallelGaussKronrod(inf, sup, x, 16, 4)
+	if !soclose(z, result, 1e-14) || e > 1e-12 {
+		t.Errorf("ParallelGaussKronrod() = %g, want %g, estimated error %g", z, result, e)
+	}
+}
+
+func TestIntegrationContext(t *testing.T) {
+	x := func(w float64) float64 {
+		return math.Exp(-w * w)
+	}
+	result := math.Sqrt(math.Pi) / 2 * math.Erf(2)
+
+	ctx := context.Background()
+	z, err := AdaptiveSimpsonContext(ctx, 0, 2, x, 1e-10, 0)
+	if err != nil || !soclose(z, result, 1e-9) {
+		t.Errorf("AdaptiveSimpsonContext() = %g, %v, want %g", z, err, result)
+	}
+	z, _, err = AdaptiveGaussKronrodContext(ctx, 0, 2, x, 1e-12, 0)
+	if err != nil || !soclose(z, result, 1e-12) {
+		t.Errorf("AdaptiveGaussKronrodContext() = %g, %v, want %g", z, err, result)
+	}
+	z, _, err = ClenshawCurtisContext(ctx, 0, 2, x, 1e-12, 0)
+	if err != nil || !soclose(z, result, 1e-12) {
+		t.Errorf("ClenshawCurtisContext() = %g, %v, want %g", z, err, result)
+	}
+	z, _, err = ImproperIntegralContext(ctx, 0, math.Inf(1), x, 1e-10, 0)
+	if err != nil || !soclose(z, math.Sqrt(math.Pi)/2, 1e-9) {
+		t.Errorf("ImproperIntegralContext() = %g, %v, want %g", z, err, math.Sqrt(math.Pi)/2)
+	}
+
+	//A budget that is too small stops the integration
+	var calls int
+	wild := func(w float64) float64 {
+		calls++
+		return math.Sin(1 / w)
+	}
+	_, err = AdaptiveSimpsonContext(ctx, 1e-6, 1, wild, 1e-14, 500)
+	if e, ok := err.(*MathError); !ok || e.code != errorBudgetExceeded || calls > 500 {
+		t.Errorf("AdaptiveSimpsonContext() with a budget = %v after %d calls", err, calls)
+	}
+	calls = 0
+	_, estimate, err := AdaptiveGaussKronrodContext(ctx, 1e-6, 1, wild, 1e-14, 500)
+	if e, ok := err.(*MathError); !ok || e.code != errorBudgetExceeded || calls > 500 || estimate <= 1e-14 {
+		t.Errorf("AdaptiveGaussKronrodContext() with a budget = %v, estimate %g after %d calls", err, estimate, calls)
+	}
+	calls = 0
+	_, _, err = ClenshawCurtisContext(ctx, 1e-6, 1, wild, 1e-14, 500)
+	if e, ok := err.(*MathError); !ok || e.code != errorBudgetExceeded || calls > 500 {
+		t.Errorf("ClenshawCurtisContext() with a budget = %v after %d calls", err, calls)
+	}
+
+	//Without a budget, the default of a million evaluations applies
+	calls = 0
+	_, _, err = AdaptiveGaussKronrodContext(ctx, 1e-6, 1, wild, 0, 0)
+	if e, ok := err.(*MathError); !ok || e.code != errorBudgetExceeded || calls > 1000000 {
+		t.Errorf("AdaptiveGaussKronrodContext() without a budget = %v after %d calls", err, calls)
+	}
+
+	//A cancelled context stops the integration
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	calls = 0
+	_, err = AdaptiveSimpsonContext(cancelled, 1e-6, 1, wild, 1e-14, 0)
+	if err != context.Canceled || calls != 0 {
+		t.Errorf("AdaptiveSimpsonContext() with a cancelled context = %v after %d calls", err, calls)
+	}
+	_, _, err = CubatureContext(cancelled, func(v []float64) float64 { return v[0] * v[1] }, []float64{0, 0}, []float64{1, 1}, 1e-10, 0)
+	if err != context.Canceled {
+		t.Errorf("CubatureContext() with a cancelled context = %v", err)
+	}
+	timeout, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+	slow := func(w float64) float64 {
+		time.Sleep(time.Millisecond)
+		return math.Sin(1 / w)
+	}
+	_, _, err = AdaptiveGaussKronrodContext(timeout, 1e-9, 1, slow, 1e-15, 0)
+	if err != context.DeadlineExceeded {
+		t.Errorf("AdaptiveGaussKronrodContext() with a timeout = %v", err)
+	}
+	v, _, err := CubatureContext(ctx, func(v []float64) float64 { return v[0] * v[1] }, []float64{0, 0}, []float64{1, 1}, 1e-10, 0)
+	if err != nil || !soclose(v, 0.25, 1e-12) {
+		t.Errorf("CubatureContext() = %g, %v, want 0.25", v, err)
+	}
+}
+
+func TestSimpsonOddAndTolerance(t *testing.T) {
+	sup := 4.59
+	inf := 2.87
+	//New function
+	x := func(w float64) float64 {
+		return math.Log(w) / w
+	}
+	prim := func(j float64) float64 {
+		return math.Log(j) * math.Log(j) / 2
+	}
+	result := prim(sup) - prim(inf)
+
+	for _, n := range []int{3, 5, 101, 1001} {
+		odd, err := Simpson(inf, sup, x, n)
+		even, _ := Simpson(inf, sup, x, n-1)
+		if err != nil || math.Abs(odd-result) > 2*math.Abs(even-result)+1e-15 {
+			t.Errorf("Simpson() with %d intervals = %g, want %g, error %v", n, odd, result, err)
+		}
+		parallel, err := ParallelSimpson(inf, sup, x, n, 4)
+		if err != nil || !soclose(parallel, odd, 1e-13) {
+			t.Errorf("ParallelSimpson() with %d intervals = %g, want %g, error %v", n, parallel, odd, err)
+		}
+	}
+	cubic := func(w float64) float64 {
+		return w * w * w
+	}
+	if z, _ := Simpson(0, 2, cubic, 3); !soclose(z, 4, 1e-15) {
+		t.Errorf("Simpson() 3/8 rule on a cubic = %g, want 4", z)
+	}
+	if _, err := Simpson(inf, sup, x, 1); err == nil {
+		t.Errorf("Simpson() with 1 interval should fail")
+	}
+
+	z, err := SimpsonWithTolerance(inf, sup, x, 1e-12)
+	if err != nil || !soclose(z, result, 1e-11) {
+		t.Errorf("SimpsonWithTolerance() = %g, want %g, error %v", z, result, err)
+	}
+	_, err = SimpsonWithTolerance(0, 1, math.Sqrt, 1e-18)
+	if e, ok := err.(*MathError); !ok || e.code != errorNotConverged {
+		t.Errorf("SimpsonWithTolerance() with an impossible tolerance = %v", err)
+	}
+}
+
+func TestVectorComplexIntegral(t *testing.T) {
+	var calls int
+	f := func(x float64) []float64 {
+		calls++
+		return []float64{math.Sin(x), x * x, math.Exp(x)}
+	}
+	want := []float64{1 - math.Cos(2), 8.0 / 3, math.Exp(2) - 1}
+
+	v, err := SimpsonVector(0, 2, f, 101)
+	if err != nil || calls != 102 {
+		t.Errorf("SimpsonVector() evaluated f %d times, error %v", calls, err)
+	}
+	for k := range want {
+		s, _ := Simpson(0, 2, func(x float64) float64 { return f(x)[k] }, 101)
+		if v[k] != s {
+			t.Errorf("SimpsonVector()[%d] = %g, want %g", k, v[k], s)
+		}
+	}
+
+	//A function reusing its output
+	buffer := make([]float64, 3)
+	reused := func(x float64) []float64 {
+		buffer[0], buffer[1], buffer[2] = math.Sin(x), x*x, math.Exp(x)
+		return buffer
+	}
+	if r, err := SimpsonVector(0, 2, reused, 101); err != nil || !reflect.DeepEqual(r, v) {
+		t.Errorf("SimpsonVector() of a function reusing its output = %v, want %v", r, v)
+	}
+
+	calls = 0
+	v, e, err := AdaptiveGaussKronrodVector(0, 2, f, 1e-12, 0)
+	if err != nil || e > 1e-12 || calls%15 != 0 {
+		t.Errorf("AdaptiveGaussKronrodVector() = %v, estimate %g, %d calls, error %v", v, e, calls, err)
+	}
+	for k := range want {
+		if !soclose(v[k], want[k], 1e-13) {
+			t.Errorf("AdaptiveGaussKronrodVector()[%d] = %g, want %g", k, v[k], want[k])
+		}
+	}
+	if r, _, err := AdaptiveGaussKronrodVector(0, 2, reused, 1e-12, 0); err != nil || !reflect.DeepEqual(r, v) {
+		t.Errorf("AdaptiveGaussKronrodVector() of a function reusing its output = %v, want %v", r, v)
+	}
+
+	//Fourier coefficient of x on [-pi, pi]: integral of x*exp(-i*3*x) is -2*pi*i/3
+	g := func(x float64) complex128 {
+		return complex(x, 0) * cmplx.Exp(complex(0, -3*x))
+	}
+	c, _, err := AdaptiveGaussKronrodComplex(-math.Pi, math.Pi, g, 1e-12, 0)
+	if err != nil || cmplx.Abs(c-complex(0, -2*math.Pi/3)) > 1e-12 {
+		t.Errorf("AdaptiveGaussKronrodComplex() = %v, want %v, error %v", c, complex(0, -2*math.Pi/3), err)
+	}
+	c, err = SimpsonComplex(-math.Pi, math.Pi, g, 1000)
+	if err != nil || cmplx.Abs(c-complex(0, -2*math.Pi/3)) > 1e-8 {
+		t.Errorf("SimpsonComplex() = %v, want %v, error %v", c, complex(0, -2*math.Pi/3), err)
+	}
+}
+
+func TestLineIntegral(t *testing.T) {
+	circle := func(r float64) Curve {
+		return func(t float64) (float64, float64) {
+			return r * math.Cos(t), r * math.Sin(t)
+		}
+	}
+	one := func(x, y float64) float64 { return 1 }
+
+	l, err := LineIntegral(circle(2), one, 0, 2*math.Pi, 1e-10)
+	if err != nil || !soclose(l, 4*math.Pi, 1e-10) {
+		t.Errorf("LineIntegral() length of a circle = %g, want %g, error %v", l, 4*math.Pi, err)
+	}
+	l, err = LineIntegral(circle(1), func(x, y float64) float64 { return x * x }, 0, 2*math.Pi, 1e-10)
+	if err != nil || !soclose(l, math.Pi, 1e-10) {
+		t.Errorf("LineIntegral() of x^2 = %g, want %g, error %v", l, math.Pi, err)
+	}
+	//Length of the parabola y = x^2 between 0 and 1
+	parabola := func(t float64) (float64, float64) { return t, t * t }
+	want := math.Sqrt(5)/2 + math.Asinh(2)/4
+	l, err = LineIntegral(parabola, one, 0, 1, 1e-10)
+	if err != nil || !soclose(l, want, 1e-10) {
+		t.Errorf("LineIntegral() length of a parabola = %g, want %g, error %v", l, want, err)
+	}
+
+	rotation := func(x, y float64) (float64, float64) { return -y, x }
+	w, err := WorkIntegral(circle(1), rotation, 0, 2*math.Pi, 1e-10)
+	if err != nil || !soclose(w, 2*math.Pi, 1e-10) {
+		t.Errorf("WorkIntegral() = %g, want %g, error %v", w, 2*math.Pi, err)
+	}
+	//A gradient field gives the difference of the potential x*y
+	gradient := func(x, y float64) (float64, float64) { return y, x }
+	w, err = WorkIntegral(parabola, gradient, 1, 0, 1e-10)
+	if err != nil || !soclose(w, -1, 1e-10) {
+		t.Errorf("WorkIntegral() of a gradient = %g, want -1, error %v", w, err)
+	}
+}
+
+func TestGaussChebyshev(t *testing.T) {
+	square := func(x float64) float64 { return x * x }
+	one := func(x float64) float64 { return 1 }
+
+	//Exact for polynomials of degree up to 2n-1
+	if z := NewGaussChebyshev(2).Integrate(square); !soclose(z, math.Pi/2, 1e-15) {
+		t.Errorf("NewGaussChebyshev(2) on x^2 = %g, want %g", z, math.Pi/2)
+	}
+	if z := NewGaussChebyshevSecondKind(2).Integrate(square); !soclose(z, math.Pi/8, 1e-15) {
+		t.Errorf("NewGaussChebyshevSecondKind(2) on x^2 = %g, want %g", z, math.Pi/8)
+	}
+	if z := GaussChebyshev(0, 2, one, 1); !soclose(z, math.Pi, 1e-15) {
+		t.Errorf("GaussChebyshev(0, 2) on 1 = %g, want %g", z, math.Pi)
+	}
+	//Half of the area of a circle of radius 3
+	if z := GaussChebyshevSecondKind(-3, 3, one, 3); !soclose(z, 9*math.Pi/2, 1e-14) {
+		t.Errorf("GaussChebyshevSecondKind(-3, 3) on 1 = %g, want %g", z, 9*math.Pi/2)
+	}
+	//The integral of cos(x)/sqrt(1-x^2) is pi*J0(1)
+	if z := GaussChebyshev(-1, 1, math.Cos, 10); !soclose(z, math.Pi*math.J0(1), 1e-14) {
+		t.Errorf("GaussChebyshev() on cos = %g, want %g", z, math.Pi*math.J0(1))
+	}
+}
+
+func TestRombergTable(t *testing.T) {
+	sup := 4.59
+	inf := 2.87
+	//New function
+	x := func(w float64) float64 {
+		return math.Log(w) / w
+	}
+	prim := func(j float64) float64 {
+		return math.Log(j) * math.Log(j) / 2
+	}
+	result := prim(sup) - prim(inf)
+
+	r := RombergTable(inf, sup, x, 0, 1e-13)
+	if !r.Converged || !soclose(r.Value, result, 1e-13) || r.Levels != len(r.Table) {
+		t.Errorf("RombergTable() = %g, want %g, levels %d, error %g", r.Value, result, r.Levels, r.Error)
+	}
+	//2^(levels-1)+1 evaluations
+	if r.Evaluations != 1<<uint(r.Levels-1)+1 {
+		t.Errorf("RombergTable() evaluated f %d times with %d levels", r.Evaluations, r.Levels)
+	}
+	//The first extrapolation of the table is Simpson's rule
+	simpson, _ := Simpson(inf, sup, x, 4)
+	if !soclose(r.Table[2][1], simpson, 1e-14) {
+		t.Errorf("RombergTable() Table[2][1] = %g, want Simpson %g", r.Table[2][1], simpson)
+	}
+	//sqrt is not smooth at 0, the table converges slowly
+	r = RombergTable(0, 1, math.Sqrt, 8, 1e-12)
+	if r.Converged || r.Levels != 8 || r.Error < 1e-6 || len(r.Table[7]) != 8 {
+		t.Errorf("RombergTable() of sqrt = %+v, should not converge", r.IntegrationResult)
+	}
+}
+
+func TestDerivativeN(t *testing.T) {
+	//New function
+	x := func(w float64) float64 {
+		return math.Log(w) / w
+	}
+	//(2 ln(w) - 3)/w^3
+	result := (2*math.Log(2.0) - 3) / 8
+	z := Derivative2(2.0, x, 1e-12)
+	if !soclose(z, result, 1e-9) {
+		t.Errorf("Derivative2(%g) = %g, want %g", 2.0, z, result)
+	}
+
+	for order := 0; order <= 4; order++ {
+		//Derivatives of sin cycle through cos, -sin, -cos, sin
+		want := math.Sin(1 + float64(order)*math.Pi/2)
+		z = DerivativeN(1, math.Sin, order, 1e-12)
+		if !soclose(z, want, math.Pow(10, float64(order)-11)) {
+			t.Errorf("DerivativeN(1, sin, %d) = %g, want %g", order, z, want)
+		}
+	}
+	z = DerivativeN(100, math.Exp, 3, 1e-12)
+	if !soclose(z, math.Exp(100), 1e-7) {
+		t.Errorf("DerivativeN(100, exp, 3) = %g, want %g", z, math.Exp(100))
+	}
+	cubic := func(w float64) float64 { return w * w * w }
+	z = DerivativeN(-3, cubic, 3, 1e-12)
+	if !soclose(z, 6, 1e-9) {
+		t.Errorf("DerivativeN(-3, cubic, 3) = %g, want 6", z)
+	}
+}
+
+func TestGradient(t *testing.T) {
+	//f(x, y, z) = x^2*y + sin(z)
+	f := func(v []float64) float64 {
+		return v[0]*v[0]*v[1] + math.Sin(v[2])
+	}
+	x := []float64{1.5, -2, 0.5}
+	want := []float64{2 * 1.5 * -2, 1.5 * 1.5, math.Cos(0.5)}
+
+	if z := Partial(x, f, 1, 1e-9); !soclose(z, want[1], 1e-9) {
+		t.Errorf("Partial(x, f, 1) = %g, want %g", z, want[1])
+	}
+	g := Gradient(x, f, 1e-9)
+	for i := range want {
+		if !soclose(g[i], want[i], 1e-9) {
+			t.Errorf("Gradient()[%d] = %g, want %g", i, g[i], want[i])
+		}
+	}
+	if x[0] != 1.5 || x[1] != -2 || x[2] != 0.5 {
+		t.Errorf("Gradient() modified x: %v", x)
+	}
+}
+
+func TestJacobian(t *testing.T) {
+	//f(x, y) = (x^2*y, 5x + sin(y), exp(x*y))
+	f := func(v []float64) []float64 {
+		return []float64{v[0] * v[0] * v[1], 5*v[0] + math.Sin(v[1]), math.Exp(v[0] * v[1])}
+	}
+	x := []float64{1.2, -0.7}
+	e := math.Exp(x[0] * x[1])
+	want := []float64{
+		2 * x[0] * x[1], x[0] * x[0],
+		5, math.Cos(x[1]),
+		x[1] * e, x[0] * e,
+	}
+
+	for _, workers := range []int{0, 2} {
+		j, err := Jacobian(x, f, 0, workers)
+		if err != nil || j.NumberOfRows != 3 || j.NumberOfColumns != 2 {
+			t.Fatalf("Jacobian() = %v, error %v", j, err)
+		}
+		for i := range want {
+			if !soclose(j.M[i], want[i], 1e-9) {
+				t.Errorf("Jacobian() with %d workers M[%d] = %g, want %g", workers, i, j.M[i], want[i])
+			}
+		}
+	}
+
+	//Large values of x need a step scaled with x
+	big := []float64{1e8}
+	j, _ := Jacobian(big, func(v []float64) []float64 { return []float64{v[0] * v[0]} }, 0, 0)
+	if !soclose(j.M[0], 2e8, 1e-9) {
+		t.Errorf("Jacobian() at 1e8 = %g, want 2e8", j.M[0])
+	}
+
+	var calls int
+	ragged := func(v []float64) []float64 {
+		calls++
+		return make([]float64, calls)
+	}
+	if _, err := Jacobian(x, ragged, 0, 0); err == nil {
+		t.Errorf("Jacobian() with outputs of different lengths should fail")
+	}
+}
+
+func TestHessian(t *testing.T) {
+	//f(x, y, z) = x^2*y + y*z^3 + exp(x)
+	f := func(v []float64) float64 {
+		return v[0]*v[0]*v[1] + v[1]*v[2]*v[2]*v[2] + math.Exp(v[0])
+	}
+	x := []float64{0.5, 2, -1}
+	want := []float64{
+		2*x[1] + math.Exp(x[0]), 2 * x[0], 0,
+		2 * x[0], 0, 3 * x[2] * x[2],
+		0, 3 * x[2] * x[2], 6 * x[1] * x[2],
+	}
+
+	h, e := Hessian(x, f, 0)
+	if h.NumberOfRows != 3 || h.NumberOfColumns != 3 {
+		t.Fatalf("Hessian() = %v", h)
+	}
+	for i := range want {
+		if math.Abs(h.M[i]-want[i]) > 1e-6 {
+			t.Errorf("Hessian() M[%d] = %g, want %g", i, h.M[i], want[i])
+		}
+	}
+	if h.M[1] != h.M[3] || h.M[5] != h.M[7] {
+		t.Errorf("Hessian() is not symmetric: %v", h.M)
+	}
+	if e <= 0 || e > 1e-4 {
+		t.Errorf("Hessian() estimated error = %g", e)
+	}
+}
+
+func TestDual(t *testing.T) {
+	//Same function as TestRidders, log(w)/w
+	v, d := DualDerivative(2, func(w Dual) Dual {
+		return w.Log().Divide(w)
+	})
+	result := -(math.Log(2.0) - 1) / (2.0 * 2.0)
+	if !veryclose(v, math.Log(2)/2) || !veryclose(d, result) {
+		t.Errorf("DualDerivative(2) = %g, %g, want %g, %g", v, d, math.Log(2)/2, result)
+	}
+
+	//f(x) = sin(x)^2 * exp(-x) + sqrt(x) + x^3, f'(x) = 2 sin cos exp(-x) - sin^2 exp(-x) + 1/(2 sqrt(x)) + 3x^2
+	x := 0.7
+	_, d = DualDerivative(x, func(w Dual) Dual {
+		s := w.Sin()
+		return s.Multiply(s).Multiply(w.Neg().Exp()).Add(w.Sqrt()).Add(w.Pow(NewDualConstant(3)))
+	})
+	s, c := math.Sincos(x)
+	want := 2*s*c*math.Exp(-x) - s*s*math.Exp(-x) + 0.5/math.Sqrt(x) + 3*x*x
+	if !close(d, want) {
+		t.Errorf("DualDerivative() = %g, want %g", d, want)
+	}
+
+	//x^x, derivative x^x (ln(x) + 1)
+	_, d = DualDerivative(1.5, func(w Dual) Dual { return w.Pow(w) })
+	want = math.Pow(1.5, 1.5) * (math.Log(1.5) + 1)
+	if !close(d, want) {
+		t.Errorf("DualDerivative(x^x) = %g, want %g", d, want)
+	}
+	//Constant exponent on a negative value
+	_, d = DualDerivative(-2, func(w Dual) Dual { return w.Pow(NewDualConstant(3)) })
+	if d != 12 {
+		t.Errorf("DualDerivative(x^3) at -2 = %g, want 12", d)
+	}
+	_, d = DualDerivative(0.3, func(w Dual) Dual { return w.Tan().Subtract(w.Atan()).Add(w.Cos()).Scale(2) })
+	want = 2 * (1/(math.Cos(0.3)*math.Cos(0.3)) - 1/(1+0.09) - math.Sin(0.3))
+	if !close(d, want) {
+		t.Errorf("DualDerivative(tan - atan + cos) = %g, want %g", d, want)
+	}
+	if a := NewDualVariable(-3).Abs(); a.Value != 3 || a.Derivative != -1 {
+		t.Errorf("Abs() = %+v", a)
+	}
+}
+
+func TestOneSidedDifferences(t *testing.T) {
+	//Only defined for w >= 0
+	x := func(w float64) float64 {
+		if w < 0 {
+			return math.NaN()
+		}
+		return math.Sqrt(w) * math.Exp(w)
+	}
+	//At 1, derivative of sqrt(w)*exp(w) is e*(1/2 + 1)
+	result := math.E * 1.5
+	for _, accuracy := range []int{1, 2} {
+		z := ForwardDiff(1, x, accuracy, 1e-10)
+		if !soclose(z, result, 1e-4) {
+			t.Errorf("ForwardDiff(1, %d) = %g, want %g", accuracy, z, result)
+		}
+		z = BackwardDiff(1, x, accuracy, 1e-10)
+		if !soclose(z, result, 1e-4) {
+			t.Errorf("BackwardDiff(1, %d) = %g, want %g", accuracy, z, result)
+		}
+	}
+	//The second order scheme is exact for a parabola
+	parabola := func(w float64) float64 { return 3*w*w - w }
+	if z := ForwardDiff(0, parabola, 2, 1e-6); !soclose(z, -1, 1e-9) {
+		t.Errorf("ForwardDiff(0, parabola, 2) = %g, want -1", z)
+	}
+	if z := BackwardDiff(0, parabola, 2, 1e-6); !soclose(z, -1, 1e-9) {
+		t.Errorf("BackwardDiff(0, parabola, 2) = %g, want -1", z)
+	}
+	//Standard evaluates outside of the domain at 0
+	if z := Standard(0, x, 1e-10); !math.IsNaN(z) {
+		t.Errorf("Standard(0) = %g, want NaN", z)
+	}
+	if z := ForwardDiff(0, func(w float64) float64 { return x(w) * x(w) }, 2, 1e-10); !soclose(z, 1, 1e-4) {
+		t.Errorf("ForwardDiff(0) = %g, want 1", z)
+	}
+	//Without an error the step scales with t and stays on the side of the domain
+	square := func(w float64) float64 { return x(w) * x(w) }
+	for _, err := range []float64{0, -1} {
+		for _, accuracy := range []int{1, 2} {
+			if z := ForwardDiff(0, square, accuracy, err); !soclose(z, 1, 1e-4) {
+				t.Errorf("ForwardDiff(0, %d, %g) = %g, want 1", accuracy, err, z)
+			}
+			if z := BackwardDiff(1, x, accuracy, err); !soclose(z, result, 1e-4) {
+				t.Errorf("BackwardDiff(1, %d, %g) = %g, want %g", accuracy, err, z, result)
+			}
+			if z := ForwardDiff(1e8, math.Log, accuracy, err); !soclose(z, 1e-8, 1e-6) {
+				t.Errorf("ForwardDiff(1e8, log, %d, %g) = %g, want 1e-8", accuracy, err, z)
+			}
+		}
+	}
+}
+
+func TestRiddersWithError(t *testing.T) {
+	//New function
+	x := func(w float64) float64 {
+		return math.Log(w) / w
+	}
+	result := -(math.Log(2.0) - 1) / (2.0 * 2.0)
+	z, e := RiddersWithError(2.0, x, 0.000000001)
+	if !soclose(z, result, 0.000000001) || e > 0.000000001 || z != Ridders(2.0, x, 0.000000001) {
+		t.Errorf("RiddersWithError(%g) = %g, %g, want %g", 2.0, z, e, result)
+	}
+
+	//Noise makes the required error impossible to reach, the best estimate is
+	//returned with its error instead of 0
+	noisy := func(w float64) float64 {
+		return math.Sin(w) + 1e-12*math.Sin(1e7*w)
+	}
+	z, e = RiddersWithError(1, noisy, 1e-12)
+	if z == 0 || e <= 1e-12 || math.Abs(z-math.Cos(1)) > 1e-3 {
+		t.Errorf("RiddersWithError(1) of a noisy function = %g, %g, want %g", z, e, math.Cos(1))
+	}
+
+	//The refinements stop once the round-off dominates, not after all of them
+	var calls int
+	counted := func(w float64) float64 {
+		calls++
+		return math.Sin(w)
+	}
+	z, e = RiddersWithError(1, counted, 1e-10)
+	if !soclose(z, math.Cos(1), 1e-10) || e > 1e-10 || calls >= 40 {
+		t.Errorf("RiddersWithError(1) of sin = %g, %g after %d calls, want %g", z, e, calls, math.Cos(1))
+	}
+	if z, e = RiddersWithError(1, math.Sin, 0); !soclose(z, math.Cos(1), 1e-9) || e > 1e-9 {
+		t.Errorf("RiddersWithError(1, 0) of sin = %g, %g, want %g", z, e, math.Cos(1))
+	}
+}
+
+func TestDerivativeFunc(t *testing.T) {
+	//The derivative of x^3/6 - 2x is x^2/2 - 2, it can be integrated and solved directly
+	var calls int
+	x := func(w float64) float64 {
+		calls++
+		return w*w*w/6 - 2*w
+	}
+	d := DerivativeFunc(x, 1e-9)
+	if z := d(3); !soclose(z, 2.5, 1e-9) {
+		t.Errorf("DerivativeFunc()(3) = %g, want 2.5", z)
+	}
+	z, err := Simpson(0, 2, d, 10)
+	if err != nil || !soclose(z, x(2)-x(0), 1e-8) {
+		t.Errorf("Simpson(DerivativeFunc()) = %g, want %g", z, x(2)-x(0))
+	}
+	root, code := Newton(1, d, 0, 1e-9)
+	if code != 0 || !soclose(root, 2, 1e-6) {
+		t.Errorf("Newton(DerivativeFunc()) = %g, want 2", root)
+	}
+
+	m := MemoizedDerivativeFunc(x, 1e-9)
+	first := m(1.5)
+	calls = 0
+	if second := m(1.5); second != first || calls != 0 {
+		t.Errorf("MemoizedDerivativeFunc() = %g then %g after %d calls", first, second, calls)
+	}
+}
+
+func TestStepSize(t *testing.T) {
+	//f varies on a scale of 1e-4 while t is tiny, sqrt(err) is much too big
+	x := func(w float64) float64 {
+		return math.Sin(1e4 * w)
+	}
+	result := 1e4 * math.Cos(1e-5)
+	if z := Standard(1e-9, x, 0); !soclose(z, result, 1e-9) {
+		t.Errorf("Standard(1e-9) with automatic step = %g, want %g", z, result)
+	}
+	if z := Standard(1e-9, x, 1e-9); soclose(z, result, 1e-3) {
+		t.Errorf("Standard(1e-9) with err 1e-9 = %g should be imprecise", z)
+	}
+
+	//t is huge, the step has to scale with it
+	cube := func(w float64) float64 { return w * w * w }
+	if z := Standard(1e10, cube, 0); !soclose(z, 3e20, 1e-9) {
+		t.Errorf("Standard(1e10) with automatic step = %g, want 3e20", z)
+	}
+	h := StepSize(1e10, cube)
+	if (1e10+h)-1e10 != h || h < 1e3 || h > 1e6 {
+		t.Errorf("StepSize(1e10) = %g", h)
+	}
+	//Linear functions have no truncation error, the first guess is kept
+	if h := StepSize(2, func(w float64) float64 { return 3 * w }); !soclose(h, 2*math.Cbrt(machineEpsilon), 1e-6) {
+		t.Errorf("StepSize() of a linear function = %g", h)
+	}
+}
+
+func TestFieldOperators(t *testing.T) {
+	//F(x, y, z) = (x*y, y*z^2, sin(x)*z)
+	field := func(v []float64) []float64 {
+		return []float64{v[0] * v[1], v[1] * v[2] * v[2], math.Sin(v[0]) * v[2]}
+	}
+	x := []float64{0.5, -1, 2}
+
+	d, err := Divergence(x, field, 1e-9)
+	want := x[1] + x[2]*x[2] + math.Sin(x[0])
+	if err != nil || !soclose(d, want, 1e-9) {
+		t.Errorf("Divergence() = %g, want %g, error %v", d, want, err)
+	}
+
+	c, err := Curl(x, field, 1e-9)
+	wantCurl := []float64{-2 * x[1] * x[2], -math.Cos(x[0]) * x[2], -x[0]}
+	if err != nil {
+		t.Fatalf("Curl() error %v", err)
+	}
+	for i := range wantCurl {
+		if !soclose(c[i], wantCurl[i], 1e-9) {
+			t.Errorf("Curl()[%d] = %g, want %g", i, c[i], wantCurl[i])
+		}
+	}
+	if _, err := Curl([]float64{1, 2}, func(v []float64) []float64 { return v }, 1e-9); err == nil {
+		t.Errorf("Curl() of a 2-D field should fail")
+	}
+	if _, err := Divergence(x, func(v []float64) []float64 { return v[:2] }, 1e-9); err == nil {
+		t.Errorf("Divergence() with mismatching dimensions should fail")
+	}
+
+	//f(x, y, z) = x^2*y + exp(z)*y^3, laplacian 2y + 6y*exp(z) + exp(z)*y^3
+	f := func(v []float64) float64 {
+		return v[0]*v[0]*v[1] + math.Exp(v[2])*v[1]*v[1]*v[1]
+	}
+	l := Laplacian(x, f, 1e-10)
+	e := math.Exp(x[2])
+	wantL := 2*x[1] + 6*x[1]*e + e*x[1]*x[1]*x[1]
+	if !soclose(l, wantL, 1e-8) {
+		t.Errorf("Laplacian() = %g, want %g", l, wantL)
+	}
+	//Harmonic function
+	if l := Laplacian([]float64{0.3, 0.7}, func(v []float64) float64 { return v[0]*v[0] - v[1]*v[1] }, 1e-10); math.Abs(l) > 1e-8 {
+		t.Errorf("Laplacian() of a harmonic function = %g, want 0", l)
+	}
+}
+
+func TestDirectionalDerivative(t *testing.T) {
+	//f(x, y) = x^2*y + exp(y)
+	f := func(v []float64) float64 {
+		return v[0]*v[0]*v[1] + math.Exp(v[1])
+	}
+	x := []float64{1.5, 0.5}
+	direction := []float64{0.6, -0.8}
+	want := 2*x[0]*x[1]*direction[0] + (x[0]*x[0]+math.Exp(x[1]))*direction[1]
+
+	for _, method := range []DirectionalMethod{DirectionalGradient, DirectionalDifference} {
+		d, err := DirectionalDerivative(x, direction, f, method, 1e-9)
+		if err != nil || !soclose(d, want, 1e-9) {
+			t.Errorf("DirectionalDerivative() with method %d = %g, want %g, error %v", method, d, want, err)
+		}
+	}
+	if _, err := DirectionalDerivative(x, []float64{1}, f, DirectionalDifference, 1e-9); err == nil {
+		t.Errorf("DirectionalDerivative() with mismatching dimensions should fail")
+	}
+}
+
+func TestBisect(t *testing.T) {
+	y := func(x float64) float64 {
+		return math.Cos(x) - x
+	}
+	result := 0.7390851332151607
+	z, err := Bisect(0, 1, y, 1e-12)
+	if err != nil || math.Abs(z-result) > 1e-12 {
+		t.Errorf("Bisect(0, 1) = %g, want %g, error %v", z, result, err)
+	}
+	//Boundaries in any order and a tolerance that cannot be reached
+	z, err = Bisect(1, 0, y, 0)
+	if err != nil || !veryclose(z, result) {
+		t.Errorf("Bisect(1, 0) = %g, want %g, error %v", z, result, err)
+	}
+	//Newton diverges on atan from 2, bisection doesn't care
+	z, err = Bisect(-10, 20, math.Atan, 1e-12)
+	if err != nil || math.Abs(z) > 1e-12 {
+		t.Errorf("Bisect(atan) = %g, want 0, error %v", z, err)
+	}
+	if z, err = Bisect(0, 3, func(x float64) float64 { return x - 3 }, 1e-12); err != nil || z != 3 {
+		t.Errorf("Bisect() with a zero on a boundary = %g, error %v", z, err)
+	}
+	_, err = Bisect(2, 3, y, 1e-12)
+	if e, ok := err.(*MathError); !ok || e.code != errorNoSignChange {
+		t.Errorf("Bisect() without sign change error = %v", err)
+	}
+}
+
+func TestBrent(t *testing.T) {
+	y := func(x float64) float64 {
+		return math.Cos(x) - x
+	}
+	result := 0.7390851332151607
+	var calls int
+	counted := func(x float64) float64 {
+		calls++
+		return y(x)
+	}
+	z, err := Brent(0, 1, counted, 1e-14)
+	if err != nil || math.Abs(z-result) > 1e-14 {
+		t.Errorf("Brent(0, 1) = %g, want %g, error %v", z, result, err)
+	}
+	//Superlinear, much faster than the ~47 iterations of bisection
+	if calls > 12 {
+		t.Errorf("Brent(0, 1) evaluated f %d times", calls)
+	}
+
+	//Hard cases: flat function, discontinuous sign change, zero on a boundary
+	z, err = Brent(-1, 4, func(x float64) float64 { return math.Pow(x-1, 5) }, 1e-12)
+	if err != nil || math.Abs(z-1) > 1e-2 {
+		t.Errorf("Brent((x-1)^5) = %g, want 1, error %v", z, err)
+	}
+	z, err = Brent(-1, 3, func(x float64) float64 {
+		if x < math.Sqrt2 {
+			return -1
+		}
+		return 1
+	}, 1e-12)
+	if err != nil || math.Abs(z-math.Sqrt2) > 1e-12 {
+		t.Errorf("Brent(step) = %g, want %g, error %v", z, math.Sqrt2, err)
+	}
+	z, err = Brent(-10, 20, math.Atan, 1e-12)
+	if err != nil || math.Abs(z) > 1e-12 {
+		t.Errorf("Brent(atan) = %g, want 0, error %v", z, err)
+	}
+	_, err = Brent(2, 3, y, 1e-12)
+	if e, ok := err.(*MathError); !ok || e.code != errorNoSignChange {
+		t.Errorf("Brent() without sign change error = %v", err)
+	}
+}
+
+func TestSecantIllinois(t *testing.T) {
+	y := func(x float64) float64 {
+		return math.Cos(x) - x
+	}
+	result := 0.7390851332151607
+
+	z, err := Secant(0, 1, y, 0, 1e-12)
+	if err != nil || math.Abs(z-result) > 1e-12 {
+		t.Errorf("Secant(0, 1) = %g, want %g, error %v", z, result, err)
+	}
+	_, err = Secant(0, 1, func(x float64) float64 { return 2 }, 0, 1e-12)
+	if e, ok := err.(*MathError); !ok || e.code != errorDivisionByZero {
+		t.Errorf("Secant() of a constant error = %v", err)
+	}
+
+	var calls int
+	counted := func(x float64) float64 {
+		calls++
+		return y(x)
+	}
+	z, err = Illinois(0, 1, counted, 1e-12)
+	if err != nil || math.Abs(z-result) > 1e-12 || calls > 20 {
+		t.Errorf("Illinois(0, 1) = %g, want %g after %d calls, error %v", z, result, calls, err)
+	}
+	//The plain false position method gets stuck on a convex function
+	convex := func(x float64) float64 { return math.Exp(x) - 2 }
+	z, err = Illinois(-5, 5, convex, 1e-12)
+	if err != nil || math.Abs(z-math.Ln2) > 1e-12 {
+		t.Errorf("Illinois(exp(x) - 2) = %g, want %g, error %v", z, math.Ln2, err)
+	}
+	_, err = Illinois(2, 3, y, 1e-12)
+	if e, ok := err.(*MathError); !ok || e.code != errorNoSignChange {
+		t.Errorf("Illinois() without sign change error = %v", err)
+	}
+}
+
+func TestNewtonWithDerivativeHalley(t *testing.T) {
+	y := func(x float64) float64 {
+		return math.Cos(x) - x
+	}
+	dy := func(x float64) float64 {
+		return -math.Sin(x) - 1
+	}
+	d2y := func(x float64) float64 {
+		return -math.Cos(x)
+	}
+	result := 0.7390851332151607
+
+	z, err := NewtonWithDerivative(0.6, y, dy, 0, 1e-14)
+	if err != nil || !veryclose(z, result) {
+		t.Errorf("NewtonWithDerivative(0.6) = %g, want %g, error %v", z, result, err)
+	}
+	var iterations int
+	counted := func(x float64) float64 {
+		iterations++
+		return y(x)
+	}
+	z, err = Halley(0.6, counted, dy, d2y, 0, 1e-14)
+	if err != nil || !veryclose(z, result) || iterations > 4 {
+		t.Errorf("Halley(0.6) = %g, want %g after %d iterations, error %v", z, result, iterations, err)
+	}
+
+	//The derivative of x^2 - 1 is 0 at 0
+	square := func(x float64) float64 { return x*x - 1 }
+	_, err = NewtonWithDerivative(0, square, func(x float64) float64 { return 2 * x }, 0, 1e-12)
+	if e, ok := err.(*MathError); !ok || e.code != errorDivisionByZero {
+		t.Errorf("NewtonWithDerivative() with a zero derivative error = %v", err)
+	}
+	//Newton diverges on atan from 2, until the derivative underflows
+	_, err = NewtonWithDerivative(2, math.Atan, func(x float64) float64 { return 1 / (1 + x*x) }, 0, 1e-12)
+	if err == nil {
+		t.Errorf("NewtonWithDerivative(atan) from 2 error = %v", err)
+	}
+}
+
+func TestFindRoots(t *testing.T) {
+	//sin has zeros at k*pi
+	roots := FindRoots(-1, 10, math.Sin, 0, 1e-12)
+	if len(roots) != 4 {
+		t.Fatalf("FindRoots(sin) = %v, want 4 zeros", roots)
+	}
+	for k, r := range roots {
+		if math.Abs(r-float64(k)*math.Pi) > 1e-12 {
+			t.Errorf("FindRoots(sin)[%d] = %g, want %g", k, r, float64(k)*math.Pi)
+		}
+	}
+
+	//(x-1)^2 (x+2) has a double root at 1 where it doesn't change sign
+	f := func(x float64) float64 { return (x - 1) * (x - 1) * (x + 2) }
+	roots = FindRoots(-3, 3, f, 100, 1e-12)
+	if len(roots) != 1 || math.Abs(roots[0]+2) > 1e-12 {
+		t.Errorf("FindRoots() = %v, want [-2]", roots)
+	}
+	roots = FindRootsWithTangents(-3, 3, f, 101, 1e-12)
+	if len(roots) != 2 || math.Abs(roots[0]+2) > 1e-12 || math.Abs(roots[1]-1) > 1e-5 {
+		t.Errorf("FindRootsWithTangents() = %v, want [-2 1]", roots)
+	}
+	//A zero on a point of the grid is found once
+	roots = FindRoots(-1, 1, func(x float64) float64 { return x }, 2, 1e-12)
+	if len(roots) != 1 || roots[0] != 0 {
+		t.Errorf("FindRoots(x) = %v, want [0]", roots)
+	}
+	//A minimum that doesn't touch the axis is not a root
+	if roots = FindRootsWithTangents(-1, 1, func(x float64) float64 { return x*x + 0.1 }, 11, 1e-12); len(roots) != 0 {
+		t.Errorf("FindRootsWithTangents(x^2 + 0.1) = %v, want no zero", roots)
+	}
+}
+
+func TestPolynomialRoots(t *testing.T) {
+	//(x-1)(x+2)(x-3)(x^2+1) = x^5 - 2x^4 - 4x^3 + 4x^2 - 5x + 6
+	p := NewPolynomial(6, -5, 4, -4, -2, 1)
+	if p.Degree() != 5 || p.Evaluate(3) != 0 {
+		t.Errorf("Degree() = %d, Evaluate(3) = %g", p.Degree(), p.Evaluate(3))
+	}
+	roots, err := p.Roots()
+	want := []complex128{-2, complex(0, -1), complex(0, 1), 1, 3}
+	if err != nil || len(roots) != len(want) {
+		t.Fatalf("Roots() = %v, error %v", roots, err)
+	}
+	//The order of roots with the same real part depends on round-off
+	for _, w := range want {
+		found := false
+		for _, r := range roots {
+			found = found || cmplx.Abs(r-w) <= 1e-12
+		}
+		if !found {
+			t.Errorf("Roots() = %v, %v not found", roots, w)
+		}
+	}
+	real, _ := p.RealRoots(1e-10)
+	if len(real) != 3 || math.Abs(real[0]+2) > 1e-12 || math.Abs(real[1]-1) > 1e-12 || math.Abs(real[2]-3) > 1e-12 {
+		t.Errorf("RealRoots() = %v, want [-2 1 3]", real)
+	}
+
+	//x^2 (x^2 - 2), the zero roots are exact
+	roots, err = NewPolynomial(0, 0, -2, 0, 1).Roots()
+	if err != nil || len(roots) != 4 || roots[1] != 0 || roots[2] != 0 || cmplx.Abs(roots[3]-complex(math.Sqrt2, 0)) > 1e-14 {
+		t.Errorf("Roots() of x^4 - 2x^2 = %v, error %v", roots, err)
+	}
+	//Wilkinson like polynomial with roots 1 to 10
+	w := NewPolynomial(1)
+	for k := 1; k <= 10; k++ {
+		next := make(Polynomial, len(w)+1)
+		for i, c := range w {
+			next[i+1] += c
+			next[i] -= float64(k) * c
+		}
+		w = next
+	}
+	real, err = w.RealRoots(1e-6)
+	if err != nil || len(real) != 10 {
+		t.Fatalf("RealRoots() of the Wilkinson polynomial = %v, error %v", real, err)
+	}
+	for k, r := range real {
+		if math.Abs(r-float64(k+1)) > 1e-7 {
+			t.Errorf("RealRoots() of the Wilkinson polynomial [%d] = %g, want %d", k, r, k+1)
+		}
+	}
+	//Double root
+	roots, _ = NewPolynomial(1, -2, 1).Roots()
+	if len(roots) != 2 || cmplx.Abs(roots[0]-1) > 1e-7 || cmplx.Abs(roots[1]-1) > 1e-7 {
+		t.Errorf("Roots() of (x-1)^2 = %v", roots)
+	}
+	if _, err = NewPolynomial(0, 0).Roots(); err == nil {
+		t.Errorf("Roots() of the zero polynomial should fail")
+	}
+	if roots, err = NewPolynomial(3).Roots(); err != nil || len(roots) != 0 {
+		t.Errorf("Roots() of a constant = %v, error %v", roots, err)
+	}
+}
+
+func TestMuller(t *testing.T) {
+	//x^2 + 1 has no real zero, Muller finds i or -i from a real value
+	z, err := Muller(1, func(x complex128) complex128 { return x*x + 1 }, 0, 1e-14)
+	if err != nil || cmplx.Abs(z*z+1) > 1e-14 || math.Abs(imag(z)) < 0.5 {
+		t.Errorf("Muller(x^2 + 1) = %v, error %v", z, err)
+	}
+	//exp(z) = 2 has the zeros ln(2) + 2k*pi*i
+	f := func(x complex128) complex128 { return cmplx.Exp(x) - 2 }
+	z, err = Muller(complex(0.5, 6), f, 0, 1e-14)
+	want := complex(math.Ln2, 2*math.Pi)
+	if err != nil || cmplx.Abs(z-want) > 1e-12 {
+		t.Errorf("Muller(exp(z) - 2) = %v, want %v, error %v", z, want, err)
+	}
+	//Same zero as Newton on a real function
+	z, err = Muller(0.6, func(x complex128) complex128 { return cmplx.Cos(x) - x }, 0, 1e-14)
+	if err != nil || cmplx.Abs(z-0.7390851332151607) > 1e-14 {
+		t.Errorf("Muller(cos(x) - x) = %v, error %v", z, err)
+	}
+}
+
+func TestFixedPoint(t *testing.T) {
+	result := 0.7390851332151607
+	z, err := FixedPoint(0.5, math.Cos, 1e-13)
+	if err != nil || math.Abs(z-result) > 1e-12 {
+		t.Errorf("FixedPoint(cos) = %g, want %g, error %v", z, result, err)
+	}
+	var calls int
+	counted := func(x float64) float64 {
+		calls++
+		return math.Cos(x)
+	}
+	z, err = FixedPointAitken(0.5, counted, 1e-13)
+	if err != nil || math.Abs(z-result) > 1e-13 || calls > 20 {
+		t.Errorf("FixedPointAitken(cos) = %g, want %g after %d calls, error %v", z, result, calls, err)
+	}
+
+	//g(x) = 3x - 2 has the fixed point 1 but |g'| = 3 > 1
+	g := func(x float64) float64 { return 3*x - 2 }
+	_, err = FixedPoint(1.5, g, 1e-12)
+	if e, ok := err.(*MathError); !ok || e.code != errorDiverged {
+		t.Errorf("FixedPoint(3x - 2) error = %v", err)
+	}
+	z, err = FixedPointAitken(1.5, g, 1e-12)
+	if err != nil || math.Abs(z-1) > 1e-12 {
+		t.Errorf("FixedPointAitken(3x - 2) = %g, want 1, error %v", z, err)
+	}
+	_, err = FixedPoint(2, math.Exp, 1e-12)
+	if e, ok := err.(*MathError); !ok || e.code != errorDiverged {
+		t.Errorf("FixedPoint(exp) error = %v", err)
+	}
+}
+
+func TestRootResult(t *testing.T) {
+	y := func(x float64) float64 {
+		return 7*math.Pow(x, 3.0) - 7*math.Pow(x, 5.0) + 3 - 3*math.Pow(x, 2.0)
+	}
+	r, err := NewtonWithResult(0.6, y, 0, 1e-9)
+	if err != nil || !r.Converged || !soclose(r.Root, 1, 1e-9) || r.Iterations == 0 || r.Residual > 1e-8 {
+		t.Errorf("NewtonWithResult(0.6) = %+v, error %v", r, err)
+	}
+	r, err = SteffensenWithResult(0.3, y, 0, 1e-10)
+	if err != nil || !r.Converged || !soclose(r.Root, 1, 1e-10) || r.Iterations == 0 || r.Residual > 1e-8 {
+		t.Errorf("SteffensenWithResult(0.3) = %+v, error %v", r, err)
+	}
+
+	//Not enough iterations
+	r, err = NewtonWithResult(0.6, y, 2, 1e-12)
+	if e, ok := err.(*MathError); !ok || e.code != errorNotConverged || r.Converged || r.Iterations != 2 {
+		t.Errorf("NewtonWithResult() with 2 iterations = %+v, error %v", r, err)
+	}
+	if _, code := Newton(0.6, y, 2, 1e-12); code != -1 {
+		t.Errorf("Newton() with 2 iterations returned %d, want -1", code)
+	}
+	if _, code := Steffensen(0.3, y, 3, 1e-12); code != -1 {
+		t.Errorf("Steffensen() with 3 iterations returned %d, want -1", code)
+	}
+}
+
+func TestNewtonErrors(t *testing.T) {
+	//The derivative of x^2 + 1 is 0 at 0
+	r, err := NewtonWithResult(0, func(x float64) float64 { return x*x + 1 }, 0, 1e-9)
+	e, ok := err.(*MathError)
+	if !ok || e.code != errorDivisionByZero || e.State == nil || e.State.Iteration != 1 || e.State.X != 0 || e.State.Value != 1 || r.Converged {
+		t.Fatalf("NewtonWithResult(x^2 + 1) = %+v, error %v", r, err)
+	}
+	if !strings.Contains(err.Error(), "iteration 1") {
+		t.Errorf("Error() = %q should give the iteration", err.Error())
+	}
+	if _, code := Newton(0, func(x float64) float64 { return x*x + 1 }, 0, 1e-9); code != -1 {
+		t.Errorf("Newton(x^2 + 1) returned %d, want -1", code)
+	}
+
+	//log is not defined for negative values, the iterate jumps there from 3
+	_, err = NewtonWithResult(3, math.Log, 0, 1e-9)
+	if e, ok := err.(*MathError); !ok || e.code != errorDiverged || e.State == nil || e.State.Iteration < 2 {
+		t.Errorf("NewtonWithResult(log) from 3 error = %v", err)
+	}
+	//Errors without state are unchanged
+	if (&MathError{code: errorNotConverged}).Error() != "Method did not converge to the required precision" {
+		t.Errorf("Error() without state changed")
+	}
+}
+
+func TestDampedNewton(t *testing.T) {
+	//Plain Newton overshoots on atan as soon as |init| > 1.39
+	if _, err := NewtonWithResult(3, math.Atan, 0, 1e-10); err == nil {
+		t.Fatalf("NewtonWithResult(atan) from 3 should fail")
+	}
+	for _, init := range []float64{3, -10, 100} {
+		r, err := DampedNewton(init, math.Atan, 0, 1e-10, 0)
+		if err != nil || !r.Converged || math.Abs(r.Root) > 1e-9 {
+			t.Errorf("DampedNewton(atan) from %g = %+v, error %v", init, r, err)
+		}
+	}
+
+	//The clamped steps stay below maxStep
+	previous := 50.0
+	r, err := DampedNewton(50, func(x float64) float64 {
+		if math.Abs(x-previous) > 1+1e-12 {
+			t.Errorf("step from %g to %g is larger than maxStep", previous, x)
+		}
+		previous = x
+		return x - 2
+	}, 0, 1e-10, 1)
+	if err != nil || math.Abs(r.Root-2) > 1e-9 {
+		t.Errorf("DampedNewton(x - 2) from 50 = %+v, error %v", r, err)
+	}
+
+	//x^2 + 1 has no real zero, |f| has a minimum at 0
+	_, err = DampedNewton(1, func(x float64) float64 { return x*x + 1 }, 0, 1e-10, 0)
+	if e, ok := err.(*MathError); !ok || e.State == nil {
+		t.Errorf("DampedNewton(x^2 + 1) error = %v, want a state", err)
+	}
+}
+
+func TestBracketRoot(t *testing.T) {
+	cases := []struct {
+		name string
+		init float64
+		f    F
+		root float64
+	}{
+		{"x - 1000", 0, func(x float64) float64 { return x - 1000 }, 1000},
+		{"x + 5", 3, func(x float64) float64 { return x + 5 }, -5},
+		{"log", 3, math.Log, 1},
+		{"sqrt(x) - 0.5", 10, func(x float64) float64 { return math.Sqrt(x) - 0.5 }, 0.25},
+		{"cos", 0.5, math.Cos, math.Pi / 2},
+	}
+	for _, c := range cases {
+		a, b, err := BracketRoot(c.init, c.f)
+		if err != nil || a > b {
+			t.Errorf("BracketRoot(%s) = [%g, %g], error %v", c.name, a, b, err)
+			continue
+		}
+		root, err := Brent(a, b, c.f, 1e-12)
+		if err != nil || math.Abs(root-c.root) > 1e-9 {
+			t.Errorf("Brent(%s) on [%g, %g] = %g, error %v", c.name, a, b, root, err)
+		}
+	}
+
+	if _, _, err := BracketRoot(0, func(x float64) float64 { return x*x + 1 }); err == nil {
+		t.Errorf("BracketRoot(x^2 + 1) should fail")
+	}
+	if a, b, err := BracketRoot(2, func(x float64) float64 { return x - 2 }); err != nil || a != 2 || b != 2 {
+		t.Errorf("BracketRoot at a zero = [%g, %g], error %v", a, b, err)
+	}
+}
+
+func TestRootSolver(t *testing.T) {
+	y := func(x float64) float64 { return x*x - 2 }
+	solvers := []RootSolver{
+		NewtonSolver{Init: 1},
+		DampedNewtonSolver{Init: 1, MaxStep: 1},
+		SteffensenSolver{Init: 1},
+		SecantSolver{X0: 1, X1: 2},
+		BisectSolver{A: 0, B: 2},
+		BrentSolver{A: 0, B: 2},
+		IllinoisSolver{A: 0, B: 2},
+	}
+	for _, solver := range solvers {
+		var history []IterationState
+		r, err := solver.Solve(y, Tolerance(1e-12), OnIteration(func(s IterationState) {
+			history = append(history, s)
+		}))
+		if err != nil || !r.Converged || math.Abs(r.Root-math.Sqrt2) > 1e-10 || r.Residual > 1e-9 {
+			t.Errorf("%T.Solve() = %+v, error %v", solver, r, err)
+			continue
+		}
+		if len(history) == 0 || len(history) > r.Iterations {
+			t.Errorf("%T called OnIteration %d times for %d iterations", solver, len(history), r.Iterations)
+			continue
+		}
+		for i, s := range history {
+			if s.Iteration != i+1 || s.Value != y(s.X) {
+				t.Errorf("%T iteration %d state = %+v", solver, i+1, s)
+				break
+			}
+		}
+
+		r, err = solver.Solve(y, Tolerance(1e-12), MaxIterations(2))
+		if e, ok := err.(*MathError); !ok || e.code != errorNotConverged || r.Iterations != 2 {
+			t.Errorf("%T.Solve() with 2 iterations = %+v, error %v", solver, r, err)
+		}
+	}
+}
+
+func TestSolverContext(t *testing.T) {
+	//x^2 + 1 has no zero, Steffensen and the secant method wander until they are stopped
+	y := func(x float64) float64 { return x*x + 1 }
+	ctx, cancel := context.WithCancel(context.Background())
+	calls := 0
+	slow := func(x float64) float64 {
+		calls++
+		if calls == 50 {
+			cancel()
+		}
+		return math.Sin(x) + 2
+	}
+	r, err := NewtonContext(ctx, 1, slow, 1000000, 1e-12)
+	if err != context.Canceled || r.Iterations > 50 {
+		t.Errorf("NewtonContext() cancelled = %+v, error %v", r, err)
+	}
+
+	ctx, cancel = context.WithCancel(context.Background())
+	cancel()
+	if r, err := SteffensenContext(ctx, 1, y, 0, 1e-12); err != context.Canceled || r.Iterations != 1 {
+		t.Errorf("SteffensenContext() with a done context = %+v, error %v", r, err)
+	}
+	if r, err := (SecantSolver{X0: 0, X1: 1}).Solve(y, Context(ctx), MaxIterations(1000000)); err != context.Canceled || r.Iterations != 1 {
+		t.Errorf("SecantSolver with a done context = %+v, error %v", r, err)
+	}
+
+	//A context that is not done changes nothing
+	r, err = NewtonContext(context.Background(), 1, func(x float64) float64 { return x*x - 2 }, 0, 1e-12)
+	if err != nil || math.Abs(r.Root-math.Sqrt2) > 1e-10 {
+		t.Errorf("NewtonContext(x^2 - 2) = %+v, error %v", r, err)
+	}
+}
+
+func TestPolynomialCalculus(t *testing.T) {
+	//p = 2x^3 - 3x + 1
+	p := NewPolynomial(1, -3, 0, 2)
+	d := p.Derivative()
+	if !reflect.DeepEqual(d, Polynomial{-3, 0, 6}) {
+		t.Errorf("Derivative() = %v", d)
+	}
+	if d := NewPolynomial(5).Derivative(); d.Degree() != -1 {
+		t.Errorf("Derivative() of a constant = %v", d)
+	}
+	q := p.Integral(4)
+	if !reflect.DeepEqual(q, Polynomial{4, 1, -1.5, 0, 0.5}) {
+		t.Errorf("Integral(4) = %v", q)
+	}
+	if !reflect.DeepEqual(q.Derivative(), p) {
+		t.Errorf("Integral(4).Derivative() = %v, want %v", q.Derivative(), p)
+	}
+	if v := p.DefiniteIntegral(-1, 2); v != 6 {
+		t.Errorf("DefiniteIntegral(-1, 2) = %g, want 6", v)
+	}
+	if v, _ := AdaptiveSimpson(0, 3, p.F(), 1e-12); !soclose(v, p.DefiniteIntegral(0, 3), 1e-10) {
+		t.Errorf("DefiniteIntegral(0, 3) = %g, AdaptiveSimpson gives %g", p.DefiniteIntegral(0, 3), v)
+	}
+	if v := (Polynomial{}).DefiniteIntegral(0, 1); v != 0 {
+		t.Errorf("DefiniteIntegral() of the zero polynomial = %g", v)
+	}
+}
+
+func TestPolynomialDivideGCD(t *testing.T) {
+	//p = (x - 1)^2 (x + 2) = x^3 - 3x + 2, q = (x - 1)(x + 3) = x^2 + 2x - 3
+	p := NewPolynomial(2, -3, 0, 1)
+	q := NewPolynomial(-3, 2, 1)
+	quotient, remainder, err := p.Divide(q)
+	if err != nil || !reflect.DeepEqual(quotient, Polynomial{-2, 1}) || !reflect.DeepEqual(remainder, Polynomial{-4, 4}) {
+		t.Errorf("Divide() = %v, %v, error %v", quotient, remainder, err)
+	}
+	for _, x := range []float64{-2, 0.5, 3} {
+		if v := quotient.Evaluate(x)*q.Evaluate(x) + remainder.Evaluate(x); !close(v, p.Evaluate(x)) {
+			t.Errorf("quotient*q + remainder at %g = %g, want %g", x, v, p.Evaluate(x))
+		}
+	}
+	quotient, remainder, _ = q.Divide(p)
+	if quotient.Degree() != -1 || !reflect.DeepEqual(remainder, q) {
+		t.Errorf("Divide() by a higher degree = %v, %v", quotient, remainder)
+	}
+	if _, _, err := p.Divide(Polynomial{0}); err == nil {
+		t.Errorf("Divide() by zero should fail")
+	}
+
+	g := p.GCD(q, 0)
+	if len(g) != 2 || !soclose(g[0], -1, 1e-12) || g[1] != 1 {
+		t.Errorf("GCD() = %v, want x - 1", g)
+	}
+	//The square-free part of p is p / gcd(p, p')
+	g = p.GCD(p.Derivative(), 0)
+	if len(g) != 2 || !soclose(g[0], -1, 1e-12) {
+		t.Errorf("GCD(p, p') = %v, want x - 1", g)
+	}
+	if g := p.GCD(NewPolynomial(1, 1), 0); !reflect.DeepEqual(g, Polynomial{1}) {
+		t.Errorf("GCD() of coprime polynomials = %v, want 1", g)
+	}
+}
+
+func TestCharacteristicPolynomial(t *testing.T) {
+	p := FromRoots([]float64{1, -2, 3})
+	if !reflect.DeepEqual(p, Polynomial{6, -5, -2, 1}) {
+		t.Errorf("FromRoots(1, -2, 3) = %v", p)
+	}
+	if p := FromRoots(nil); !reflect.DeepEqual(p, Polynomial{1}) {
+		t.Errorf("FromRoots() without roots = %v", p)
+	}
+	if p := NewPolynomial(1, 1).Multiply(NewPolynomial(-1, 1)); !reflect.DeepEqual(p, Polynomial{-1, 0, 1}) {
+		t.Errorf("(x + 1)(x - 1) = %v", p)
+	}
+
+	//Upper triangular, the eigenvalues are on the diagonal
+	m := NewMatrix(3, 3)
+	m.M = []float64{
+		2, 1, 4,
+		0, -1, 5,
+		0, 0, 3,
+	}
+	p, err := m.CharacteristicPolynomial()
+	want := FromRoots([]float64{2, -1, 3})
+	if err != nil || len(p) != len(want) {
+		t.Fatalf("CharacteristicPolynomial() = %v, error %v", p, err)
+	}
+	for i := range want {
+		if !close(p[i], want[i]) {
+			t.Errorf("CharacteristicPolynomial() = %v, want %v", p, want)
+			break
+		}
+	}
+
+	//Symmetric matrix with eigenvalues 1 and 3, the constant term is the determinant
+	m = NewMatrix(2, 2)
+	m.M = []float64{2, 1, 1, 2}
+	p, _ = m.CharacteristicPolynomial()
+	det, _ := m.Determinant()
+	roots, _ := p.RealRoots(1e-12)
+	if !close(p[0], det) || len(roots) != 2 || !close(roots[0], 1) || !close(roots[1], 3) {
+		t.Errorf("CharacteristicPolynomial() = %v with roots %v, determinant %g", p, roots, det)
+	}
+
+	if _, err := NewMatrix(2, 3).CharacteristicPolynomial(); err == nil {
+		t.Errorf("CharacteristicPolynomial() of a non square matrix should fail")
+	}
+}
+
+func TestSturm(t *testing.T) {
+	//Roots -2, 0.5, 1 (double) and 4
+	p := FromRoots([]float64{-2, 0.5, 1, 1, 4})
+	if s := p.SturmSequence(); len(s) < 3 || !reflect.DeepEqual(s[1], p.Derivative()) {
+		t.Errorf("SturmSequence() = %v", s)
+	}
+	cases := []struct {
+		a, b  float64
+		count int
+	}{
+		{-10, 10, 4},
+		{0, 2, 2},
+		{0.5, 2, 1},
+		{-2, 0, 0},
+		{1.5, 3.9, 0},
+		{3, 5, 1},
+	}
+	for _, c := range cases {
+		if n := p.CountRootsIn(c.a, c.b); n != c.count {
+			t.Errorf("CountRootsIn(%g, %g) = %d, want %d", c.a, c.b, n, c.count)
+		}
+	}
+	if n := NewPolynomial(1, 0, 1).CountRootsIn(-100, 100); n != 0 {
+		t.Errorf("CountRootsIn() of x^2 + 1 = %d", n)
+	}
+
+	intervals := p.IsolateRoots(-10, 10)
+	want := []float64{-2, 0.5, 1, 4}
+	if len(intervals) != len(want) {
+		t.Fatalf("IsolateRoots() = %v", intervals)
+	}
+	for i, interval := range intervals {
+		if want[i] <= interval[0] || want[i] > interval[1] || p.CountRootsIn(interval[0], interval[1]) != 1 {
+			t.Errorf("IsolateRoots() interval %d = %v, want it around %g", i, interval, want[i])
+		}
+		if want[i] == 1 {
+			continue
+		}
+		if root, err := Brent(interval[0], interval[1], p.F(), 1e-12); err != nil || math.Abs(root-want[i]) > 1e-10 {
+			t.Errorf("Brent() on %v = %g, error %v", interval, root, err)
+		}
+	}
+}
+
+func TestOrthogonalPolynomials(t *testing.T) {
+	checks := []struct {
+		name string
+		got  Polynomial
+		want Polynomial
+	}{
+		{"P_3", LegendrePolynomials(3)[3], Polynomial{0, -1.5, 0, 2.5}},
+		{"T_4", ChebyshevTPolynomials(4)[4], Polynomial{1, 0, -8, 0, 8}},
+		{"U_3", ChebyshevUPolynomials(3)[3], Polynomial{0, -4, 0, 8}},
+		{"H_3", HermitePolynomials(3)[3], Polynomial{0, -12, 0, 8}},
+		{"L_2", LaguerrePolynomials(2)[2], Polynomial{1, -2, 0.5}},
+	}
+	for _, c := range checks {
+		if len(c.got) != len(c.want) {
+			t.Errorf("%s = %v, want %v", c.name, c.got, c.want)
+			continue
+		}
+		for i := range c.want {
+			if !close(c.got[i], c.want[i]) {
+				t.Errorf("%s = %v, want %v", c.name, c.got, c.want)
+				break
+			}
+		}
+	}
+	if p := LegendrePolynomials(0); len(p) != 1 || LegendrePolynomials(-1) != nil {
+		t.Errorf("LegendrePolynomials(0) = %v", p)
+	}
+
+	//The recurrence agrees with the coefficients and with T_n(cos t) = cos(nt)
+	x := 0.3
+	for n, p := range HermitePolynomials(8) {
+		if !close(HermiteH(n, x), p.Evaluate(x)) {
+			t.Errorf("HermiteH(%d, %g) = %g, want %g", n, x, HermiteH(n, x), p.Evaluate(x))
+		}
+	}
+	for n, p := range LaguerrePolynomials(8) {
+		if !soclose(LaguerreL(n, x), p.Evaluate(x), 1e-13) {
+			t.Errorf("LaguerreL(%d, %g) = %g, want %g", n, x, LaguerreL(n, x), p.Evaluate(x))
+		}
+	}
+	if v := ChebyshevT(100, math.Cos(0.7)); !soclose(v, math.Cos(70), 1e-12) {
+		t.Errorf("ChebyshevT(100, cos 0.7) = %g, want %g", v, math.Cos(70))
+	}
+	if v := ChebyshevU(50, math.Cos(0.7)); !soclose(v, math.Sin(51*0.7)/math.Sin(0.7), 1e-11) {
+		t.Errorf("ChebyshevU(50, cos 0.7) = %g", v)
+	}
+	if !math.IsNaN(LegendreP(-1, 0)) {
+		t.Errorf("LegendreP(-1) should be NaN")
+	}
+
+	//Orthogonality of the Legendre polynomials on [-1, 1]
+	p := LegendrePolynomials(5)
+	if v := p[3].Multiply(p[5]).DefiniteIntegral(-1, 1); math.Abs(v) > 1e-14 {
+		t.Errorf("integral of P_3 P_5 = %g", v)
+	}
+	if v := p[4].Multiply(p[4]).DefiniteIntegral(-1, 1); !close(v, 2.0/9) {
+		t.Errorf("integral of P_4^2 = %g, want 2/9", v)
+	}
+	if v := LegendreP(20, 1); !close(v, 1) {
+		t.Errorf("LegendreP(20, 1) = %g, want 1", v)
+	}
+}
+
+func TestPade(t *testing.T) {
+	//exp: [2/2] is (1 + x/2 + x^2/12)/(1 - x/2 + x^2/12)
+	taylor := []float64{1, 1, 1.0 / 2, 1.0 / 6, 1.0 / 24}
+	r, err := Pade(taylor, 2, 2)
+	if err != nil || len(r.Numerator) != 3 || len(r.Denominator) != 3 ||
+		!close(r.Numerator[1], 0.5) || !close(r.Numerator[2], 1.0/12) ||
+		!close(r.Denominator[1], -0.5) || !close(r.Denominator[2], 1.0/12) {
+		t.Fatalf("Pade(exp, 2, 2) = %+v, error %v", r, err)
+	}
+	if v := r.Evaluate(1); math.Abs(v-math.E) > 4e-3 {
+		t.Errorf("[2/2] exp(1) = %g, want %g", v, math.E)
+	}
+
+	//log(1 + x) has a radius of convergence of 1, the series diverges at 2
+	var series []float64
+	series = append(series, 0)
+	for k := 1; k <= 10; k++ {
+		series = append(series, math.Pow(-1, float64(k+1))/float64(k))
+	}
+	r, err = Pade(series, 5, 5)
+	if err != nil || math.Abs(r.Evaluate(2)-math.Log(3)) > 1e-4 {
+		t.Errorf("[5/5] log(1 + 2) = %g, want %g, error %v", r.Evaluate(2), math.Log(3), err)
+	}
+
+	//sin has zero coefficients, the Levinson recursion fails on the [2/2] system
+	//whose first element is 0, the approximant is x/(1 + x^2/6)
+	sin := []float64{0, 1, 0, -1.0 / 6, 0}
+	r, err = Pade(sin, 2, 2)
+	if err != nil || !reflect.DeepEqual(r.Numerator, Polynomial{0, 1}) || len(r.Denominator) != 3 ||
+		r.Denominator[1] != 0 || !close(r.Denominator[2], 1.0/6) {
+		t.Errorf("Pade(sin, 2, 2) = %+v, error %v", r, err)
+	}
+
+	if r, err := Pade(taylor, 4, 0); err != nil || !reflect.DeepEqual(r.Numerator, NewPolynomial(taylor...)) {
+		t.Errorf("Pade(exp, 4, 0) = %+v, error %v", r, err)
+	}
+	if _, err := Pade(taylor, 3, 2); err == nil {
+		t.Errorf("Pade() with too few coefficients should fail")
+	}
+}
+
+func TestSolveTridiagonal(t *testing.T) {
+	x, err := SolveTridiagonal([]float64{1, 1}, []float64{4, 4, 4}, []float64{1, 1}, []float64{5, 6, 5})
+	if err != nil || len(x) != 3 || !close(x[0], 1) || !close(x[1], 1) || !close(x[2], 1) {
+		t.Errorf("SolveTridiagonal() = %v, error %v", x, err)
+	}
+	if _, err := SolveTridiagonal([]float64{1}, []float64{0, 1}, []float64{1}, []float64{1, 1}); err == nil {
+		t.Errorf("SolveTridiagonal() with a zero pivot should fail")
+	}
+	if _, err := SolveTridiagonal([]float64{1}, []float64{1, 1}, nil, []float64{1, 1}); err == nil {
+		t.Errorf("SolveTridiagonal() with a missing diagonal should fail")
+	}
+}
+
+func TestCubicSpline(t *testing.T) {
+	//A cubic is reproduced exactly by the clamped and not-a-knot splines
+	cubic := func(x float64) float64 { return x*x*x - 2*x + 1 }
+	x := []float64{-1, -0.2, 0.5, 1, 2.5, 3}
+	y := make([]float64, len(x))
+	for i := range x {
+		y[i] = cubic(x[i])
+	}
+	clamped, err := NewCubicSpline(x, y, ClampedSpline, 1, 25)
+	if err != nil {
+		t.Fatal(err)
+	}
+	notAKnot, _ := NewCubicSpline(x, y, NotAKnotSpline, 0, 0)
+	for _, v := range []float64{-1, -0.7, 0.1, 0.5, 2, 3} {
+		if !soclose(clamped.Evaluate(v), cubic(v), 1e-12) || !soclose(notAKnot.Evaluate(v), cubic(v), 1e-12) {
+			t.Errorf("spline(%g) = %g and %g, want %g", v, clamped.Evaluate(v), notAKnot.Evaluate(v), cubic(v))
+		}
+		if !soclose(clamped.Derivative(v), 3*v*v-2, 1e-11) || !soclose(clamped.SecondDerivative(v), 6*v, 1e-10) {
+			t.Errorf("spline'(%g) = %g, spline''(%g) = %g", v, clamped.Derivative(v), v, clamped.SecondDerivative(v))
+		}
+	}
+	if v := notAKnot.Integrate(-0.5, 2.8); !soclose(v, Polynomial{1, -2, 0, 1}.DefiniteIntegral(-0.5, 2.8), 1e-12) {
+		t.Errorf("Integrate(-0.5, 2.8) = %g", v)
+	}
+	if v := notAKnot.Integrate(2.8, -0.5); !soclose(v, -notAKnot.Integrate(-0.5, 2.8), 1e-14) {
+		t.Errorf("Integrate(2.8, -0.5) = %g", v)
+	}
+
+	//The natural spline has a zero second derivative at the ends and interpolates
+	natural, _ := NewCubicSpline(x, y, NaturalSpline, 0, 0)
+	if math.Abs(natural.SecondDerivative(-1)) > 1e-12 || math.Abs(natural.SecondDerivative(3)) > 1e-12 {
+		t.Errorf("natural spline'' at the ends = %g, %g", natural.SecondDerivative(-1), natural.SecondDerivative(3))
+	}
+	for i := range x {
+		if !close(natural.Evaluate(x[i]), y[i]) {
+			t.Errorf("natural spline(%g) = %g, want %g", x[i], natural.Evaluate(x[i]), y[i])
+		}
+	}
+	//Continuity of the second derivative at a knot
+	if !soclose(natural.SecondDerivative(0.5-1e-9), natural.SecondDerivative(0.5+1e-9), 1e-6) {
+		t.Errorf("natural spline'' is not continuous at 0.5")
+	}
+	//Convergence on sin with many points
+	var xs, ys []float64
+	for i := 0; i <= 40; i++ {
+		xs = append(xs, float64(i)*math.Pi/40)
+		ys = append(ys, math.Sin(xs[i]))
+	}
+	s, _ := NewCubicSpline(xs, ys, NotAKnotSpline, 0, 0)
+	if v := s.Integrate(0, math.Pi); math.Abs(v-2) > 1e-6 {
+		t.Errorf("integral of the sin spline = %g, want 2", v)
+	}
+	if v, _ := AdaptiveSimpson(0, 1, s.F(), 1e-12); !soclose(v, s.Integrate(0, 1), 1e-10) {
+		t.Errorf("AdaptiveSimpson(spline) = %g, want %g", v, s.Integrate(0, 1))
+	}
+
+	if s, _ := NewCubicSpline([]float64{0, 1, 3}, []float64{0, 1, 9}, NotAKnotSpline, 0, 0); !close(s.Evaluate(2), 4) {
+		t.Errorf("not-a-knot spline with 3 points is not the parabola")
+	}
+	if _, err := NewCubicSpline([]float64{0, 1, 1}, []float64{0, 1, 2}, NaturalSpline, 0, 0); err == nil {
+		t.Errorf("NewCubicSpline() with repeated abscissas should fail")
+	}
+	if _, err := NewCubicSpline([]float64{0}, []float64{0}, NaturalSpline, 0, 0); err == nil {
+		t.Errorf("NewCubicSpline() with one point should fail")
+	}
+}
+
+func TestPCHIPAkima(t *testing.T) {
+	//Step like data, the cubic spline overshoots and goes negative
+	x := []float64{0, 1, 2, 3, 4, 5, 6}
+	y := []float64{0, 0, 0, 1, 1, 1, 1}
+	spline, _ := NewCubicSpline(x, y, NaturalSpline, 0, 0)
+	pchip, err := NewPCHIP(x, y)
+	if err != nil {
+		t.Fatal(err)
+	}
+	akima, err := NewAkima(x, y)
+	if err != nil {
+		t.Fatal(err)
+	}
+	overshoot := false
+	previous := pchip.Evaluate(0)
+	for v := 0.0; v <= 6; v += 0.01 {
+		if spline.Evaluate(v) < -1e-3 {
+			overshoot = true
+		}
+		p := pchip.Evaluate(v)
+		if p < previous-1e-15 || p < 0 || p > 1 {
+			t.Fatalf("PCHIP is not monotone at %g: %g after %g", v, p, previous)
+		}
+		previous = p
+		if a := akima.Evaluate(v); a < -1e-15 || a > 1+1e-15 {
+			t.Fatalf("Akima overshoots at %g: %g", v, a)
+		}
+	}
+	if !overshoot {
+		t.Errorf("the cubic spline should overshoot on this data")
+	}
+	for i := range x {
+		if pchip.Evaluate(x[i]) != y[i] || akima.Evaluate(x[i]) != y[i] {
+			t.Errorf("interpolants at %g = %g, %g, want %g", x[i], pchip.Evaluate(x[i]), akima.Evaluate(x[i]), y[i])
+		}
+	}
+
+	//Both are exact on a line
+	line := []float64{1, 3, 5, 7, 9, 11, 13}
+	pchip, _ = NewPCHIP(x, line)
+	akima, _ = NewAkima(x, line)
+	if !close(pchip.Evaluate(2.5), 6) || !close(akima.Evaluate(2.5), 6) || !close(pchip.Derivative(4.2), 2) {
+		t.Errorf("interpolants of a line at 2.5 = %g, %g", pchip.Evaluate(2.5), akima.Evaluate(2.5))
+	}
+	//First derivative continuity at a knot
+	pchip, _ = NewPCHIP(x, []float64{0, 1, 4, 9, 16, 25, 36})
+	if !soclose(pchip.Derivative(3-1e-9), pchip.Derivative(3+1e-9), 1e-6) {
+		t.Errorf("PCHIP' is not continuous at 3")
+	}
+	if _, err := NewAkima([]float64{1, 0}, []float64{0, 1}); err == nil {
+		t.Errorf("NewAkima() with decreasing abscissas should fail")
+	}
+	if s, err := NewPCHIP([]float64{0, 2}, []float64{1, 3}); err != nil || !close(s.Evaluate(1), 2) {
+		t.Errorf("NewPCHIP() with 2 points should be a line")
+	}
+}
+
+func TestInterp2D(t *testing.T) {
+	x := []float64{0, 0.5, 1.5, 2, 3}
+	y := []float64{-1, 0, 1, 2}
+	grid := func(f func(x, y float64) float64) *Matrix {
+		z := NewMatrix(uint(len(x)), uint(len(y)))
+		for i := range x {
+			for j := range y {
+				z.Set(uint(i), uint(j), f(x[i], y[j]))
+			}
+		}
+		return z
+	}
+
+	//Both methods are exact on a + bx + cy + dxy
+	bilinearF := func(x, y float64) float64 { return 1 + 2*x - y + 0.5*x*y }
+	z := grid(bilinearF)
+	for _, method := range []Interp2DMethod{Bilinear, Bicubic} {
+		g, err := NewInterp2D(x, y, z, method)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, p := range [][2]float64{{0.2, -0.3}, {1.7, 1.9}, {3, 2}, {0, -1}, {2.4, 0.5}} {
+			if v := g.Evaluate(p[0], p[1]); !close(v, bilinearF(p[0], p[1])) {
+				t.Errorf("method %d at %v = %g, want %g", method, p, v, bilinearF(p[0], p[1]))
+			}
+		}
+		v, _, err := Cubature(g.Fn(), []float64{0, -1}, []float64{3, 2}, 1e-10)
+		//integral of 1 + 2x - y + xy/2 on [0, 3]x[-1, 2]
+		if want := 9 + 27 - 4.5 + 0.5*4.5*1.5; err != nil || !soclose(v, want, 1e-9) {
+			t.Errorf("method %d integral = %g, want %g, error %v", method, v, want, err)
+		}
+	}
+
+	//On a smooth function the bicubic interpolation is much more accurate, and its
+	//gradient is continuous across the cells
+	smooth := func(x, y float64) float64 { return math.Sin(x) * math.Cos(y) }
+	x = nil
+	for i := 0; i <= 30; i++ {
+		x = append(x, float64(i)/10)
+	}
+	y = x
+	z = grid(smooth)
+	linear, _ := NewInterp2D(x, y, z, Bilinear)
+	cubic, _ := NewInterp2D(x, y, z, Bicubic)
+	p := []float64{1.23, 1.87}
+	errLinear := math.Abs(linear.Evaluate(p[0], p[1]) - smooth(p[0], p[1]))
+	errCubic := math.Abs(cubic.Evaluate(p[0], p[1]) - smooth(p[0], p[1]))
+	if errCubic > 1e-4 || errCubic > errLinear/5 {
+		t.Errorf("bicubic error %g, bilinear error %g", errCubic, errLinear)
+	}
+	left := Gradient([]float64{1.2 - 1e-7, 1.5}, cubic.Fn(), 1e-10)
+	right := Gradient([]float64{1.2 + 1e-7, 1.5}, cubic.Fn(), 1e-10)
+	if !soclose(left[0], right[0], 1e-4) {
+		t.Errorf("bicubic derivative in x is not continuous at 1.2: %g, %g", left[0], right[0])
+	}
+
+	//Changing the caller's matrix doesn't change the interpolation
+	before := cubic.Evaluate(p[0], p[1])
+	z.M[0] = 100
+	if after := cubic.Evaluate(p[0], p[1]); after != before || cubic.Z.M[0] == 100 {
+		t.Errorf("NewInterp2D() keeps the caller's matrix: %g then %g", before, after)
+	}
+
+	if _, err := NewInterp2D(x, y, NewMatrix(3, 3), Bilinear); err == nil {
+		t.Errorf("NewInterp2D() with a matrix of the wrong size should fail")
+	}
+}
+
+func TestBSpline(t *testing.T) {
+	knots := []float64{0, 0, 0, 1, 2, 2.5, 4, 4, 4}
+	//Partition of unity on [0, 4], including the last knot
+	for _, x := range []float64{0, 0.3, 1, 2.2, 3.9, 4} {
+		var sum float64
+		for i := 0; i < 6; i++ {
+			sum += BSplineBasis(knots, 2, i, x)
+		}
+		if !close(sum, 1) {
+			t.Errorf("sum of the basis functions at %g = %g", x, sum)
+		}
+	}
+	//Hat function
+	if v := BSplineBasis([]float64{0, 1, 2}, 1, 0, 0.5); v != 0.5 {
+		t.Errorf("BSplineBasis(hat, 0.5) = %g, want 0.5", v)
+	}
+
+	control := [][]float64{{0, 0}, {1, 2}, {2, -1}, {3, 3}, {4, 0}, {5, 1}}
+	s, err := NewBSpline(knots, 2, control)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p := s.Evaluate(0); p[0] != 0 || p[1] != 0 {
+		t.Errorf("clamped curve starts at %v", p)
+	}
+	if p := s.Evaluate(4); !close(p[0], 5) || !close(p[1], 1) {
+		t.Errorf("clamped curve ends at %v", p)
+	}
+	//de Boor agrees with the sum of the basis functions
+	for _, x := range []float64{0.4, 1.5, 2.25, 3.3} {
+		p := s.Evaluate(x)
+		var want [2]float64
+		for i := range control {
+			n := BSplineBasis(knots, 2, i, x)
+			want[0] += n * control[i][0]
+			want[1] += n * control[i][1]
+		}
+		if !close(p[0], want[0]) || !close(p[1], want[1]) {
+			t.Errorf("Evaluate(%g) = %v, want %v", x, p, want)
+		}
+	}
+
+	//Knot insertion keeps the curve
+	refined := s.InsertKnot(1.7).InsertKnot(1.7).InsertKnot(3)
+	if len(refined.Control) != 9 || len(refined.Knots) != 12 {
+		t.Errorf("InsertKnot() gave %d control points and %d knots", len(refined.Control), len(refined.Knots))
+	}
+	for x := 0.0; x <= 4; x += 0.125 {
+		p, q := s.Evaluate(x), refined.Evaluate(x)
+		if !close(p[0], q[0]) || !close(p[1], q[1]) {
+			t.Errorf("refined curve at %g = %v, want %v", x, q, p)
+		}
+	}
+
+	//Fitting points on a line is exact, on a circle arc it is close
+	var line, arc [][]float64
+	for i := 0; i <= 20; i++ {
+		u := float64(i) / 20
+		line = append(line, []float64{u, 2*u + 1})
+		arc = append(arc, []float64{math.Cos(u * math.Pi / 2), math.Sin(u * math.Pi / 2)})
+	}
+	fit, err := FitBSpline(line, 3, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p := fit.Evaluate(0.35); !soclose(p[0], 0.35, 1e-12) || !soclose(p[1], 1.7, 1e-12) {
+		t.Errorf("fitted line at 0.35 = %v", p)
+	}
+	fit, _ = FitBSpline(arc, 3, 8)
+	for _, x := range []float64{0, 0.21, 0.5, 0.83, 1} {
+		p := fit.Evaluate(x)
+		if r := math.Hypot(p[0], p[1]); math.Abs(r-1) > 1e-4 {
+			t.Errorf("fitted arc at %g = %v, radius %g", x, p, r)
+		}
+	}
+
+	if _, err := NewBSpline(knots[:8], 2, control); err == nil {
+		t.Errorf("NewBSpline() with a missing knot should fail")
+	}
+	if _, err := FitBSpline(arc, 3, 30); err == nil {
+		t.Errorf("FitBSpline() with more control points than points should fail")
+	}
+}
+
+func TestCubicHermite(t *testing.T) {
+	//Exact on a cubic with its derivative
+	x := []float64{-1, 0.5, 2}
+	p := NewPolynomial(2, -1, 0.5, 1)
+	d := p.Derivative()
+	y := []float64{p.Evaluate(-1), p.Evaluate(0.5), p.Evaluate(2)}
+	dydx := []float64{d.Evaluate(-1), d.Evaluate(0.5), d.Evaluate(2)}
+	h, err := NewCubicHermite(x, y, dydx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, v := range []float64{-1, -0.3, 0.5, 1.2, 2} {
+		if !close(h.Evaluate(v), p.Evaluate(v)) || !soclose(h.Derivative(v), d.Evaluate(v), 1e-13) {
+			t.Errorf("Hermite at %g = %g, %g, want %g, %g", v, h.Evaluate(v), h.Derivative(v), p.Evaluate(v), d.Evaluate(v))
+		}
+	}
+
+	//Derivatives of exp, fourth order accuracy
+	var xs, ys []float64
+	for i := 0; i <= 10; i++ {
+		xs = append(xs, float64(i)/10)
+		ys = append(ys, math.Exp(xs[i]))
+	}
+	h, _ = NewCubicHermite(xs, ys, ys)
+	if e := math.Abs(h.Evaluate(0.55) - math.Exp(0.55)); e > 1e-6 {
+		t.Errorf("Hermite exp(0.55) error = %g", e)
+	}
+
+	if v := CubicHermite(0, 1, 0, 0, 0.5); v != 0.5 {
+		t.Errorf("CubicHermite() ease at 0.5 = %g", v)
+	}
+	if v := CubicHermite(2, 5, 3, 3, 0.25); !close(v, 2.75) {
+		t.Errorf("CubicHermite() of a line = %g, want 2.75", v)
+	}
+	if _, err := NewCubicHermite(x, y, dydx[:2]); err == nil {
+		t.Errorf("NewCubicHermite() with missing derivatives should fail")
+	}
+}
+
+func TestEulerRK4(t *testing.T) {
+	//Harmonic oscillator y'' = -y, y(0) = 0, y'(0) = 1 so y = sin
+	oscillator := func(t float64, y []float64) []float64 {
+		return []float64{y[1], -y[0]}
+	}
+	r, err := RK4(oscillator, 0, []float64{0, 1}, math.Pi/2, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	times, states := r.Times, r.States
+	if len(times) != 101 || len(states) != 101 || times[100] != math.Pi/2 {
+		t.Fatalf("RK4() gave %d times, error %v", len(times), err)
+	}
+	if !soclose(states[100][0], 1, 1e-9) || math.Abs(states[100][1]) > 1e-9 {
+		t.Errorf("RK4() y(pi/2) = %v, want [1 0]", states[100])
+	}
+	for i := range times {
+		if math.Abs(states[i][0]-math.Sin(times[i])) > 1e-9 {
+			t.Fatalf("RK4() y(%g) = %g, want %g", times[i], states[i][0], math.Sin(times[i]))
+		}
+	}
+
+	//Order of convergence on y' = y
+	growth := func(t float64, y []float64) []float64 { return []float64{y[0]} }
+	errorAt := func(method func(ODE, float64, []float64, float64, int) (*ODEResult, error), n int) float64 {
+		r, _ := method(growth, 0, []float64{1}, 1, n)
+		return math.Abs(r.States[n][0] - math.E)
+	}
+	if r := errorAt(Euler, 100) / errorAt(Euler, 200); r < 1.9 || r > 2.1 {
+		t.Errorf("Euler error ratio = %g, want 2", r)
+	}
+	if r := errorAt(RK4, 10) / errorAt(RK4, 20); r < 15 || r > 17 {
+		t.Errorf("RK4 error ratio = %g, want 16", r)
+	}
+	//Backward in time
+	r, _ = RK4(growth, 1, []float64{math.E}, 0, 50)
+	states = r.States
+	if !soclose(states[50][0], 1, 1e-8) {
+		t.Errorf("RK4() backward = %g, want 1", states[50][0])
+	}
+
+	if _, err := RK4(func(t float64, y []float64) []float64 { return []float64{1} }, 0, []float64{0, 0}, 1, 10); err == nil {
+		t.Errorf("RK4() with a wrong dimension should fail")
+	}
+	blowUp := func(t float64, y []float64) []float64 { return []float64{y[0] * y[0]} }
+	r, err = Euler(blowUp, 0, []float64{1}, 2, 1000)
+	if err == nil || len(r.Times) == 1001 {
+		t.Errorf("Euler() on a solution going to infinity should fail, %d points, error %v", len(r.Times), err)
+	}
+	if _, err := Euler(growth, 0, []float64{1}, 1, 0); err == nil {
+		t.Errorf("Euler() without steps should fail")
+	}
+}
+
+func TestRK45(t *testing.T) {
+	oscillator := func(t float64, y []float64) []float64 {
+		return []float64{y[1], -y[0]}
+	}
+	for _, tol := range []float64{1e-4, 1e-8, 1e-11} {
+		r, err := RK45(oscillator, 0, []float64{0, 1}, 10, ODEOptions{RelTol: tol, AbsTol: tol})
+		times, states := r.Times, r.States
+		if err != nil || times[len(times)-1] != 10 {
+			t.Fatalf("RK45(tol %g) ends at %g, error %v", tol, times[len(times)-1], err)
+		}
+		last := states[len(states)-1]
+		if e := math.Abs(last[0] - math.Sin(10)); e > 100*tol {
+			t.Errorf("RK45(tol %g) error = %g after %d steps", tol, e, len(times)-1)
+		}
+	}
+
+	//Multi-scale: the step grows when the fast transient is over
+	fast := func(t float64, y []float64) []float64 {
+		return []float64{-50 * (y[0] - math.Cos(t))}
+	}
+	r, err := RK45(fast, 0, []float64{0}, 5, ODEOptions{})
+	times, states := r.Times, r.States
+	if err != nil {
+		t.Fatal(err)
+	}
+	first := times[1] - times[0]
+	largest := 0.0
+	for i := 1; i < len(times); i++ {
+		largest = math.Max(largest, times[i]-times[i-1])
+	}
+	if largest < 5*first {
+		t.Errorf("RK45() step went from %g to at most %g", first, largest)
+	}
+	//y is close to cos(t) + sin(t)/50 once the transient is over
+	if v := states[len(states)-1][0]; math.Abs(v-(2500*math.Cos(5)+50*math.Sin(5))/2501) > 1e-5 {
+		t.Errorf("RK45() y(5) = %g", v)
+	}
+
+	//Backward, MaxStep and MaxSteps
+	growth := func(t float64, y []float64) []float64 { return []float64{y[0]} }
+	r, err = RK45(growth, 1, []float64{math.E}, 0, ODEOptions{MaxStep: 0.01})
+	times, states = r.Times, r.States
+	if err != nil || !soclose(states[len(states)-1][0], 1, 1e-6) || len(times) < 100 {
+		t.Errorf("RK45() backward = %g with %d steps, error %v", states[len(states)-1][0], len(times)-1, err)
+	}
+	if _, err := RK45(growth, 0, []float64{1}, 100, ODEOptions{MaxSteps: 5}); err == nil {
+		t.Errorf("RK45() with 5 steps should fail")
+	}
+	if _, err := RK45(fast, 0, []float64{0}, 5, ODEOptions{MinStep: 1}); err == nil {
+		t.Errorf("RK45() with a too large MinStep should fail")
+	}
+}
+
+func TestMatrixSolve(t *testing.T) {
+	m := NewMatrix(3, 3)
+	m.M = []float64{
+		0, 2, 1,
+		1, 1, 1,
+		2, 1, 0,
+	}
+	x, err := m.Solve([]float64{7, 6, 4})
+	if err != nil || !close(x[0], 1) || !close(x[1], 2) || !close(x[2], 3) {
+		t.Errorf("Solve() = %v, error %v", x, err)
+	}
+	m.M = []float64{1, 2, 3, 2, 4, 6, 0, 1, 1}
+	if _, err := m.Solve([]float64{1, 2, 3}); err == nil {
+		t.Errorf("Solve() of a singular system should fail")
+	}
+	if _, err := m.Solve([]float64{1, 2}); err == nil {
+		t.Errorf("Solve() with a wrong right hand side should fail")
+	}
+}
+
+func TestImplicitODE(t *testing.T) {
+	//Stiff: y' = -1000 (y - cos t), the explicit methods blow up with h = 0.01
+	stiff := func(t float64, y []float64) []float64 {
+		return []float64{-1000 * (y[0] - math.Cos(t))}
+	}
+	//Solution after the transient
+	exact := func(t float64) float64 {
+		return (1e6*math.Cos(t) + 1000*math.Sin(t)) / (1e6 + 1)
+	}
+	if r, err := RK4(stiff, 0, []float64{0}, 2, 200); err == nil && math.Abs(r.States[200][0]) < 1e10 {
+		t.Errorf("RK4() should blow up on the stiff problem, y(2) = %g", r.States[200][0])
+	}
+	for name, method := range map[string]func(ODE, float64, []float64, float64, int) (*ODEResult, error){
+		"BackwardEuler": BackwardEuler,
+		"BDF2":          BDF2,
+	} {
+		r, err := method(stiff, 0, []float64{0}, 2, 200)
+		if err != nil {
+			t.Fatalf("%s() error %v", name, err)
+		}
+		times, states := r.Times, r.States
+		if len(times) != 201 || times[200] != 2 {
+			t.Fatalf("%s() gave %d times, error %v", name, len(times), err)
+		}
+		if e := math.Abs(states[200][0] - exact(2)); e > 1e-3 {
+			t.Errorf("%s() y(2) = %g, want %g", name, states[200][0], exact(2))
+		}
+	}
+
+	//Orders of convergence on a non stiff problem y' = -y^2, y = 1/(1+t)
+	decay := func(t float64, y []float64) []float64 { return []float64{-y[0] * y[0]} }
+	errorAt := func(method func(ODE, float64, []float64, float64, int) (*ODEResult, error), n int) float64 {
+		r, _ := method(decay, 0, []float64{1}, 1, n)
+		return math.Abs(r.States[n][0] - 0.5)
+	}
+	if r := errorAt(BackwardEuler, 100) / errorAt(BackwardEuler, 200); r < 1.9 || r > 2.1 {
+		t.Errorf("BackwardEuler error ratio = %g, want 2", r)
+	}
+	if r := errorAt(BDF2, 100) / errorAt(BDF2, 200); r < 3.7 || r > 4.3 {
+		t.Errorf("BDF2 error ratio = %g, want 4", r)
+	}
+	if r, err := BDF2(decay, 0, []float64{1}, 1, 1); err != nil || len(r.Times) != 2 || r.Times[1] != 1 {
+		t.Errorf("BDF2() with one step = %v, error %v", r, err)
+	}
+	if _, err := BDF2(decay, 0, []float64{1}, 1, 0); err == nil {
+		t.Errorf("BDF2() without steps should fail")
+	}
+}
+
+func TestODEEvents(t *testing.T) {
+	//Projectile thrown up at 5 m/s from 10 m
+	const g = 9.81
+	projectile := func(t float64, y []float64) []float64 {
+		return []float64{y[1], -g}
+	}
+	events := []ODEEvent{
+		//Hits the ground
+		{G: func(t float64, y []float64) float64 { return y[0] }, Direction: -1, Terminal: true},
+		//Apex
+		{G: func(t float64, y []float64) float64 { return y[1] }},
+		//Ignored, the projectile goes down when it is at 5 m
+		{G: func(t float64, y []float64) float64 { return y[0] - 5 }, Direction: 1},
+	}
+	ground := (5 + math.Sqrt(25+2*g*10)) / g
+	r, err := RK45(projectile, 0, []float64{10, 5}, 100, ODEOptions{Events: events})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r.Events) != 2 || r.Events[0].Event != 1 || r.Events[1].Event != 0 {
+		t.Fatalf("RK45() events = %+v", r.Events)
+	}
+	if !soclose(r.Events[0].T, 5/g, 1e-12) || !soclose(r.Events[1].T, ground, 1e-12) || math.Abs(r.Events[1].Y[0]) > 1e-10 {
+		t.Errorf("apex at %g, ground at %g, want %g and %g", r.Events[0].T, r.Events[1].T, 5/g, ground)
+	}
+	if last := r.Times[len(r.Times)-1]; last != r.Events[1].T {
+		t.Errorf("integration stopped at %g, want %g", last, r.Events[1].T)
+	}
+
+	//Continuous output between the steps
+	oscillator := func(t float64, y []float64) []float64 {
+		return []float64{y[1], -y[0]}
+	}
+	r, _ = RK45(oscillator, 0, []float64{0, 1}, 10, ODEOptions{RelTol: 1e-10, AbsTol: 1e-10})
+	for _, v := range []float64{0, 0.123, 3.3, 7.77, 10} {
+		if y := r.At(v); math.Abs(y[0]-math.Sin(v)) > 1e-6 || math.Abs(y[1]-math.Cos(v)) > 1e-6 {
+			t.Errorf("At(%g) = %v, want [%g %g]", v, y, math.Sin(v), math.Cos(v))
+		}
+	}
+	if r.At(-1) != nil || r.At(11) != nil {
+		t.Errorf("At() out of the interval should be nil")
+	}
+
+	//Fixed step trajectory, backward in time
+	r, _ = RK4(oscillator, 10, []float64{math.Sin(10), math.Cos(10)}, 0, 200)
+	times, states := r.Times, r.States
+	zero := []ODEEvent{{G: func(t float64, y []float64) float64 { return y[0] }}}
+	r, err = NewODEResult(oscillator, times, states, zero)
+	if err != nil || len(r.Events) != 3 {
+		t.Fatalf("NewODEResult() events = %+v, error %v", r.Events, err)
+	}
+	for i, want := range []float64{3 * math.Pi, 2 * math.Pi, math.Pi} {
+		if math.Abs(r.Events[i].T-want) > 1e-6 {
+			t.Errorf("zero %d of sin at %g, want %g", i, r.Events[i].T, want)
+		}
+	}
+	if y := r.At(5.5); math.Abs(y[0]-math.Sin(5.5)) > 1e-6 {
+		t.Errorf("At(5.5) = %v", y)
+	}
+	zero[0].Terminal = true
+	r, _ = NewODEResult(oscillator, times, states, zero)
+	if len(r.Events) != 1 || r.Times[len(r.Times)-1] != r.Events[0].T {
+		t.Errorf("terminal event on a fixed step trajectory = %+v", r.Events)
+	}
+}
+
+func TestAdams(t *testing.T) {
+	var calls int
+	oscillator := func(t float64, y []float64) []float64 {
+		calls++
+		return []float64{y[1], -y[0]}
+	}
+	type method func(ODE, float64, []float64, float64, int) (*ODEResult, error)
+	//Largest error on the trajectory
+	errorAt := func(m method, n int) float64 {
+		r, err := m(oscillator, 0, []float64{0, 1}, 5, n)
+		if err != nil {
+			t.Fatal(err)
+		}
+		times, states := r.Times, r.States
+		var e float64
+		for i := range times {
+			e = math.Max(e, math.Abs(states[i][0]-math.Sin(times[i])))
+		}
+		return e
+	}
+	for name, m := range map[string]method{"AdamsBashforth": AdamsBashforth, "AdamsBashforthMoulton": AdamsBashforthMoulton} {
+		if r := errorAt(m, 200) / errorAt(m, 400); r < 14 || r > 18 {
+			t.Errorf("%s error ratio = %g, want 16", name, r)
+		}
+	}
+	if errorAt(AdamsBashforthMoulton, 200) > errorAt(AdamsBashforth, 200)/5 {
+		t.Errorf("the corrector should improve the error: %g and %g", errorAt(AdamsBashforthMoulton, 200), errorAt(AdamsBashforth, 200))
+	}
+
+	//One evaluation per step after the RK4 start
+	calls = 0
+	r, _ := AdamsBashforth(oscillator, 0, []float64{0, 1}, 5, 200)
+	times := r.Times
+	if len(times) != 201 || times[200] != 5 || calls != 3*4+4+197 {
+		t.Errorf("AdamsBashforth() used %d evaluations for %d points", calls, len(times))
+	}
+	//Few steps fall back on RK4
+	if r, err := AdamsBashforthMoulton(oscillator, 0, []float64{0, 1}, 1, 2); err != nil || len(r.Times) != 3 {
+		t.Errorf("AdamsBashforthMoulton() with 2 steps = %v, error %v", r, err)
+	}
+}
+
+func TestShooting(t *testing.T) {
+	//Linear: y'' = -y, y(0) = 0, y(pi/2) = 2 so y = 2 sin(x)
+	linear := func(x float64, y []float64) []float64 {
+		return []float64{y[1], -y[0]}
+	}
+	r, slope, err := Shooting(linear, 0, math.Pi/2, 0, 2, 0, 1, nil, ODEOptions{RelTol: 1e-10, AbsTol: 1e-12})
+	if err != nil || !soclose(slope, 2, 1e-8) {
+		t.Fatalf("Shooting() slope = %g, error %v", slope, err)
+	}
+	if y := r.At(math.Pi / 6); !soclose(y[0], 1, 1e-7) {
+		t.Errorf("Shooting() y(pi/6) = %g, want 1", y[0])
+	}
+
+	//Nonlinear (Bratu like): y'' = -exp(y), y(0) = y(1) = 0, the lower branch has
+	//y'(0) = 0.5493...
+	bratu := func(x float64, y []float64) []float64 {
+		return []float64{y[1], -math.Exp(y[0])}
+	}
+	r, slope, err = Shooting(bratu, 0, 1, 0, 0, 0, 1, nil, ODEOptions{RelTol: 1e-10, AbsTol: 1e-12})
+	if err != nil || math.Abs(r.States[len(r.States)-1][0]) > 1e-8 {
+		t.Fatalf("Shooting(Bratu) slope = %g, error %v", slope, err)
+	}
+	//Symmetric solution, maximum at 0.5
+	if y := r.At(0.5); math.Abs(y[1]) > 1e-6 || !soclose(slope, 0.549352, 1e-5) {
+		t.Errorf("Shooting(Bratu) y'(0.5) = %g, slope %g", y[1], slope)
+	}
+
+	//A third component integrating y^2 from 1: 1 + int(4 sin^2) = 1 + pi at pi/2
+	withIntegral := func(x float64, y []float64) []float64 {
+		return []float64{y[1], -y[0] + 0*y[2], y[0] * y[0]}
+	}
+	r, slope, err = Shooting(withIntegral, 0, math.Pi/2, 0, 2, 0, 1, []float64{1}, ODEOptions{RelTol: 1e-10, AbsTol: 1e-12})
+	if err != nil || !soclose(slope, 2, 1e-8) || !soclose(r.States[len(r.States)-1][2], 1+math.Pi, 1e-8) {
+		t.Errorf("Shooting() with 3 components slope = %g, error %v", slope, err)
+	}
+
+	//No solution: y'' = 0 with y(0) = 0 is a line, the guesses give the same residual
+	if _, _, err := Shooting(func(x float64, y []float64) []float64 { return []float64{0, 0} }, 0, 1, 0, 1, 0, 1, nil, ODEOptions{}); err == nil {
+		t.Errorf("Shooting() without solution should fail")
+	}
+}
+
+func TestODEResult(t *testing.T) {
+	oscillator := func(t float64, y []float64) []float64 {
+		return []float64{y[1], -y[0]}
+	}
+	r, err := RK4(oscillator, 0, []float64{0, 1}, 2, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := r.StatesMatrix()
+	if m.NumberOfRows != 5 || m.NumberOfColumns != 2 || m.Get(4, 0) != r.States[4][0] || m.Get(2, 1) != r.States[2][1] {
+		t.Errorf("StatesMatrix() = %v", m)
+	}
+
+	//Evenly spaced points from the adaptive solver
+	r, err = RK45(oscillator, 0, []float64{0, 1}, 10, ODEOptions{RelTol: 1e-10, AbsTol: 1e-10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	times := make([]float64, 11)
+	for i := range times {
+		times[i] = float64(i)
+	}
+	s, err := r.Resample(times)
+	if err != nil || len(s.States) != 11 {
+		t.Fatalf("Resample() = %v, error %v", s, err)
+	}
+	for i, v := range s.Times {
+		if math.Abs(s.States[i][0]-math.Sin(v)) > 1e-6 {
+			t.Errorf("Resample() y(%g) = %g, want %g", v, s.States[i][0], math.Sin(v))
+		}
+	}
+	if y := s.At(2.5); math.Abs(y[0]-math.Sin(2.5)) > 1e-2 {
+		t.Errorf("At(2.5) on the resampled solution = %v", y)
+	}
+	if _, err := r.Resample([]float64{5, 11}); err == nil {
+		t.Errorf("Resample() out of the interval should fail")
+	}
+
+	var b bytes.Buffer
+	if err := s.WriteCSV(&b); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(b.String()), "\n")
+	if len(lines) != 12 || lines[0] != "t,y0,y1" || !strings.HasPrefix(lines[1], "0,0,1") {
+		t.Errorf("WriteCSV() = %q", b.String())
+	}
+	read, header, err := ReadCSVWithOptions(&b, CSVOptions{Header: true})
+	if err != nil || len(header) != 3 || read.Get(10, 0) != 10 || read.Get(10, 1) != s.States[10][0] {
+		t.Errorf("WriteCSV() read back as %v, error %v", read, err)
+	}
+
+	//Events are exported with the states
+	ground := []ODEEvent{{G: func(t float64, y []float64) float64 { return y[0] }, Terminal: true}}
+	r, _ = RK45(oscillator, 0.5, []float64{math.Sin(0.5), math.Cos(0.5)}, 10, ODEOptions{Events: ground})
+	data, err := json.Marshal(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded struct {
+		Times  []float64
+		States Matrix
+		Events []EventHit
+	}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if len(decoded.Times) != len(r.Times) || decoded.States.NumberOfRows != uint(len(r.Times)) || len(decoded.Events) != 1 || !soclose(decoded.Events[0].T, math.Pi, 1e-6) {
+		t.Errorf("MarshalJSON() = %s", data)
+	}
+}
+
+func TestPDE(t *testing.T) {
+	//u = exp(-pi^2 t) sin(pi x)
+	heat := HeatEquation{
+		Diffusivity: 1,
+		Length:      1,
+		Initial:     func(x float64) float64 { return math.Sin(math.Pi * x) },
+	}
+	exact := math.Exp(-math.Pi*math.Pi*0.1) * math.Sin(math.Pi*0.5)
+	u, err := heat.Explicit(20, 100, 0.1)
+	if err != nil || u.NumberOfRows != 101 || u.NumberOfColumns != 21 {
+		t.Fatalf("Explicit() = %v, error %v", u, err)
+	}
+	if v := u.Get(100, 10); !soclose(v, exact, 1e-2) {
+		t.Errorf("Explicit() u(0.5, 0.1) = %g, want %g", v, exact)
+	}
+	if _, err := heat.Explicit(20, 10, 0.1); err == nil {
+		t.Errorf("Explicit() with r = 4 should fail")
+	}
+	//Second order in time: the error is divided by 4 when both steps are halved
+	errorAt := func(n int) float64 {
+		u, err := heat.CrankNicolson(n, n, 0.1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return math.Abs(u.Get(uint(n), uint(n/2)) - exact)
+	}
+	if r := errorAt(20) / errorAt(40); r < 3.8 || r > 4.2 {
+		t.Errorf("CrankNicolson error ratio = %g, want 4", r)
+	}
+	//Large steps are stable
+	u, err = heat.CrankNicolson(50, 5, 0.1)
+	if err != nil || !soclose(u.Get(5, 25), exact, 1e-2) {
+		t.Errorf("CrankNicolson() with r = 50 gives %g, want %g", u.Get(5, 25), exact)
+	}
+	//Steady state between two temperatures is linear
+	heat.Left = func(t float64) float64 { return 1 }
+	u, _ = heat.CrankNicolson(10, 100, 5)
+	for i := 0; i <= 10; i++ {
+		if v := u.Get(100, uint(i)); math.Abs(v-(1-float64(i)/10)) > 1e-8 {
+			t.Errorf("steady state u(%g) = %g", float64(i)/10, v)
+		}
+	}
+
+	//Standing wave u = sin(pi x) cos(pi t)
+	wave := WaveEquation{
+		Speed:   1,
+		Length:  1,
+		Initial: func(x float64) float64 { return math.Sin(math.Pi * x) },
+	}
+	u, err = wave.Leapfrog(20, 30, 1.5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for k := 0; k <= 30; k++ {
+		want := math.Sin(math.Pi*0.25) * math.Cos(math.Pi*float64(k)*0.05)
+		if v := u.Get(uint(k), 5); math.Abs(v-want) > 1e-12 {
+			t.Fatalf("Leapfrog() with C = 1 u(0.25, %g) = %g, want %g", float64(k)*0.05, v, want)
+		}
+	}
+	//Initial velocity: u = sin(pi x) sin(pi t) / pi
+	wave.Initial = func(x float64) float64 { return 0 }
+	wave.Velocity = func(x float64) float64 { return math.Sin(math.Pi * x) }
+	u, err = wave.Leapfrog(50, 200, 2)
+	want := math.Sin(math.Pi*0.5) * math.Sin(math.Pi*0.5) / math.Pi
+	if err != nil || math.Abs(u.Get(50, 25)-want) > 1e-3 {
+		t.Errorf("Leapfrog() u(0.5, 0.5) = %g, want %g, error %v", u.Get(50, 25), want, err)
+	}
+	if _, err := wave.Leapfrog(50, 50, 2); err == nil {
+		t.Errorf("Leapfrog() with C = 2 should fail")
+	}
+	if _, err := wave.Leapfrog(1, 50, 2); err == nil {
+		t.Errorf("Leapfrog() with one interval should fail")
+	}
+}
+
+func TestPoisson(t *testing.T) {
+	//u = x^2 + y^2 is exact with the five-point stencil
+	quadratic := PoissonEquation{
+		Source:   func(p []float64) float64 { return 4 },
+		Boundary: func(p []float64) float64 { return p[0]*p[0] + p[1]*p[1] },
+		X0:       -1, X1: 1,
+		Y0: 0, Y1: 3,
+	}
+	for _, method := range []PoissonMethod{ConjugateGradient, SOR} {
+		u, err := quadratic.Solve(20, 30, PoissonOptions{Method: method, Tolerance: 1e-12})
+		if err != nil || u.NumberOfRows != 21 || u.NumberOfColumns != 31 {
+			t.Fatalf("Solve(%d) = %v, error %v", method, u, err)
+		}
+		for i := 0; i <= 20; i++ {
+			for j := 0; j <= 30; j++ {
+				x, y := -1+float64(i)*0.1, float64(j)*0.1
+				if v := u.Get(uint(i), uint(j)); math.Abs(v-(x*x+y*y)) > 1e-8 {
+					t.Fatalf("Solve(%d) u(%g, %g) = %g, want %g", method, x, y, v, x*x+y*y)
+				}
+			}
+		}
+	}
+
+	//u = sin(pi x) sin(pi y), second order
+	sine := PoissonEquation{
+		Source: func(p []float64) float64 {
+			return -2 * math.Pi * math.Pi * math.Sin(math.Pi*p[0]) * math.Sin(math.Pi*p[1])
+		},
+		X1: 1,
+		Y1: 1,
+	}
+	errorAt := func(n int) float64 {
+		u, err := sine.Solve(n, n, PoissonOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return math.Abs(u.Get(uint(n/2), uint(n/2)) - 1)
+	}
+	if r := errorAt(16) / errorAt(32); r < 3.9 || r > 4.1 {
+		t.Errorf("Poisson error ratio = %g, want 4", r)
+	}
+	//Works on the result as on any grid
+	u, _ := sine.Solve(32, 32, PoissonOptions{Method: SOR})
+	grid := make([]float64, 33)
+	for i := range grid {
+		grid[i] = float64(i) / 32
+	}
+	g, err := NewInterp2D(grid, grid, u, Bicubic)
+	if err != nil || math.Abs(g.Evaluate(0.3, 0.6)-math.Sin(0.3*math.Pi)*math.Sin(0.6*math.Pi)) > 1e-2 {
+		t.Errorf("interpolated Poisson solution = %g, error %v", g.Evaluate(0.3, 0.6), err)
+	}
+
+	if _, err := sine.Solve(32, 32, PoissonOptions{Method: SOR, MaxIterations: 3}); err == nil {
+		t.Errorf("Solve() with 3 iterations should fail")
+	}
+	if _, err := sine.Solve(1, 32, PoissonOptions{}); err == nil {
+		t.Errorf("Solve() without interior point should fail")
+	}
+}
+
+func TestMinimize1D(t *testing.T) {
+	parabola := func(x float64) float64 { return (x-2)*(x-2) + 1 }
+	golden, err := Minimize1D(0, 5, parabola, GoldenSection, 0)
+	if err != nil || math.Abs(golden.X-2) > 1e-7 || golden.Value != parabola(golden.X) {
+		t.Errorf("Minimize1D(GoldenSection) = %+v, error %v", golden, err)
+	}
+	brent, err := Minimize1D(5, 0, parabola, BrentMinimization, 0)
+	if err != nil || math.Abs(brent.X-2) > 1e-7 || !brent.Converged {
+		t.Errorf("Minimize1D(BrentMinimization) = %+v, error %v", brent, err)
+	}
+	if brent.Iterations >= golden.Iterations {
+		t.Errorf("Brent used %d iterations, golden section %d", brent.Iterations, golden.Iterations)
+	}
+	for _, method := range []Minimize1DMethod{GoldenSection, BrentMinimization} {
+		if r, err := Minimize1D(3, 4, math.Cos, method, 1e-10); err != nil || math.Abs(r.X-math.Pi) > 1e-7 {
+			t.Errorf("Minimize1D(cos, %d) = %+v, error %v", method, r, err)
+		}
+		//Decreasing up to the end of the interval
+		if r, _ := Minimize1D(0, 1, func(x float64) float64 { return -x }, method, 0); math.Abs(r.X-1) > 1e-6 {
+			t.Errorf("Minimize1D(-x, %d) = %+v", method, r)
+		}
+	}
+	//Not smooth
+	if r, err := Minimize1D(-1, 3, func(x float64) float64 { return math.Abs(x - 0.3) }, BrentMinimization, 0); err != nil || math.Abs(r.X-0.3) > 1e-7 {
+		t.Errorf("Minimize1D(|x-0.3|) = %+v, error %v", r, err)
+	}
+
+	a, b, c, err := BracketMinimum(10, parabola)
+	if err != nil || a >= b || b >= c || parabola(b) > parabola(a) || parabola(b) > parabola(c) || a > 2 || c < 2 {
+		t.Errorf("BracketMinimum() = %g %g %g, error %v", a, b, c, err)
+	}
+	r, err := Minimize1DFrom(-30, func(x float64) float64 { return math.Cosh(x - 7) }, BrentMinimization, 1e-10)
+	if err != nil || math.Abs(r.X-7) > 1e-5 || !soclose(r.Value, 1, 1e-12) {
+		t.Errorf("Minimize1DFrom() = %+v, error %v", r, err)
+	}
+	if _, err := Minimize1DFrom(0, func(x float64) float64 { return -x * x }, GoldenSection, 0); err == nil {
+		t.Errorf("Minimize1DFrom() of a function not bounded below should fail")
+	}
+}
+
+func TestGradientDescent(t *testing.T) {
+	f := func(x []float64) float64 { return (x[0]-1)*(x[0]-1) + 10*(x[1]+2)*(x[1]+2) }
+	gradient := func(x []float64) []float64 { return []float64{2 * (x[0] - 1), 20 * (x[1] + 2)} }
+	at := func(r OptimizeResult, tol float64) bool {
+		return math.Abs(r.X[0]-1) <= tol && math.Abs(r.X[1]+2) <= tol
+	}
+
+	var calls int
+	vanilla, err := GradientDescent(f, []float64{0, 0}, VanillaDescent, 0.005, OptimizeOptions{
+		Gradient:    gradient,
+		OnIteration: func(s OptimizeState) { calls++ },
+	})
+	if err != nil || !at(vanilla, 1e-6) || Norm(vanilla.Gradient) > 1e-6 || calls != vanilla.Iterations || vanilla.Evaluations != vanilla.Iterations+1 {
+		t.Errorf("GradientDescent(VanillaDescent) = %+v, %d callbacks, error %v", vanilla, calls, err)
+	}
+	momentum, err := GradientDescent(f, []float64{0, 0}, MomentumDescent, 0.005, OptimizeOptions{Gradient: gradient})
+	if err != nil || !at(momentum, 1e-6) || momentum.Iterations >= vanilla.Iterations {
+		t.Errorf("GradientDescent(MomentumDescent) = %+v after %d iterations, vanilla %d", momentum.X, momentum.Iterations, vanilla.Iterations)
+	}
+	adam, err := GradientDescent(f, []float64{0, 0}, AdamDescent, 0.05, OptimizeOptions{Gradient: gradient, GradientTolerance: 1e-4})
+	if err != nil || !at(adam, 1e-4) {
+		t.Errorf("GradientDescent(AdamDescent) = %+v, error %v", adam, err)
+	}
+
+	//Numerical gradient, stopped on the value
+	r, err := GradientDescent(f, []float64{0, 0}, VanillaDescent, 0.04, OptimizeOptions{ValueTolerance: 1e-14})
+	if err != nil || !at(r, 1e-5) || r.Evaluations <= 10*r.Iterations {
+		t.Errorf("GradientDescent() with numerical gradient = %+v, error %v", r, err)
+	}
+
+	if r, err := GradientDescent(f, []float64{0, 0}, VanillaDescent, 1, OptimizeOptions{Gradient: gradient}); err == nil {
+		t.Errorf("GradientDescent() with a too large rate should diverge, got %+v", r)
+	}
+	if _, err := GradientDescent(f, []float64{0, 0}, VanillaDescent, 1e-6, OptimizeOptions{Gradient: gradient, MaxIterations: 10}); err == nil {
+		t.Errorf("GradientDescent() with 10 iterations should not converge")
+	}
+}
+
+func TestNelderMead(t *testing.T) {
+	rosenbrock := func(x []float64) float64 {
+		return 100*(x[1]-x[0]*x[0])*(x[1]-x[0]*x[0]) + (1-x[0])*(1-x[0])
+	}
+	var calls int
+	r, err := NelderMead(rosenbrock, []float64{-1.2, 1}, 0, OptimizeOptions{
+		OnIteration: func(s OptimizeState) { calls++ },
+	})
+	if err != nil || math.Abs(r.X[0]-1) > 1e-6 || math.Abs(r.X[1]-1) > 1e-6 || calls != r.Iterations || r.Gradient != nil {
+		t.Errorf("NelderMead(Rosenbrock) = %+v, error %v", r, err)
+	}
+
+	//Not smooth, in 3 dimensions
+	kink := func(x []float64) float64 { return math.Abs(x[0]-1) + 2*math.Abs(x[1]+3) + math.Abs(x[2]) }
+	r, err = NelderMead(kink, []float64{0, 0, 0}, 1, OptimizeOptions{})
+	if err != nil || math.Abs(r.X[0]-1) > 1e-6 || math.Abs(r.X[1]+3) > 1e-6 || math.Abs(r.X[2]) > 1e-6 {
+		t.Errorf("NelderMead(kink) = %+v, error %v", r, err)
+	}
+
+	//Noisy, stopped on the values
+	noisy := func(x []float64) float64 {
+		return (x[0]-2)*(x[0]-2) + (x[1]-1)*(x[1]-1) + 1e-8*math.Sin(1e7*x[0])
+	}
+	r, err = NelderMead(noisy, []float64{0, 0}, 0, OptimizeOptions{ValueTolerance: 1e-7})
+	if err != nil || math.Abs(r.X[0]-2) > 1e-3 || math.Abs(r.X[1]-1) > 1e-3 {
+		t.Errorf("NelderMead(noisy) = %+v, error %v", r, err)
+	}
+
+	//Undefined out of x > 0
+	domain := func(x []float64) float64 {
+		if x[0] <= 0 {
+			return math.NaN()
+		}
+		return x[0] - math.Log(x[0]) + x[1]*x[1]
+	}
+	r, err = NelderMead(domain, []float64{3, 1}, 0, OptimizeOptions{})
+	if err != nil || math.Abs(r.X[0]-1) > 1e-6 || math.Abs(r.X[1]) > 1e-6 {
+		t.Errorf("NelderMead(domain) = %+v, error %v", r, err)
+	}
+
+	if _, err := NelderMead(rosenbrock, []float64{-1.2, 1}, 0, OptimizeOptions{MaxIterations: 10}); err == nil {
+		t.Errorf("NelderMead() with 10 iterations should not converge")
+	}
+	if _, err := NelderMead(rosenbrock, nil, 0, OptimizeOptions{}); err == nil {
+		t.Errorf("NelderMead() without variables should fail")
+	}
+}
+
+func TestBFGS(t *testing.T) {
+	rosenbrock := func(x []float64) float64 {
+		var sum float64
+		for i := 0; i+1 < len(x); i++ {
+			sum += 100*(x[i+1]-x[i]*x[i])*(x[i+1]-x[i]*x[i]) + (1-x[i])*(1-x[i])
+		}
+		return sum
+	}
+	gradient := func(x []float64) []float64 {
+		g := make([]float64, len(x))
+		for i := 0; i+1 < len(x); i++ {
+			g[i] += -400*x[i]*(x[i+1]-x[i]*x[i]) - 2*(1-x[i])
+			g[i+1] += 200 * (x[i+1] - x[i]*x[i])
+		}
+		return g
+	}
+	ones := func(r OptimizeResult, tol float64) bool {
+		for _, v := range r.X {
+			if math.Abs(v-1) > tol {
+				return false
+			}
+		}
+		return true
+	}
+
+	var calls int
+	r, err := BFGS(rosenbrock, []float64{-1.2, 1}, OptimizeOptions{
+		Gradient:          gradient,
+		GradientTolerance: 1e-9,
+		OnIteration:       func(s OptimizeState) { calls++ },
+	})
+	if err != nil || !ones(r, 1e-8) || r.Iterations > 100 || calls != r.Iterations {
+		t.Errorf("BFGS(Rosenbrock) = %+v, error %v", r, err)
+	}
+	r, err = LBFGS(rosenbrock, []float64{-1.2, 1}, 5, OptimizeOptions{Gradient: gradient, GradientTolerance: 1e-9})
+	if err != nil || !ones(r, 1e-8) || r.Iterations > 100 {
+		t.Errorf("LBFGS(Rosenbrock) = %+v, error %v", r, err)
+	}
+
+	//Many variables
+	x0 := make([]float64, 50)
+	for i := range x0 {
+		x0[i] = -1.2
+		if i%2 == 1 {
+			x0[i] = 1
+		}
+	}
+	r, err = LBFGS(rosenbrock, x0, 0, OptimizeOptions{Gradient: gradient})
+	if err != nil || !ones(r, 1e-5) {
+		t.Errorf("LBFGS(Rosenbrock 50) value %g after %d iterations, error %v", r.Value, r.Iterations, err)
+	}
+
+	//Convex quadratic with a numerical gradient: the exact line searches are not
+	//needed to converge in a few iterations
+	quadratic := func(x []float64) float64 {
+		return (x[0]-1)*(x[0]-1) + 10*(x[1]+2)*(x[1]+2) + 0.5*(x[2]-x[0])*(x[2]-x[0])
+	}
+	r, err = BFGS(quadratic, []float64{0, 0, 0}, OptimizeOptions{})
+	if err != nil || math.Abs(r.X[0]-1) > 1e-6 || math.Abs(r.X[1]+2) > 1e-6 || math.Abs(r.X[2]-1) > 1e-6 || r.Iterations > 20 {
+		t.Errorf("BFGS(quadratic) = %+v, error %v", r, err)
+	}
+
+	if _, err := BFGS(rosenbrock, []float64{-1.2, 1}, OptimizeOptions{Gradient: gradient, MaxIterations: 3}); err == nil {
+		t.Errorf("BFGS() with 3 iterations should not converge")
+	}
+	wrong := func(x []float64) []float64 { return []float64{-1, -1} }
+	if _, err := LBFGS(rosenbrock, []float64{-1.2, 1}, 0, OptimizeOptions{Gradient: wrong}); err == nil {
+		t.Errorf("LBFGS() with a wrong gradient should fail")
+	}
+}
+
+func TestConstrainedOptimization(t *testing.T) {
+	f := func(x []float64) float64 { return (x[0]-2)*(x[0]-2) + (x[1]+1)*(x[1]+1) }
+	gradient := func(x []float64) []float64 { return []float64{2 * (x[0] - 2), 2 * (x[1] + 1)} }
+	box := OptimizeOptions{
+		Gradient: gradient,
+		Lower:    []float64{0, 0},
+		Upper:    []float64{1, 3},
+	}
+	methods := map[string]minimizer{
+		"GradientDescent": func(f Fn, x0 []float64, o OptimizeOptions) (OptimizeResult, error) {
+			return GradientDescent(f, x0, VanillaDescent, 0.1, o)
+		},
+		"NelderMead": func(f Fn, x0 []float64, o OptimizeOptions) (OptimizeResult, error) {
+			return NelderMead(f, x0, 0, o)
+		},
+		"BFGS": BFGS,
+		"LBFGS": func(f Fn, x0 []float64, o OptimizeOptions) (OptimizeResult, error) {
+			return LBFGS(f, x0, 0, o)
+		},
+	}
+	for name, method := range methods {
+		//The starting point is out of the bounds
+		r, err := method(f, []float64{5, 2}, box)
+		if err != nil || math.Abs(r.X[0]-1) > 1e-6 || math.Abs(r.X[1]) > 1e-6 {
+			t.Errorf("%s() with bounds = %+v, error %v", name, r, err)
+		}
+	}
+	//Only an upper bound on the second variable
+	r, err := BFGS(f, []float64{0, 0}, OptimizeOptions{Upper: []float64{math.Inf(1), -2}})
+	if err != nil || math.Abs(r.X[0]-2) > 1e-6 || r.X[1] != -2 {
+		t.Errorf("BFGS() with an upper bound = %+v, error %v", r, err)
+	}
+
+	//Closest point to (2, -1) on the line x + y = 3: (3, 0)
+	line := OptimizeOptions{
+		Gradient: gradient,
+		Equality: []Fn{func(x []float64) float64 { return x[0] + x[1] - 3 }},
+	}
+	//The penalty makes f steeper, the learning rate must be smaller
+	methods["GradientDescent"] = func(f Fn, x0 []float64, o OptimizeOptions) (OptimizeResult, error) {
+		return GradientDescent(f, x0, VanillaDescent, 0.01, o)
+	}
+	for name, method := range methods {
+		r, err := method(f, []float64{0, 0}, line)
+		if err != nil || math.Abs(r.X[0]-3) > 1e-5 || math.Abs(r.X[1]) > 1e-5 || !soclose(r.Value, f(r.X), 1e-15) {
+			t.Errorf("%s() with an equality = %+v, error %v", name, r, err)
+		}
+	}
+	//Both: on the circle of radius 1 with y >= 0, closest to (2, -1) is (1, 0)
+	circle := OptimizeOptions{
+		Lower:    []float64{math.Inf(-1), 0},
+		Equality: []Fn{func(x []float64) float64 { return x[0]*x[0] + x[1]*x[1] - 1 }},
+	}
+	r, err = LBFGS(f, []float64{0, 1}, 0, circle)
+	if err != nil || math.Abs(r.X[0]-1) > 1e-5 || math.Abs(r.X[1]) > 1e-5 {
+		t.Errorf("LBFGS() on the half circle = %+v, error %v", r, err)
+	}
+
+	if _, err := BFGS(f, []float64{0, 0}, OptimizeOptions{Lower: []float64{0}}); err == nil {
+		t.Errorf("BFGS() with a wrong number of bounds should fail")
+	}
+	if _, err := NelderMead(f, []float64{0, 0}, 0, OptimizeOptions{Lower: []float64{0, 1}, Upper: []float64{1, 0}}); err == nil {
+		t.Errorf("NelderMead() with lower > upper should fail")
+	}
+}
+
+func TestGlobalOptimization(t *testing.T) {
+	//Rastrigin: many local minima, the global one at 0
+	rastrigin := func(x []float64) float64 {
+		sum := 10 * float64(len(x))
+		for _, v := range x {
+			sum += v*v - 10*math.Cos(2*math.Pi*v)
+		}
+		return sum
+	}
+	bounds := OptimizeOptions{
+		Lower: []float64{-5.12, -5.12},
+		Upper: []float64{5.12, 5.12},
+	}
+	//A local method stays in a local minimum
+	if r, _ := BFGS(rastrigin, []float64{3.1, -2.9}, OptimizeOptions{}); r.Value < 1 {
+		t.Fatalf("BFGS() should be stuck, got %+v", r)
+	}
+
+	r, err := DifferentialEvolution(rastrigin, EvolutionOptions{}, bounds)
+	if err != nil || math.Abs(r.X[0]) > 1e-6 || math.Abs(r.X[1]) > 1e-6 || r.Evaluations != 20000 || !r.Converged {
+		t.Errorf("DifferentialEvolution() = %+v, error %v", r, err)
+	}
+	//Same seed, same result
+	again, _ := DifferentialEvolution(rastrigin, EvolutionOptions{Source: rand.NewSource(1)}, bounds)
+	if !reflect.DeepEqual(r.X, again.X) {
+		t.Errorf("DifferentialEvolution() with the same seed = %v and %v", r.X, again.X)
+	}
+	early := bounds
+	early.ValueTolerance = 1e-10
+	if e, err := DifferentialEvolution(rastrigin, EvolutionOptions{Population: 30}, early); err != nil || e.Evaluations >= 20000 || e.Value > 1e-8 {
+		t.Errorf("DifferentialEvolution() with a tolerance = %+v, error %v", e, err)
+	}
+
+	var calls int
+	sa, err := SimulatedAnnealing(rastrigin, []float64{3.1, -2.9}, AnnealingOptions{Schedule: GeometricCooling(10, 0.9995), Source: rand.NewSource(7)}, OptimizeOptions{
+		Lower:       bounds.Lower,
+		Upper:       bounds.Upper,
+		OnIteration: func(s OptimizeState) { calls++ },
+	})
+	if err != nil || sa.Value > 0.05 || sa.Evaluations != 20000 || calls != sa.Iterations {
+		t.Errorf("SimulatedAnnealing() = %+v, error %v", sa, err)
+	}
+	//Polished with a local method
+	if p, err := BFGS(rastrigin, sa.X, OptimizeOptions{}); err != nil || p.Value > 1e-10 {
+		t.Errorf("BFGS() from the annealing = %+v, error %v", p, err)
+	}
+	if LogarithmicCooling(1)(0) != 1/math.Log(2) {
+		t.Errorf("LogarithmicCooling(1)(0) = %g", LogarithmicCooling(1)(0))
+	}
+
+	if _, err := DifferentialEvolution(rastrigin, EvolutionOptions{}, OptimizeOptions{Lower: []float64{0, 0}}); err == nil {
+		t.Errorf("DifferentialEvolution() without upper bounds should fail")
+	}
+	if _, err := DifferentialEvolution(rastrigin, EvolutionOptions{Population: 3}, bounds); err == nil {
+		t.Errorf("DifferentialEvolution() with 3 points should fail")
+	}
+	line := bounds
+	line.Equality = []Fn{func(x []float64) float64 { return x[0] - x[1] }}
+	if _, err := DifferentialEvolution(rastrigin, EvolutionOptions{Population: 3}, line); err == nil {
+		t.Errorf("DifferentialEvolution() with 3 points and a constraint should fail")
+	}
+
+	//The budget is shared by the rounds of the method of multipliers
+	var evaluations int
+	counted := func(x []float64) float64 {
+		evaluations++
+		return (x[0]-1)*(x[0]-1) + (x[1]-2)*(x[1]-2)
+	}
+	constrained := bounds
+	constrained.MaxEvaluations = 1000
+	constrained.Equality = []Fn{func(x []float64) float64 { return x[0] + x[1] - 1 }}
+	SimulatedAnnealing(counted, []float64{0, 0}, AnnealingOptions{}, constrained)
+	if evaluations > 1000 {
+		t.Errorf("SimulatedAnnealing() with a constraint used %d evaluations, want at most 1000", evaluations)
+	}
+	evaluations = 0
+	DifferentialEvolution(counted, EvolutionOptions{}, constrained)
+	if evaluations > 1000 {
+		t.Errorf("DifferentialEvolution() with a constraint used %d evaluations, want at most 1000", evaluations)
+	}
+	evaluations = 0
+	constrained.MaxEvaluations = 0
+	if r, err := DifferentialEvolution(counted, EvolutionOptions{}, constrained); err != nil || evaluations > 20000 || math.Abs(r.X[0]) > 1e-6 || math.Abs(r.X[1]-1) > 1e-6 {
+		t.Errorf("DifferentialEvolution() with a constraint = %+v, error %v, %d evaluations", r, err, evaluations)
+	}
+}
+
+func TestQuadraticProgram(t *testing.T) {
+	spd := NewMatrix(2, 2)
+	spd.M = []float64{4, 2, 2, 3}
+	l, err := spd.Cholesky()
+	if err != nil || !reflect.DeepEqual(l.M[:3], []float64{2, 0, 1}) || !veryclose(l.M[3], math.Sqrt2) {
+		t.Errorf("Cholesky() = %v, error %v", l, err)
+	}
+	indefinite := NewMatrix(2, 2)
+	indefinite.M = []float64{1, 2, 2, 1}
+	if _, err := indefinite.Cholesky(); err == nil {
+		t.Errorf("Cholesky() of an indefinite matrix should fail")
+	}
+
+	//Example 16.4 of Nocedal and Wright: (x-1)^2 + (y-2.5)^2 in a pentagon
+	constraints := NewMatrix(5, 2)
+	constraints.M = []float64{-1, 2, 1, 2, 1, -2, -1, 0, 0, -1}
+	twice := NewMatrix(2, 2)
+	twice.M = []float64{2, 0, 0, 2}
+	example := QuadraticProgram{
+		Q: twice,
+		C: []float64{-2, -5},
+		A: constraints,
+		B: []float64{2, 6, 2, 0, 0},
+	}
+	r, err := example.Solve()
+	if err != nil || !r.Converged || !veryclose(r.X[0], 1.4) || !veryclose(r.X[1], 1.7) || !veryclose(r.Value, 0.8-7.25) {
+		t.Errorf("Solve() = %+v, error %v", r, err)
+	}
+
+	//Minimum variance portfolio of two uncorrelated assets (Q being twice the
+	//covariance), fully invested, no short selling, with an expected return of at
+	//least 1.5
+	covariance := NewMatrix(2, 2)
+	covariance.M = []float64{2, 0, 0, 8}
+	budget := NewMatrix(1, 2)
+	budget.M = []float64{1, 1}
+	portfolio := QuadraticProgram{
+		Q:   covariance,
+		Aeq: budget,
+		Beq: []float64{1},
+	}
+	if r, err := portfolio.Solve(); err != nil || !veryclose(r.X[0], 0.8) || !veryclose(r.X[1], 0.2) {
+		t.Errorf("Solve() of the portfolio = %+v, error %v", r, err)
+	}
+	returns := NewMatrix(3, 2)
+	returns.M = []float64{-1, -2, -1, 0, 0, -1}
+	portfolio.A, portfolio.B = returns, []float64{-1.5, 0, 0}
+	if r, err := portfolio.Solve(); err != nil || !veryclose(r.X[0], 0.5) || !veryclose(r.X[1], 0.5) || !veryclose(r.Value, 1.25) {
+		t.Errorf("Solve() of the portfolio with a return = %+v, error %v", r, err)
+	}
+	//An equality reached from the other side
+	portfolio.A, portfolio.B, portfolio.Beq = nil, nil, []float64{-1}
+	if r, err := portfolio.Solve(); err != nil || !veryclose(r.X[0], -0.8) || !veryclose(r.X[1], -0.2) {
+		t.Errorf("Solve() of the short portfolio = %+v, error %v", r, err)
+	}
+
+	infeasible := NewMatrix(2, 1)
+	infeasible.M = []float64{1, -1}
+	if _, err := (QuadraticProgram{Q: NewIdentity(1), A: infeasible, B: []float64{0, -1}}).Solve(); err == nil {
+		t.Errorf("Solve() with x <= 0 and x >= 1 should fail")
+	}
+	if _, err := (QuadraticProgram{Q: indefinite}).Solve(); err == nil {
+		t.Errorf("Solve() with an indefinite Q should fail")
+	}
+	if _, err := (QuadraticProgram{Q: NewIdentity(2), C: []float64{1}}).Solve(); err == nil {
+		t.Errorf("Solve() with a wrong size should fail")
+	}
+}
+
+func TestLineSearch(t *testing.T) {
+	rosenbrock := func(x []float64) float64 {
+		return 100*(x[1]-x[0]*x[0])*(x[1]-x[0]*x[0]) + (1-x[0])*(1-x[0])
+	}
+	gradient := func(x []float64) []float64 {
+		return []float64{-400*x[0]*(x[1]-x[0]*x[0]) - 2*(1-x[0]), 200 * (x[1] - x[0]*x[0])}
+	}
+	x := []float64{-1.2, 1}
+	g := gradient(x)
+	d := []float64{-g[0], -g[1]}
+	slope := g[0]*d[0] + g[1]*d[1]
+
+	b, err := BacktrackingLineSearch(rosenbrock, gradient, x, d, 1, 0)
+	if err != nil || b.Step <= 0 || b.Value > rosenbrock(x)+1e-4*b.Step*slope || b.Gradient != nil || b.Value != rosenbrock(b.X) {
+		t.Errorf("BacktrackingLineSearch() = %+v, error %v", b, err)
+	}
+	//The largest power of 2 accepted
+	if rosenbrock([]float64{x[0] + 2*b.Step*d[0], x[1] + 2*b.Step*d[1]}) <= rosenbrock(x)+2e-4*b.Step*slope {
+		t.Errorf("BacktrackingLineSearch() step %g is too short", b.Step)
+	}
+
+	w, err := WolfeLineSearch(rosenbrock, gradient, x, d, 1e-4, 0, 0.1)
+	if err != nil || w.Value > rosenbrock(x)+1e-4*w.Step*slope || math.Abs(w.Gradient[0]*d[0]+w.Gradient[1]*d[1]) > -0.1*slope {
+		t.Errorf("WolfeLineSearch() = %+v, error %v", w, err)
+	}
+	//Numerical gradient
+	if n, err := WolfeLineSearch(rosenbrock, nil, x, d, 1e-4, 0, 0.1); err != nil || !soclose(n.Step, w.Step, 1e-6) {
+		t.Errorf("WolfeLineSearch() with a numerical gradient = %+v, error %v", n, err)
+	}
+
+	//A steepest descent built on the line search reaches the bottom of the valley
+	for i := 0; i < 100; i++ {
+		g := gradient(x)
+		r, err := WolfeLineSearch(rosenbrock, gradient, x, []float64{-g[0], -g[1]}, 0, 0, 0.1)
+		if err != nil {
+			t.Fatalf("WolfeLineSearch() at iteration %d, error %v", i, err)
+		}
+		x = r.X
+	}
+	if rosenbrock(x) > 0.5 {
+		t.Errorf("Steepest descent reached %v, f = %g", x, rosenbrock(x))
+	}
+
+	if _, err := BacktrackingLineSearch(rosenbrock, gradient, []float64{-1.2, 1}, g, 0, 0); err == nil {
+		t.Errorf("BacktrackingLineSearch() uphill should fail")
+	}
+	if _, err := WolfeLineSearch(rosenbrock, gradient, []float64{-1.2, 1}, d, 0, 0.5, 0.4); err == nil {
+		t.Errorf("WolfeLineSearch() with c1 > c2 should fail")
+	}
+	if _, err := WolfeLineSearch(rosenbrock, gradient, []float64{-1.2, 1}, []float64{1}, 0, 0, 0); err == nil {
+		t.Errorf("WolfeLineSearch() with a wrong direction size should fail")
+	}
+}
+
+func TestDescriptiveStatistics(t *testing.T) {
+	data := []float64{2, 4, 4, 4, 5, 5, 7, 9}
+	check := func(name string, got float64, err error, want float64) {
+		if err != nil || !veryclose(got, want) {
+			t.Errorf("%s = %g, want %g, error %v", name, got, want, err)
+		}
+	}
+	m, err := Mean(data, PropagateNaN)
+	check("Mean()", m, err, 5)
+	v, err := Variance(data, false, PropagateNaN)
+	check("Variance()", v, err, 4)
+	v, err = Variance(data, true, PropagateNaN)
+	check("Variance() unbiased", v, err, 32.0/7)
+	s, err := StdDev(data, false, PropagateNaN)
+	check("StdDev()", s, err, 2)
+	median, err := Median(data, PropagateNaN)
+	check("Median()", median, err, 4.5)
+	median, err = Median([]float64{3, 1, 2}, PropagateNaN)
+	check("Median() odd", median, err, 2)
+	q, err := Quantile(data, 0.9, PropagateNaN)
+	check("Quantile(0.9)", q, err, 7.6)
+	q, err = Quantile(data, 1, PropagateNaN)
+	check("Quantile(1)", q, err, 9)
+	skew, err := Skewness(data, false, PropagateNaN)
+	check("Skewness()", skew, err, 0.65625)
+	skew, err = Skewness(data, true, PropagateNaN)
+	check("Skewness() unbiased", skew, err, 0.65625*math.Sqrt(56)/6)
+	kurt, err := Kurtosis(data, false, PropagateNaN)
+	check("Kurtosis()", kurt, err, -0.21875)
+	kurt, err = Kurtosis(data, true, PropagateNaN)
+	check("Kurtosis() unbiased", kurt, err, 0.940625)
+	if !reflect.DeepEqual(data, []float64{2, 4, 4, 4, 5, 5, 7, 9}) {
+		t.Errorf("Quantile() modified the sample: %v", data)
+	}
+
+	//A large mean doesn't spoil the variance
+	shifted := make([]float64, len(data))
+	for i := range data {
+		shifted[i] = data[i] + 1e9
+	}
+	v, err = Variance(shifted, false, PropagateNaN)
+	check("Variance() shifted", v, err, 4)
+
+	missing := []float64{2, 4, math.NaN(), 4, 4, 5, 5, 7, math.NaN(), 9}
+	if m, err := Mean(missing, PropagateNaN); err != nil || !math.IsNaN(m) {
+		t.Errorf("Mean() with NaN = %g, error %v", m, err)
+	}
+	if q, err := Median(missing, PropagateNaN); err != nil || !math.IsNaN(q) {
+		t.Errorf("Median() with NaN = %g, error %v", q, err)
+	}
+	m, err = Mean(missing, OmitNaN)
+	check("Mean() omitting NaN", m, err, 5)
+	kurt, err = Kurtosis(missing, true, OmitNaN)
+	check("Kurtosis() omitting NaN", kurt, err, 0.940625)
+	if _, err := Variance(missing, true, RejectNaN); err == nil {
+		t.Errorf("Variance() should reject NaN")
+	}
+
+	if _, err := Mean(nil, PropagateNaN); err == nil {
+		t.Errorf("Mean() of an empty sample should fail")
+	}
+	if _, err := Mean([]float64{math.NaN()}, OmitNaN); err == nil {
+		t.Errorf("Mean() of NaN only should fail when they are omitted")
+	}
+	if _, err := Variance([]float64{1}, true, PropagateNaN); err == nil {
+		t.Errorf("Variance() unbiased of a single value should fail")
+	}
+	if _, err := Quantile(data, 1.5, PropagateNaN); err == nil {
+		t.Errorf("Quantile(1.5) should fail")
+	}
+	if _, err := Skewness([]float64{3, 3, 3}, false, PropagateNaN); err == nil {
+		t.Errorf("Skewness() of a constant sample should fail")
+	}
+}
+
+func TestRegression(t *testing.T) {
+	r, err := LinearRegression([]float64{1, 2, 3, 4, 5}, []float64{2, 4, 5, 4, 5})
+	if err != nil || !veryclose(r.Coefficients[0], 2.2) || !veryclose(r.Coefficients[1], 0.6) {
+		t.Fatalf("LinearRegression() = %+v, error %v", r, err)
+	}
+	if !soclose(r.RSquared, 0.6, 1e-12) || !soclose(r.AdjustedRSquared, 1-0.4*4/3, 1e-12) || !soclose(r.ResidualStdError, math.Sqrt(0.8), 1e-12) {
+		t.Errorf("LinearRegression() R2 = %g, adjusted %g, residual error %g", r.RSquared, r.AdjustedRSquared, r.ResidualStdError)
+	}
+	if !soclose(r.StandardErrors[0], math.Sqrt(0.88), 1e-12) || !soclose(r.StandardErrors[1], math.Sqrt(0.08), 1e-12) {
+		t.Errorf("LinearRegression() standard errors = %v", r.StandardErrors)
+	}
+	for i, want := range []float64{-0.8, 0.6, 1, -0.6, -0.2} {
+		if !soclose(r.Residuals[i], want, 1e-12) {
+			t.Errorf("LinearRegression() residual %d = %g, want %g", i, r.Residuals[i], want)
+		}
+	}
+
+	//y = 1 + 2 x1 - 3 x2 exactly
+	x := NewMatrix(6, 2)
+	x.M = []float64{0, 1, 1, 0, 2, 3, 3, 1, 4, 4, 5, 2}
+	y := make([]float64, 6)
+	for i := range y {
+		y[i] = 1 + 2*x.M[2*i] - 3*x.M[2*i+1]
+	}
+	m, err := MultipleRegression(x, y)
+	if err != nil || !soclose(m.Coefficients[0], 1, 1e-12) || !soclose(m.Coefficients[1], 2, 1e-12) || !soclose(m.Coefficients[2], -3, 1e-12) || !soclose(m.RSquared, 1, 1e-12) || m.StandardErrors[2] > 1e-12 {
+		t.Errorf("MultipleRegression() = %+v, error %v", m, err)
+	}
+
+	//The second variable is twice the first
+	collinear := NewMatrix(4, 2)
+	collinear.M = []float64{1, 2, 2, 4, 3, 6, 4, 8}
+	if _, err := MultipleRegression(collinear, []float64{1, 2, 3, 5}); err == nil {
+		t.Errorf("MultipleRegression() of collinear variables should fail")
+	}
+	if _, err := LinearRegression([]float64{1, 2}, []float64{1, 2}); err == nil {
+		t.Errorf("LinearRegression() of 2 points should fail")
+	}
+	if _, err := LinearRegression([]float64{1, 2, 3}, []float64{1, 2}); err == nil {
+		t.Errorf("LinearRegression() with a wrong size should fail")
+	}
+}
+
+func TestSampling(t *testing.T) {
+	normal := NormalSample(100000, 3, 2, rand.NewSource(5))
+	m, _ := Mean(normal, PropagateNaN)
+	s, _ := StdDev(normal, true, PropagateNaN)
+	if !soclose(m, 3, 0.01) || !soclose(s, 2, 0.01) {
+		t.Errorf("NormalSample() mean %g, standard deviation %g", m, s)
+	}
+	if !reflect.DeepEqual(NormalSample(10, 0, 1, nil), NormalSample(10, 0, 1, rand.NewSource(1))) {
+		t.Errorf("NormalSample() should use the seed 1 by default")
+	}
+
+	covariance := NewMatrix(2, 2)
+	covariance.M = []float64{4, 1.2, 1.2, 1}
+	mvn, err := MultivariateNormalSample(100000, []float64{1, -1}, covariance, rand.NewSource(5))
+	if err != nil || mvn.NumberOfRows != 100000 || mvn.NumberOfColumns != 2 {
+		t.Fatalf("MultivariateNormalSample() = %v, error %v", mvn, err)
+	}
+	means := mvn.columnMeans()
+	estimate, _ := mvn.Covariance(true)
+	if !soclose(means[0], 1, 0.02) || !soclose(means[1], -1, 0.02) {
+		t.Errorf("MultivariateNormalSample() means = %v", means)
+	}
+	for i, want := range covariance.M {
+		if !soclose(estimate.M[i], want, 0.03) {
+			t.Errorf("MultivariateNormalSample() covariance = %v", estimate.M)
+			break
+		}
+	}
+	if _, err := MultivariateNormalSample(10, []float64{0}, covariance, nil); err == nil {
+		t.Errorf("MultivariateNormalSample() with a wrong mean size should fail")
+	}
+	singular := NewMatrix(2, 2)
+	singular.M = []float64{1, 1, 1, 1}
+	if _, err := MultivariateNormalSample(10, []float64{0, 0}, singular, nil); err == nil {
+		t.Errorf("MultivariateNormalSample() with a singular covariance should fail")
+	}
+
+	p := NewRandomPermutation(20, rand.NewSource(3))
+	if !p.IsValid() || reflect.DeepEqual(p, NewPermutation(20)) {
+		t.Errorf("NewRandomPermutation() = %v", p)
+	}
+	data := []float64{1, 2, 3, 4, 5, 6, 7, 8}
+	shuffled := Shuffle(data, rand.NewSource(3))
+	sorted := append([]float64(nil), shuffled...)
+	sort.Float64s(sorted)
+	if !reflect.DeepEqual(sorted, data) || reflect.DeepEqual(shuffled, data) {
+		t.Errorf("Shuffle() = %v", shuffled)
+	}
+	//Every index is equally likely at position 0
+	counts := make([]int, 4)
+	random := rand.NewSource(9)
+	for k := 0; k < 40000; k++ {
+		counts[NewRandomPermutation(4, random)[0]]++
+	}
+	for _, c := range counts {
+		if c < 9500 || c > 10500 {
+			t.Errorf("NewRandomPermutation() first index counts = %v", counts)
+			break
+		}
+	}
+
+	resample := Resample(data, rand.NewSource(4))
+	for _, v := range resample {
+		if v < 1 || v > 8 || v != math.Trunc(v) {
+			t.Errorf("Resample() = %v", resample)
+		}
+	}
+	//The standard error of the mean is sigma/sqrt(n)
+	sample := NormalSample(100, 0, 1, rand.NewSource(8))
+	means2, err := Bootstrap(sample, 2000, func(x []float64) float64 {
+		m, _ := Mean(x, PropagateNaN)
+		return m
+	}, rand.NewSource(8))
+	sigma, _ := StdDev(sample, true, PropagateNaN)
+	se, _ := StdDev(means2, true, PropagateNaN)
+	if err != nil || len(means2) != 2000 || !soclose(se, sigma/10, 0.1) {
+		t.Errorf("Bootstrap() standard error = %g, want about %g, error %v", se, sigma/10, err)
+	}
+	if _, err := Bootstrap(nil, 10, func(x []float64) float64 { return 0 }, nil); err == nil {
+		t.Errorf("Bootstrap() of an empty sample should fail")
+	}
+}
+
+func TestPCA(t *testing.T) {
+	symmetric := NewMatrix(3, 3)
+	symmetric.M = []float64{4, 1, 2, 1, 3, 0, 2, 0, 5}
+	values, vectors, err := symmetric.SymmetricEigen()
+	if err != nil || values[0] < values[1] || values[1] < values[2] {
+		t.Fatalf("SymmetricEigen() = %v, error %v", values, err)
+	}
+	trace, _ := symmetric.Trace()
+	det, _ := symmetric.Determinant()
+	if !veryclose(values[0]+values[1]+values[2], trace) || !soclose(values[0]*values[1]*values[2], det, 1e-13) {
+		t.Errorf("SymmetricEigen() = %v, trace %g, determinant %g", values, trace, det)
+	}
+	//A v = lambda v and V'V = I
+	for k := 0; k < 3; k++ {
+		v := vectors.GetColumn(uint(k))
+		for i := 0; i < 3; i++ {
+			av := symmetric.M[3*i]*v[0] + symmetric.M[3*i+1]*v[1] + symmetric.M[3*i+2]*v[2]
+			if math.Abs(av-values[k]*v[i]) > 1e-13 {
+				t.Errorf("SymmetricEigen() eigenvector %d = %v is wrong", k, v)
+			}
+		}
+		for j := 0; j < 3; j++ {
+			d, _ := Dot(v, vectors.GetColumn(uint(j)))
+			if (j == k && math.Abs(d-1) > 1e-14) || (j != k && math.Abs(d) > 1e-14) {
+				t.Errorf("SymmetricEigen() eigenvectors %d and %d have a product %g", k, j, d)
+			}
+		}
+	}
+	if _, _, err := NewMatrix(2, 3).SymmetricEigen(); err == nil {
+		t.Errorf("SymmetricEigen() of a non square matrix should fail")
+	}
+
+	//Points along the direction (3, 4)/5 with a small orthogonal noise
+	sample := NormalSample(400, 0, 1, rand.NewSource(2))
+	data := NewMatrix(200, 2)
+	for i := 0; i < 200; i++ {
+		s, e := 10*sample[2*i], 0.1*sample[2*i+1]
+		data.M[2*i] = 1 + 0.6*s - 0.8*e
+		data.M[2*i+1] = 2 + 0.8*s + 0.6*e
+	}
+	pca, err := PCA(data)
+	if err != nil {
+		t.Fatalf("PCA() error %v", err)
+	}
+	if !soclose(pca.Components.M[0], 0.6, 1e-3) || !soclose(pca.Components.M[2], 0.8, 1e-3) || pca.ExplainedRatio[0] < 0.999 || !veryclose(pca.ExplainedRatio[0]+pca.ExplainedRatio[1], 1) {
+		t.Errorf("PCA() = %+v", pca)
+	}
+	//The variance of the scores are the variances of the components, and the scores
+	//give the data back
+	scores := pca.Scores.GetColumn(0)
+	v, _ := Variance(scores, true, PropagateNaN)
+	if !veryclose(v, pca.Variances[0]) {
+		t.Errorf("PCA() variance of the scores %g, want %g", v, pca.Variances[0])
+	}
+	for i := 0; i < 200; i++ {
+		for j := 0; j < 2; j++ {
+			x := pca.Means[j] + pca.Scores.M[2*i]*pca.Components.M[2*j] + pca.Scores.M[2*i+1]*pca.Components.M[2*j+1]
+			if math.Abs(x-data.M[2*i+j]) > 1e-12 {
+				t.Fatalf("PCA() reconstruction of %d = %g, want %g", i, x, data.M[2*i+j])
+			}
+		}
+	}
+	if _, err := PCA(NewMatrix(1, 2)); err == nil {
+		t.Errorf("PCA() of a single observation should fail")
+	}
+
+	//Larger random matrices, A v = lambda v up to round-off
+	const n = 40
+	for seed := int64(0); seed < 10; seed++ {
+		random := NormalSample(n*n, 0, 1, rand.NewSource(seed))
+		large := NewMatrix(n, n)
+		for i := 0; i < n; i++ {
+			for j := 0; j <= i; j++ {
+				large.M[i*n+j], large.M[j*n+i] = random[i*n+j], random[i*n+j]
+			}
+		}
+		values, vectors, err := large.SymmetricEigen()
+		if err != nil {
+			t.Fatalf("SymmetricEigen() of a random %dx%d matrix returned error %v", n, n, err)
+		}
+		for k := 0; k < n; k++ {
+			v := vectors.GetColumn(uint(k))
+			for i := 0; i < n; i++ {
+				if av := dot(large.M[i*n:(i+1)*n], v); math.Abs(av-values[k]*v[i]) > 1e-12 {
+					t.Fatalf("SymmetricEigen() eigenvector %d of a random matrix is wrong", k)
+				}
+			}
+		}
+
+		randomData := NewMatrix(100, n)
+		randomData.M = NormalSample(100*n, 0, 1, rand.NewSource(seed+100))
+		if _, err := PCA(randomData); err != nil {
+			t.Errorf("PCA() of random 100x%d data returned error %v", n, err)
+		}
+	}
+}
+
+func TestCorrelation(t *testing.T) {
+	x := []float64{1, 2, 3, 4, 5}
+	y := []float64{2, 4, 5, 4, 5}
+	c, err := Covariance(x, y, true)
+	if err != nil || !veryclose(c, 1.5) {
+		t.Errorf("Covariance() = %g, error %v", c, err)
+	}
+	c, err = Covariance(x, y, false)
+	if err != nil || !veryclose(c, 1.2) {
+		t.Errorf("Covariance() biased = %g, error %v", c, err)
+	}
+	r, err := Correlation(x, y)
+	if err != nil || !veryclose(r, 6/math.Sqrt(60)) {
+		t.Errorf("Correlation() = %g, error %v", r, err)
+	}
+	if r, err := Correlation(x, []float64{10, 8, 6, 4, 2}); err != nil || r != -1 {
+		t.Errorf("Correlation() of a decreasing line = %g, error %v", r, err)
+	}
+
+	//Monotonic but not linear
+	cubes := []float64{1, 8, 27, 64, 125}
+	if s, err := SpearmanCorrelation(x, cubes); err != nil || !veryclose(s, 1) {
+		t.Errorf("SpearmanCorrelation() = %g, error %v", s, err)
+	}
+	if !reflect.DeepEqual(ranks([]float64{10, 20, 10, 30, 20, 10}), []float64{2, 4.5, 2, 6, 4.5, 2}) {
+		t.Errorf("ranks() = %v", ranks([]float64{10, 20, 10, 30, 20, 10}))
+	}
+	//Ranks of y: 1, 2.5, 4.5, 2.5, 4.5
+	s, err := SpearmanCorrelation(x, y)
+	want, _ := Correlation([]float64{1, 2, 3, 4, 5}, []float64{1, 2.5, 4.5, 2.5, 4.5})
+	if err != nil || !veryclose(s, want) {
+		t.Errorf("SpearmanCorrelation() with ties = %g, want %g, error %v", s, want, err)
+	}
+
+	data := NewMatrix(5, 3)
+	for i := range x {
+		data.SetRow(uint(i), []float64{x[i], y[i], cubes[i]})
+	}
+	matrix, err := data.SpearmanCorrelation()
+	if err != nil || !veryclose(matrix.Get(0, 1), s) || !veryclose(matrix.Get(2, 0), 1) || matrix.Get(1, 1) != 1 {
+		t.Errorf("SpearmanCorrelation() matrix = %v, error %v", matrix, err)
+	}
+
+	if _, err := Correlation(x, []float64{3, 3, 3, 3, 3}); err == nil {
+		t.Errorf("Correlation() with a constant series should fail")
+	}
+	if _, err := SpearmanCorrelation(x, []float64{3, 3, 3, 3, 3}); err == nil {
+		t.Errorf("SpearmanCorrelation() with a constant series should fail")
+	}
+	if _, err := Covariance(x, y[:4], false); err == nil {
+		t.Errorf("Covariance() with different sizes should fail")
+	}
+	data.SetRow(0, []float64{1, 4, 1})
+	data.SetRow(1, []float64{1, 4, 8})
+	data.SetRow(2, []float64{1, 5, 27})
+	data.SetRow(3, []float64{1, 4, 64})
+	data.SetRow(4, []float64{1, 5, 125})
+	if _, err := data.SpearmanCorrelation(); err == nil {
+		t.Errorf("SpearmanCorrelation() with a constant column should fail")
+	}
+}
+
+func TestDensity(t *testing.T) {
+	h, err := Histogram([]float64{0, 1, 1, 2, 2, 2, 3, math.NaN(), 4}, 4)
+	if err != nil || !reflect.DeepEqual(h.Edges, []float64{0, 1, 2, 3, 4}) || !reflect.DeepEqual(h.Counts, []int{1, 2, 3, 2}) {
+		t.Errorf("Histogram() = %+v, error %v", h, err)
+	}
+	if d := h.Density(); !reflect.DeepEqual(d, []float64{0.125, 0.25, 0.375, 0.25}) {
+		t.Errorf("Density() = %v", d)
+	}
+
+	sample := NormalSample(1000, 0, 1, rand.NewSource(6))
+	auto, err := Histogram(sample, 0)
+	if err != nil {
+		t.Fatalf("Histogram() error %v", err)
+	}
+	//Freedman-Diaconis: a width of about 2*1.35/10
+	width := auto.Edges[1] - auto.Edges[0]
+	var total int
+	for _, c := range auto.Counts {
+		total += c
+	}
+	if width < 0.2 || width > 0.35 || total != 1000 {
+		t.Errorf("Histogram() automatic width %g, %d values", width, total)
+	}
+	//A far outlier would ask for 10^13 bins with the Freedman-Diaconis rule
+	uniform := make([]float64, 1001)
+	random := rand.New(rand.NewSource(6))
+	for i := 0; i < 1000; i++ {
+		uniform[i] = random.Float64()
+	}
+	for _, outlier := range []float64{1e12, 1e300} {
+		uniform[1000] = outlier
+		uniform[0] = -outlier
+		o, err := Histogram(uniform, 0)
+		total = 0
+		for _, c := range o.Counts {
+			total += c
+		}
+		if err != nil || len(o.Counts) > 1001 || total != 1001 || o.Edges[len(o.Edges)-1] != outlier || o.Counts[0] != 1 || o.Counts[len(o.Counts)-1] != 1 {
+			t.Errorf("Histogram() with an outlier %g: %d bins, %d values, error %v", outlier, len(o.Counts), total, err)
+		}
+	}
+	if c, err := Histogram([]float64{2, 2, 2}, 0); err != nil || c.Edges[0] != 1.5 || c.Edges[len(c.Edges)-1] != 2.5 {
+		t.Errorf("Histogram() of a constant sample = %+v, error %v", c, err)
+	}
+	if _, err := Histogram([]float64{math.NaN()}, 0); err == nil {
+		t.Errorf("Histogram() without values should fail")
+	}
+
+	kde, err := GaussianKDE(sample, 0)
+	if err != nil {
+		t.Fatalf("GaussianKDE() error %v", err)
+	}
+	area, err := AdaptiveSimpson(-10, 10, kde, 1e-8)
+	if err != nil || !soclose(area, 1, 1e-6) {
+		t.Errorf("Integral of GaussianKDE() = %g, error %v", area, err)
+	}
+	if !soclose(kde(0), 1/math.Sqrt(2*math.Pi), 0.1) || kde(8) > 1e-6 {
+		t.Errorf("GaussianKDE() = %g at 0 and %g at 8", kde(0), kde(8))
+	}
+	single, _ := GaussianKDE([]float64{1}, 0.5)
+	if !veryclose(single(1), 1/(0.5*math.Sqrt(2*math.Pi))) {
+		t.Errorf("GaussianKDE() of a single value = %g", single(1))
+	}
+	if _, err := GaussianKDE([]float64{3, 3}, 0); err == nil {
+		t.Errorf("GaussianKDE() of a constant sample should fail")
+	}
+}
+
+func TestHypothesisTests(t *testing.T) {
+	//Student distributions with 1 and 2 degrees of freedom have closed form CDFs
+	r, err := OneSampleTTest([]float64{1, 3}, 0, TwoSided)
+	if err != nil || !veryclose(r.Statistic, 2) || r.DegreesOfFreedom != 1 || !soclose(r.PValue, 1-2/math.Pi*math.Atan(2), 1e-13) {
+		t.Errorf("OneSampleTTest() with 1 degree of freedom = %+v, error %v", r, err)
+	}
+	ts := 2 * math.Sqrt(3)
+	r, err = OneSampleTTest([]float64{1, 2, 3}, 0, TwoSided)
+	if err != nil || !veryclose(r.Statistic, ts) || !soclose(r.PValue, 1-ts/math.Sqrt(ts*ts+2), 1e-13) {
+		t.Errorf("OneSampleTTest() with 2 degrees of freedom = %+v, error %v", r, err)
+	}
+	greater, _ := OneSampleTTest([]float64{1, 2, 3}, 0, Greater)
+	less, _ := OneSampleTTest([]float64{1, 2, 3}, 0, Less)
+	if !veryclose(greater.PValue, r.PValue/2) || !veryclose(less.PValue+greater.PValue, 1) {
+		t.Errorf("OneSampleTTest() one-sided p-values %g and %g", less.PValue, greater.PValue)
+	}
+	//Close to the normal distribution with many degrees of freedom
+	if p, err := studentPValue(1.96, 1e6, TwoSided); err != nil || !soclose(p, math.Erfc(1.96/math.Sqrt2), 1e-5) {
+		t.Errorf("studentPValue(1.96) = %g, error %v", p, err)
+	}
+
+	x := []float64{5.1, 4.9, 5.6, 5.8, 6.0, 5.7, 5.3, 5.5}
+	y := []float64{4.8, 4.7, 5.0, 5.2, 4.6, 5.1, 4.9, 5.4}
+	student, err := TwoSampleTTest(x, y, true, TwoSided)
+	welch, err2 := TwoSampleTTest(x, y, false, TwoSided)
+	//With samples of the same size the statistics are equal, not the degrees of freedom
+	if err != nil || err2 != nil || !veryclose(student.Statistic, welch.Statistic) || student.DegreesOfFreedom != 14 || welch.DegreesOfFreedom >= 14 || welch.PValue < student.PValue || student.PValue > 0.01 {
+		t.Errorf("TwoSampleTTest() = %+v and %+v, errors %v %v", student, welch, err, err2)
+	}
+	if same, err := TwoSampleTTest(x, x, false, TwoSided); err != nil || same.Statistic != 0 || !veryclose(same.PValue, 1) {
+		t.Errorf("TwoSampleTTest() of the same sample = %+v, error %v", same, err)
+	}
+
+	//The chi-square distribution with 2 degrees of freedom is exponential
+	c, err := ChiSquareTest([]float64{10, 20, 30}, nil)
+	if err != nil || !veryclose(c.Statistic, 10) || c.DegreesOfFreedom != 2 || !soclose(c.PValue, math.Exp(-5), 1e-13) {
+		t.Errorf("ChiSquareTest() = %+v, error %v", c, err)
+	}
+	c, err = ChiSquareTest([]float64{30, 10}, []float64{0.5, 0.5})
+	if err != nil || !veryclose(c.Statistic, 10) || !soclose(c.PValue, math.Erfc(math.Sqrt(5)), 1e-12) {
+		t.Errorf("ChiSquareTest() with 1 degree of freedom = %+v, error %v", c, err)
+	}
+	c, err = ChiSquareTest([]float64{18, 55, 27}, []float64{1, 2, 1})
+	if err != nil || !veryclose(c.Statistic, 49.0/25+25.0/50+4.0/25) || !soclose(c.PValue, math.Exp(-1.31), 1e-13) {
+		t.Errorf("ChiSquareTest() with frequencies = %+v, error %v", c, err)
+	}
+
+	//The 5% critical value of the Kolmogorov distribution is 1.3581
+	if p := kolmogorovPValue(1.3581/1e4, 100000000); !soclose(p, 0.05, 1e-3) {
+		t.Errorf("kolmogorovPValue() = %g, want 0.05", p)
+	}
+	normalCDF := func(x float64) float64 { return (1 + math.Erf(x/math.Sqrt2)) / 2 }
+	sample := NormalSample(500, 0, 1, rand.NewSource(4))
+	ks, err := KolmogorovSmirnovTest(sample, normalCDF)
+	if err != nil || ks.PValue < 0.05 {
+		t.Errorf("KolmogorovSmirnovTest() of a normal sample = %+v, error %v", ks, err)
+	}
+	shifted := make([]float64, len(sample))
+	for i := range sample {
+		shifted[i] = sample[i] + 0.5
+	}
+	if ks, err := KolmogorovSmirnovTest(shifted, normalCDF); err != nil || ks.PValue > 1e-6 {
+		t.Errorf("KolmogorovSmirnovTest() of a shifted sample = %+v, error %v", ks, err)
+	}
+	if ks, _ := KolmogorovSmirnovTest([]float64{0.5}, func(x float64) float64 { return x }); ks.Statistic != 0.5 {
+		t.Errorf("KolmogorovSmirnovTest() statistic = %g, want 0.5", ks.Statistic)
+	}
+
+	if _, err := OneSampleTTest([]float64{2, 2, 2}, 0, TwoSided); err == nil {
+		t.Errorf("OneSampleTTest() of a constant sample should fail")
+	}
+	if _, err := TwoSampleTTest([]float64{1}, y, false, TwoSided); err == nil {
+		t.Errorf("TwoSampleTTest() with a single value should fail")
+	}
+	if _, err := ChiSquareTest([]float64{1, 2}, []float64{1, 0}); err == nil {
+		t.Errorf("ChiSquareTest() with a zero frequency should fail")
+	}
+	if _, err := KolmogorovSmirnovTest(nil, normalCDF); err == nil {
+		t.Errorf("KolmogorovSmirnovTest() of an empty sample should fail")
+	}
+}
+
+func TestSmoothing(t *testing.T) {
+	p, err := PolynomialFit([]float64{-1, 0, 1, 2, 3}, []float64{6, 1, 0, 3, 10}, 2)
+	if err != nil || len(p) != 3 || !soclose(p[0], 1, 1e-13) || !soclose(p[1], -3, 1e-13) || !soclose(p[2], 2, 1e-13) {
+		t.Errorf("PolynomialFit() = %v, error %v", p, err)
+	}
+	if _, err := PolynomialFit([]float64{1, 1, 1}, []float64{1, 2, 3}, 1); err == nil {
+		t.Errorf("PolynomialFit() with a single abscissa should fail")
+	}
+
+	data := []float64{1, 2, 3, 100, 5, 6, 7, 8}
+	means, err := RollingMean(data, 3)
+	if err != nil || !reflect.DeepEqual(means, []float64{2, 35, 36, 37, 6, 7}) {
+		t.Errorf("RollingMean() = %v, error %v", means, err)
+	}
+	medians, err := RollingMedian(data, 3)
+	if err != nil || !reflect.DeepEqual(medians, []float64{2, 3, 5, 6, 6, 7}) {
+		t.Errorf("RollingMedian() = %v, error %v", medians, err)
+	}
+	medians, err = RollingMedian(data, 4)
+	if err != nil || !reflect.DeepEqual(medians, []float64{2.5, 4, 5.5, 6.5, 6.5}) {
+		t.Errorf("RollingMedian() of an even window = %v, error %v", medians, err)
+	}
+	//The windows holding a NaN have a NaN mean and median
+	means, err = RollingMean([]float64{1, math.NaN(), 3, 5, 7, math.Inf(1), 2, 4}, 2)
+	if err != nil || !math.IsNaN(means[0]) || !math.IsNaN(means[1]) || !reflect.DeepEqual(means[2:4], []float64{4, 6}) || !math.IsInf(means[4], 1) || !math.IsInf(means[5], 1) || means[6] != 3 {
+		t.Errorf("RollingMean() with NaN = %v, error %v", means, err)
+	}
+	medians, err = RollingMedian([]float64{math.NaN(), 1, 2, 3, math.NaN(), 5, 6, 7}, 2)
+	if err != nil || len(medians) != 7 || !math.IsNaN(medians[0]) || !reflect.DeepEqual(medians[1:3], []float64{1.5, 2.5}) || !math.IsNaN(medians[3]) || !math.IsNaN(medians[4]) || !reflect.DeepEqual(medians[5:], []float64{5.5, 6.5}) {
+		t.Errorf("RollingMedian() with NaN = %v, error %v", medians, err)
+	}
+
+	//Long series: the running sum stays accurate
+	long := NormalSample(10000, 1e6, 1, rand.NewSource(2))
+	means, _ = RollingMean(long, 7)
+	last, _ := Mean(long[len(long)-7:], PropagateNaN)
+	if !soclose(means[len(means)-1], last, 1e-15) {
+		t.Errorf("RollingMean() last = %.17g, want %.17g", means[len(means)-1], last)
+	}
+
+	//A polynomial of the degree of the filter goes through unchanged, edges included
+	cubic := NewPolynomial(1, -2, 0.5, 0.1)
+	series := make([]float64, 20)
+	for i := range series {
+		series[i] = cubic.Evaluate(float64(i))
+	}
+	smoothed, err := SavitzkyGolay(series, 7, 3)
+	if err != nil {
+		t.Fatalf("SavitzkyGolay() error %v", err)
+	}
+	for i := range series {
+		if !soclose(smoothed[i], series[i], 1e-10) {
+			t.Errorf("SavitzkyGolay() at %d = %g, want %g", i, smoothed[i], series[i])
+		}
+	}
+	//Classic weights of the 5 points quadratic filter: (-3, 12, 17, 12, -3)/35
+	impulse := []float64{0, 0, 0, 0, 1, 0, 0, 0, 0}
+	response, _ := SavitzkyGolay(impulse, 5, 2)
+	for i, want := range []float64{-3, 12, 17, 12, -3} {
+		if !soclose(response[2+i], want/35, 1e-13) {
+			t.Errorf("SavitzkyGolay() weights = %v", response[2:7])
+			break
+		}
+	}
+	//The noise is reduced
+	noisy := NormalSample(200, 0, 1, rand.NewSource(3))
+	filtered, _ := SavitzkyGolay(noisy, 11, 2)
+	before, _ := Variance(noisy[10:190], false, PropagateNaN)
+	after, _ := Variance(filtered[10:190], false, PropagateNaN)
+	if after > before/2 {
+		t.Errorf("SavitzkyGolay() variance %g, before %g", after, before)
+	}
+
+	if _, err := SavitzkyGolay(series, 6, 2); err == nil {
+		t.Errorf("SavitzkyGolay() with an even window should fail")
+	}
+	if _, err := SavitzkyGolay(series, 5, 5); err == nil {
+		t.Errorf("SavitzkyGolay() with a degree as large as the window should fail")
+	}
+	if _, err := RollingMean(data, 9); err == nil {
+		t.Errorf("RollingMean() with a window larger than the data should fail")
+	}
+}
+
+func TestRobustRegression(t *testing.T) {
+	x := NewMatrix(5, 1)
+	x.M = []float64{1, 2, 3, 4, 5}
+	y := []float64{2, 4, 5, 4, 5}
+	ols, _ := MultipleRegression(x, y)
+	w, err := WeightedRegression(x, y, []float64{1, 1, 1, 1, 1})
+	if err != nil || !reflect.DeepEqual(w.Weights, []float64{1, 1, 1, 1, 1}) || ols.Weights != nil {
+		t.Fatalf("WeightedRegression() = %+v, error %v", w, err)
+	}
+	for j := range ols.Coefficients {
+		if !soclose(w.Coefficients[j], ols.Coefficients[j], 1e-13) || !soclose(w.StandardErrors[j], ols.StandardErrors[j], 1e-13) {
+			t.Errorf("WeightedRegression() with unit weights = %+v, want %+v", w, ols)
+		}
+	}
+	//A weight of 2 is the same as a repeated observation
+	w, err = WeightedRegression(x, y, []float64{1, 2, 1, 1, 1})
+	repeatedX := NewMatrix(6, 1)
+	repeatedX.M = []float64{1, 2, 2, 3, 4, 5}
+	repeated, _ := MultipleRegression(repeatedX, []float64{2, 4, 4, 5, 4, 5})
+	if err != nil || !soclose(w.Coefficients[0], repeated.Coefficients[0], 1e-13) || !soclose(w.Coefficients[1], repeated.Coefficients[1], 1e-13) || !soclose(w.RSquared, repeated.RSquared, 1e-13) {
+		t.Errorf("WeightedRegression() = %+v, want %+v, error %v", w, repeated, err)
+	}
+
+	//y = 2 + 3x with a small noise and 3 gross outliers
+	n := 50
+	line := NewMatrix(uint(n), 1)
+	noise := NormalSample(n, 0, 0.1, rand.NewSource(11))
+	values := make([]float64, n)
+	for i := 0; i < n; i++ {
+		line.M[i] = float64(i) / 5
+		values[i] = 2 + 3*line.M[i] + noise[i]
+	}
+	outliers := []int{5, 25, 45}
+	for _, i := range outliers {
+		values[i] += 30
+	}
+	plain, _ := MultipleRegression(line, values)
+	robust, err := RobustRegression(line, values, 0)
+	if err != nil || !soclose(robust.Coefficients[0], 2, 0.05) || !soclose(robust.Coefficients[1], 3, 0.01) {
+		t.Errorf("RobustRegression() = %v, error %v", robust.Coefficients, err)
+	}
+	if soclose(plain.Coefficients[0], 2, 0.2) {
+		t.Errorf("MultipleRegression() should be spoiled by the outliers: %v", plain.Coefficients)
+	}
+	for _, i := range outliers {
+		if robust.Weights[i] > 0.05 {
+			t.Errorf("RobustRegression() weight of the outlier %d = %g", i, robust.Weights[i])
+		}
+	}
+	//Without outliers the fit is nearly the ordinary one
+	for _, i := range outliers {
+		values[i] -= 30
+	}
+	clean, _ := MultipleRegression(line, values)
+	robust, err = RobustRegression(line, values, 0)
+	if err != nil || !soclose(robust.Coefficients[1], clean.Coefficients[1], 0.01) {
+		t.Errorf("RobustRegression() without outliers = %v, want %v, error %v", robust.Coefficients, clean.Coefficients, err)
+	}
+
+	if _, err := WeightedRegression(x, y, []float64{1, 1, -1, 1, 1}); err == nil {
+		t.Errorf("WeightedRegression() with a negative weight should fail")
+	}
+	if _, err := WeightedRegression(x, y, []float64{1, 1}); err == nil {
+		t.Errorf("WeightedRegression() with a wrong number of weights should fail")
+	}
+}
@@ -0,0 +1,191 @@
+package advmath
+
+/*
+AffineTransform is an affine transformation of points in 2-D or 3-D (or any
+dimension) stored as a matrix in homogeneous coordinates. Transforms are
+immutable: every method returns a new transform, applying the new operation
+after the existing ones, so that
+
+	t, err := NewAffineTransform(2).Scale(2, 2)
+	if err == nil {
+		t, err = t.Translate(1, 0)
+	}
+
+first scales and then translates. The methods only fail when the sizes don't
+match the dimension of the transform.
+*/
+type AffineTransform struct {
+	Dimension uint
+	//M is the (Dimension+1)x(Dimension+1) homogeneous matrix
+	M *Matrix
+}
+
+/*
+NewAffineTransform is a method to create the identity transform.
+First parameter is the dimension of the points (2 for the plane)
+*/
+func NewAffineTransform(dimension uint) AffineTransform {
+	return AffineTransform{
+		Dimension: dimension,
+		M:         NewIdentity(dimension + 1),
+	}
+}
+
+/*
+NewAffineTransformFromMatrix is a method to create a transform from a
+homogeneous matrix. The last row must be [0 ... 0 1].
+First parameter is the homogeneous matrix
+*/
+func NewAffineTransformFromMatrix(m *Matrix) (AffineTransform, error) {
+	if !m.IsSquare() || m.NumberOfRows == 0 {
+		return AffineTransform{}, &MathError{
+			code: errorNonSquareMatrix,
+		}
+	}
+	n := m.NumberOfRows - 1
+	var j uint
+	for j = 0; j <= n; j++ {
+		expected := 0.0
+		if j == n {
+			expected = 1.0
+		}
+		if m.Get(n, j) != expected {
+			return AffineTransform{}, &MathError{
+				s: "Not an affine transform, last row must be [0 ... 0 1]",
+			}
+		}
+	}
+	c := NewMatrix(m.NumberOfRows, m.NumberOfColumns)
+	copy(c.M, m.M)
+	return AffineTransform{Dimension: n, M: c}, nil
+}
+
+/*
+Compose is a method returning the transform applying t and then next.
+First parameter is the transform applied after t
+*/
+func (t AffineTransform) Compose(next AffineTransform) (AffineTransform, error) {
+	if next.Dimension != t.Dimension {
+		return AffineTransform{}, &MathError{
+			code: errorDimensionMismatch,
+		}
+	}
+	m, err := next.M.Multiply(t.M)
+	if err != nil {
+		return AffineTransform{}, err
+	}
+	return AffineTransform{Dimension: t.Dimension, M: m}, nil
+}
+
+/*
+Linear is a method returning the transform followed by a linear transformation
+(rotation, scaling, reflection...) given as a DimensionxDimension matrix.
+First parameter is the linear transformation
+*/
+func (t AffineTransform) Linear(l *Matrix) (AffineTransform, error) {
+	if l.NumberOfRows != t.Dimension || l.NumberOfColumns != t.Dimension {
+		return AffineTransform{}, &MathError{
+			code: errorDimensionMismatch,
+		}
+	}
+	h, _ := l.Homogeneous()
+	return t.Compose(AffineTransform{Dimension: t.Dimension, M: h})
+}
+
+/*
+Translate is a method returning the transform followed by a translation.
+Parameters are the offsets for each axis
+*/
+func (t AffineTransform) Translate(offsets ...float64) (AffineTransform, error) {
+	if uint(len(offsets)) != t.Dimension {
+		return AffineTransform{}, &MathError{
+			code: errorDimensionMismatch,
+		}
+	}
+	return t.Compose(AffineTransform{Dimension: t.Dimension, M: NewTranslation(offsets...)})
+}
+
+/*
+Scale is a method returning the transform followed by a scaling.
+Parameters are the factors for each axis
+*/
+func (t AffineTransform) Scale(factors ...float64) (AffineTransform, error) {
+	return t.Linear(NewScaling(factors...))
+}
+
+/*
+Rotate2D is a method returning the 2-D transform followed by a counterclockwise
+rotation around the origin.
+First parameter is the angle in radians
+*/
+func (t AffineTransform) Rotate2D(theta float64) (AffineTransform, error) {
+	return t.Linear(NewRotation2D(theta))
+}
+
+/*
+Rotate3D is a method returning the 3-D transform followed by the rotation
+given by a quaternion.
+First parameter is the unit quaternion of the rotation
+*/
+func (t AffineTransform) Rotate3D(q Quaternion) (AffineTransform, error) {
+	return t.Linear(q.ToRotationMatrix())
+}
+
+/*
+Shear is a method returning the transform followed by a shear adding factor
+times the coordinate along axis source to the coordinate along axis target.
+For instance Shear(0, 1, k) maps (x, y) to (x + k*y, y).
+First parameter is the axis modified
+Second parameter is the axis used to shear
+Third parameter is the shear factor
+*/
+func (t AffineTransform) Shear(target, source uint, factor float64) (AffineTransform, error) {
+	if target >= t.Dimension || source >= t.Dimension || target == source {
+		return AffineTransform{}, &MathError{
+			code: errorDimensionMismatch,
+		}
+	}
+	s := NewIdentity(t.Dimension)
+	s.Set(target, source, factor)
+	return t.Linear(s)
+}
+
+/*
+Inverse is a method returning the transform undoing t. An error is returned if
+the linear part is not inversible (a scaling by 0 for instance) or so badly
+conditioned that the inverse is meaningless (see Matrix.Inverse).
+*/
+func (t AffineTransform) Inverse() (AffineTransform, error) {
+	inv, err := t.M.Inverse()
+	if err != nil {
+		return AffineTransform{}, err
+	}
+	return AffineTransform{Dimension: t.Dimension, M: inv}, nil
+}
+
+/*
+Apply is a method to transform a set of points. Each row of the matrix is a
+point, the result has the same layout.
+First parameter is the matrix of points (one point per row)
+*/
+func (t AffineTransform) Apply(points *Matrix) (*Matrix, error) {
+	if points.NumberOfColumns != t.Dimension {
+		return nil, &MathError{
+			code: errorDimensionMismatch,
+		}
+	}
+
+	d := t.Dimension
+	ret := NewMatrix(points.NumberOfRows, d)
+	var p, i, j uint
+	for p = 0; p < points.NumberOfRows; p++ {
+		for i = 0; i < d; i++ {
+			v := t.M.Get(i, d)
+			for j = 0; j < d; j++ {
+				v += t.M.Get(i, j) * points.Get(p, j)
+			}
+			ret.Set(p, i, v)
+		}
+	}
+	return ret, nil
+}
@@ -0,0 +1,324 @@
+package advmath
+
+import (
+	"math/big"
+)
+
+/*
+BigMatrix is a matrix of arbitrary precision floating point numbers. It is
+meant for ill-conditioned problems (Hilbert matrices for instance) where
+float64 does not carry enough digits for the result to make sense.
+Every value of the matrix uses the precision (in bits) given at creation.
+*/
+type BigMatrix struct {
+	NumberOfRows    uint
+	NumberOfColumns uint
+	Precision       uint
+	M               []*big.Float
+}
+
+/*
+NewBigMatrix is a method to create a new arbitrary precision matrix filled
+with zeros.
+First parameter is the number of rows
+Second parameter is the number of columns
+Third parameter is the precision of the mantissa in bits (53 is float64)
+*/
+func NewBigMatrix(rows, cols, prec uint) *BigMatrix {
+	m := new(BigMatrix)
+	m.NumberOfRows = rows
+	m.NumberOfColumns = cols
+	m.Precision = prec
+	m.M = make([]*big.Float, rows*cols)
+	for i := range m.M {
+		m.M[i] = m.newFloat()
+	}
+	return m
+}
+
+/*
+NewBigIdentity is a method to create an arbitrary precision identity matrix.
+First parameter is the number of rows and columns
+Second parameter is the precision of the mantissa in bits
+*/
+func NewBigIdentity(rows, prec uint) *BigMatrix {
+	i := NewBigMatrix(rows, rows, prec)
+
+	var j uint
+	for j = 0; j < rows; j++ {
+		i.M[j*i.NumberOfColumns+j].SetInt64(1)
+	}
+
+	return i
+}
+
+/*
+ToBigMatrix is a method to convert a matrix to an arbitrary precision matrix.
+First parameter is the precision of the mantissa in bits
+*/
+func (m Matrix) ToBigMatrix(prec uint) *BigMatrix {
+	ret := NewBigMatrix(m.NumberOfRows, m.NumberOfColumns, prec)
+	for i, v := range m.M {
+		ret.M[i].SetFloat64(v)
+	}
+	return ret
+}
+
+/*
+ToMatrix is a method to round an arbitrary precision matrix to a float64 Matrix.
+*/
+func (m BigMatrix) ToMatrix() *Matrix {
+	ret := NewMatrix(m.NumberOfRows, m.NumberOfColumns)
+	for i, v := range m.M {
+		ret.M[i], _ = v.Float64()
+	}
+	return ret
+}
+
+func (m BigMatrix) newFloat() *big.Float {
+	return new(big.Float).SetPrec(m.Precision)
+}
+
+/*
+IsSquare is a method to find if a matrix is a square matrix or not.
+*/
+func (m BigMatrix) IsSquare() bool {
+	return m.NumberOfColumns == m.NumberOfRows
+}
+
+/*
+Get is a method to retrieve the content of a matrix at the given
+row and column. The value returned is a copy, changing it doesn't change
+the matrix.
+*/
+func (m BigMatrix) Get(row uint, column uint) *big.Float {
+	return m.newFloat().Set(m.M[row*m.NumberOfColumns+column])
+}
+
+/*
+Set is a method to set the value at the given row and column, the value is
+rounded to the precision of the matrix.
+*/
+func (m *BigMatrix) Set(row uint, column uint, value *big.Float) {
+	m.M[row*m.NumberOfColumns+column].Set(value)
+}
+
+/*
+SetRow is a method to set the value at the given row from float64 values, it
+changes the underlying matrix and returns it.
+*/
+func (m *BigMatrix) SetRow(rowNumber uint, row []float64) *BigMatrix {
+	var cols uint
+	for cols = 0; cols < m.NumberOfColumns; cols++ {
+		m.M[rowNumber*m.NumberOfColumns+cols].SetFloat64(row[cols])
+	}
+	return m
+}
+
+/*
+Multiply is a method to multiply the matrix by the given matrix (A*B).
+The result uses the precision of the receiver.
+First parameter is the matrix used for the multiplication
+*/
+func (m BigMatrix) Multiply(in *BigMatrix) (*BigMatrix, error) {
+	if m.NumberOfColumns != in.NumberOfRows {
+		return nil, &MathError{
+			code: errorCannotMultiply,
+		}
+	}
+
+	result := NewBigMatrix(m.NumberOfRows, in.NumberOfColumns, m.Precision)
+	tmp := m.newFloat()
+
+	var i, j, k uint
+	for i = 0; i < m.NumberOfRows; i++ {
+		for j = 0; j < in.NumberOfColumns; j++ {
+			sum := result.M[i*result.NumberOfColumns+j]
+			for k = 0; k < m.NumberOfColumns; k++ {
+				tmp.Mul(m.M[i*m.NumberOfColumns+k], in.M[k*in.NumberOfColumns+j])
+				sum.Add(sum, tmp)
+			}
+		}
+	}
+	return result, nil
+}
+
+/*
+LUDecomposition is a method to create the LU decomposition of a square matrix
+(Doolittle algorithm, same as Matrix.LUDecomposition). It provides a lower
+triangular matrix with ones on the diagonal and an upper triangular matrix.
+Since there is no pivoting, an error is returned when a zero pivot is found.
+*/
+func (m BigMatrix) LUDecomposition() (*BigMatrix, *BigMatrix, error) {
+	if !m.IsSquare() {
+		return nil, nil, &MathError{
+			code: errorNonSquareMatrix,
+		}
+	}
+
+	n := m.NumberOfColumns
+	l := NewBigMatrix(n, n, m.Precision)
+	u := NewBigMatrix(n, n, m.Precision)
+	sum := m.newFloat()
+	tmp := m.newFloat()
+
+	var i, j, k uint
+	for i = 0; i < n; i++ {
+		// Upper Triangular
+		for k = i; k < n; k++ {
+			sum.SetInt64(0)
+			for j = 0; j < i; j++ {
+				tmp.Mul(l.M[i*n+j], u.M[j*n+k])
+				sum.Add(sum, tmp)
+			}
+			u.M[i*n+k].Sub(m.M[i*n+k], sum)
+		}
+		// Lower Triangular
+		l.M[i*n+i].SetInt64(1)
+		if i+1 < n && u.M[i*n+i].Sign() == 0 {
+			return nil, nil, &MathError{
+				code: errorZeroPivot,
+			}
+		}
+		for k = i + 1; k < n; k++ {
+			sum.SetInt64(0)
+			for j = 0; j < i; j++ {
+				tmp.Mul(l.M[k*n+j], u.M[j*n+i])
+				sum.Add(sum, tmp)
+			}
+			l.M[k*n+i].Sub(m.M[k*n+i], sum)
+			l.M[k*n+i].Quo(l.M[k*n+i], u.M[i*n+i])
+		}
+	}
+
+	return l, u, nil
+}
+
+/*
+Determinant is a method to compute the determinant of a square matrix. It uses
+a gaussian elimination with partial pivoting so it works even when
+LUDecomposition would meet a zero pivot.
+*/
+func (m BigMatrix) Determinant() (*big.Float, error) {
+	if !m.IsSquare() {
+		return nil, &MathError{
+			code: errorNonSquareMatrix,
+		}
+	}
+
+	a := m.copyValues()
+	n := int(m.NumberOfRows)
+	det := m.newFloat().SetInt64(1)
+	factor := m.newFloat()
+	tmp := m.newFloat()
+
+	for k := 0; k < n; k++ {
+		p := m.pivotRow(a, k)
+		if a[p*n+k].Sign() == 0 {
+			return m.newFloat(), nil
+		}
+		if p != k {
+			for j := 0; j < n; j++ {
+				a[k*n+j], a[p*n+j] = a[p*n+j], a[k*n+j]
+			}
+			det.Neg(det)
+		}
+		det.Mul(det, a[k*n+k])
+		for i := k + 1; i < n; i++ {
+			factor.Quo(a[i*n+k], a[k*n+k])
+			for j := k; j < n; j++ {
+				tmp.Mul(factor, a[k*n+j])
+				a[i*n+j].Sub(a[i*n+j], tmp)
+			}
+		}
+	}
+
+	return det, nil
+}
+
+/*
+Inverse is a method to compute the inverse of a square matrix. It uses a
+Gauss-Jordan elimination with partial pivoting. An error is returned if the
+matrix is not square or not inversible.
+*/
+func (m BigMatrix) Inverse() (*BigMatrix, error) {
+	if !m.IsSquare() {
+		return nil, &MathError{
+			code: errorNonSquareMatrix,
+		}
+	}
+
+	a := m.copyValues()
+	n := int(m.NumberOfRows)
+	inv := NewBigIdentity(m.NumberOfRows, m.Precision)
+	factor := m.newFloat()
+	tmp := m.newFloat()
+
+	for k := 0; k < n; k++ {
+		p := m.pivotRow(a, k)
+		if a[p*n+k].Sign() == 0 {
+			return nil, &MathError{
+				code: errorNotInversible,
+			}
+		}
+		if p != k {
+			for j := 0; j < n; j++ {
+				a[k*n+j], a[p*n+j] = a[p*n+j], a[k*n+j]
+				inv.M[k*n+j], inv.M[p*n+j] = inv.M[p*n+j], inv.M[k*n+j]
+			}
+		}
+
+		//Normalize the pivot row
+		pivot := m.newFloat().Set(a[k*n+k])
+		for j := 0; j < n; j++ {
+			a[k*n+j].Quo(a[k*n+j], pivot)
+			inv.M[k*n+j].Quo(inv.M[k*n+j], pivot)
+		}
+
+		//Eliminate the column in every other row
+		for i := 0; i < n; i++ {
+			if i == k || a[i*n+k].Sign() == 0 {
+				continue
+			}
+			factor.Set(a[i*n+k])
+			for j := 0; j < n; j++ {
+				tmp.Mul(factor, a[k*n+j])
+				a[i*n+j].Sub(a[i*n+j], tmp)
+				tmp.Mul(factor, inv.M[k*n+j])
+				inv.M[i*n+j].Sub(inv.M[i*n+j], tmp)
+			}
+		}
+	}
+
+	return inv, nil
+}
+
+/*
+copyValues returns a deep copy of the values of the matrix so that eliminations
+can be done without changing the matrix.
+*/
+func (m BigMatrix) copyValues() []*big.Float {
+	a := make([]*big.Float, len(m.M))
+	for i, v := range m.M {
+		a[i] = m.newFloat().Set(v)
+	}
+	return a
+}
+
+/*
+pivotRow returns the row (from k) holding the biggest absolute value of
+column k in the square values a.
+*/
+func (m BigMatrix) pivotRow(a []*big.Float, k int) int {
+	n := int(m.NumberOfRows)
+	p := k
+	best := m.newFloat().Abs(a[k*n+k])
+	current := m.newFloat()
+	for i := k + 1; i < n; i++ {
+		if current.Abs(a[i*n+k]).Cmp(best) > 0 {
+			p = i
+			best.Set(current)
+		}
+	}
+	return p
+}
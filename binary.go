@@ -0,0 +1,76 @@
+package advmath
+
+import (
+	"encoding/binary"
+	"encoding/gob"
+	"math"
+)
+
+const (
+	//binaryMagic starts every binary encoded matrix
+	binaryMagic = "AMX"
+	//binaryVersion is the version of the binary layout
+	binaryVersion = 1
+	//binaryHeaderSize is the magic, the version and the two dimensions
+	binaryHeaderSize = len(binaryMagic) + 1 + 8 + 8
+)
+
+func init() {
+	//Allows a *Matrix to be sent as an interface value with gob
+	gob.Register(&Matrix{})
+}
+
+/*
+MarshalBinary implements encoding.BinaryMarshaler. The layout is a small header
+(magic "AMX", a version byte, the number of rows and columns as little endian
+uint64) followed by the values as little endian float64, row after row.
+It is also what gob uses to encode a Matrix.
+*/
+func (m Matrix) MarshalBinary() ([]byte, error) {
+	data := make([]byte, binaryHeaderSize+8*len(m.M))
+	copy(data, binaryMagic)
+	data[len(binaryMagic)] = binaryVersion
+	binary.LittleEndian.PutUint64(data[len(binaryMagic)+1:], uint64(m.NumberOfRows))
+	binary.LittleEndian.PutUint64(data[len(binaryMagic)+9:], uint64(m.NumberOfColumns))
+
+	payload := data[binaryHeaderSize:]
+	for i, v := range m.M {
+		binary.LittleEndian.PutUint64(payload[8*i:], math.Float64bits(v))
+	}
+	return data, nil
+}
+
+/*
+UnmarshalBinary implements encoding.BinaryUnmarshaler, it reads the layout
+written by MarshalBinary.
+*/
+func (m *Matrix) UnmarshalBinary(data []byte) error {
+	if len(data) < binaryHeaderSize || string(data[:len(binaryMagic)]) != binaryMagic {
+		return &MathError{
+			s: "Invalid binary matrix, header not found",
+		}
+	}
+	if data[len(binaryMagic)] != binaryVersion {
+		return &MathError{
+			s: "Unsupported binary matrix version",
+		}
+	}
+
+	rows := binary.LittleEndian.Uint64(data[len(binaryMagic)+1:])
+	cols := binary.LittleEndian.Uint64(data[len(binaryMagic)+9:])
+	payload := data[binaryHeaderSize:]
+	if cols != 0 && rows > uint64(len(payload))/8/cols || uint64(len(payload)) != 8*rows*cols {
+		return &MathError{
+			s: "Invalid binary matrix, the size of data doesn't match rows*cols",
+		}
+	}
+
+	values := make([]float64, rows*cols)
+	for i := range values {
+		values[i] = math.Float64frombits(binary.LittleEndian.Uint64(payload[8*i:]))
+	}
+	m.NumberOfRows = uint(rows)
+	m.NumberOfColumns = uint(cols)
+	m.M = values
+	return nil
+}
@@ -0,0 +1,265 @@
+package advmath
+
+import (
+	"math"
+)
+
+/*
+BSplineBasis is a method to compute the B-spline basis function N(i, degree) of the
+knot vector at x with the Cox-de Boor recursion:
+
+	N(i, 0)(x) = 1 if knots[i] <= x < knots[i+1], 0 otherwise
+	N(i, p)(x) = (x - knots[i])/(knots[i+p] - knots[i]) N(i, p-1)(x)
+	           + (knots[i+p+1] - x)/(knots[i+p+1] - knots[i+1]) N(i+1, p-1)(x)
+
+the fractions with a zero denominator being 0. The last non empty interval is closed
+so that the basis functions still sum to 1 at the last knot.
+First parameter knots is the knot vector, non decreasing
+Second parameter degree is the degree of the basis functions
+Third parameter i is the index of the basis function, from 0 to len(knots)-degree-2
+Fourth parameter x is where the function is computed
+*/
+func BSplineBasis(knots []float64, degree int, i int, x float64) float64 {
+	if i < 0 || degree < 0 || i+degree+1 >= len(knots) {
+		return 0
+	}
+	if degree == 0 {
+		if knots[i] <= x && x < knots[i+1] {
+			return 1
+		}
+		//x at the end of the last non empty interval
+		last := len(knots) - 1
+		return boolToFloat(x == knots[last] && knots[i] < knots[i+1] && knots[i+1] == knots[last])
+	}
+
+	var v float64
+	if d := knots[i+degree] - knots[i]; d != 0 {
+		v += (x - knots[i]) / d * BSplineBasis(knots, degree-1, i, x)
+	}
+	if d := knots[i+degree+1] - knots[i+1]; d != 0 {
+		v += (knots[i+degree+1] - x) / d * BSplineBasis(knots, degree-1, i+1, x)
+	}
+	return v
+}
+
+func boolToFloat(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+/*
+BSpline is a B-spline curve sum(Control[i] N(i, Degree)(t)), the control points having
+any dimension. It is defined for t in [Knots[Degree], Knots[len(Control)]].
+*/
+type BSpline struct {
+	Knots   []float64
+	Degree  int
+	Control [][]float64
+}
+
+/*
+NewBSpline is a method to create a B-spline curve.
+First parameter knots is the knot vector, non decreasing, with len(control)+degree+1
+values
+Second parameter degree is the degree of the curve
+Third parameter control are the control points, all of the same dimension
+It returns an error if the sizes don't match or if the knots are decreasing
+*/
+func NewBSpline(knots []float64, degree int, control [][]float64) (*BSpline, error) {
+	if degree < 0 || len(control) <= degree || len(knots) != len(control)+degree+1 {
+		return nil, &MathError{
+			code: errorDimensionMismatch,
+		}
+	}
+	for i := 1; i < len(knots); i++ {
+		if knots[i] < knots[i-1] {
+			return nil, &MathError{
+				s: "The knots must be non decreasing",
+			}
+		}
+	}
+	if knots[degree] == knots[len(control)] {
+		return nil, &MathError{
+			s: "The curve is defined on an empty interval",
+		}
+	}
+	points := make([][]float64, len(control))
+	for i := range control {
+		if len(control[i]) != len(control[0]) {
+			return nil, &MathError{
+				code: errorDimensionMismatch,
+			}
+		}
+		points[i] = append([]float64(nil), control[i]...)
+	}
+	return &BSpline{
+		Knots:   append([]float64(nil), knots...),
+		Degree:  degree,
+		Control: points,
+	}, nil
+}
+
+/*
+ClampedKnots is a method to create the uniform clamped knot vector for n control
+points on [a, b]: a and b are repeated degree+1 times, so that the curve starts at the
+first control point and ends at the last one
+*/
+func ClampedKnots(n int, degree int, a float64, b float64) []float64 {
+	if n <= degree {
+		return nil
+	}
+	knots := make([]float64, n+degree+1)
+	inner := n - degree
+	for i := range knots {
+		switch {
+		case i <= degree:
+			knots[i] = a
+		case i >= n:
+			knots[i] = b
+		default:
+			knots[i] = a + (b-a)*float64(i-degree)/float64(inner)
+		}
+	}
+	return knots
+}
+
+/*
+span returns k such that Knots[k] <= t < Knots[k+1], Degree <= k < len(Control), t
+being clamped to the domain of the curve
+*/
+func (s *BSpline) span(t float64) (int, float64) {
+	n := len(s.Control)
+	t = math.Max(s.Knots[s.Degree], math.Min(t, s.Knots[n]))
+	k := n - 1
+	for k > s.Degree && s.Knots[k] > t {
+		k--
+	}
+	//Empty intervals at the end
+	for k > s.Degree && s.Knots[k] == s.Knots[k+1] {
+		k--
+	}
+	return k, t
+}
+
+/*
+Evaluate is a method to compute the point of the curve at t with the de Boor
+algorithm, t being clamped to the domain of the curve
+*/
+func (s *BSpline) Evaluate(t float64) []float64 {
+	p := s.Degree
+	k, t := s.span(t)
+	d := make([][]float64, p+1)
+	for j := range d {
+		d[j] = append([]float64(nil), s.Control[j+k-p]...)
+	}
+	for r := 1; r <= p; r++ {
+		for j := p; j >= r; j-- {
+			alpha := (t - s.Knots[j+k-p]) / (s.Knots[j+1+k-r] - s.Knots[j+k-p])
+			for c := range d[j] {
+				d[j][c] = (1-alpha)*d[j-1][c] + alpha*d[j][c]
+			}
+		}
+	}
+	return d[p]
+}
+
+/*
+InsertKnot is a method to insert the knot t with the Boehm algorithm: the result has
+one more control point and exactly the same curve. It is used to refine a curve before
+editing it locally or to split it.
+*/
+func (s *BSpline) InsertKnot(t float64) *BSpline {
+	p := s.Degree
+	k, t := s.span(t)
+	control := make([][]float64, len(s.Control)+1)
+	for i := range control {
+		switch {
+		case i <= k-p:
+			control[i] = append([]float64(nil), s.Control[i]...)
+		case i > k:
+			control[i] = append([]float64(nil), s.Control[i-1]...)
+		default:
+			alpha := (t - s.Knots[i]) / (s.Knots[i+p] - s.Knots[i])
+			control[i] = make([]float64, len(s.Control[i]))
+			for c := range control[i] {
+				control[i][c] = (1-alpha)*s.Control[i-1][c] + alpha*s.Control[i][c]
+			}
+		}
+	}
+	knots := make([]float64, 0, len(s.Knots)+1)
+	knots = append(knots, s.Knots[:k+1]...)
+	knots = append(knots, t)
+	knots = append(knots, s.Knots[k+1:]...)
+	return &BSpline{
+		Knots:   knots,
+		Degree:  p,
+		Control: control,
+	}
+}
+
+/*
+FitBSpline is a method to compute the B-spline curve with n control points and a
+uniform clamped knot vector on [0, 1] closest to the points in the least squares
+sense. The parameter of each point is its cumulated chord length divided by the total
+length.
+First parameter points are the points to approximate, all of the same dimension
+Second parameter degree is the degree of the curve
+Third parameter n is the number of control points, between degree+1 and len(points)
+It returns an error if the sizes don't match or if the system is singular (e.g. too
+many control points for the spread of the parameters)
+*/
+func FitBSpline(points [][]float64, degree int, n int) (*BSpline, error) {
+	m := len(points)
+	if degree < 0 || n <= degree || n > m {
+		return nil, &MathError{
+			code: errorDimensionMismatch,
+		}
+	}
+	dim := len(points[0])
+	for _, p := range points {
+		if len(p) != dim {
+			return nil, &MathError{
+				code: errorDimensionMismatch,
+			}
+		}
+	}
+
+	//Chord length parameters
+	t := make([]float64, m)
+	for i := 1; i < m; i++ {
+		var d float64
+		for c := range points[i] {
+			d += (points[i][c] - points[i-1][c]) * (points[i][c] - points[i-1][c])
+		}
+		t[i] = t[i-1] + math.Sqrt(d)
+	}
+	if t[m-1] == 0 {
+		return nil, &MathError{
+			code: errorNotInversible,
+		}
+	}
+	for i := range t {
+		t[i] /= t[m-1]
+	}
+
+	knots := ClampedKnots(n, degree, 0, 1)
+	a := NewMatrix(uint(m), uint(n))
+	b := NewMatrix(uint(m), uint(dim))
+	for i := 0; i < m; i++ {
+		for j := 0; j < n; j++ {
+			a.M[i*n+j] = BSplineBasis(knots, degree, j, t[i])
+		}
+		copy(b.M[i*dim:(i+1)*dim], points[i])
+	}
+	x, err := leastSquares(a, b)
+	if err != nil {
+		return nil, err
+	}
+	control := make([][]float64, n)
+	for j := range control {
+		control[j] = x.M[j*dim : (j+1)*dim]
+	}
+	return NewBSpline(knots, degree, control)
+}
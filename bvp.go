@@ -0,0 +1,52 @@
+package advmath
+
+/*
+Shooting is a method to solve the two-point boundary value problem of a second order
+equation (y')' = g(x, y, y'), y(a) = alpha, y(b) = beta with the shooting method: the
+initial value problem with y(a) = alpha, y'(a) = s is solved with RK45 and the secant
+method looks for the slope s for which y(b) = beta. The equation is given as the first
+order system [y, y']' = f(x, [y, y']) = [y', g(x, y, y')], more components can be added
+after the first two with their initial values given in extra.
+
+First parameter f is the right hand side of the system, y[0] being y and y[1] its
+derivative
+Second and third parameters a and b are the ends of the interval
+Fourth and fifth parameters alpha and beta are the values of y at a and b
+Sixth and seventh parameters slope0 and slope1 are two different guesses of y'(a)
+Eighth parameter extra are the initial values of the components after the first two,
+nil for a system of two components
+Ninth parameter options are the options given to RK45, the slope is found with a
+precision of RelTol/1000 (1e-9 by default)
+It returns the solution of the initial value problem with the slope found, the slope,
+and an error if RK45 failed or if the secant method didn't converge (e.g. no solution
+or bad guesses)
+*/
+func Shooting(f ODE, a, b, alpha, beta, slope0, slope1 float64, extra []float64, options ODEOptions) (*ODEResult, float64, error) {
+	o := options.withDefaults(a, b)
+	var failure error
+	var result *ODEResult
+	solve := func(s float64) (*ODEResult, error) {
+		y0 := append([]float64{alpha, s}, extra...)
+		return RK45(f, a, y0, b, options)
+	}
+	residual := func(s float64) float64 {
+		r, err := solve(s)
+		if err != nil {
+			failure = err
+			return 0
+		}
+		result = r
+		return r.States[len(r.States)-1][0] - beta
+	}
+
+	slope, err := Secant(slope0, slope1, residual, 100, 1e-3*o.RelTol)
+	if failure != nil {
+		return result, slope, failure
+	}
+	if err != nil {
+		return result, slope, err
+	}
+	//The last solution computed may not be the one at the slope returned
+	result, err = solve(slope)
+	return result, slope, err
+}
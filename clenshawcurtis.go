@@ -0,0 +1,124 @@
+package advmath
+
+import (
+	"context"
+	"math"
+)
+
+/*
+ClenshawCurtis computes the integral of a function between inf and sup with the
+Clenshaw-Curtis rule: the function is evaluated at the Chebyshev points
+cos(k*pi/N) (mapped to [inf, sup]), which is nearly as accurate as a Gauss rule of
+the same size for smooth functions. The points of the rule with N points are part of
+the rule with 2N points, so the number of points is doubled until two successive
+results agree within the tolerance, each refinement only evaluating the new points.
+
+First parameter inf is the lower boundary
+Second parameter sup is the upper boundary
+Third parameter f is the function to integrate
+Fourth parameter tol is the absolute tolerance required
+First return value is the integral
+Second return value is the estimated absolute error (difference with the previous level)
+Third return value is an error if the tolerance was not met with 4096 intervals
+*/
+func ClenshawCurtis(inf float64, sup float64, f F, tol float64) (float64, float64, error) {
+	return clenshawCurtis(inf, sup, f, tol, nil)
+}
+
+/*
+ClenshawCurtisContext is the same as ClenshawCurtis but stops when ctx is done or
+when the next doubling would exceed maxEvaluations (0 means the default of a
+million). The value
+and the error estimate returned are then the ones of the last level computed and
+the error is ctx.Err() or the budget error.
+*/
+func ClenshawCurtisContext(ctx context.Context, inf float64, sup float64, f F, tol float64, maxEvaluations int) (float64, float64, error) {
+	return clenshawCurtis(inf, sup, f, tol, newIntegrationGuard(ctx, maxEvaluations))
+}
+
+func clenshawCurtis(inf float64, sup float64, f F, tol float64, guard *integrationGuard) (float64, float64, error) {
+	const maxN = 4096
+	if guard.stop(3) {
+		return 0, math.Inf(1), guard.err
+	}
+	f = guard.count(f)
+	center := (inf + sup) / 2
+	half := (sup - inf) / 2
+
+	//values[k] = f at cos(k*pi/n)
+	n := 2
+	values := make([]float64, n+1)
+	for k := range values {
+		values[k] = f(center + half*math.Cos(float64(k)*math.Pi/float64(n)))
+	}
+	previous := half * clenshawCurtisSum(values)
+
+	estimate := math.Inf(1)
+	for n < maxN {
+		//Doubling adds n new points
+		if guard.stop(n) {
+			return previous, estimate, guard.err
+		}
+		n *= 2
+		refined := make([]float64, n+1)
+		for k := range refined {
+			if k%2 == 0 {
+				refined[k] = values[k/2]
+			} else {
+				refined[k] = f(center + half*math.Cos(float64(k)*math.Pi/float64(n)))
+			}
+		}
+		values = refined
+
+		current := half * clenshawCurtisSum(values)
+		estimate = math.Abs(current - previous)
+		if estimate <= tol {
+			return current, estimate, nil
+		}
+		previous = current
+	}
+
+	return previous, estimate, &MathError{
+		code: errorNotConverged,
+	}
+}
+
+/*
+clenshawCurtisSum applies the Clenshaw-Curtis weights on [-1, 1] to the values of
+the function at the n+1 points cos(k*pi/n), n being even.
+*/
+func clenshawCurtisSum(values []float64) float64 {
+	n := len(values) - 1
+	var sum float64
+	for k := 0; k <= n; k++ {
+		w := 1.0
+		for j := 1; j <= n/2; j++ {
+			b := 2.0
+			if 2*j == n {
+				b = 1.0
+			}
+			w -= b / float64(4*j*j-1) * math.Cos(2*float64(j*k)*math.Pi/float64(n))
+		}
+		c := 2.0
+		if k == 0 || k == n {
+			c = 1.0
+		}
+		sum += c / float64(n) * w * values[k]
+	}
+	return sum
+}
+
+/*
+ClenshawCurtisWithResult is the same as ClenshawCurtis but returns an
+IntegrationResult.
+*/
+func ClenshawCurtisWithResult(inf float64, sup float64, f F, tol float64) IntegrationResult {
+	var evaluations int
+	value, estimate, err := ClenshawCurtis(inf, sup, countEvaluations(f, &evaluations), tol)
+	return IntegrationResult{
+		Value:       value,
+		Error:       estimate,
+		Evaluations: evaluations,
+		Converged:   err == nil,
+	}
+}
@@ -0,0 +1,242 @@
+package advmath
+
+import (
+	"math/cmplx"
+)
+
+/*
+ComplexMatrix is a matrix of complex numbers, it is stored the same way as
+Matrix (row after row in a single slice).
+*/
+type ComplexMatrix struct {
+	NumberOfRows    uint
+	NumberOfColumns uint
+	M               []complex128
+}
+
+/*
+NewComplexMatrix is a method to create a new complex matrix filled with zeros.
+First parameter is the number of rows
+Second parameter is the number of columns
+*/
+func NewComplexMatrix(rows, cols uint) *ComplexMatrix {
+	m := new(ComplexMatrix)
+	m.NumberOfRows = rows
+	m.NumberOfColumns = cols
+	m.M = make([]complex128, rows*cols)
+	return m
+}
+
+/*
+NewComplexIdentity is a method to create a complex identity square matrix.
+First parameter is the number of rows and columns
+*/
+func NewComplexIdentity(rows uint) *ComplexMatrix {
+	i := NewComplexMatrix(rows, rows)
+
+	var j uint
+	for j = 0; j < rows; j++ {
+		i.M[j*i.NumberOfColumns+j] = 1.0
+	}
+
+	return i
+}
+
+/*
+IsSquare is a method to find if a matrix is a square matrix or not.
+*/
+func (m ComplexMatrix) IsSquare() bool {
+	return m.NumberOfColumns == m.NumberOfRows
+}
+
+/*
+Get is a method to retrieve the content of a matrix at the given
+row and column.
+*/
+func (m ComplexMatrix) Get(row uint, column uint) complex128 {
+	return m.M[row*m.NumberOfColumns+column]
+}
+
+/*
+Set is a method to set the value at the given row and column.
+*/
+func (m *ComplexMatrix) Set(row uint, column uint, value complex128) {
+	m.M[row*m.NumberOfColumns+column] = value
+}
+
+/*
+SetRow is a method to set the value at the given row, it changes the
+underlying matrix and returns it.
+*/
+func (m *ComplexMatrix) SetRow(rowNumber uint, row []complex128) *ComplexMatrix {
+	copy(m.M[rowNumber*m.NumberOfColumns:(rowNumber+1)*m.NumberOfColumns], row)
+	return m
+}
+
+/*
+Add is a method to add a matrix to another matrix
+First parameter is a matrix to add
+*/
+func (m ComplexMatrix) Add(in *ComplexMatrix) (*ComplexMatrix, error) {
+	if in.NumberOfColumns != m.NumberOfColumns || in.NumberOfRows != m.NumberOfRows {
+		return nil, &MathError{
+			code: errorCannotAdd,
+		}
+	}
+
+	result := NewComplexMatrix(m.NumberOfRows, m.NumberOfColumns)
+	for i := range m.M {
+		result.M[i] = m.M[i] + in.M[i]
+	}
+
+	return result, nil
+}
+
+/*
+Multiply is a method to multiply the matrix by the given matrix (A*B).
+First parameter is the matrix used for the multiplication
+*/
+func (m ComplexMatrix) Multiply(in *ComplexMatrix) (*ComplexMatrix, error) {
+	if m.NumberOfColumns != in.NumberOfRows {
+		return nil, &MathError{
+			code: errorCannotMultiply,
+		}
+	}
+
+	result := NewComplexMatrix(m.NumberOfRows, in.NumberOfColumns)
+
+	var i, j, k uint
+	for i = 0; i < m.NumberOfRows; i++ {
+		for j = 0; j < in.NumberOfColumns; j++ {
+			for k = 0; k < m.NumberOfColumns; k++ {
+				result.M[i*result.NumberOfColumns+j] += m.M[i*m.NumberOfColumns+k] * in.M[k*in.NumberOfColumns+j]
+			}
+		}
+	}
+	return result, nil
+}
+
+/*
+ConjugateTranspose is a method to compute the conjugate transpose (also called
+hermitian transpose) of the matrix, i.e. the transpose where every element is
+replaced by its complex conjugate.
+*/
+func (m ComplexMatrix) ConjugateTranspose() *ComplexMatrix {
+	ret := NewComplexMatrix(m.NumberOfColumns, m.NumberOfRows)
+
+	var i, j uint
+	for i = 0; i < m.NumberOfRows; i++ {
+		for j = 0; j < m.NumberOfColumns; j++ {
+			ret.M[j*ret.NumberOfColumns+i] = cmplx.Conj(m.M[i*m.NumberOfColumns+j])
+		}
+	}
+	return ret
+}
+
+/*
+Determinant is a method to compute the determinant of a square complex matrix.
+It uses a gaussian elimination with partial pivoting, the determinant being
+the product of the pivots (with a sign change for each row swap).
+*/
+func (m ComplexMatrix) Determinant() (complex128, error) {
+	if !m.IsSquare() {
+		return 0, &MathError{
+			code: errorNonSquareMatrix,
+		}
+	}
+
+	n := int(m.NumberOfRows)
+	a := make([]complex128, len(m.M))
+	copy(a, m.M)
+
+	det := complex(1.0, 0.0)
+	for k := 0; k < n; k++ {
+		//Find the biggest pivot in the column
+		p := k
+		for i := k + 1; i < n; i++ {
+			if cmplx.Abs(a[i*n+k]) > cmplx.Abs(a[p*n+k]) {
+				p = i
+			}
+		}
+		if a[p*n+k] == 0 {
+			return 0, nil
+		}
+		if p != k {
+			for j := 0; j < n; j++ {
+				a[k*n+j], a[p*n+j] = a[p*n+j], a[k*n+j]
+			}
+			det = -det
+		}
+		det *= a[k*n+k]
+		for i := k + 1; i < n; i++ {
+			factor := a[i*n+k] / a[k*n+k]
+			for j := k; j < n; j++ {
+				a[i*n+j] -= factor * a[k*n+j]
+			}
+		}
+	}
+
+	return det, nil
+}
+
+/*
+Inverse is a method to compute the inverse of a square complex matrix. It uses
+a Gauss-Jordan elimination with partial pivoting. An error is returned if the
+matrix is not square or not inversible.
+*/
+func (m ComplexMatrix) Inverse() (*ComplexMatrix, error) {
+	if !m.IsSquare() {
+		return nil, &MathError{
+			code: errorNonSquareMatrix,
+		}
+	}
+
+	n := int(m.NumberOfRows)
+	a := make([]complex128, len(m.M))
+	copy(a, m.M)
+	inv := NewComplexIdentity(m.NumberOfRows)
+
+	for k := 0; k < n; k++ {
+		p := k
+		for i := k + 1; i < n; i++ {
+			if cmplx.Abs(a[i*n+k]) > cmplx.Abs(a[p*n+k]) {
+				p = i
+			}
+		}
+		if a[p*n+k] == 0 {
+			return nil, &MathError{
+				code: errorNotInversible,
+			}
+		}
+		if p != k {
+			for j := 0; j < n; j++ {
+				a[k*n+j], a[p*n+j] = a[p*n+j], a[k*n+j]
+				inv.M[k*n+j], inv.M[p*n+j] = inv.M[p*n+j], inv.M[k*n+j]
+			}
+		}
+
+		//Normalize the pivot row
+		pivot := a[k*n+k]
+		for j := 0; j < n; j++ {
+			a[k*n+j] /= pivot
+			inv.M[k*n+j] /= pivot
+		}
+
+		//Eliminate the column in every other row
+		for i := 0; i < n; i++ {
+			if i == k {
+				continue
+			}
+			factor := a[i*n+k]
+			if factor == 0 {
+				continue
+			}
+			for j := 0; j < n; j++ {
+				a[i*n+j] -= factor * a[k*n+j]
+				inv.M[i*n+j] -= factor * inv.M[k*n+j]
+			}
+		}
+	}
+
+	return inv, nil
+}
@@ -0,0 +1,239 @@
+package advmath
+
+import (
+	"math"
+)
+
+/*
+bounded tells if there are bounds on the variables
+*/
+func (o OptimizeOptions) bounded() bool {
+	return o.Lower != nil || o.Upper != nil
+}
+
+/*
+checkBounds returns an error if the bounds don't match the n variables or if a lower
+bound is above the upper bound
+*/
+func (o OptimizeOptions) checkBounds(n int) error {
+	if (o.Lower != nil && len(o.Lower) != n) || (o.Upper != nil && len(o.Upper) != n) {
+		return &MathError{
+			code: errorDimensionMismatch,
+		}
+	}
+	if o.Lower != nil && o.Upper != nil {
+		for i := range o.Lower {
+			if o.Lower[i] > o.Upper[i] {
+				return &MathError{
+					s: "A lower bound is above the upper bound",
+				}
+			}
+		}
+	}
+	return nil
+}
+
+/*
+project moves x into the bounds, in place
+*/
+func (o OptimizeOptions) project(x []float64) {
+	for i := range x {
+		if o.Lower != nil && x[i] < o.Lower[i] {
+			x[i] = o.Lower[i]
+		}
+		if o.Upper != nil && x[i] > o.Upper[i] {
+			x[i] = o.Upper[i]
+		}
+	}
+}
+
+/*
+atBound tells if the variable i of x is on a bound which keeps it from moving against
+the gradient g
+*/
+func (o OptimizeOptions) atBound(x, g []float64, i int) bool {
+	return (o.Lower != nil && x[i] <= o.Lower[i] && g[i] > 0) || (o.Upper != nil && x[i] >= o.Upper[i] && g[i] < 0)
+}
+
+/*
+projectedGradient is the gradient without the components blocked by the bounds, it
+is 0 at a minimum on the bounds
+*/
+func (o OptimizeOptions) projectedGradient(x, g []float64) []float64 {
+	if !o.bounded() {
+		return g
+	}
+	p := append([]float64(nil), g...)
+	for i := range p {
+		if o.atBound(x, g, i) {
+			p[i] = 0
+		}
+	}
+	return p
+}
+
+/*
+projectedLineSearch backtracks along the projection of x + step*direction on the
+bounds until f decreases enough (Armijo condition on the projected step)
+*/
+func projectedLineSearch(obj *objective, o OptimizeOptions, x []float64, value float64, g []float64, direction []float64, step float64) (linePoint, error) {
+	const (
+		c1            = 1e-4
+		maxIterations = 50
+	)
+	for i := 0; i < maxIterations; i++ {
+		trial := axpy(step, direction, x)
+		o.project(trial)
+		s := make([]float64, len(x))
+		for j := range s {
+			s[j] = trial[j] - x[j]
+		}
+		decrease := dot(g, s)
+		if decrease >= 0 {
+			//Nothing left once projected
+			break
+		}
+		v := obj.value(trial)
+		if v <= value+c1*decrease {
+			return linePoint{step: step, x: trial, value: v, g: obj.grad(trial)}, nil
+		}
+		step /= 2
+	}
+	return linePoint{x: x, value: value, g: g}, &MathError{
+		code: errorNotConverged,
+	}
+}
+
+/*
+minimizer is a minimization method without equality constraints
+*/
+type minimizer func(f Fn, x0 []float64, options OptimizeOptions) (OptimizeResult, error)
+
+/*
+minimizeConstrained checks the bounds and starts from the projection of x0, then
+handles the equality constraints with the method of multipliers (augmented
+Lagrangian): f + sum(lambda_i h_i + mu/2 h_i^2) is minimized, lambda_i is updated
+with mu h_i and mu is multiplied by 10 when the constraints don't decrease enough.
+Unlike a pure penalty, the constraints are met without an infinite mu.
+budget is the number of evaluations shared by all the rounds, each one getting a
+tenth of it through MaxEvaluations, or 0 for the local methods which have their own
+stopping criteria.
+*/
+func minimizeConstrained(f Fn, x0 []float64, options OptimizeOptions, budget int, method minimizer) (OptimizeResult, error) {
+	if len(x0) == 0 {
+		return OptimizeResult{}, &MathError{
+			code: errorDimensionMismatch,
+		}
+	}
+	if err := options.checkBounds(len(x0)); err != nil {
+		return OptimizeResult{}, err
+	}
+	x := append([]float64(nil), x0...)
+	options.project(x)
+	if len(options.Equality) == 0 {
+		if budget > 0 {
+			options.MaxEvaluations = budget
+		}
+		return method(f, x, options)
+	}
+
+	const maxRounds = 50
+	tolerance := options.ConstraintTolerance
+	if tolerance <= 0 {
+		tolerance = 1e-6
+	}
+	constraints := options.Equality
+	lambda := make([]float64, len(constraints))
+	mu := 10.0
+	violation := func(x []float64) ([]float64, float64) {
+		h := make([]float64, len(constraints))
+		var largest float64
+		for i, c := range constraints {
+			h[i] = c(x)
+			largest = math.Max(largest, math.Abs(h[i]))
+		}
+		return h, largest
+	}
+	lagrangian := func(x []float64) float64 {
+		v := f(x)
+		for i, c := range constraints {
+			h := c(x)
+			v += lambda[i]*h + mu/2*h*h
+		}
+		return v
+	}
+	inner := options
+	inner.Equality = nil
+	if options.Gradient != nil {
+		inner.Gradient = func(x []float64) []float64 {
+			g := append([]float64(nil), options.Gradient(x)...)
+			for i, c := range constraints {
+				weight := lambda[i] + mu*c(x)
+				for j, d := range Gradient(x, c, 1e-4) {
+					g[j] += weight * d
+				}
+			}
+			return g
+		}
+	}
+
+	total := OptimizeResult{}
+	_, previous := violation(x)
+	if budget > 0 {
+		//One evaluation is kept for the value of f at the result
+		budget--
+	}
+	share := budget / 10
+	if share == 0 {
+		share = budget
+	}
+	spent := false
+	for round := 0; round < maxRounds; round++ {
+		if budget > 0 {
+			remaining := budget - total.Evaluations
+			if remaining < share {
+				spent = true
+				break
+			}
+			//The last round gets what remains rather than leaving less than a share
+			inner.MaxEvaluations = share
+			if remaining < 2*share {
+				inner.MaxEvaluations = remaining
+			}
+		}
+		r, err := method(lagrangian, x, inner)
+		total.Iterations += r.Iterations
+		total.Evaluations += r.Evaluations
+		total.X, total.Gradient = r.X, r.Gradient
+		if err != nil {
+			//The method may fail without a point (wrong settings)
+			if len(total.X) == len(x0) {
+				total.Value = f(total.X)
+			}
+			return total, err
+		}
+		x = r.X
+		h, largest := violation(x)
+		if largest <= tolerance {
+			total.Value = f(x)
+			total.Converged = true
+			return total, nil
+		}
+		for i := range lambda {
+			lambda[i] += mu * h[i]
+		}
+		if largest > previous/4 {
+			mu *= 10
+		}
+		previous = largest
+	}
+	total.Value = f(x)
+	if spent {
+		return total, &MathError{
+			code: errorBudgetExceeded,
+		}
+	}
+	return total, &MathError{
+		code: errorNotConverged,
+	}
+}
@@ -0,0 +1,147 @@
+package advmath
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+)
+
+/*
+CSVOptions holds the settings used to read or write a matrix as CSV.
+The zero value reads and writes comma separated values without header.
+*/
+type CSVOptions struct {
+	//Delimiter is the field separator, a comma is used when it is 0
+	Delimiter rune
+	//Header tells if the first line holds the names of the columns. When
+	//reading, the line is skipped and returned. When writing, Columns is
+	//written first.
+	Header bool
+	//Columns are the names written in the header line
+	Columns []string
+}
+
+/*
+ReadCSV is a method to read a matrix from comma separated values without header.
+Every line is a row of the matrix and all the lines must have the same number
+of fields.
+First parameter is the reader holding the CSV data
+*/
+func ReadCSV(r io.Reader) (*Matrix, error) {
+	m, _, err := ReadCSVWithOptions(r, CSVOptions{})
+	return m, err
+}
+
+/*
+ReadCSVWithOptions is a method to read a matrix from a CSV source using the
+given delimiter and header settings.
+First parameter is the reader holding the CSV data
+Second parameter are the options
+First return value is the matrix
+Second return value is the header if there is one
+Third return value is the error, from the reader or if a field is not a number
+*/
+func ReadCSVWithOptions(r io.Reader, options CSVOptions) (*Matrix, []string, error) {
+	reader := csv.NewReader(r)
+	if options.Delimiter != 0 {
+		reader.Comma = options.Delimiter
+	}
+	reader.TrimLeadingSpace = true
+
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var header []string
+	if options.Header && len(records) > 0 {
+		header = records[0]
+		records = records[1:]
+	}
+
+	var cols int
+	if len(records) > 0 {
+		cols = len(records[0])
+	}
+	m := NewMatrix(uint(len(records)), uint(cols))
+	for i, record := range records {
+		for j, field := range record {
+			v, err := strconv.ParseFloat(field, 64)
+			if err != nil {
+				return nil, header, err
+			}
+			m.M[i*cols+j] = v
+		}
+	}
+
+	return m, header, nil
+}
+
+/*
+WriteCSV is a method to write the matrix as comma separated values, one line
+per row, without header.
+First parameter is the writer
+*/
+func (m *Matrix) WriteCSV(w io.Writer) error {
+	return m.WriteCSVWithOptions(w, CSVOptions{})
+}
+
+/*
+WriteCSVWithOptions is a method to write the matrix as CSV using the given
+delimiter and header settings. Values are written with the shortest
+representation that reads back to the same float64.
+First parameter is the writer
+Second parameter are the options
+*/
+func (m *Matrix) WriteCSVWithOptions(w io.Writer, options CSVOptions) error {
+	writer := csv.NewWriter(w)
+	if options.Delimiter != 0 {
+		writer.Comma = options.Delimiter
+	}
+
+	if options.Header {
+		if err := writer.Write(options.Columns); err != nil {
+			return err
+		}
+	}
+
+	record := make([]string, m.NumberOfColumns)
+	var row, col uint
+	for row = 0; row < m.NumberOfRows; row++ {
+		for col = 0; col < m.NumberOfColumns; col++ {
+			record[col] = strconv.FormatFloat(m.Get(row, col), 'g', -1, 64)
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+/*
+WriteCSV is a method to write the solution of an ODE as comma separated values, with a
+header line "t,y0,y1,..." then one line per time: the time followed by the state.
+First parameter is the writer
+*/
+func (r *ODEResult) WriteCSV(w io.Writer) error {
+	var cols uint = 1
+	if len(r.States) > 0 {
+		cols += uint(len(r.States[0]))
+	}
+	m := NewMatrix(uint(len(r.Times)), cols)
+	columns := []string{"t"}
+	var col uint
+	for col = 1; col < cols; col++ {
+		columns = append(columns, "y"+strconv.Itoa(int(col-1)))
+	}
+	for i, t := range r.Times {
+		m.M[uint(i)*cols] = t
+		copy(m.M[uint(i)*cols+1:], r.States[i])
+	}
+	return m.WriteCSVWithOptions(w, CSVOptions{
+		Header:  true,
+		Columns: columns,
+	})
+}
@@ -0,0 +1,260 @@
+package advmath
+
+import (
+	"container/heap"
+	"context"
+	"math"
+)
+
+/*
+Cubature computes the integral of a function of several variables over a
+hyper-rectangle with the adaptive algorithm of Genz and Malik: each region is
+integrated with a degree 7 rule and an embedded degree 5 rule, the difference
+giving the error estimate. The region with the biggest error is split in two along
+the direction where the function varies the most, until the total error estimate
+is below the tolerance. It works for 2 to 15 dimensions (the number of points per
+region grows like 2^n), 2 to 7 being where it is the most efficient.
+
+First parameter f is the function to integrate
+Second parameter lower are the lower boundaries for each variable
+Third parameter upper are the upper boundaries for each variable
+Fourth parameter tol is the absolute tolerance required
+First return value is the integral
+Second return value is the estimated absolute error
+Third return value is an error if the dimensions are wrong or if the tolerance
+was not met within a million evaluations
+*/
+func Cubature(f func([]float64) float64, lower, upper []float64, tol float64) (float64, float64, error) {
+	return cubature(f, lower, upper, tol, nil)
+}
+
+/*
+CubatureContext is the same as Cubature but stops when ctx is done or when the next
+subdivision would exceed maxEvaluations (0 means the default of a million, as for
+the other Context variants, a bigger value raises it). The value and the error
+estimate returned are then the ones reached so far and the error is ctx.Err() or the
+budget error.
+*/
+func CubatureContext(ctx context.Context, f func([]float64) float64, lower, upper []float64, tol float64, maxEvaluations int) (float64, float64, error) {
+	return cubature(f, lower, upper, tol, newIntegrationGuard(ctx, maxEvaluations))
+}
+
+func cubature(f func([]float64) float64, lower, upper []float64, tol float64, guard *integrationGuard) (float64, float64, error) {
+	n := len(lower)
+	if n < 2 || n > 15 || len(upper) != n {
+		return 0, 0, &MathError{
+			code: errorDimensionMismatch,
+		}
+	}
+
+	rule := newGenzMalik(n)
+	//Each split evaluates f on two new regions, the limit counts these evaluations
+	limit := defaultMaxEvaluations
+	if guard != nil && guard.maxEvaluations > limit {
+		limit = guard.maxEvaluations
+	}
+	evaluations := rule.points
+
+	if guard.stop(rule.points) {
+		return 0, math.Inf(1), guard.err
+	}
+	if guard != nil {
+		guard.evaluations += rule.points
+	}
+
+	center := make([]float64, n)
+	half := make([]float64, n)
+	for i := range lower {
+		center[i] = (lower[i] + upper[i]) / 2
+		half[i] = (upper[i] - lower[i]) / 2
+	}
+	first := rule.integrate(f, center, half)
+	regions := &regionHeap{first}
+	value, err := first.value, first.err
+
+	for err > tol && evaluations+2*rule.points <= limit && !guard.stop(2*rule.points) {
+		evaluations += 2 * rule.points
+		if guard != nil {
+			guard.evaluations += 2 * rule.points
+		}
+		worst := heap.Pop(regions).(cubatureRegion)
+		d := worst.splitDim
+
+		//Split the region in two along d
+		h := make([]float64, n)
+		copy(h, worst.half)
+		h[d] /= 2
+		left := make([]float64, n)
+		right := make([]float64, n)
+		copy(left, worst.center)
+		copy(right, worst.center)
+		left[d] -= h[d]
+		right[d] += h[d]
+		heap.Push(regions, rule.integrate(f, left, h))
+		heap.Push(regions, rule.integrate(f, right, h))
+
+		value, err = regions.sum()
+	}
+
+	if guard != nil && guard.err != nil {
+		return value, err, guard.err
+	}
+	if err > tol {
+		return value, err, &MathError{
+			code: errorNotConverged,
+		}
+	}
+	return value, err, nil
+}
+
+/*
+genzMalik holds the weights of the degree 7 and degree 5 rules for a given dimension
+*/
+type genzMalik struct {
+	n      int
+	points int
+	//Degree 7 weights
+	w1, w2, w3, w4, w5 float64
+	//Degree 5 weights
+	v1, v2, v3, v4 float64
+}
+
+var (
+	//Generators of the Genz-Malik rule
+	gmLambda2 = math.Sqrt(9.0 / 70.0)
+	gmLambda3 = math.Sqrt(9.0 / 10.0)
+	gmLambda5 = math.Sqrt(9.0 / 19.0)
+)
+
+func newGenzMalik(n int) genzMalik {
+	nf := float64(n)
+	return genzMalik{
+		n:      n,
+		points: 1 + 4*n + 2*n*(n-1) + (1 << uint(n)),
+		w1:     (12824 - 9120*nf + 400*nf*nf) / 19683,
+		w2:     980.0 / 6561,
+		w3:     (1820 - 400*nf) / 19683,
+		w4:     200.0 / 19683,
+		w5:     6859.0 / 19683 / math.Pow(2, nf),
+		v1:     (729 - 950*nf + 50*nf*nf) / 729,
+		v2:     245.0 / 486,
+		v3:     (265 - 100*nf) / 1458,
+		v4:     25.0 / 729,
+	}
+}
+
+/*
+integrate applies both rules on the region and finds the best direction to split it
+*/
+func (r genzMalik) integrate(f func([]float64) float64, center, half []float64) cubatureRegion {
+	n := r.n
+	x := make([]float64, n)
+	eval := func() float64 {
+		return f(x)
+	}
+	reset := func() {
+		copy(x, center)
+	}
+
+	reset()
+	f0 := eval()
+
+	var sum2, sum3, sum4, sum5 float64
+	splitDim := 0
+	maxDiff := -1.0
+	for i := 0; i < n; i++ {
+		reset()
+		x[i] = center[i] - gmLambda2*half[i]
+		a := eval()
+		x[i] = center[i] + gmLambda2*half[i]
+		b := eval()
+		x[i] = center[i] - gmLambda3*half[i]
+		c := eval()
+		x[i] = center[i] + gmLambda3*half[i]
+		d := eval()
+		sum2 += a + b
+		sum3 += c + d
+
+		//Fourth divided difference to choose where to split
+		diff := math.Abs(a + b - 2*f0 - (gmLambda2*gmLambda2)/(gmLambda3*gmLambda3)*(c+d-2*f0))
+		if diff > maxDiff {
+			maxDiff = diff
+			splitDim = i
+		}
+	}
+
+	for i := 0; i < n; i++ {
+		for j := i + 1; j < n; j++ {
+			for _, si := range []float64{-1, 1} {
+				for _, sj := range []float64{-1, 1} {
+					reset()
+					x[i] = center[i] + si*gmLambda3*half[i]
+					x[j] = center[j] + sj*gmLambda3*half[j]
+					sum4 += eval()
+				}
+			}
+		}
+	}
+
+	for corner := 0; corner < 1<<uint(n); corner++ {
+		for i := 0; i < n; i++ {
+			if corner&(1<<uint(i)) != 0 {
+				x[i] = center[i] + gmLambda5*half[i]
+			} else {
+				x[i] = center[i] - gmLambda5*half[i]
+			}
+		}
+		sum5 += eval()
+	}
+
+	volume := 1.0
+	for _, h := range half {
+		volume *= 2 * h
+	}
+	i7 := volume * (r.w1*f0 + r.w2*sum2 + r.w3*sum3 + r.w4*sum4 + r.w5*sum5)
+	i5 := volume * (r.v1*f0 + r.v2*sum2 + r.v3*sum3 + r.v4*sum4)
+
+	c := make([]float64, n)
+	h := make([]float64, n)
+	copy(c, center)
+	copy(h, half)
+	return cubatureRegion{
+		center:   c,
+		half:     h,
+		value:    i7,
+		err:      math.Abs(i7 - i5),
+		splitDim: splitDim,
+	}
+}
+
+type cubatureRegion struct {
+	center, half []float64
+	value, err   float64
+	splitDim     int
+}
+
+/*
+regionHeap is a max heap of regions ordered by their error estimate
+*/
+type regionHeap []cubatureRegion
+
+func (h regionHeap) Len() int            { return len(h) }
+func (h regionHeap) Less(i, j int) bool  { return h[i].err > h[j].err }
+func (h regionHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *regionHeap) Push(x interface{}) { *h = append(*h, x.(cubatureRegion)) }
+func (h *regionHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	x := old[n-1]
+	*h = old[:n-1]
+	return x
+}
+
+func (h regionHeap) sum() (float64, float64) {
+	var value, err float64
+	for _, r := range h {
+		value += r.value
+		err += r.err
+	}
+	return value, err
+}
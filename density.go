@@ -0,0 +1,157 @@
+package advmath
+
+import (
+	"math"
+	"sort"
+)
+
+/*
+HistogramResult holds the counts of a sample in consecutive bins
+*/
+type HistogramResult struct {
+	//Edges are the bounds of the bins, bin i being [Edges[i], Edges[i+1]), the last
+	//one including its upper bound
+	Edges []float64
+	//Counts are the number of values in each bin
+	Counts []int
+}
+
+/*
+Density is a method returning the height of each bin normalized so that the area of
+the histogram is 1, comparable to a probability density
+*/
+func (h HistogramResult) Density() []float64 {
+	var total int
+	for _, c := range h.Counts {
+		total += c
+	}
+	density := make([]float64, len(h.Counts))
+	if total == 0 {
+		return density
+	}
+	for i, c := range h.Counts {
+		density[i] = float64(c) / (float64(total) * (h.Edges[i+1] - h.Edges[i]))
+	}
+	return density
+}
+
+/*
+interquartileRange is Q3 - Q1 of a sorted sample
+*/
+func interquartileRange(sorted []float64) float64 {
+	q1, _ := Quantile(sorted, 0.25, PropagateNaN)
+	q3, _ := Quantile(sorted, 0.75, PropagateNaN)
+	return q3 - q1
+}
+
+/*
+Histogram is a function to count the values of a sample in equal bins spanning its
+range. With bins = 0 the number of bins is chosen with the Freedman-Diaconis rule, a
+width of 2 IQR/n^(1/3) (IQR being the interquartile range), which is robust to
+outliers; the Sturges rule log2(n)+1 is used when the IQR is 0 or when the rule asks
+for more than n bins, which happens with far outliers.
+First parameter is the sample, the NaN values are ignored
+Second parameter is the number of bins, chosen automatically when it is 0
+It returns the histogram, and an error if the sample has no value or if the number of
+bins is negative
+*/
+func Histogram(data []float64, bins int) (HistogramResult, error) {
+	values, _, _ := applyNaNPolicy(data, OmitNaN)
+	if len(values) == 0 || bins < 0 {
+		return HistogramResult{}, &MathError{
+			code: errorDimensionMismatch,
+		}
+	}
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	lo, hi := sorted[0], sorted[len(sorted)-1]
+	if math.IsInf(lo, 0) || math.IsInf(hi, 0) {
+		return HistogramResult{}, &MathError{
+			s: "Cannot compute the histogram of infinite values",
+		}
+	}
+	if lo == hi {
+		//A single bin centered on the value
+		lo, hi = lo-0.5, hi+0.5
+	}
+	if bins == 0 {
+		n := float64(len(sorted))
+		bins = int(math.Ceil(math.Log2(n))) + 1
+		if width := 2 * interquartileRange(sorted) / math.Cbrt(n); width > 0 {
+			//Divided separately so that a range of more than MaxFloat64 doesn't
+			//overflow, and capped at n bins because of the outliers
+			count := math.Ceil(hi/width - lo/width)
+			if count <= n {
+				bins = int(math.Max(1, count))
+			}
+		}
+	}
+
+	h := HistogramResult{
+		Edges:  make([]float64, bins+1),
+		Counts: make([]int, bins),
+	}
+	width := hi/float64(bins) - lo/float64(bins)
+	for i := range h.Edges {
+		h.Edges[i] = lo + float64(i)*width
+	}
+	h.Edges[bins] = hi
+	for _, v := range sorted {
+		i := int(v/width - lo/width)
+		if i >= bins {
+			i = bins - 1
+		} else if i < 0 {
+			i = 0
+		}
+		h.Counts[i]++
+	}
+	return h, nil
+}
+
+/*
+GaussianKDE is a function to estimate the probability density of a sample with a
+Gaussian kernel density estimator: the mean of normal densities of standard
+deviation h centered on the values. The result is a smooth density which can be
+evaluated anywhere, integrated or differentiated like any other F. Each evaluation
+costs O(n).
+First parameter is the sample, the NaN values are ignored
+Second parameter is the bandwidth h, chosen with the rule of thumb of Silverman
+0.9 min(s, IQR/1.34) n^(-1/5) when it is 0
+It returns the density, and an error if the sample has no value or if the automatic
+bandwidth is 0 (constant sample)
+*/
+func GaussianKDE(data []float64, bandwidth float64) (F, error) {
+	values, _, _ := applyNaNPolicy(data, OmitNaN)
+	if len(values) == 0 {
+		return nil, &MathError{
+			code: errorDimensionMismatch,
+		}
+	}
+	points := append([]float64(nil), values...)
+	n := float64(len(points))
+	if bandwidth <= 0 {
+		sort.Float64s(points)
+		spread := interquartileRange(points) / 1.34
+		if len(points) > 1 {
+			s, _ := StdDev(points, true, PropagateNaN)
+			if spread == 0 || s < spread {
+				spread = s
+			}
+		}
+		bandwidth = 0.9 * spread * math.Pow(n, -0.2)
+		if !(bandwidth > 0) {
+			return nil, &MathError{
+				s: "Cannot choose the bandwidth of a constant sample",
+			}
+		}
+	}
+	scale := 1 / (n * bandwidth * math.Sqrt(2*math.Pi))
+	return func(x float64) float64 {
+		var sum float64
+		for _, p := range points {
+			u := (x - p) / bandwidth
+			sum += math.Exp(-u * u / 2)
+		}
+		return sum * scale
+	}, nil
+}
@@ -2,6 +2,7 @@ package advmath
 
 import (
 	"math"
+	"sync"
 )
 
 /*
@@ -10,13 +11,34 @@ Ridders computes derivative based on the ridders algorithm from 'Numerical Recip
 First parameter (t) is the value to use for the computation
 Second parameter (f) is the function for which we want a derivative
 The method returns the derivative value computed for the function, note that it doesn't verify that
-the function can be derived or not.
+the function can be derived or not. Use RiddersWithError to know how precise it is.
 */
 func Ridders(t float64, f F, err float64) float64 {
+	d, _ := RiddersWithError(t, f, err)
+	return d
+}
+
+/*
+RiddersWithError is the same as Ridders but also returns the estimated error of the
+derivative, computed from the differences between the successive extrapolations.
+When the required error cannot be reached, the best estimate found is returned with
+its (bigger) error so that the caller can decide to trust it or not.
+
+First parameter (t) is the value to use for the computation
+Second parameter (f) is the function for which we want a derivative
+Third parameter (err) is the error required, the first step being sqrt(err) or the
+one of StepSize when it is 0
+First return value is the derivative
+Second return value is the estimated absolute error
+*/
+func RiddersWithError(t float64, f F, err float64) (float64, float64) {
 	var calculatedError float64
 	var fac float64
 
 	h := math.Sqrt(err)
+	if err <= 0 {
+		h = StepSize(t, f)
+	}
 	cn := 1.2
 	cn2 := cn * cn
 	const n = 20
@@ -24,30 +46,46 @@ func Ridders(t float64, f F, err float64) float64 {
 	var a [n][n]float64
 	d := 0.0
 	a[0][0] = (f(t+h) - f(t-h)) / (2.0 * h)
+	//best is used when the required error is never reached
+	best := a[0][0]
+	bestError := math.Inf(1)
+	found := false
 
 	for i := 1; i < n; i++ {
 		h = h / cn
 		a[0][i] = (f(t+h) - f(t-h)) / (2.0 * h)
 		fac = cn2
-		for j := 1; j < i; j++ {
+		for j := 1; j <= i; j++ {
 			a[j][i] = (a[j-1][i]*fac - a[j-1][i-1]) / (fac - 1.0)
 			fac = cn2 * fac
 			calculatedError = math.Max(math.Abs(a[j][i]-a[j-1][i]), math.Abs(a[j][i]-a[j-1][i-1]))
+			if calculatedError < bestError {
+				bestError = calculatedError
+				best = a[j][i]
+			}
 			if calculatedError <= err {
 				err = calculatedError
 				d = a[j][i]
+				found = true
 			}
-			if math.Abs(a[i][i]-a[i-1][i-1]) >= 2*err {
-				return d
-			}
+		}
+		//The extrapolation of highest order got worse than the best estimate by a
+		//significant factor, the round-off dominates from now on
+		if math.Abs(a[i][i]-a[i-1][i-1]) >= 2*bestError {
+			break
 		}
 	}
-	return d
+	if !found {
+		return best, bestError
+	}
+	return d, err
 }
 
 /*
 Standard is a function to compute the derivative using the good old Newton's difference quotient.
 Ridders usually gives results probably faster but precision might be better with this one ...
+The step is sqrt(err), which is badly scaled when t is very large or very small, if
+err is 0 the step is chosen by StepSize instead.
 
 First parameter t is the value to use for the computation
 Second parameter f is the function for which we want a derivative
@@ -55,5 +93,511 @@ It returns the derivative value
 */
 func Standard(t float64, f F, err float64) float64 {
 	h := math.Sqrt(err)
+	if err <= 0 {
+		h = StepSize(t, f)
+	}
 	return (f(t+h) - f(t-h)) / (2.0 * h)
 }
+
+/*
+StepSize chooses the step of a central difference at t, balancing the truncation
+error h^2*|f3|/6 (f3 being the third derivative) and the round-off error
+eps*|f|/h. The first guess is cbrt(eps)*max(|t|, 1), so that the step scales with
+t, then f3 is estimated with this step to take the scale on which f varies into
+account. The step returned is
+exactly representable as (t+h)-t.
+
+First parameter t is the value where the derivative will be computed
+Second parameter f is the function
+*/
+func StepSize(t float64, f F) float64 {
+	h0 := math.Cbrt(machineEpsilon) * math.Max(math.Abs(t), 1)
+	f1 := f(t - 2*h0)
+	f2 := f(t - h0)
+	f3 := f(t + h0)
+	f4 := f(t + 2*h0)
+	difference := math.Abs(f4 - 2*f3 + 2*f2 - f1)
+	noise := machineEpsilon * math.Max(math.Max(math.Abs(f1), math.Abs(f4)), math.SmallestNonzeroFloat64)
+
+	//When the difference is only round-off, the third derivative is unknown and h0 is kept
+	h := h0
+	if difference > 8*noise && !math.IsInf(difference, 0) && !math.IsNaN(difference) {
+		third := difference / (2 * h0 * h0 * h0)
+		h = math.Cbrt(3 * noise / third)
+		//The estimate of the third derivative is not reliable enough to go too far from h0
+		h = math.Max(math.Min(h, 1e3*h0), 1e-6*h0)
+	}
+	temp := t + h
+	return temp - t
+}
+
+/*
+centralDifference computes the central difference of order n of f at t with step h,
+the sum of (-1)^k C(n, k) f(t + (n/2 - k)h) divided by h^n. Its error is in h^2
+whatever the order.
+*/
+func centralDifference(t float64, f F, order int, h float64) float64 {
+	var sum float64
+	c := 1.0
+	for k := 0; k <= order; k++ {
+		v := c * f(t+(float64(order)/2-float64(k))*h)
+		if k%2 == 1 {
+			v = -v
+		}
+		sum += v
+		c = c * float64(order-k) / float64(k+1)
+	}
+	return sum / math.Pow(h, float64(order))
+}
+
+/*
+DerivativeN computes the derivative of the given order using central differences
+refined with Richardson extrapolation, as Ridders does for the first derivative: the
+step is divided by 1.4 at each iteration and the differences are extrapolated to a
+zero step. The best estimate is returned when its error is below err or when
+reducing the step makes the result worse because of round-off. Round-off grows
+quickly with the order, so expect about 16/(order+1) correct digits.
+
+First parameter t is the value to use for the computation
+Second parameter f is the function for which we want a derivative
+Third parameter is the order of the derivative, 0 returns f(t)
+Fourth parameter err is the error required
+*/
+func DerivativeN(t float64, f F, order int, err float64) float64 {
+	if order <= 0 {
+		return f(t)
+	}
+	const n = 10
+	const safe = 2.0
+	cn := 1.4
+	cn2 := cn * cn
+
+	//A step scaled with t would be far too big for functions like exp
+	h := 0.1
+	var a [n][n]float64
+	a[0][0] = centralDifference(t, f, order, h)
+	best := a[0][0]
+	bestError := math.Inf(1)
+
+	for i := 1; i < n; i++ {
+		h = h / cn
+		a[0][i] = centralDifference(t, f, order, h)
+		fac := cn2
+		for j := 1; j <= i; j++ {
+			a[j][i] = (a[j-1][i]*fac - a[j-1][i-1]) / (fac - 1.0)
+			fac = cn2 * fac
+			calculatedError := math.Max(math.Abs(a[j][i]-a[j-1][i]), math.Abs(a[j][i]-a[j-1][i-1]))
+			if calculatedError <= bestError {
+				bestError = calculatedError
+				best = a[j][i]
+			}
+		}
+		if bestError <= err || math.Abs(a[i][i]-a[i-1][i-1]) >= safe*bestError {
+			break
+		}
+	}
+	return best
+}
+
+/*
+Derivative2 computes the second derivative of f at t, see DerivativeN
+
+First parameter t is the value to use for the computation
+Second parameter f is the function for which we want a derivative
+Third parameter err is the error required
+*/
+func Derivative2(t float64, f F, err float64) float64 {
+	return DerivativeN(t, f, 2, err)
+}
+
+/*
+Partial computes the partial derivative of f with respect to the i-th variable at x,
+with Ridders on the function of one variable where all the other variables are
+fixed. x is not modified.
+
+First parameter x is the point where the derivative is computed
+Second parameter f is the function for which we want a derivative
+Third parameter i is the index of the variable
+Fourth parameter err is the error required, as for Ridders
+*/
+func Partial(x []float64, f Fn, i int, err float64) float64 {
+	point := make([]float64, len(x))
+	copy(point, x)
+	g := func(t float64) float64 {
+		point[i] = t
+		return f(point)
+	}
+	return Ridders(x[i], g, err)
+}
+
+/*
+Gradient computes the vector of the partial derivatives of f at x, see Partial
+
+First parameter x is the point where the gradient is computed
+Second parameter f is the function for which we want the gradient
+Third parameter err is the error required, as for Ridders
+*/
+func Gradient(x []float64, f Fn, err float64) []float64 {
+	gradient := make([]float64, len(x))
+	for i := range x {
+		gradient[i] = Partial(x, f, i, err)
+	}
+	return gradient
+}
+
+/*
+Jacobian computes the matrix of the partial derivatives of f at x, J[i][j] being the
+derivative of the i-th component of f with respect to the j-th variable. Each column
+is computed with a central difference, the step of variable j being
+step*max(1, |x[j]|) so that it scales with x. The columns can be computed
+concurrently, f must then be safe to call from several goroutines.
+
+First parameter x is the point where the Jacobian is computed
+Second parameter f is the function
+Third parameter step is the relative step, the cube root of the machine epsilon
+(optimal for central differences) is used when it is 0
+Fourth parameter is the number of workers computing columns at the same time, 0 or 1
+computes them one after the other
+It returns an error if f doesn't always return slices of the same length
+*/
+func Jacobian(x []float64, f VectorFn, step float64, workers int) (*Matrix, error) {
+	if step <= 0 {
+		step = math.Cbrt(machineEpsilon)
+	}
+	m := len(f(x))
+	n := len(x)
+	jacobian := NewMatrix(uint(m), uint(n))
+
+	failed := make([]bool, n)
+	column := func(j int) {
+		point := make([]float64, n)
+		copy(point, x)
+		h := step * math.Max(1, math.Abs(x[j]))
+		//Use the step actually represented in floating point
+		point[j] = x[j] + h
+		h = point[j] - x[j]
+		forward := f(point)
+		point[j] = x[j] - h
+		backward := f(point)
+		if len(forward) != m || len(backward) != m {
+			failed[j] = true
+			return
+		}
+		for i := 0; i < m; i++ {
+			jacobian.M[i*n+j] = (forward[i] - backward[i]) / (2 * h)
+		}
+	}
+
+	if workers > 1 {
+		parallelPanels(n, workers, column)
+	} else {
+		for j := 0; j < n; j++ {
+			column(j)
+		}
+	}
+
+	for _, fail := range failed {
+		if fail {
+			return nil, &MathError{
+				code: errorDimensionMismatch,
+			}
+		}
+	}
+	return jacobian, nil
+}
+
+/*
+hessianWithStep computes the Hessian of f at x with central differences, the step of
+variable i being step*max(1, |x[i]|). Only the upper triangle is computed, the lower
+one is a copy so that the result is exactly symmetric.
+*/
+func hessianWithStep(x []float64, f Fn, step float64) *Matrix {
+	n := len(x)
+	hessian := NewMatrix(uint(n), uint(n))
+	point := make([]float64, n)
+	copy(point, x)
+	h := make([]float64, n)
+	for i := range x {
+		point[i] = x[i] + step*math.Max(1, math.Abs(x[i]))
+		h[i] = point[i] - x[i]
+		point[i] = x[i]
+	}
+	//at evaluates f at x + si*h[i]*e_i + sj*h[j]*e_j
+	at := func(i int, si float64, j int, sj float64) float64 {
+		point[i] += si * h[i]
+		point[j] += sj * h[j]
+		v := f(point)
+		point[i] = x[i]
+		point[j] = x[j]
+		return v
+	}
+
+	center := f(x)
+	for i := 0; i < n; i++ {
+		point[i] = x[i] + h[i]
+		forward := f(point)
+		point[i] = x[i] - h[i]
+		backward := f(point)
+		point[i] = x[i]
+		hessian.M[i*n+i] = (forward - 2*center + backward) / (h[i] * h[i])
+
+		for j := i + 1; j < n; j++ {
+			v := (at(i, 1, j, 1) - at(i, 1, j, -1) - at(i, -1, j, 1) + at(i, -1, j, -1)) / (4 * h[i] * h[j])
+			hessian.M[i*n+j] = v
+			hessian.M[j*n+i] = v
+		}
+	}
+	return hessian
+}
+
+/*
+Hessian computes the symmetric matrix of the second partial derivatives of f at x
+with central differences. The error of central differences is in h^2, so the matrix
+is computed a second time with a doubled step and the difference between both, divided
+by 3, gives an estimate of the error (Richardson).
+
+First parameter x is the point where the Hessian is computed
+Second parameter f is the function
+Third parameter step is the relative step, the fourth root of the machine epsilon
+(optimal for second differences) is used when it is 0
+First return value is the Hessian
+Second return value is the estimated absolute error, the biggest of all the entries
+*/
+func Hessian(x []float64, f Fn, step float64) (*Matrix, float64) {
+	if step <= 0 {
+		step = math.Sqrt(math.Sqrt(machineEpsilon))
+	}
+	hessian := hessianWithStep(x, f, step)
+	coarse := hessianWithStep(x, f, 2*step)
+
+	var err float64
+	for i, v := range hessian.M {
+		err = math.Max(err, math.Abs(v-coarse.M[i])/3)
+	}
+	return hessian, err
+}
+
+/*
+ForwardDiff computes the derivative of f at t using only values of f at t and after
+t, for functions that are not defined before t (at the lower boundary of their
+domain for instance). The first order scheme is (f(t+h) - f(t))/h, the second order
+one (-3f(t) + 4f(t+h) - f(t+2h))/(2h), their errors being in h and h^2.
+
+First parameter t is the value to use for the computation
+Second parameter f is the function for which we want a derivative
+Third parameter is the order of accuracy, 1 or 2
+Fourth parameter err is used as for Standard, the step being sqrt(err), if err is 0
+the step is chosen by oneSidedStep instead
+*/
+func ForwardDiff(t float64, f F, accuracy int, err float64) float64 {
+	h := math.Sqrt(err)
+	if err <= 0 {
+		h = oneSidedStep(t, accuracy)
+	}
+	return oneSidedDifference(t, f, accuracy, h)
+}
+
+/*
+BackwardDiff is the same as ForwardDiff using only values of f at t and before t,
+for functions that are not defined after t.
+*/
+func BackwardDiff(t float64, f F, accuracy int, err float64) float64 {
+	h := math.Sqrt(err)
+	if err <= 0 {
+		h = oneSidedStep(t, accuracy)
+	}
+	return oneSidedDifference(t, f, accuracy, -h)
+}
+
+/*
+oneSidedStep is the step of a one sided difference at t when none is given. Unlike
+StepSize it doesn't evaluate f, which may not be defined on the other side of t: the
+step balancing the truncation and the round-off errors is sqrt(eps)*max(|t|, 1) for
+the first order and cbrt(eps)*max(|t|, 1) for the second order, made exactly
+representable as (t+h)-t.
+*/
+func oneSidedStep(t float64, accuracy int) float64 {
+	h := math.Sqrt(machineEpsilon) * math.Max(math.Abs(t), 1)
+	if accuracy >= 2 {
+		h = math.Cbrt(machineEpsilon) * math.Max(math.Abs(t), 1)
+	}
+	temp := t + h
+	return temp - t
+}
+
+/*
+oneSidedDifference computes the one sided difference of the given order of accuracy,
+h being negative for the backward differences
+*/
+func oneSidedDifference(t float64, f F, accuracy int, h float64) float64 {
+	if accuracy >= 2 {
+		return (-3*f(t) + 4*f(t+h) - f(t+2*h)) / (2 * h)
+	}
+	return (f(t+h) - f(t)) / h
+}
+
+/*
+DerivativeFunc returns the derivative of f as a function, each call computing f'(x)
+with Ridders, so that it can be given to anything expecting an F (Newton, the
+integration methods, ...).
+
+First parameter f is the function to derive
+Second parameter err is the error required, as for Ridders
+*/
+func DerivativeFunc(f F, err float64) F {
+	return func(x float64) float64 {
+		return Ridders(x, f, err)
+	}
+}
+
+/*
+MemoizedDerivativeFunc is the same as DerivativeFunc but keeps the derivatives
+already computed, which is useful when the same points are used several times (a
+solver iterating, a table of values). The returned function is safe to call from
+several goroutines, the memory used grows with the number of different points.
+
+First parameter f is the function to derive
+Second parameter err is the error required, as for Ridders
+*/
+func MemoizedDerivativeFunc(f F, err float64) F {
+	var lock sync.Mutex
+	cache := make(map[float64]float64)
+	return func(x float64) float64 {
+		lock.Lock()
+		d, ok := cache[x]
+		lock.Unlock()
+		if ok {
+			return d
+		}
+		d = Ridders(x, f, err)
+		lock.Lock()
+		cache[x] = d
+		lock.Unlock()
+		return d
+	}
+}
+
+/*
+component returns the i-th component of a vector field as a function of several
+variables
+*/
+func component(field VectorFn, i int) Fn {
+	return func(x []float64) float64 {
+		return field(x)[i]
+	}
+}
+
+/*
+Divergence computes the divergence of a vector field at x, the sum of the partial
+derivatives of its i-th component with respect to the i-th variable.
+
+First parameter x is the point where the divergence is computed
+Second parameter field is the vector field, it must have as many components as variables
+Third parameter err is the error required, as for Ridders
+It returns an error if the dimensions of x and of the field do not match
+*/
+func Divergence(x []float64, field VectorFn, err float64) (float64, error) {
+	if len(field(x)) != len(x) {
+		return 0, &MathError{
+			code: errorDimensionMismatch,
+		}
+	}
+	var divergence float64
+	for i := range x {
+		divergence += Partial(x, component(field, i), i, err)
+	}
+	return divergence, nil
+}
+
+/*
+Curl computes the curl of a 3-D vector field at x:
+(dFz/dy - dFy/dz, dFx/dz - dFz/dx, dFy/dx - dFx/dy)
+
+First parameter x is the point where the curl is computed
+Second parameter field is the vector field
+Third parameter err is the error required, as for Ridders
+It returns an error if x or the field are not 3-D
+*/
+func Curl(x []float64, field VectorFn, err float64) ([]float64, error) {
+	if len(x) != 3 || len(field(x)) != 3 {
+		return nil, &MathError{
+			code: errorDimensionMismatch,
+		}
+	}
+	fx := component(field, 0)
+	fy := component(field, 1)
+	fz := component(field, 2)
+	return []float64{
+		Partial(x, fz, 1, err) - Partial(x, fy, 2, err),
+		Partial(x, fx, 2, err) - Partial(x, fz, 0, err),
+		Partial(x, fy, 0, err) - Partial(x, fx, 1, err),
+	}, nil
+}
+
+/*
+Laplacian computes the laplacian of f at x, the sum of its second partial
+derivatives with respect to each variable, with DerivativeN.
+
+First parameter x is the point where the laplacian is computed
+Second parameter f is the function
+Third parameter err is the error required, as for DerivativeN
+*/
+func Laplacian(x []float64, f Fn, err float64) float64 {
+	point := make([]float64, len(x))
+	copy(point, x)
+	var laplacian float64
+	for i := range x {
+		g := func(t float64) float64 {
+			point[i] = t
+			return f(point)
+		}
+		laplacian += Derivative2(x[i], g, err)
+		point[i] = x[i]
+	}
+	return laplacian
+}
+
+/*
+DirectionalMethod is the way DirectionalDerivative is computed
+*/
+type DirectionalMethod int
+
+const (
+	//DirectionalGradient computes the gradient and its dot product with the direction,
+	//it costs one derivative per variable but the gradient can be reused
+	DirectionalGradient DirectionalMethod = iota
+	//DirectionalDifference differentiates f(x + t*direction) at t = 0, a single
+	//derivative whatever the number of variables
+	DirectionalDifference
+)
+
+/*
+DirectionalDerivative computes the derivative of f at x along the direction. The
+direction is used as given, normalize it to get the slope per unit of length.
+
+First parameter x is the point where the derivative is computed
+Second parameter direction is the direction
+Third parameter f is the function
+Fourth parameter method is DirectionalGradient or DirectionalDifference
+Fifth parameter err is the error required, as for Ridders
+It returns an error if the dimensions of x and of the direction do not match
+*/
+func DirectionalDerivative(x []float64, direction []float64, f Fn, method DirectionalMethod, err float64) (float64, error) {
+	if len(x) != len(direction) {
+		return 0, &MathError{
+			code: errorDimensionMismatch,
+		}
+	}
+	if method == DirectionalGradient {
+		return Dot(Gradient(x, f, err), direction)
+	}
+
+	point := make([]float64, len(x))
+	g := func(t float64) float64 {
+		for i := range x {
+			point[i] = x[i] + t*direction[i]
+		}
+		return f(point)
+	}
+	return Ridders(0, g, err), nil
+}
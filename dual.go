@@ -0,0 +1,178 @@
+package advmath
+
+import (
+	"math"
+)
+
+/*
+Dual is a dual number Value + Derivative*e with e*e = 0. Computing f on the dual
+number x + 1*e gives f(x) + f'(x)*e, so writing a function with the methods of Dual
+instead of float64 gives its exact derivative (forward mode automatic
+differentiation), without the step and round-off issues of finite differences.
+*/
+type Dual struct {
+	Value      float64
+	Derivative float64
+}
+
+/*
+NewDualVariable is a method to create the dual number of the variable with respect to
+which the derivative is computed, its derivative is 1.
+First parameter is the value of the variable
+*/
+func NewDualVariable(x float64) Dual {
+	return Dual{Value: x, Derivative: 1}
+}
+
+/*
+NewDualConstant is a method to create the dual number of a constant, its derivative
+is 0.
+First parameter is the value of the constant
+*/
+func NewDualConstant(c float64) Dual {
+	return Dual{Value: c}
+}
+
+/*
+DualDerivative computes f(x) and f'(x) for a function written with dual numbers.
+First parameter x is the value to use for the computation
+Second parameter f is the function
+*/
+func DualDerivative(x float64, f func(Dual) Dual) (float64, float64) {
+	d := f(NewDualVariable(x))
+	return d.Value, d.Derivative
+}
+
+/*
+Add is a method to compute d + e
+*/
+func (d Dual) Add(e Dual) Dual {
+	return Dual{Value: d.Value + e.Value, Derivative: d.Derivative + e.Derivative}
+}
+
+/*
+Subtract is a method to compute d - e
+*/
+func (d Dual) Subtract(e Dual) Dual {
+	return Dual{Value: d.Value - e.Value, Derivative: d.Derivative - e.Derivative}
+}
+
+/*
+Neg is a method to compute -d
+*/
+func (d Dual) Neg() Dual {
+	return Dual{Value: -d.Value, Derivative: -d.Derivative}
+}
+
+/*
+Scale is a method to compute k*d for a real k
+*/
+func (d Dual) Scale(k float64) Dual {
+	return Dual{Value: k * d.Value, Derivative: k * d.Derivative}
+}
+
+/*
+Multiply is a method to compute d*e
+*/
+func (d Dual) Multiply(e Dual) Dual {
+	return Dual{Value: d.Value * e.Value, Derivative: d.Derivative*e.Value + d.Value*e.Derivative}
+}
+
+/*
+Divide is a method to compute d/e, the values are infinite if e.Value is 0
+*/
+func (d Dual) Divide(e Dual) Dual {
+	return Dual{
+		Value:      d.Value / e.Value,
+		Derivative: (d.Derivative*e.Value - d.Value*e.Derivative) / (e.Value * e.Value),
+	}
+}
+
+/*
+chain applies a function whose value is v and derivative is dv at d.Value
+*/
+func (d Dual) chain(v float64, dv float64) Dual {
+	return Dual{Value: v, Derivative: dv * d.Derivative}
+}
+
+/*
+Sin is a method to compute sin(d)
+*/
+func (d Dual) Sin() Dual {
+	s, c := math.Sincos(d.Value)
+	return d.chain(s, c)
+}
+
+/*
+Cos is a method to compute cos(d)
+*/
+func (d Dual) Cos() Dual {
+	s, c := math.Sincos(d.Value)
+	return d.chain(c, -s)
+}
+
+/*
+Tan is a method to compute tan(d)
+*/
+func (d Dual) Tan() Dual {
+	t := math.Tan(d.Value)
+	return d.chain(t, 1+t*t)
+}
+
+/*
+Atan is a method to compute atan(d)
+*/
+func (d Dual) Atan() Dual {
+	return d.chain(math.Atan(d.Value), 1/(1+d.Value*d.Value))
+}
+
+/*
+Exp is a method to compute exp(d)
+*/
+func (d Dual) Exp() Dual {
+	e := math.Exp(d.Value)
+	return d.chain(e, e)
+}
+
+/*
+Log is a method to compute the natural logarithm of d
+*/
+func (d Dual) Log() Dual {
+	return d.chain(math.Log(d.Value), 1/d.Value)
+}
+
+/*
+Sqrt is a method to compute the square root of d
+*/
+func (d Dual) Sqrt() Dual {
+	s := math.Sqrt(d.Value)
+	return d.chain(s, 0.5/s)
+}
+
+/*
+Abs is a method to compute |d|, the derivative at 0 is taken as 0
+*/
+func (d Dual) Abs() Dual {
+	switch {
+	case d.Value > 0:
+		return d
+	case d.Value < 0:
+		return d.Neg()
+	}
+	return Dual{}
+}
+
+/*
+Pow is a method to compute d^e. When the exponent is a constant, d can be negative
+(with an integer exponent) as with math.Pow, otherwise d has to be positive.
+*/
+func (d Dual) Pow(e Dual) Dual {
+	v := math.Pow(d.Value, e.Value)
+	if e.Derivative == 0 {
+		return d.chain(v, e.Value*math.Pow(d.Value, e.Value-1))
+	}
+	return Dual{
+		Value:      v,
+		Derivative: v * (e.Derivative*math.Log(d.Value) + e.Value*d.Derivative/d.Value),
+	}
+}
@@ -0,0 +1,113 @@
+package advmath
+
+import (
+	"math"
+	"sort"
+)
+
+/*
+SymmetricEigen is a method to compute the eigenvalues and eigenvectors of a symmetric
+matrix with the cyclic Jacobi method: each off-diagonal element is zeroed in turn by
+a plane rotation, until the matrix is diagonal up to round-off. It is slower than the
+QR algorithm for large matrices but very accurate, even for the small eigenvalues,
+and the eigenvectors are orthonormal by construction. Only the symmetric part of the
+matrix is used.
+First return value are the eigenvalues in decreasing order
+Second return value is the orthogonal matrix whose column k is the eigenvector of the
+eigenvalue k
+Third return value is the error that can occur in the process (if non square matrix
+or if the method didn't converge)
+*/
+func (m Matrix) SymmetricEigen() ([]float64, *Matrix, error) {
+	if !m.IsSquare() {
+		return nil, nil, &MathError{
+			code: errorNonSquareMatrix,
+		}
+	}
+	const maxSweeps = 50
+	n := int(m.NumberOfRows)
+	a := make([]float64, len(m.M))
+	for i := 0; i < n; i++ {
+		for j := 0; j < n; j++ {
+			a[i*n+j] = (m.M[i*n+j] + m.M[j*n+i]) / 2
+		}
+	}
+	v := NewIdentity(uint(n))
+
+	converged := false
+	for sweep := 0; sweep < maxSweeps && !converged; sweep++ {
+		var off, total float64
+		for i := 0; i < n; i++ {
+			for j := 0; j < n; j++ {
+				total += a[i*n+j] * a[i*n+j]
+				if i != j {
+					off += a[i*n+j] * a[i*n+j]
+				}
+			}
+		}
+		if off == 0 || off <= machineEpsilon*machineEpsilon*total {
+			converged = true
+			break
+		}
+		for p := 0; p < n-1; p++ {
+			for q := p + 1; q < n; q++ {
+				apq := a[p*n+q]
+				if apq == 0 {
+					continue
+				}
+				//After a few sweeps an element negligible next to both diagonal elements
+				//is set to zero instead of being rotated (as in Numerical Recipes)
+				g := 100 * math.Abs(apq)
+				if sweep > 3 && math.Abs(a[p*n+p])+g == math.Abs(a[p*n+p]) && math.Abs(a[q*n+q])+g == math.Abs(a[q*n+q]) {
+					a[p*n+q], a[q*n+p] = 0, 0
+					continue
+				}
+				//Rotation zeroing a[p][q]: t = tan of the angle, the smallest root
+				theta := (a[q*n+q] - a[p*n+p]) / (2 * apq)
+				t := 1 / (math.Abs(theta) + math.Hypot(theta, 1))
+				if theta < 0 {
+					t = -t
+				}
+				c := 1 / math.Hypot(t, 1)
+				s := t * c
+				for k := 0; k < n; k++ {
+					akp, akq := a[k*n+p], a[k*n+q]
+					a[k*n+p], a[k*n+q] = c*akp-s*akq, s*akp+c*akq
+				}
+				for k := 0; k < n; k++ {
+					apk, aqk := a[p*n+k], a[q*n+k]
+					a[p*n+k], a[q*n+k] = c*apk-s*aqk, s*apk+c*aqk
+				}
+				for k := 0; k < n; k++ {
+					vkp, vkq := v.M[k*n+p], v.M[k*n+q]
+					v.M[k*n+p], v.M[k*n+q] = c*vkp-s*vkq, s*vkp+c*vkq
+				}
+				//The rotation zeroes a[p][q] up to a round-off which would prevent the
+				//convergence, it is set exactly
+				a[p*n+q], a[q*n+p] = 0, 0
+			}
+		}
+	}
+	if !converged {
+		return nil, nil, &MathError{
+			code: errorNotConverged,
+		}
+	}
+
+	order := make([]int, n)
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return a[order[i]*n+order[i]] > a[order[j]*n+order[j]]
+	})
+	values := make([]float64, n)
+	vectors := NewMatrix(uint(n), uint(n))
+	for k, i := range order {
+		values[k] = a[i*n+i]
+		for r := 0; r < n; r++ {
+			vectors.M[r*n+k] = v.M[r*n+i]
+		}
+	}
+	return values, vectors, nil
+}
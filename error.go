@@ -1,5 +1,9 @@
 package advmath
 
+import (
+	"fmt"
+)
+
 const (
 	//When we try to divide by zero
 	errorDivisionByZero = 1
@@ -14,20 +18,69 @@ const (
 	errorCannotAdd = 5
 	//Error when we cannot find an inverse for the matrix
 	errorNotInversible = 6
+	//Error when a decomposition without pivoting meets a zero pivot
+	errorZeroPivot = 7
+	//Error when vectors or matrices given as parameters do not have the
+	//expected size
+	errorDimensionMismatch = 8
+	//Error when a matrix is so close to singular that the result cannot
+	//be trusted
+	errorIllConditioned = 9
+	//Error when an iterative or adaptive method didn't reach the required
+	//precision
+	errorNotConverged = 10
+	//Error when a method stopped because it reached the maximum number of
+	//evaluations it was given
+	errorBudgetExceeded = 11
+	//Error when a method needing a bracket is given an interval where the function
+	//doesn't change sign
+	errorNoSignChange = 12
+	//Error when the iterates of a method move away instead of converging
+	errorDiverged = 13
 )
 
+/*
+machineEpsilon is the difference between 1 and the next float64
+*/
+const machineEpsilon = 2.220446049250313e-16
+
 /*
 MathError is the error type used throughout the library
 */
 type MathError struct {
 	code int
 	s    string
+	//State is where an iterative method was when it failed, nil for the other errors
+	State *IterationState
+}
+
+/*
+IterationState describes the iteration where an iterative method failed, so that the
+caller can see where the method was going
+*/
+type IterationState struct {
+	//Iteration is the number of the iteration, starting at 1
+	Iteration int
+	//X is the estimate of the zero at this iteration
+	X float64
+	//Value is f(X)
+	Value float64
+	//Derivative is the derivative used at X, 0 if the method doesn't use one
+	Derivative float64
 }
 
 /*
-Error returns the description of the error
+Error returns the description of the error, with the iteration state if there is one
 */
 func (e *MathError) Error() string {
+	if e.State != nil {
+		return fmt.Sprintf("%s (iteration %d, x = %g, f(x) = %g, f'(x) = %g)",
+			e.message(), e.State.Iteration, e.State.X, e.State.Value, e.State.Derivative)
+	}
+	return e.message()
+}
+
+func (e *MathError) message() string {
 	if e.code != 0 {
 		switch e.code {
 		case errorDivisionByZero:
@@ -42,6 +95,20 @@ func (e *MathError) Error() string {
 			return "Can only add matrices of same size"
 		case errorNotInversible:
 			return "Matrix is not inversible"
+		case errorZeroPivot:
+			return "Found a zero pivot, the decomposition cannot be done without pivoting"
+		case errorDimensionMismatch:
+			return "Dimensions of the parameters do not match"
+		case errorIllConditioned:
+			return "Matrix is singular to working precision, result is unreliable"
+		case errorNotConverged:
+			return "Method did not converge to the required precision"
+		case errorBudgetExceeded:
+			return "Method reached the maximum number of evaluations"
+		case errorNoSignChange:
+			return "Function doesn't change sign between the boundaries of the interval"
+		case errorDiverged:
+			return "Method diverged"
 		}
 	}
 	return e.s
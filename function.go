@@ -4,3 +4,31 @@ package advmath
 F is a basic real mathematic function
 */
 type F func(float64) float64
+
+/*
+VectorF is a real function with values in R^n, all the calls must return slices of
+the same length
+*/
+type VectorF func(float64) []float64
+
+/*
+ComplexF is a real function with complex values
+*/
+type ComplexF func(float64) complex128
+
+/*
+Fn is a real function of several variables
+*/
+type Fn func([]float64) float64
+
+/*
+VectorFn is a function of several variables with values in R^m, all the calls must
+return slices of the same length
+*/
+type VectorFn func([]float64) []float64
+
+/*
+ODE is the right hand side f of a system of ordinary differential equations
+y'(t) = f(t, y), the result must have the length of y
+*/
+type ODE func(t float64, y []float64) []float64
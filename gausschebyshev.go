@@ -0,0 +1,102 @@
+package advmath
+
+import (
+	"math"
+)
+
+/*
+QuadratureRule is a set of nodes and weights on [-1, 1] such that the sum of the
+weights times the function at the nodes approximates a (weighted) integral
+*/
+type QuadratureRule struct {
+	Nodes   []float64
+	Weights []float64
+}
+
+/*
+Integrate applies the rule to f
+*/
+func (r QuadratureRule) Integrate(f F) float64 {
+	var sum float64
+	for i, x := range r.Nodes {
+		sum += r.Weights[i] * f(x)
+	}
+	return sum
+}
+
+/*
+NewGaussChebyshev returns the n points Gauss-Chebyshev rule of the first kind, for
+the integral of f(x)/sqrt(1-x^2) on [-1, 1]. The nodes are the zeros of the Chebyshev
+polynomial T_n, cos((2k-1)pi/2n), and all the weights are pi/n. It is exact when f
+is a polynomial of degree up to 2n-1.
+First parameter is the number of points
+*/
+func NewGaussChebyshev(n int) QuadratureRule {
+	r := QuadratureRule{
+		Nodes:   make([]float64, n),
+		Weights: make([]float64, n),
+	}
+	for k := 0; k < n; k++ {
+		r.Nodes[k] = math.Cos(float64(2*k+1) * math.Pi / float64(2*n))
+		r.Weights[k] = math.Pi / float64(n)
+	}
+	return r
+}
+
+/*
+NewGaussChebyshevSecondKind returns the n points Gauss-Chebyshev rule of the second
+kind, for the integral of f(x)*sqrt(1-x^2) on [-1, 1]. The nodes are the zeros of
+the Chebyshev polynomial U_n, cos(k*pi/(n+1)), and the weights are
+pi/(n+1)*sin^2(k*pi/(n+1)). It is exact when f is a polynomial of degree up to 2n-1.
+First parameter is the number of points
+*/
+func NewGaussChebyshevSecondKind(n int) QuadratureRule {
+	r := QuadratureRule{
+		Nodes:   make([]float64, n),
+		Weights: make([]float64, n),
+	}
+	for k := 1; k <= n; k++ {
+		theta := float64(k) * math.Pi / float64(n+1)
+		s := math.Sin(theta)
+		r.Nodes[k-1] = math.Cos(theta)
+		r.Weights[k-1] = math.Pi / float64(n+1) * s * s
+	}
+	return r
+}
+
+/*
+GaussChebyshev computes the integral of f(x)/sqrt((x-inf)(sup-x)) between inf and sup
+with the n points Gauss-Chebyshev rule of the first kind. The singularities of the
+weight at the boundaries are handled exactly, only f has to be smooth. On [-1, 1]
+it is the integral of f(x)/sqrt(1-x^2), the weight of the Chebyshev expansions.
+
+First parameter inf is the lower boundary
+Second parameter sup is the upper boundary
+Third parameter f is the function to integrate, without the weight
+Fourth parameter is the number of points
+*/
+func GaussChebyshev(inf float64, sup float64, f F, n int) float64 {
+	center := (inf + sup) / 2
+	half := (sup - inf) / 2
+	//The change of variable x = center + half*t cancels with the weight
+	return NewGaussChebyshev(n).Integrate(func(t float64) float64 {
+		return f(center + half*t)
+	})
+}
+
+/*
+GaussChebyshevSecondKind computes the integral of f(x)*sqrt((x-inf)(sup-x)) between
+inf and sup with the n points Gauss-Chebyshev rule of the second kind.
+
+First parameter inf is the lower boundary
+Second parameter sup is the upper boundary
+Third parameter f is the function to integrate, without the weight
+Fourth parameter is the number of points
+*/
+func GaussChebyshevSecondKind(inf float64, sup float64, f F, n int) float64 {
+	center := (inf + sup) / 2
+	half := (sup - inf) / 2
+	return half * half * NewGaussChebyshevSecondKind(n).Integrate(func(t float64) float64 {
+		return f(center + half*t)
+	})
+}
@@ -0,0 +1,405 @@
+package advmath
+
+import (
+	"container/heap"
+	"context"
+	"math"
+)
+
+var (
+	//Abscissae of the 15 points Kronrod rule, the odd ones are the 7 points Gauss rule
+	xgk = [8]float64{
+		0.991455371120812639206854697526329,
+		0.949107912342758524526189684047851,
+		0.864864423359769072789712788640926,
+		0.741531185599394439863864773280788,
+		0.586087235467691130294144845693013,
+		0.405845151377397166906606412076961,
+		0.207784955007898467600689403773245,
+		0.000000000000000000000000000000000,
+	}
+
+	//Weights of the 15 points Kronrod rule
+	wgk = [8]float64{
+		0.022935322010529224963732008058970,
+		0.063092092629978553290700663189204,
+		0.104790010322250183839876322541518,
+		0.140653259715525918745189590510238,
+		0.169004726639267902826583426598550,
+		0.190350578064785409913256402421014,
+		0.204432940075298892414161999234649,
+		0.209482141084727828012999174891714,
+	}
+
+	//Weights of the 7 points Gauss rule
+	wg = [4]float64{
+		0.129484966168869693270611432679082,
+		0.279705391489276667901467771423780,
+		0.381830050505118944950369775488975,
+		0.417959183673469387755102040816327,
+	}
+)
+
+/*
+GaussKronrod computes the integral of a function between inf and sup with the
+15 points Gauss-Kronrod rule. The 7 points Gauss rule is computed with the same
+evaluations and the difference between both gives an estimate of the error
+(using the QUADPACK heuristic). It is exact for polynomials up to degree 22.
+
+First parameter inf is the lower boundary
+Second parameter sup is the upper boundary
+Third parameter f is the function to integrate
+First return value is the integral
+Second return value is the estimated absolute error
+*/
+func GaussKronrod(inf float64, sup float64, f F) (float64, float64) {
+	center := (inf + sup) / 2
+	half := (sup - inf) / 2
+	absHalf := math.Abs(half)
+
+	fc := f(center)
+	resultGauss := fc * wg[3]
+	resultKronrod := fc * wgk[7]
+	resultAbs := math.Abs(resultKronrod)
+
+	var fv1, fv2 [7]float64
+	for j := 0; j < 7; j++ {
+		dx := half * xgk[j]
+		fv1[j] = f(center - dx)
+		fv2[j] = f(center + dx)
+		sum := fv1[j] + fv2[j]
+		resultKronrod += wgk[j] * sum
+		resultAbs += wgk[j] * (math.Abs(fv1[j]) + math.Abs(fv2[j]))
+		if j%2 == 1 {
+			resultGauss += wg[j/2] * sum
+		}
+	}
+
+	mean := resultKronrod / 2
+	resultAsc := wgk[7] * math.Abs(fc-mean)
+	for j := 0; j < 7; j++ {
+		resultAsc += wgk[j] * (math.Abs(fv1[j]-mean) + math.Abs(fv2[j]-mean))
+	}
+
+	result := resultKronrod * half
+	resultAbs *= absHalf
+	resultAsc *= absHalf
+	err := math.Abs((resultKronrod - resultGauss) * half)
+	if resultAsc != 0 && err != 0 {
+		err = resultAsc * math.Min(1, math.Pow(200*err/resultAsc, 1.5))
+	}
+	if resultAbs > math.SmallestNonzeroFloat64/(50*machineEpsilon) {
+		err = math.Max(50*machineEpsilon*resultAbs, err)
+	}
+	return result, err
+}
+
+/*
+AdaptiveGaussKronrod computes the integral of a function between inf and sup with a
+globally adaptive strategy (like QUADPACK's QAG): the interval with the biggest error
+estimate is always the one split in two, and each piece is integrated with GaussKronrod,
+until the sum of the error estimates is below the tolerance.
+
+First parameter inf is the lower boundary
+Second parameter sup is the upper boundary
+Third parameter f is the function to integrate
+Fourth parameter tol is the absolute tolerance required
+Fifth parameter is the maximum number of intervals, it is optional and set to 1000 by default
+First return value is the integral
+Second return value is the estimated absolute error
+Third return value is an error if the tolerance could not be reached
+*/
+func AdaptiveGaussKronrod(inf float64, sup float64, f F, tol float64, maxIntervals int) (float64, float64, error) {
+	return adaptiveGaussKronrod(inf, sup, f, tol, maxIntervals, nil)
+}
+
+/*
+AdaptiveGaussKronrodContext is the same as AdaptiveGaussKronrod but stops when ctx
+is done or when the next subdivision would exceed maxEvaluations (0 means the default
+of a million), so that a pathological function cannot hang the caller. The value and the error
+estimate returned are then the ones reached so far and the error is ctx.Err() or
+the budget error.
+
+First parameter ctx is the context of the caller
+Second parameter inf is the lower boundary
+Third parameter sup is the upper boundary
+Fourth parameter f is the function to integrate
+Fifth parameter tol is the absolute tolerance required
+Sixth parameter is the maximum number of evaluations of f
+*/
+func AdaptiveGaussKronrodContext(ctx context.Context, inf float64, sup float64, f F, tol float64, maxEvaluations int) (float64, float64, error) {
+	//The number of intervals is only limited by the budget
+	return adaptiveGaussKronrod(inf, sup, f, tol, math.MaxInt32, newIntegrationGuard(ctx, maxEvaluations))
+}
+
+func adaptiveGaussKronrod(inf float64, sup float64, f F, tol float64, maxIntervals int, guard *integrationGuard) (float64, float64, error) {
+	if maxIntervals <= 0 {
+		maxIntervals = 1000
+	}
+	//Each call of GaussKronrod evaluates f 15 times
+	if guard.stop(15) {
+		return 0, math.Inf(1), guard.err
+	}
+	f = guard.count(f)
+
+	value, err := GaussKronrod(inf, sup, f)
+	intervals := &gkHeap{{inf, sup, value, err}}
+
+	for err > tol && intervals.Len() < maxIntervals && !guard.stop(30) {
+		worst := heap.Pop(intervals).(gkInterval)
+		m := (worst.a + worst.b) / 2
+		if m == worst.a || m == worst.b {
+			//Cannot split anymore
+			heap.Push(intervals, worst)
+			break
+		}
+		lv, le := GaussKronrod(worst.a, m, f)
+		rv, re := GaussKronrod(m, worst.b, f)
+		heap.Push(intervals, gkInterval{worst.a, m, lv, le})
+		heap.Push(intervals, gkInterval{m, worst.b, rv, re})
+
+		//Sum again instead of updating to avoid accumulating round-off
+		value, err = intervals.sum()
+	}
+
+	if guard != nil && guard.err != nil {
+		return value, err, guard.err
+	}
+	if err > tol {
+		return value, err, &MathError{
+			code: errorNotConverged,
+		}
+	}
+	return value, err, nil
+}
+
+type gkInterval struct {
+	a, b       float64
+	value, err float64
+}
+
+/*
+gkHeap is a max heap of intervals ordered by their error estimate
+*/
+type gkHeap []gkInterval
+
+func (h gkHeap) Len() int            { return len(h) }
+func (h gkHeap) Less(i, j int) bool  { return h[i].err > h[j].err }
+func (h gkHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *gkHeap) Push(x interface{}) { *h = append(*h, x.(gkInterval)) }
+func (h *gkHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	x := old[n-1]
+	*h = old[:n-1]
+	return x
+}
+
+func (h gkHeap) sum() (float64, float64) {
+	var value, err float64
+	for _, i := range h {
+		value += i.value
+		err += i.err
+	}
+	return value, err
+}
+
+/*
+ImproperIntegral computes the integral of a function over an interval where one or
+both boundaries are infinite (math.Inf(-1) or math.Inf(1)). The interval is mapped to
+a finite one with a change of variable and the result is integrated with
+AdaptiveGaussKronrod, whose nodes never touch the boundaries where the transformed
+function is not defined:
+
+	(a, +inf):    x = a + t/(1-t), t in [0, 1)
+	(-inf, b):    x = b - (1-t)/t, t in (0, 1]
+	(-inf, +inf): x = t/(1-t^2),   t in (-1, 1)
+
+The function must of course decrease fast enough for the integral to exist.
+If both boundaries are finite, it is the same as AdaptiveGaussKronrod.
+
+First parameter inf is the lower boundary
+Second parameter sup is the upper boundary
+Third parameter f is the function to integrate
+Fourth parameter tol is the absolute tolerance required
+First return value is the integral
+Second return value is the estimated absolute error
+Third return value is an error if the tolerance could not be reached
+*/
+func ImproperIntegral(inf float64, sup float64, f F, tol float64) (float64, float64, error) {
+	return improperIntegral(inf, sup, f, tol, nil)
+}
+
+/*
+ImproperIntegralContext is the same as ImproperIntegral but stops when ctx is done
+or when maxEvaluations would be exceeded (0 means the default of a million), as
+AdaptiveGaussKronrodContext does.
+*/
+func ImproperIntegralContext(ctx context.Context, inf float64, sup float64, f F, tol float64, maxEvaluations int) (float64, float64, error) {
+	return improperIntegral(inf, sup, f, tol, newIntegrationGuard(ctx, maxEvaluations))
+}
+
+func improperIntegral(inf float64, sup float64, f F, tol float64, guard *integrationGuard) (float64, float64, error) {
+	if inf > sup {
+		value, err, e := improperIntegral(sup, inf, f, tol, guard)
+		return -value, err, e
+	}
+	maxIntervals := 0
+	if guard != nil {
+		maxIntervals = math.MaxInt32
+	}
+
+	switch {
+	case math.IsInf(inf, -1) && math.IsInf(sup, 1):
+		g := func(t float64) float64 {
+			d := 1 - t*t
+			return f(t/d) * (1 + t*t) / (d * d)
+		}
+		return adaptiveGaussKronrod(-1, 1, g, tol, maxIntervals, guard)
+	case math.IsInf(sup, 1):
+		g := func(t float64) float64 {
+			d := 1 - t
+			return f(inf+t/d) / (d * d)
+		}
+		return adaptiveGaussKronrod(0, 1, g, tol, maxIntervals, guard)
+	case math.IsInf(inf, -1):
+		g := func(t float64) float64 {
+			return f(sup-(1-t)/t) / (t * t)
+		}
+		return adaptiveGaussKronrod(0, 1, g, tol, maxIntervals, guard)
+	}
+	return adaptiveGaussKronrod(inf, sup, f, tol, maxIntervals, guard)
+}
+
+/*
+Double computes the double integral of f(x, y) over the rectangle [ax, bx]x[ay, by]
+by nesting AdaptiveGaussKronrod: the inner integral over y is computed for every
+x needed by the outer integral.
+
+First parameter f is the function to integrate
+Second and third parameters are the boundaries for x
+Fourth and fifth parameters are the boundaries for y
+Sixth parameter tol is the absolute tolerance required
+The method returns the integral, and an error if the tolerance could not be met
+*/
+func Double(f func(x, y float64) float64, ax, bx, ay, by, tol float64) (float64, error) {
+	lower := func(float64) float64 { return ay }
+	upper := func(float64) float64 { return by }
+	return DoubleRegion(f, ax, bx, lower, upper, tol)
+}
+
+/*
+DoubleRegion computes the double integral of f(x, y) over the region
+ax <= x <= bx, lower(x) <= y <= upper(x), for instance a disk or a triangle, by
+nesting AdaptiveGaussKronrod.
+
+First parameter f is the function to integrate
+Second and third parameters are the boundaries for x
+Fourth parameter is the lower boundary for y as a function of x
+Fifth parameter is the upper boundary for y as a function of x
+Sixth parameter tol is the absolute tolerance required
+The method returns the integral, and an error if the tolerance could not be met
+*/
+func DoubleRegion(f func(x, y float64) float64, ax, bx float64, lower, upper F, tol float64) (float64, error) {
+	//Half of the error budget for the inner integrals, spread over the x range
+	innerTol := tol / 2 / math.Max(math.Abs(bx-ax), 1)
+	var innerErr error
+
+	inner := func(x float64) float64 {
+		fy := func(y float64) float64 {
+			return f(x, y)
+		}
+		v, _, err := AdaptiveGaussKronrod(lower(x), upper(x), fy, innerTol, 0)
+		if err != nil && innerErr == nil {
+			innerErr = err
+		}
+		return v
+	}
+
+	value, _, err := AdaptiveGaussKronrod(ax, bx, inner, tol/2, 0)
+	if err != nil {
+		return value, err
+	}
+	return value, innerErr
+}
+
+/*
+AdaptiveGaussKronrodWithResult is the same as AdaptiveGaussKronrod but returns an
+IntegrationResult.
+*/
+func AdaptiveGaussKronrodWithResult(inf float64, sup float64, f F, tol float64, maxIntervals int) IntegrationResult {
+	var evaluations int
+	value, estimate, err := AdaptiveGaussKronrod(inf, sup, countEvaluations(f, &evaluations), tol, maxIntervals)
+	return IntegrationResult{
+		Value:       value,
+		Error:       estimate,
+		Evaluations: evaluations,
+		Converged:   err == nil,
+	}
+}
+
+/*
+ImproperIntegralWithResult is the same as ImproperIntegral but returns an
+IntegrationResult.
+*/
+func ImproperIntegralWithResult(inf float64, sup float64, f F, tol float64) IntegrationResult {
+	var evaluations int
+	value, estimate, err := ImproperIntegral(inf, sup, countEvaluations(f, &evaluations), tol)
+	return IntegrationResult{
+		Value:       value,
+		Error:       estimate,
+		Evaluations: evaluations,
+		Converged:   err == nil,
+	}
+}
+
+/*
+PrincipalValue computes the Cauchy principal value of the integral of f between inf and
+sup when f has a simple pole at c (inf < c < sup), for instance f(x) = g(x)/(x-c):
+
+	PV = lim (integral from inf to c-e + integral from c+e to sup) when e -> 0
+
+The interval symmetric around c is folded, f(c+t) + f(c-t) being regular since the
+poles of both sides cancel. A small symmetric exclusion [c-e, c+e] is left out to avoid
+evaluating f too close to the pole and the result is extrapolated to e = 0 (the
+excluded part is proportional to e). The rest of the interval is a regular integral.
+
+First parameter inf is the lower boundary
+Second parameter sup is the upper boundary
+Third parameter f is the function to integrate
+Fourth parameter c is the position of the pole
+Fifth parameter tol is the absolute tolerance required
+*/
+func PrincipalValue(inf float64, sup float64, f F, c float64, tol float64) (float64, error) {
+	if !(inf < c && c < sup) {
+		return 0, &MathError{
+			s: "The pole must be strictly inside the interval",
+		}
+	}
+
+	d := math.Min(c-inf, sup-c)
+	folded := func(t float64) float64 {
+		return f(c+t) + f(c-t)
+	}
+
+	e := d * 1e-3
+	coarse, _, err := AdaptiveGaussKronrod(e, d, folded, tol/4, 0)
+	if err != nil {
+		return coarse, err
+	}
+	fine, _, err := AdaptiveGaussKronrod(e/2, d, folded, tol/4, 0)
+	if err != nil {
+		return fine, err
+	}
+	//Richardson extrapolation of the linear term
+	symmetric := 2*fine - coarse
+
+	var rest float64
+	switch {
+	case c+d < sup:
+		rest, _, err = AdaptiveGaussKronrod(c+d, sup, f, tol/2, 0)
+	case c-d > inf:
+		rest, _, err = AdaptiveGaussKronrod(inf, c-d, f, tol/2, 0)
+	}
+	return symmetric + rest, err
+}
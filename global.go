@@ -0,0 +1,283 @@
+package advmath
+
+import (
+	"math"
+	"math/rand"
+)
+
+/*
+AnnealingOptions holds the settings of SimulatedAnnealing. The zero value uses the
+defaults given for each field.
+*/
+type AnnealingOptions struct {
+	//Schedule gives the temperature at iteration k (starting at 0), the default is
+	//GeometricCooling from a temperature of 1 down to 1e-6 at the end of the budget
+	Schedule func(k int) float64
+	//Step is the standard deviation of the moves relative to the width of the bounds,
+	//or to max(1, |x0[i]|) for a variable without bounds, 0.1 when it is 0
+	Step float64
+	//Source is the random generator, rand.NewSource(1) when it is nil so that the
+	//results are reproducible
+	Source rand.Source
+}
+
+/*
+GeometricCooling returns the schedule t0*factor^k, factor being between 0 and 1 (0.99
+to 0.9999 usually)
+*/
+func GeometricCooling(t0, factor float64) func(k int) float64 {
+	return func(k int) float64 {
+		return t0 * math.Pow(factor, float64(k))
+	}
+}
+
+/*
+LogarithmicCooling returns the schedule t0/log(k+2), which cools very slowly: it
+finds the global minimum with probability 1 in theory, but only after very many
+iterations
+*/
+func LogarithmicCooling(t0 float64) func(k int) float64 {
+	return func(k int) float64 {
+		return t0 / math.Log(float64(k+2))
+	}
+}
+
+/*
+EvolutionOptions holds the settings of DifferentialEvolution. The zero value uses the
+defaults given for each field.
+*/
+type EvolutionOptions struct {
+	//Population is the number of points, 10*n (at least 5) when it is 0
+	Population int
+	//Weight is the differential weight F applied to the difference of two points,
+	//0.8 when it is 0
+	Weight float64
+	//Crossover is the probability CR to take each variable from the mutant, 0.9
+	//when it is 0
+	Crossover float64
+	//Source is the random generator, rand.NewSource(1) when it is nil so that the
+	//results are reproducible
+	Source rand.Source
+}
+
+/*
+globalBudget returns the number of evaluations allowed to a global method with n
+variables
+*/
+func (o OptimizeOptions) globalBudget(n int) int {
+	if o.MaxEvaluations > 0 {
+		return o.MaxEvaluations
+	}
+	return 10000 * n
+}
+
+/*
+SimulatedAnnealing is a method to look for the global minimum of f with simulated
+annealing: a random move from the current point is always accepted when it decreases
+f, and with the probability exp(-increase/T) otherwise, so that the search can escape
+from local minima while the temperature T is high. It uses all the evaluations of the
+budget, the best point visited being returned; polish it with BFGS or NelderMead if
+a precise minimum is needed.
+
+First parameter f is the function to minimize
+Second parameter x0 is the starting point
+Third parameter annealing are the cooling schedule, the moves and the random generator
+Fourth parameter options are the budget (MaxEvaluations, 10000*n by default, shared
+by the rounds of the method of multipliers when there are equality constraints), the
+callback and the constraints, the other stopping criteria are not used
+It returns the best point found, Converged being true once the budget is spent, and
+an error if the budget is spent before the equality constraints are met
+*/
+func SimulatedAnnealing(f Fn, x0 []float64, annealing AnnealingOptions, options OptimizeOptions) (OptimizeResult, error) {
+	return minimizeConstrained(f, x0, options, options.globalBudget(len(x0)), func(f Fn, x0 []float64, o OptimizeOptions) (OptimizeResult, error) {
+		return simulatedAnnealing(f, x0, annealing, o)
+	})
+}
+
+func simulatedAnnealing(f Fn, x0 []float64, annealing AnnealingOptions, o OptimizeOptions) (OptimizeResult, error) {
+	n := len(x0)
+	budget := o.globalBudget(n)
+	schedule := annealing.Schedule
+	if schedule == nil {
+		schedule = GeometricCooling(1, math.Pow(1e-6, 1/float64(budget)))
+	}
+	step := annealing.Step
+	if step <= 0 {
+		step = 0.1
+	}
+	random := randomGenerator(annealing.Source)
+	scales := make([]float64, n)
+	for i := range scales {
+		if o.Lower != nil && o.Upper != nil && !math.IsInf(o.Upper[i]-o.Lower[i], 0) {
+			scales[i] = step * (o.Upper[i] - o.Lower[i])
+		} else {
+			scales[i] = step * math.Max(1, math.Abs(x0[i]))
+		}
+	}
+
+	obj := newObjective(f, o)
+	x := append([]float64(nil), x0...)
+	value := obj.value(x)
+	best := append([]float64(nil), x...)
+	bestValue := value
+	result := OptimizeResult{}
+	for k := 0; obj.evaluations < budget; k++ {
+		result.Iterations++
+		candidate := make([]float64, n)
+		for i := range candidate {
+			candidate[i] = x[i] + scales[i]*random.NormFloat64()
+		}
+		o.project(candidate)
+		v := obj.value(candidate)
+		if math.IsNaN(v) {
+			v = math.Inf(1)
+		}
+		if v <= value || random.Float64() < math.Exp(-(v-value)/schedule(k)) {
+			x, value = candidate, v
+			if value < bestValue {
+				best, bestValue = append([]float64(nil), x...), value
+			}
+		}
+		if o.OnIteration != nil {
+			o.OnIteration(OptimizeState{Iteration: result.Iterations, X: best, Value: bestValue})
+		}
+	}
+	result.X, result.Value, result.Evaluations = best, bestValue, obj.evaluations
+	result.Converged = true
+	return result, nil
+}
+
+/*
+DifferentialEvolution is a method to look for the global minimum of f in a box with
+the DE/rand/1/bin differential evolution: each point of a population is crossed with
+a mutant a + F(b - c) built from three other random points, and replaced when the
+result is better. The population explores the whole box then gathers around the best
+minimum, which makes it robust on multimodal functions.
+
+First parameter f is the function to minimize
+Second parameter evolution are the population, the weights and the random generator
+Third parameter options are the bounds (Lower and Upper, required and finite), the
+budget (MaxEvaluations, 10000*n by default, shared by the rounds of the method of
+multipliers when there are equality constraints), the callback and the equality
+constraints; the method stops early when the values of the population differ by at
+most ValueTolerance*(1+|f|) if ValueTolerance is set
+It returns the best point found, Converged being true once the budget is spent or the
+population has converged, and an error if the bounds are missing or if the budget is
+spent before the equality constraints are met
+*/
+func DifferentialEvolution(f Fn, evolution EvolutionOptions, options OptimizeOptions) (OptimizeResult, error) {
+	if options.Lower == nil || options.Upper == nil || len(options.Lower) != len(options.Upper) {
+		return OptimizeResult{}, &MathError{
+			s: "Differential evolution needs lower and upper bounds",
+		}
+	}
+	center := make([]float64, len(options.Lower))
+	for i := range center {
+		if math.IsInf(options.Lower[i], 0) || math.IsInf(options.Upper[i], 0) {
+			return OptimizeResult{}, &MathError{
+				s: "Differential evolution needs finite bounds",
+			}
+		}
+		center[i] = (options.Lower[i] + options.Upper[i]) / 2
+	}
+	return minimizeConstrained(f, center, options, options.globalBudget(len(center)), func(f Fn, x0 []float64, o OptimizeOptions) (OptimizeResult, error) {
+		return differentialEvolution(f, x0, evolution, o)
+	})
+}
+
+/*
+differentialEvolution starts from a random population in the bounds holding x0
+*/
+func differentialEvolution(f Fn, x0 []float64, evolution EvolutionOptions, o OptimizeOptions) (OptimizeResult, error) {
+	n := len(x0)
+	budget := o.globalBudget(n)
+	size := evolution.Population
+	if size <= 0 {
+		size = int(math.Max(5, float64(10*n)))
+	}
+	if size < 4 {
+		return OptimizeResult{}, &MathError{
+			s: "Differential evolution needs a population of at least 4 points",
+		}
+	}
+	weight := evolution.Weight
+	if weight <= 0 {
+		weight = 0.8
+	}
+	crossover := evolution.Crossover
+	if crossover <= 0 {
+		crossover = 0.9
+	}
+	random := randomGenerator(evolution.Source)
+
+	obj := newObjective(func(x []float64) float64 {
+		if v := f(x); !math.IsNaN(v) {
+			return v
+		}
+		return math.Inf(1)
+	}, o)
+	population := make([]vertex, size)
+	population[0] = vertex{x: append([]float64(nil), x0...)}
+	for k := 1; k < size; k++ {
+		x := make([]float64, n)
+		for i := range x {
+			x[i] = o.Lower[i] + random.Float64()*(o.Upper[i]-o.Lower[i])
+		}
+		population[k].x = x
+	}
+	best := 0
+	for k := range population {
+		population[k].value = obj.value(population[k].x)
+		if population[k].value < population[best].value {
+			best = k
+		}
+	}
+
+	result := OptimizeResult{}
+	for obj.evaluations < budget {
+		result.Iterations++
+		for k := 0; k < size && obj.evaluations < budget; k++ {
+			//Three distinct points, different from k
+			var picked [3]int
+			for p := range picked {
+				for {
+					picked[p] = random.Intn(size)
+					if picked[p] != k && (p < 1 || picked[p] != picked[0]) && (p < 2 || picked[p] != picked[1]) {
+						break
+					}
+				}
+			}
+			a, b, c := population[picked[0]].x, population[picked[1]].x, population[picked[2]].x
+			trial := append([]float64(nil), population[k].x...)
+			//At least one variable comes from the mutant
+			forced := random.Intn(n)
+			for i := range trial {
+				if i == forced || random.Float64() < crossover {
+					trial[i] = a[i] + weight*(b[i]-c[i])
+				}
+			}
+			o.project(trial)
+			if v := obj.value(trial); v <= population[k].value {
+				population[k] = vertex{x: trial, value: v}
+				if v < population[best].value {
+					best = k
+				}
+			}
+		}
+		if o.OnIteration != nil {
+			o.OnIteration(OptimizeState{Iteration: result.Iterations, X: population[best].x, Value: population[best].value})
+		}
+		if o.ValueTolerance > 0 {
+			worst := population[best].value
+			for _, p := range population {
+				worst = math.Max(worst, p.value)
+			}
+			if worst-population[best].value <= o.ValueTolerance*(1+math.Abs(population[best].value)) {
+				break
+			}
+		}
+	}
+	result.X, result.Value, result.Evaluations = population[best].x, population[best].value, obj.evaluations
+	result.Converged = true
+	return result, nil
+}
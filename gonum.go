@@ -0,0 +1,55 @@
+package advmath
+
+/*
+GonumMatrix is the part of gonum's mat.Matrix interface needed to read a
+matrix. Every gonum matrix (mat.Dense, mat.SymDense, mat.VecDense, ...)
+satisfies it, which means this package doesn't have to depend on gonum.
+*/
+type GonumMatrix interface {
+	Dims() (r, c int)
+	At(i, j int) float64
+}
+
+/*
+Dims returns the number of rows and columns of the matrix, as gonum's
+mat.Matrix does.
+*/
+func (m Matrix) Dims() (r, c int) {
+	return int(m.NumberOfRows), int(m.NumberOfColumns)
+}
+
+/*
+At returns the value at the given row and column, as gonum's mat.Matrix does.
+*/
+func (m Matrix) At(i, j int) float64 {
+	return m.Get(uint(i), uint(j))
+}
+
+/*
+ToGonum is a method returning the dimensions and a copy of the values of the
+matrix in the order expected by gonum's mat.NewDense, so that converting a
+matrix is simply:
+
+	d := mat.NewDense(m.ToGonum())
+*/
+func (m Matrix) ToGonum() (r, c int, data []float64) {
+	data = make([]float64, len(m.M))
+	copy(data, m.M)
+	return int(m.NumberOfRows), int(m.NumberOfColumns), data
+}
+
+/*
+FromGonum is a method to create a matrix from any gonum matrix (or anything
+with the Dims and At methods). Values are copied.
+First parameter is the matrix to convert
+*/
+func FromGonum(g GonumMatrix) *Matrix {
+	r, c := g.Dims()
+	m := NewMatrix(uint(r), uint(c))
+	for i := 0; i < r; i++ {
+		for j := 0; j < c; j++ {
+			m.M[i*c+j] = g.At(i, j)
+		}
+	}
+	return m
+}
@@ -0,0 +1,349 @@
+package advmath
+
+import (
+	"math"
+	"sort"
+)
+
+/*
+Alternative is the alternative hypothesis of a test, against the null hypothesis of
+no difference
+*/
+type Alternative int
+
+const (
+	//TwoSided tests a difference in either direction
+	TwoSided Alternative = iota
+	//Less tests that the mean (or the first mean) is less than the other
+	Less
+	//Greater tests that the mean (or the first mean) is greater than the other
+	Greater
+)
+
+/*
+TestResult holds the outcome of a hypothesis test
+*/
+type TestResult struct {
+	//Statistic is the value of the statistic of the test
+	Statistic float64
+	//PValue is the probability of a statistic at least as extreme under the null
+	//hypothesis, which is rejected at the level alpha when PValue < alpha
+	PValue float64
+	//DegreesOfFreedom are the degrees of freedom of the distribution of the
+	//statistic, 0 for the Kolmogorov-Smirnov test
+	DegreesOfFreedom float64
+}
+
+/*
+continuedFractionIterations is the maximum number of terms of the series and
+continued fractions of the distributions
+*/
+const continuedFractionIterations = 1000
+
+/*
+incompleteBetaFraction is the continued fraction of the incomplete beta function,
+evaluated with the modified Lentz method
+*/
+func incompleteBetaFraction(a, b, x float64) (float64, error) {
+	const tiny = 1e-300
+	c, d := 1.0, 1-(a+b)*x/(a+1)
+	if math.Abs(d) < tiny {
+		d = tiny
+	}
+	d = 1 / d
+	h := d
+	for m := 1; m <= continuedFractionIterations; m++ {
+		fm := float64(m)
+		for _, coefficient := range []float64{
+			fm * (b - fm) * x / ((a + 2*fm - 1) * (a + 2*fm)),
+			-(a + fm) * (a + b + fm) * x / ((a + 2*fm) * (a + 2*fm + 1)),
+		} {
+			d = 1 + coefficient*d
+			if math.Abs(d) < tiny {
+				d = tiny
+			}
+			c = 1 + coefficient/c
+			if math.Abs(c) < tiny {
+				c = tiny
+			}
+			d = 1 / d
+			h *= d * c
+		}
+		if math.Abs(d*c-1) < machineEpsilon {
+			return h, nil
+		}
+	}
+	return h, &MathError{
+		code: errorNotConverged,
+	}
+}
+
+/*
+regularizedIncompleteBeta is I_x(a, b), the CDF at x of the beta distribution
+*/
+func regularizedIncompleteBeta(a, b, x float64) (float64, error) {
+	if x <= 0 {
+		return 0, nil
+	}
+	if x >= 1 {
+		return 1, nil
+	}
+	la, _ := math.Lgamma(a)
+	lb, _ := math.Lgamma(b)
+	lab, _ := math.Lgamma(a + b)
+	front := math.Exp(lab - la - lb + a*math.Log(x) + b*math.Log1p(-x))
+	//The continued fraction converges quickly for x < (a+1)/(a+b+2), the symmetry
+	//I_x(a, b) = 1 - I_1-x(b, a) is used otherwise
+	if x < (a+1)/(a+b+2) {
+		f, err := incompleteBetaFraction(a, b, x)
+		return front * f / a, err
+	}
+	f, err := incompleteBetaFraction(b, a, 1-x)
+	return 1 - front*f/b, err
+}
+
+/*
+regularizedUpperGamma is Q(a, x) = Gamma(a, x)/Gamma(a), the survival function at x
+of the gamma distribution of shape a
+*/
+func regularizedUpperGamma(a, x float64) (float64, error) {
+	if x <= 0 {
+		return 1, nil
+	}
+	lg, _ := math.Lgamma(a)
+	front := math.Exp(-x + a*math.Log(x) - lg)
+	if x < a+1 {
+		//Series of P(a, x)
+		term := 1 / a
+		sum := term
+		for n := 1; n <= continuedFractionIterations; n++ {
+			term *= x / (a + float64(n))
+			sum += term
+			if math.Abs(term) < math.Abs(sum)*machineEpsilon {
+				return 1 - front*sum, nil
+			}
+		}
+		return 1 - front*sum, &MathError{
+			code: errorNotConverged,
+		}
+	}
+	//Continued fraction of Q(a, x), modified Lentz method
+	const tiny = 1e-300
+	b := x + 1 - a
+	c, d := 1/tiny, 1/b
+	h := d
+	for n := 1; n <= continuedFractionIterations; n++ {
+		an := -float64(n) * (float64(n) - a)
+		b += 2
+		d = an*d + b
+		if math.Abs(d) < tiny {
+			d = tiny
+		}
+		c = b + an/c
+		if math.Abs(c) < tiny {
+			c = tiny
+		}
+		d = 1 / d
+		h *= d * c
+		if math.Abs(d*c-1) < machineEpsilon {
+			return front * h, nil
+		}
+	}
+	return front * h, &MathError{
+		code: errorNotConverged,
+	}
+}
+
+/*
+studentPValue is the p-value of the statistic t of a Student distribution with dof
+degrees of freedom
+*/
+func studentPValue(t, dof float64, alternative Alternative) (float64, error) {
+	//P(|T| >= |t|)
+	both, err := regularizedIncompleteBeta(dof/2, 0.5, dof/(dof+t*t))
+	switch alternative {
+	case Less:
+		if t < 0 {
+			return both / 2, err
+		}
+		return 1 - both/2, err
+	case Greater:
+		if t > 0 {
+			return both / 2, err
+		}
+		return 1 - both/2, err
+	}
+	return both, err
+}
+
+/*
+OneSampleTTest is a function to test if the mean of a normal population is mu with
+Student's t-test: t = (mean - mu)/(s/sqrt(n)) follows a Student distribution with n-1
+degrees of freedom under the null hypothesis.
+First parameter is the sample
+Second parameter is the mean mu of the null hypothesis
+Third parameter is the alternative hypothesis
+It returns the statistic t and its p-value, and an error if the sample has less than
+2 values or is constant
+*/
+func OneSampleTTest(data []float64, mu float64, alternative Alternative) (TestResult, error) {
+	variance, err := Variance(data, true, PropagateNaN)
+	if err != nil {
+		return TestResult{}, err
+	}
+	if !(variance > 0) {
+		return TestResult{}, &MathError{
+			s: "Cannot test a constant sample",
+		}
+	}
+	n := float64(len(data))
+	result := TestResult{
+		Statistic:        (mean(data) - mu) / math.Sqrt(variance/n),
+		DegreesOfFreedom: n - 1,
+	}
+	result.PValue, err = studentPValue(result.Statistic, result.DegreesOfFreedom, alternative)
+	return result, err
+}
+
+/*
+TwoSampleTTest is a function to test if two normal populations have the same mean,
+from independent samples. With equal variances it is Student's test with a pooled
+variance and n1+n2-2 degrees of freedom, otherwise it is Welch's test whose degrees
+of freedom come from the Welch-Satterthwaite equation; Welch's test is the safer
+choice when the variances may differ.
+First parameter is the first sample
+Second parameter is the second sample
+Third parameter tells if the variances of the populations are assumed equal
+Fourth parameter is the alternative hypothesis, about the first mean compared to the
+second one
+It returns the statistic t and its p-value, and an error if a sample has less than
+2 values or if both are constant
+*/
+func TwoSampleTTest(x, y []float64, equalVariances bool, alternative Alternative) (TestResult, error) {
+	vx, err := Variance(x, true, PropagateNaN)
+	if err != nil {
+		return TestResult{}, err
+	}
+	vy, err := Variance(y, true, PropagateNaN)
+	if err != nil {
+		return TestResult{}, err
+	}
+	nx, ny := float64(len(x)), float64(len(y))
+	var se2 float64
+	result := TestResult{}
+	if equalVariances {
+		pooled := ((nx-1)*vx + (ny-1)*vy) / (nx + ny - 2)
+		se2 = pooled * (1/nx + 1/ny)
+		result.DegreesOfFreedom = nx + ny - 2
+	} else {
+		ex, ey := vx/nx, vy/ny
+		se2 = ex + ey
+		result.DegreesOfFreedom = se2 * se2 / (ex*ex/(nx-1) + ey*ey/(ny-1))
+	}
+	if !(se2 > 0) {
+		return TestResult{}, &MathError{
+			s: "Cannot test constant samples",
+		}
+	}
+	result.Statistic = (mean(x) - mean(y)) / math.Sqrt(se2)
+	result.PValue, err = studentPValue(result.Statistic, result.DegreesOfFreedom, alternative)
+	return result, err
+}
+
+/*
+ChiSquareTest is a function to test if observed counts follow expected frequencies
+with Pearson's chi-square goodness of fit test: sum((O-E)^2/E) follows a chi-square
+distribution with k-1 degrees of freedom under the null hypothesis. The approximation
+needs expected counts of at least 5 or so.
+First parameter are the observed counts of the k categories
+Second parameter are the expected frequencies, scaled to the total of the observed
+counts, or nil for equal frequencies
+It returns the statistic and its p-value, and an error if there are less than 2
+categories, if the sizes differ or if an expected frequency is not positive
+*/
+func ChiSquareTest(observed, expected []float64) (TestResult, error) {
+	k := len(observed)
+	if k < 2 || (expected != nil && len(expected) != k) {
+		return TestResult{}, &MathError{
+			code: errorDimensionMismatch,
+		}
+	}
+	var total, expectedTotal float64
+	for i, o := range observed {
+		total += o
+		if expected == nil {
+			expectedTotal++
+			continue
+		}
+		if !(expected[i] > 0) {
+			return TestResult{}, &MathError{
+				s: "The expected frequencies must be positive",
+			}
+		}
+		expectedTotal += expected[i]
+	}
+	result := TestResult{DegreesOfFreedom: float64(k - 1)}
+	for i, o := range observed {
+		e := total / expectedTotal
+		if expected != nil {
+			e *= expected[i]
+		}
+		result.Statistic += (o - e) * (o - e) / e
+	}
+	var err error
+	result.PValue, err = regularizedUpperGamma(result.DegreesOfFreedom/2, result.Statistic/2)
+	return result, err
+}
+
+/*
+kolmogorovPValue is P(D > d) for the largest distance d between the empirical CDF of
+n values and the true one, with the asymptotic Kolmogorov distribution and the
+correction of Stephens for small n
+*/
+func kolmogorovPValue(d float64, n int) float64 {
+	sqrtN := math.Sqrt(float64(n))
+	lambda := (sqrtN + 0.12 + 0.11/sqrtN) * d
+	if lambda < 0.2 {
+		//The series converges too slowly, the p-value is 1 up to 1e-20
+		return 1
+	}
+	var sum float64
+	sign := 1.0
+	for k := 1; k <= 100; k++ {
+		term := sign * math.Exp(-2*float64(k*k)*lambda*lambda)
+		sum += term
+		if math.Abs(term) <= 1e-16*math.Abs(sum) {
+			break
+		}
+		sign = -sign
+	}
+	return math.Max(0, math.Min(1, 2*sum))
+}
+
+/*
+KolmogorovSmirnovTest is a function to test if a sample comes from a continuous
+distribution with the one-sample Kolmogorov-Smirnov test: the statistic is the
+largest distance between the empirical CDF of the sample and the CDF of the
+distribution. Unlike ChiSquareTest, it doesn't need to group the values in bins.
+First parameter is the sample
+Second parameter is the CDF of the distribution of the null hypothesis, e.g. built on
+math.Erf for a normal distribution
+It returns the statistic and its p-value (asymptotic with a correction for small
+samples), and an error if the sample is empty
+*/
+func KolmogorovSmirnovTest(data []float64, cdf F) (TestResult, error) {
+	n := len(data)
+	if n == 0 {
+		return TestResult{}, &MathError{
+			code: errorDimensionMismatch,
+		}
+	}
+	sorted := append([]float64(nil), data...)
+	sort.Float64s(sorted)
+	var d float64
+	for i, v := range sorted {
+		f := cdf(v)
+		d = math.Max(d, math.Max(float64(i+1)/float64(n)-f, f-float64(i)/float64(n)))
+	}
+	return TestResult{Statistic: d, PValue: kolmogorovPValue(d, n)}, nil
+}
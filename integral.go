@@ -1,12 +1,102 @@
 package advmath
 
 import (
+	"context"
 	"math"
 )
 
+/*
+IntegrationResult is what the ...WithResult versions of the integration methods
+return, so that the caller can check how much the value can be trusted
+*/
+type IntegrationResult struct {
+	//Value is the integral computed
+	Value float64
+	//Error is the estimated absolute error of Value
+	Error float64
+	//Evaluations is the number of times the function has been evaluated
+	Evaluations int
+	//Converged tells if the tolerance required was met
+	Converged bool
+}
+
+/*
+countEvaluations wraps f so that each call increments count
+*/
+func countEvaluations(f F, count *int) F {
+	return func(x float64) float64 {
+		*count++
+		return f(x)
+	}
+}
+
+/*
+integrationGuard stops an adaptive integration when its context is done or when
+the next step would exceed the maximum number of evaluations. A nil guard never
+stops, which is what the methods without context use.
+*/
+type integrationGuard struct {
+	ctx            context.Context
+	maxEvaluations int
+	evaluations    int
+	err            error
+}
+
+/*
+defaultMaxEvaluations is the budget of the Context variants of the integration methods
+when their maxEvaluations is 0
+*/
+const defaultMaxEvaluations = 1000000
+
+func newIntegrationGuard(ctx context.Context, maxEvaluations int) *integrationGuard {
+	if maxEvaluations <= 0 {
+		maxEvaluations = defaultMaxEvaluations
+	}
+	return &integrationGuard{
+		ctx:            ctx,
+		maxEvaluations: maxEvaluations,
+	}
+}
+
+/*
+count wraps f so that the guard knows how many evaluations have been done
+*/
+func (g *integrationGuard) count(f F) F {
+	if g == nil {
+		return f
+	}
+	return countEvaluations(f, &g.evaluations)
+}
+
+/*
+stop tells if the integration has to stop before doing next more evaluations,
+the reason is then kept in err
+*/
+func (g *integrationGuard) stop(next int) bool {
+	if g == nil {
+		return false
+	}
+	if g.err != nil {
+		return true
+	}
+	if err := g.ctx.Err(); err != nil {
+		g.err = err
+		return true
+	}
+	if g.evaluations+next > g.maxEvaluations {
+		g.err = &MathError{
+			code: errorBudgetExceeded,
+		}
+		return true
+	}
+	return false
+}
+
 /*
 Simpson uses the simpson method to compute the integral of a given function between a and b.
 Number of intervals computed are by default 10^5, this is the best precision that you can have with go.
+When the number of intervals is odd, the last three intervals are integrated with the
+Simpson 3/8 rule, which has the same order, so any n from 2 can be used.
 
 First parameter inf is the lower boundary
 Second parameter sup is the upper boundary
@@ -15,13 +105,27 @@ Fourth parameter f is the function to integrate
 The method returns the value of the integral
 */
 func Simpson(inf float64, sup float64, f F, n int) (float64, error) {
-	if n%2 != 0 {
+	if n < 2 {
 		return 0, &MathError{
-			s: "Invalid number of iterations, for simpson, iterations number has to be even",
+			s: "Invalid number of iterations, for simpson, at least 2 iterations are needed",
 		}
 	}
 	h := (sup - inf) / float64(n)
-	s := f(inf) + f(sup)
+	var threeEighths float64
+	var fEnd float64
+	if n%2 != 0 {
+		//The last three intervals use the 3/8 rule
+		n -= 3
+		x := inf + float64(n)*h
+		fEnd = f(x)
+		threeEighths = 3 * h / 8 * (fEnd + 3*f(x+h) + 3*f(x+2*h) + f(sup))
+		if n == 0 {
+			return threeEighths, nil
+		}
+	} else {
+		fEnd = f(sup)
+	}
+	s := f(inf) + fEnd
 	var i, j int
 	for i = 1; i < n; i += 2 {
 		s += 4 * f(inf+float64(i)*h)
@@ -29,7 +133,51 @@ func Simpson(inf float64, sup float64, f F, n int) (float64, error) {
 	for j = 2; j < n-1; j += 2 {
 		s += 2 * f(inf+float64(j)*h)
 	}
-	return s * h / 3, nil
+	return s*h/3 + threeEighths, nil
+}
+
+/*
+SimpsonWithTolerance uses the simpson method doubling the number of intervals,
+starting from 4, until the Richardson estimate of the error |S(2n) - S(n)|/15 is
+below tol. Every evaluation is reused by the next step, so it costs the same as a
+single call of Simpson with the final number of intervals.
+
+First parameter inf is the lower boundary
+Second parameter sup is the upper boundary
+Third parameter f is the function to integrate
+Fourth parameter tol is the absolute tolerance required
+The method returns the value of the integral, and an error if the tolerance was not
+met with 2^20 intervals (the value is still the best estimate)
+*/
+func SimpsonWithTolerance(inf float64, sup float64, f F, tol float64) (float64, error) {
+	const maxN = 1 << 20
+	//ends, odd and even are the sums of f at the ends, at the odd and at the even
+	//inner points, so that S(n) = h/3 * (ends + 4*odd + 2*even)
+	n := 2
+	h := (sup - inf) / 2
+	ends := f(inf) + f(sup)
+	even := 0.0
+	odd := f(inf + h)
+	previous := h / 3 * (ends + 4*odd)
+
+	for n < maxN {
+		n *= 2
+		h /= 2
+		even += odd
+		odd = 0
+		for i := 1; i < n; i += 2 {
+			odd += f(inf + float64(i)*h)
+		}
+		current := h / 3 * (ends + 4*odd + 2*even)
+		if math.Abs(current-previous)/15 <= tol {
+			return current, nil
+		}
+		previous = current
+	}
+
+	return previous, &MathError{
+		code: errorNotConverged,
+	}
 }
 
 /*
@@ -62,6 +210,96 @@ func Trapezoidal(inf float64, sup float64, f F, n int, precision float64) float6
 	return result
 }
 
+/*
+SimpsonWithResult is the same as Simpson but returns an IntegrationResult. The error is
+estimated with the Richardson extrapolation |S(n) - S(n/2)|/15, S(n/2) being computed
+with the same evaluations, so n has to be a multiple of 4 (otherwise the error is
+unknown and set to +Inf). There is no tolerance, Converged is only false when the
+error is unknown. Use SimpsonWithTolerance to choose n from a tolerance.
+*/
+func SimpsonWithResult(inf float64, sup float64, f F, n int) (IntegrationResult, error) {
+	if n%2 != 0 {
+		value, err := Simpson(inf, sup, f, n)
+		return IntegrationResult{
+			Value:       value,
+			Error:       math.Inf(1),
+			Evaluations: n + 1,
+		}, err
+	}
+	if n <= 0 {
+		return IntegrationResult{}, &MathError{
+			s: "Invalid number of iterations, for simpson, at least 2 iterations are needed",
+		}
+	}
+
+	h := (sup - inf) / float64(n)
+	fa := f(inf)
+	fb := f(sup)
+	//fine is the Simpson sum with n intervals, coarse the one with n/2 intervals
+	fine := fa + fb
+	coarse := fa + fb
+	for i := 1; i < n; i++ {
+		v := f(inf + float64(i)*h)
+		switch {
+		case i%2 == 1:
+			fine += 4 * v
+		case i%4 == 2:
+			fine += 2 * v
+			coarse += 4 * v
+		default:
+			fine += 2 * v
+			coarse += 2 * v
+		}
+	}
+
+	result := IntegrationResult{
+		Value:       fine * h / 3,
+		Error:       math.Inf(1),
+		Evaluations: n + 1,
+	}
+	if n%4 == 0 {
+		result.Error = math.Abs(result.Value-coarse*2*h/3) / 15
+		result.Converged = true
+	}
+	return result, nil
+}
+
+/*
+TrapezoidalWithResult uses the Trapezoidal rule with n intervals (100000 if n is 0) and
+returns an IntegrationResult. The error is estimated with the Richardson extrapolation
+|T(n) - T(n/2)|/3 (T(n/2) reusing the same evaluations) and Converged tells if it is
+below precision.
+*/
+func TrapezoidalWithResult(inf float64, sup float64, f F, n int, precision float64) IntegrationResult {
+	if n <= 0 {
+		n = 100000
+	}
+	if n%2 != 0 {
+		n++
+	}
+
+	h := (sup - inf) / float64(n)
+	ends := 0.5*f(inf) + 0.5*f(sup)
+	var odd, even float64
+	for i := 1; i < n; i++ {
+		if i%2 == 1 {
+			odd += f(inf + float64(i)*h)
+		} else {
+			even += f(inf + float64(i)*h)
+		}
+	}
+	fine := (ends + odd + even) * h
+	coarse := (ends + even) * 2 * h
+	estimate := math.Abs(fine-coarse) / 3
+
+	return IntegrationResult{
+		Value:       fine,
+		Error:       estimate,
+		Evaluations: n + 1,
+		Converged:   estimate <= precision,
+	}
+}
+
 /*
 Romberg uses the romberg method to compute the integral of a function. It provides a better
 approximation than the Trapezoidal method.
@@ -72,12 +310,25 @@ Third is the function
 Fourth parameter is the precision
 */
 func Romberg(inf float64, sup float64, f F, maxSteps int, precision float64) float64 {
+	return RombergWithResult(inf, sup, f, maxSteps, precision).Value
+}
+
+/*
+RombergWithResult is the same as Romberg but returns an IntegrationResult telling
+if the precision was reached within maxSteps iterations, the error estimate being
+the difference between the last two extrapolated values. RombergTable returns the
+whole extrapolation table.
+*/
+func RombergWithResult(inf float64, sup float64, f F, maxSteps int, precision float64) IntegrationResult {
 	if maxSteps == 0 {
 		//This should be enough for most precisions but it will be a bit slower!
 		maxSteps = 20
 	}
+	var evaluations int
+	f = countEvaluations(f, &evaluations)
 	previousNew := 0.0
 	currentNew := 0.0
+	estimate := math.Inf(1)
 
 	for i := 1; i <= maxSteps; i++ {
 		previous := previousNew
@@ -88,13 +339,85 @@ func Romberg(inf float64, sup float64, f F, maxSteps int, precision float64) flo
 		} else {
 			current := currentNew
 			currentNew = (4.0*previousNew - previous) / 3.0
-			if i > 1 && math.Abs(currentNew-current) < precision {
+			estimate = math.Abs(currentNew - current)
+			if i > 1 && estimate < precision {
 				break
 			}
 		}
 	}
 	//Might not be the best result if we've been through the maxSteps iterations
-	return currentNew
+	return IntegrationResult{
+		Value:       currentNew,
+		Error:       estimate,
+		Evaluations: evaluations,
+		Converged:   estimate < precision,
+	}
+}
+
+/*
+RombergResult is what RombergTable returns: the IntegrationResult and the
+extrapolation table, to see how fast (or slowly) the values converge
+*/
+type RombergResult struct {
+	IntegrationResult
+	//Table[i][j] is the value with 2^i intervals extrapolated j times, so Table[i][0]
+	//is the trapezoidal rule and Table[i][i] the best value of the row
+	Table [][]float64
+	//Levels is the number of rows computed
+	Levels int
+}
+
+/*
+RombergTable uses the full Romberg method: the trapezoidal rule with 1, 2, 4, ...
+intervals is extrapolated as many times as possible, Table[i][j] being
+Table[i][j-1] + (Table[i][j-1] - Table[i-1][j-1])/(4^j - 1). It stops when the last
+two diagonal values are closer than precision. Unlike Romberg, the whole table is
+returned so that slow convergence (a singularity, a function that is not smooth)
+can be diagnosed: the values of a column should converge faster than the ones of
+the previous column.
+
+First parameter inf is the lower boundary
+Second parameter sup is the upper boundary
+Third parameter f is the function to integrate
+Fourth parameter is the maximum number of rows, it is optional and set to 20 by default
+Fifth parameter is the precision
+*/
+func RombergTable(inf float64, sup float64, f F, maxSteps int, precision float64) RombergResult {
+	if maxSteps <= 0 {
+		maxSteps = 20
+	}
+	var evaluations int
+	f = countEvaluations(f, &evaluations)
+
+	result := RombergResult{
+		IntegrationResult: IntegrationResult{
+			Error: math.Inf(1),
+		},
+	}
+	var trapezoid float64
+	for i := 0; i < maxSteps; i++ {
+		trapezoid = trapezoidalr(inf, sup, f, i+1, trapezoid)
+		row := make([]float64, i+1)
+		row[0] = trapezoid
+		factor := 1.0
+		for j := 1; j <= i; j++ {
+			factor *= 4
+			row[j] = row[j-1] + (row[j-1]-result.Table[i-1][j-1])/(factor-1)
+		}
+		result.Table = append(result.Table, row)
+		result.Levels = i + 1
+		result.Value = row[i]
+
+		if i > 0 {
+			result.Error = math.Abs(row[i] - result.Table[i-1][i-1])
+			if result.Error < precision {
+				result.Converged = true
+				break
+			}
+		}
+	}
+	result.Evaluations = evaluations
+	return result
 }
 
 /*
@@ -116,3 +439,178 @@ func trapezoidalr(inf float64, sup float64, f F, m int, previous float64) float6
 	}
 	return (sup - inf) / 2.0 * (f(sup) + f(inf))
 }
+
+/*
+AdaptiveSimpson uses an adaptive version of the Simpson method to compute the integral
+of a function between inf and sup. Instead of using the same step everywhere, each
+interval is split in two until the difference between the Simpson rule on the interval
+and on its two halves shows that the local error is below the tolerance. Smooth regions
+need only a few evaluations while the effort is spent where the function varies quickly.
+
+First parameter inf is the lower boundary
+Second parameter sup is the upper boundary
+Third parameter f is the function to integrate
+Fourth parameter tol is the absolute tolerance required
+The method returns the value of the integral, and an error if the maximum depth of
+subdivision was reached before the tolerance was met (the value is still the best estimate)
+*/
+func AdaptiveSimpson(inf float64, sup float64, f F, tol float64) (float64, error) {
+	result := AdaptiveSimpsonWithResult(inf, sup, f, tol)
+	if !result.Converged {
+		return result.Value, &MathError{
+			code: errorNotConverged,
+		}
+	}
+	return result.Value, nil
+}
+
+/*
+AdaptiveSimpsonWithResult is the same as AdaptiveSimpson but returns an
+IntegrationResult, the error estimate being the sum of the local estimates.
+*/
+func AdaptiveSimpsonWithResult(inf float64, sup float64, f F, tol float64) IntegrationResult {
+	return adaptiveSimpson(inf, sup, f, tol, nil)
+}
+
+/*
+AdaptiveSimpsonContext is the same as AdaptiveSimpson but stops when ctx is done or
+when maxEvaluations would be exceeded (0 means the default of a million), so that a
+pathological function cannot hang the caller. The value returned is then the best estimate so far
+and the error is ctx.Err() or the budget error.
+*/
+func AdaptiveSimpsonContext(ctx context.Context, inf float64, sup float64, f F, tol float64, maxEvaluations int) (float64, error) {
+	guard := newIntegrationGuard(ctx, maxEvaluations)
+	result := adaptiveSimpson(inf, sup, f, tol, guard)
+	if guard.err != nil {
+		return result.Value, guard.err
+	}
+	if !result.Converged {
+		return result.Value, &MathError{
+			code: errorNotConverged,
+		}
+	}
+	return result.Value, nil
+}
+
+func adaptiveSimpson(inf float64, sup float64, f F, tol float64, guard *integrationGuard) IntegrationResult {
+	const maxDepth = 50
+	if guard.stop(3) {
+		return IntegrationResult{
+			Error: math.Inf(1),
+		}
+	}
+	var evaluations int
+	f = countEvaluations(guard.count(f), &evaluations)
+	fa := f(inf)
+	fb := f(sup)
+	m := (inf + sup) / 2
+	fm := f(m)
+	whole := (sup - inf) / 6 * (fa + 4*fm + fb)
+
+	converged := true
+	var estimate float64
+	result := adaptiveSimpsonr(f, inf, sup, fa, fm, fb, whole, tol, maxDepth, guard, &converged, &estimate)
+	return IntegrationResult{
+		Value:       result,
+		Error:       estimate,
+		Evaluations: evaluations,
+		Converged:   converged,
+	}
+}
+
+/*
+adaptiveSimpsonr is the recursive part of AdaptiveSimpson, fa, fm and fb are the values
+of f at a, (a+b)/2 and b so that they are never computed twice. The local error
+estimates are added to estimate.
+*/
+func adaptiveSimpsonr(f F, a, b, fa, fm, fb, whole, tol float64, depth int, guard *integrationGuard, converged *bool, estimate *float64) float64 {
+	//When stopped the error of whole is not known
+	if guard.stop(2) {
+		*converged = false
+		*estimate = math.Inf(1)
+		return whole
+	}
+	m := (a + b) / 2
+	lm := (a + m) / 2
+	rm := (m + b) / 2
+	flm := f(lm)
+	frm := f(rm)
+	left := (m - a) / 6 * (fa + 4*flm + fm)
+	right := (b - m) / 6 * (fm + 4*frm + fb)
+	delta := left + right - whole
+
+	//The error of the two halves is about delta/15
+	if math.Abs(delta) <= 15*tol {
+		*estimate += math.Abs(delta) / 15
+		return left + right + delta/15
+	}
+	if depth <= 0 || m == a || m == b {
+		*converged = false
+		*estimate += math.Abs(delta) / 15
+		return left + right + delta/15
+	}
+	return adaptiveSimpsonr(f, a, m, fa, flm, fm, left, tol/2, depth-1, guard, converged, estimate) +
+		adaptiveSimpsonr(f, m, b, fm, frm, fb, right, tol/2, depth-1, guard, converged, estimate)
+}
+
+/*
+CumulativeIntegral computes the integral of a function from inf to each of the n+1
+equally spaced points between inf and sup, i.e. a sampled antiderivative (a CDF
+from a density for instance). Each step is integrated with the Simpson rule so the
+values are much more accurate than a running trapezoidal sum.
+
+First parameter inf is the lower boundary
+Second parameter sup is the upper boundary
+Third parameter f is the function to integrate
+Fourth parameter n is the number of steps
+First return value are the points
+Second return value are the integrals from inf to each point, the first one being 0
+*/
+func CumulativeIntegral(inf float64, sup float64, f F, n int) ([]float64, []float64) {
+	if n <= 0 {
+		n = 1
+	}
+	h := (sup - inf) / float64(n)
+	x := make([]float64, n+1)
+	values := make([]float64, n+1)
+
+	x[0] = inf
+	previous := f(inf)
+	for i := 1; i <= n; i++ {
+		x[i] = inf + float64(i)*h
+		current := f(x[i])
+		values[i] = values[i-1] + h/6*(previous+4*f(x[i]-h/2)+current)
+		previous = current
+	}
+	x[n] = sup
+	return x, values
+}
+
+/*
+Antiderivative returns the function F(x) = integral of f from inf to x for x in
+[inf, sup]. The values at n+1 points are computed once with CumulativeIntegral, the
+closure then only integrates from the closest point below x with a Simpson rule,
+so each call costs 3 evaluations of f.
+
+First parameter inf is the lower boundary
+Second parameter sup is the upper boundary
+Third parameter f is the function to integrate
+Fourth parameter n is the number of points cached
+*/
+func Antiderivative(inf float64, sup float64, f F, n int) F {
+	x, values := CumulativeIntegral(inf, sup, f, n)
+	h := (sup - inf) / float64(len(x)-1)
+	return func(t float64) float64 {
+		i := int(math.Floor((t - inf) / h))
+		if i < 0 {
+			i = 0
+		} else if i >= len(x) {
+			i = len(x) - 1
+		}
+		d := t - x[i]
+		if d == 0 {
+			return values[i]
+		}
+		return values[i] + d/6*(f(x[i])+4*f(x[i]+d/2)+f(t))
+	}
+}
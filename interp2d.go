@@ -0,0 +1,164 @@
+package advmath
+
+import (
+	"sort"
+)
+
+/*
+Interp2DMethod is the interpolation used between the points of the grid
+*/
+type Interp2DMethod int
+
+const (
+	//Bilinear is linear in x and y on each cell, it is continuous only
+	Bilinear Interp2DMethod = iota
+	//Bicubic is a cubic Hermite patch on each cell, the derivatives at the points
+	//being estimated with finite differences, it has continuous first derivatives
+	Bicubic
+)
+
+/*
+Interp2D interpolates values on a grid: Z.Get(i, j) is the value at (X[i], Y[j]). Out
+of the grid the patches of the cells on the border are extrapolated.
+*/
+type Interp2D struct {
+	X      []float64
+	Y      []float64
+	Z      *Matrix
+	Method Interp2DMethod
+	//Derivatives at the points for Bicubic, same layout as Z.M
+	fx  []float64
+	fy  []float64
+	fxy []float64
+}
+
+/*
+NewInterp2D is a method to create the interpolation of gridded data, a lookup table
+for instance, so that it can be used as a smooth function.
+First parameter x are the abscissas of the rows of z, strictly increasing
+Second parameter y are the abscissas of the columns of z, strictly increasing
+Third parameter z are the values, len(x) rows and len(y) columns
+Fourth parameter method is Bilinear or Bicubic
+It returns an error if the sizes don't match, if there are less than 2 points in a
+direction or if the abscissas are not increasing
+*/
+func NewInterp2D(x, y []float64, z *Matrix, method Interp2DMethod) (*Interp2D, error) {
+	if z == nil || z.NumberOfRows != uint(len(x)) || z.NumberOfColumns != uint(len(y)) {
+		return nil, &MathError{
+			code: errorDimensionMismatch,
+		}
+	}
+	if err := checkSamples(x, x); err != nil {
+		return nil, err
+	}
+	if err := checkSamples(y, y); err != nil {
+		return nil, err
+	}
+
+	//z is copied as x and y are, the derivatives computed from it must stay in sync
+	values := NewMatrix(z.NumberOfRows, z.NumberOfColumns)
+	copy(values.M, z.M)
+	g := &Interp2D{
+		X:      append([]float64(nil), x...),
+		Y:      append([]float64(nil), y...),
+		Z:      values,
+		Method: method,
+	}
+	if method == Bicubic {
+		nx, ny := len(x), len(y)
+		g.fx = make([]float64, nx*ny)
+		g.fy = make([]float64, nx*ny)
+		g.fxy = make([]float64, nx*ny)
+		for i := 0; i < nx; i++ {
+			i0, i1 := neighbours(i, nx)
+			for j := 0; j < ny; j++ {
+				j0, j1 := neighbours(j, ny)
+				dx := x[i1] - x[i0]
+				dy := y[j1] - y[j0]
+				g.fx[i*ny+j] = (z.M[i1*ny+j] - z.M[i0*ny+j]) / dx
+				g.fy[i*ny+j] = (z.M[i*ny+j1] - z.M[i*ny+j0]) / dy
+				g.fxy[i*ny+j] = (z.M[i1*ny+j1] - z.M[i1*ny+j0] - z.M[i0*ny+j1] + z.M[i0*ny+j0]) / (dx * dy)
+			}
+		}
+	}
+	return g, nil
+}
+
+/*
+neighbours returns the points used by the centered (one-sided at the ends) difference
+at the point i of n
+*/
+func neighbours(i, n int) (int, int) {
+	i0, i1 := i-1, i+1
+	if i0 < 0 {
+		i0 = 0
+	}
+	if i1 > n-1 {
+		i1 = n - 1
+	}
+	return i0, i1
+}
+
+/*
+cell returns the index of the interval of v in the increasing points
+*/
+func cell(points []float64, v float64) int {
+	i := sort.SearchFloat64s(points, v) - 1
+	if i < 0 {
+		return 0
+	}
+	if i > len(points)-2 {
+		return len(points) - 2
+	}
+	return i
+}
+
+/*
+hermiteBasis returns the four cubic Hermite basis functions at t
+*/
+func hermiteBasis(t float64) (float64, float64, float64, float64) {
+	t2 := t * t
+	t3 := t2 * t
+	return 2*t3 - 3*t2 + 1, t3 - 2*t2 + t, -2*t3 + 3*t2, t3 - t2
+}
+
+/*
+Evaluate is a method to compute the interpolated value at (x, y)
+*/
+func (g *Interp2D) Evaluate(x, y float64) float64 {
+	i, j := cell(g.X, x), cell(g.Y, y)
+	ny := len(g.Y)
+	hx := g.X[i+1] - g.X[i]
+	hy := g.Y[j+1] - g.Y[j]
+	t := (x - g.X[i]) / hx
+	u := (y - g.Y[j]) / hy
+	k00, k01 := i*ny+j, i*ny+j+1
+	k10, k11 := (i+1)*ny+j, (i+1)*ny+j+1
+	z := g.Z.M
+
+	if g.Method != Bicubic {
+		return (1-t)*(1-u)*z[k00] + t*(1-u)*z[k10] + (1-t)*u*z[k01] + t*u*z[k11]
+	}
+
+	h00, h10, h01, h11 := hermiteBasis(t)
+	//Values and y derivatives interpolated in x along the two sides of the cell
+	side := func(k0, k1 int) (float64, float64) {
+		v := h00*z[k0] + h01*z[k1] + hx*(h10*g.fx[k0]+h11*g.fx[k1])
+		dv := h00*g.fy[k0] + h01*g.fy[k1] + hx*(h10*g.fxy[k0]+h11*g.fxy[k1])
+		return v, dv
+	}
+	v0, dv0 := side(k00, k10)
+	v1, dv1 := side(k01, k11)
+	g00, g10, g01, g11 := hermiteBasis(u)
+	return g00*v0 + g01*v1 + hy*(g10*dv0+g11*dv1)
+}
+
+/*
+Fn is a method returning the interpolation as a function of [x, y], to give it to the
+derivative (Gradient, Hessian...) or cubature methods
+*/
+func (g *Interp2D) Fn() Fn {
+	return func(p []float64) float64 {
+		return g.Evaluate(p[0], p[1])
+	}
+}
@@ -0,0 +1,152 @@
+package advmath
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+/*
+JSONFormat is the layout used to encode a Matrix in JSON
+*/
+type JSONFormat int
+
+const (
+	//JSONObject encodes a matrix as {"rows": 2, "cols": 2, "data": [1, 2, 3, 4]}
+	JSONObject JSONFormat = iota
+	//JSONNestedArray encodes a matrix as [[1, 2], [3, 4]]
+	JSONNestedArray
+)
+
+/*
+NestedJSON is a Matrix encoded as nested arrays [[1, 2], [3, 4]] by json.Marshal,
+the conversion NestedJSON(*m) choosing the format of a single value. A
+structure can hold a NestedJSON field to encode its matrix this way.
+*/
+type NestedJSON Matrix
+
+/*
+MarshalJSON implements json.Marshaler using JSONNestedArray
+*/
+func (n NestedJSON) MarshalJSON() ([]byte, error) {
+	return Matrix(n).MarshalJSONFormat(JSONNestedArray)
+}
+
+/*
+UnmarshalJSON implements json.Unmarshaler, both formats are accepted as for Matrix
+*/
+func (n *NestedJSON) UnmarshalJSON(b []byte) error {
+	return (*Matrix)(n).UnmarshalJSON(b)
+}
+
+type jsonMatrix struct {
+	Rows uint      `json:"rows"`
+	Cols uint      `json:"cols"`
+	Data []float64 `json:"data"`
+}
+
+/*
+MarshalJSON implements json.Marshaler using JSONObject, see NestedJSON for the
+nested arrays
+*/
+func (m Matrix) MarshalJSON() ([]byte, error) {
+	return m.MarshalJSONFormat(JSONObject)
+}
+
+/*
+MarshalJSONFormat is a method to encode the matrix in JSON using the given format.
+First parameter is the format, either JSONObject or JSONNestedArray
+*/
+func (m Matrix) MarshalJSONFormat(format JSONFormat) ([]byte, error) {
+	if format == JSONNestedArray {
+		rows := make([][]float64, m.NumberOfRows)
+		var row uint
+		for row = 0; row < m.NumberOfRows; row++ {
+			rows[row] = m.GetRow(row)
+		}
+		return json.Marshal(rows)
+	}
+
+	data := m.M
+	if data == nil {
+		data = []float64{}
+	}
+	return json.Marshal(jsonMatrix{
+		Rows: m.NumberOfRows,
+		Cols: m.NumberOfColumns,
+		Data: data,
+	})
+}
+
+/*
+UnmarshalJSON implements json.Unmarshaler, both the object and the nested
+array formats are accepted. An error is returned when the size of the data
+doesn't match the dimensions or when the rows have different lengths.
+*/
+func (m *Matrix) UnmarshalJSON(b []byte) error {
+	if bytes.HasPrefix(bytes.TrimSpace(b), []byte("[")) {
+		var rows [][]float64
+		if err := json.Unmarshal(b, &rows); err != nil {
+			return err
+		}
+		var cols int
+		if len(rows) > 0 {
+			cols = len(rows[0])
+		}
+		ret := NewMatrix(uint(len(rows)), uint(cols))
+		for i, row := range rows {
+			if len(row) != cols {
+				return &MathError{
+					s: "Invalid JSON matrix, all the rows must have the same length",
+				}
+			}
+			ret.SetRow(uint(i), row)
+		}
+		*m = *ret
+		return nil
+	}
+
+	var obj jsonMatrix
+	if err := json.Unmarshal(b, &obj); err != nil {
+		return err
+	}
+	//Divided first so that a huge rows*cols can't overflow into the size of data
+	if obj.Cols != 0 && obj.Rows > uint(len(obj.Data))/obj.Cols || uint(len(obj.Data)) != obj.Rows*obj.Cols {
+		return &MathError{
+			s: "Invalid JSON matrix, the size of data doesn't match rows*cols",
+		}
+	}
+	m.NumberOfRows = obj.Rows
+	m.NumberOfColumns = obj.Cols
+	m.M = obj.Data
+	if m.M == nil {
+		m.M = []float64{}
+	}
+	return nil
+}
+
+type jsonODEResult struct {
+	Times  []float64  `json:"times"`
+	States *Matrix    `json:"states"`
+	Events []EventHit `json:"events"`
+}
+
+/*
+MarshalJSON implements json.Marshaler for the solution of an ODE, it is encoded as
+{"times": [...], "states": matrix, "events": [{"event": 0, "t": 1, "y": [...]}]}, the
+states matrix having one row per time and using JSONObject
+*/
+func (r *ODEResult) MarshalJSON() ([]byte, error) {
+	times := r.Times
+	if times == nil {
+		times = []float64{}
+	}
+	events := r.Events
+	if events == nil {
+		events = []EventHit{}
+	}
+	return json.Marshal(jsonODEResult{
+		Times:  times,
+		States: r.StatesMatrix(),
+		Events: events,
+	})
+}
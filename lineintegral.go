@@ -0,0 +1,68 @@
+package advmath
+
+import (
+	"math"
+)
+
+/*
+Curve is a parametric plane curve t -> (x(t), y(t))
+*/
+type Curve func(t float64) (x, y float64)
+
+/*
+curveDerivative computes (x'(t), y'(t)) with the five points central difference,
+whose error is in h^4, h being scaled with t so that round-off stays small.
+*/
+func curveDerivative(curve Curve, t float64) (float64, float64) {
+	h := 1e-3 * math.Max(1, math.Abs(t))
+	x1, y1 := curve(t - 2*h)
+	x2, y2 := curve(t - h)
+	x3, y3 := curve(t + h)
+	x4, y4 := curve(t + 2*h)
+	return (x1 - 8*x2 + 8*x3 - x4) / (12 * h), (y1 - 8*y2 + 8*y3 - y4) / (12 * h)
+}
+
+/*
+LineIntegral computes the integral of a scalar field along a curve, i.e. the
+integral of f(x(t), y(t)) * |r'(t)| for t between t0 and t1. The derivative of the
+curve is computed numerically (so the curve must be defined a little outside of
+[t0, t1]) and the integral with AdaptiveGaussKronrod. With f = 1 it gives the length
+of the curve.
+
+First parameter curve is the parametrization of the curve
+Second parameter f is the scalar field
+Third and fourth parameters are the boundaries of the parameter
+Fifth parameter tol is the absolute tolerance required
+The method returns the integral, and an error if the tolerance could not be met
+*/
+func LineIntegral(curve Curve, f func(x, y float64) float64, t0 float64, t1 float64, tol float64) (float64, error) {
+	integrand := func(t float64) float64 {
+		x, y := curve(t)
+		dx, dy := curveDerivative(curve, t)
+		return f(x, y) * math.Hypot(dx, dy)
+	}
+	value, _, err := AdaptiveGaussKronrod(t0, t1, integrand, tol, 0)
+	return value, err
+}
+
+/*
+WorkIntegral computes the integral of a vector field along a curve, i.e. the work
+of the field: the integral of F(r(t)) . r'(t) for t between t0 and t1. Unlike
+LineIntegral, the result depends on the direction of the curve.
+
+First parameter curve is the parametrization of the curve
+Second parameter field is the vector field
+Third and fourth parameters are the boundaries of the parameter
+Fifth parameter tol is the absolute tolerance required
+The method returns the integral, and an error if the tolerance could not be met
+*/
+func WorkIntegral(curve Curve, field func(x, y float64) (fx, fy float64), t0 float64, t1 float64, tol float64) (float64, error) {
+	integrand := func(t float64) float64 {
+		x, y := curve(t)
+		dx, dy := curveDerivative(curve, t)
+		fx, fy := field(x, y)
+		return fx*dx + fy*dy
+	}
+	value, _, err := AdaptiveGaussKronrod(t0, t1, integrand, tol, 0)
+	return value, err
+}
@@ -0,0 +1,266 @@
+package advmath
+
+import (
+	"math"
+)
+
+/*
+linePoint is a point x + step*direction of a line search, with f and its gradient
+there, slope being the derivative of f along the direction
+*/
+type linePoint struct {
+	step  float64
+	x     []float64
+	value float64
+	g     []float64
+	slope float64
+}
+
+/*
+lineSearch evaluates f along a direction starting at x
+*/
+type lineSearch struct {
+	obj       *objective
+	x         []float64
+	direction []float64
+}
+
+func (l *lineSearch) at(step float64) linePoint {
+	x := axpy(step, l.direction, l.x)
+	p := linePoint{step: step, x: x, value: l.obj.value(x)}
+	if math.IsNaN(p.value) || math.IsInf(p.value, 0) {
+		//Out of the domain of f, the step is too long
+		p.value = math.Inf(1)
+		p.slope = math.Inf(1)
+		return p
+	}
+	p.g = l.obj.grad(x)
+	p.slope = dot(p.g, l.direction)
+	return p
+}
+
+/*
+wolfeLineSearch looks for a step along direction from x satisfying the strong Wolfe
+conditions: f decreases enough (f(x+a d) <= f(x) + c1 a g.d) and the slope is
+reduced enough (|g(x+a d).d| <= c2 |g.d|). It is the algorithm 3.5 of Nocedal and
+Wright, 'Numerical Optimization': the step is doubled until the conditions hold or an
+interval containing an acceptable step is found, which is then reduced with cubic
+interpolation (zoom).
+*/
+func wolfeLineSearch(obj *objective, x []float64, value float64, g []float64, direction []float64, step, c1, c2 float64) (linePoint, error) {
+	const maxIterations = 40
+	l := &lineSearch{obj: obj, x: x, direction: direction}
+	start := linePoint{x: x, value: value, g: g, slope: dot(g, direction)}
+	if start.slope >= 0 {
+		return start, &MathError{
+			s: "The search direction is not a descent direction",
+		}
+	}
+	sufficient := func(p linePoint) bool {
+		return p.value <= value+c1*p.step*start.slope
+	}
+	curvature := func(p linePoint) bool {
+		return math.Abs(p.slope) <= -c2*start.slope
+	}
+
+	previous := start
+	for i := 0; i < maxIterations; i++ {
+		p := l.at(step)
+		if !sufficient(p) || (i > 0 && p.value >= previous.value) {
+			return l.zoom(previous, p, start, sufficient, curvature)
+		}
+		if curvature(p) {
+			return p, nil
+		}
+		if p.slope >= 0 {
+			return l.zoom(p, previous, start, sufficient, curvature)
+		}
+		previous = p
+		step *= 2
+	}
+	return previous, &MathError{
+		code: errorNotConverged,
+	}
+}
+
+/*
+zoom reduces the interval between lo, the best point so far satisfying the sufficient
+decrease, and hi until a step satisfies the strong Wolfe conditions
+*/
+func (l *lineSearch) zoom(lo, hi, start linePoint, sufficient, curvature func(linePoint) bool) (linePoint, error) {
+	const maxIterations = 40
+	for i := 0; i < maxIterations; i++ {
+		step := cubicMinimizer(lo, hi)
+		p := l.at(step)
+		if !sufficient(p) || p.value >= lo.value {
+			hi = p
+			continue
+		}
+		if curvature(p) {
+			return p, nil
+		}
+		if p.slope*(hi.step-lo.step) >= 0 {
+			hi = lo
+		}
+		lo = p
+	}
+	//The best point found, which decreases f enough
+	if lo.step > 0 {
+		return lo, nil
+	}
+	return lo, &MathError{
+		code: errorNotConverged,
+	}
+}
+
+/*
+cubicMinimizer is the minimum of the cubic interpolating the values and the slopes at
+a and b, the middle of the interval when it is not safely inside
+*/
+func cubicMinimizer(a, b linePoint) float64 {
+	lo, hi := math.Min(a.step, b.step), math.Max(a.step, b.step)
+	middle := (lo + hi) / 2
+	if math.IsInf(b.value, 0) || math.IsInf(a.value, 0) {
+		return middle
+	}
+	d1 := a.slope + b.slope - 3*(a.value-b.value)/(a.step-b.step)
+	d2 := d1*d1 - a.slope*b.slope
+	if d2 < 0 {
+		return middle
+	}
+	d2 = math.Copysign(math.Sqrt(d2), b.step-a.step)
+	step := b.step - (b.step-a.step)*(b.slope+d2-d1)/(b.slope-a.slope+2*d2)
+	//Keep away from the ends so that the interval shrinks
+	margin := 0.1 * (hi - lo)
+	if math.IsNaN(step) || step < lo+margin || step > hi-margin {
+		return middle
+	}
+	return step
+}
+
+/*
+LineSearchResult holds the step found by a line search
+*/
+type LineSearchResult struct {
+	//Step is the accepted step length a
+	Step float64
+	//X is the new point x + a*direction
+	X []float64
+	//Value is f(X)
+	Value float64
+	//Gradient is the gradient at X, nil for BacktrackingLineSearch which doesn't need
+	//it
+	Gradient []float64
+	//Evaluations is the number of calls to f, including the ones made to compute
+	//numerical gradients
+	Evaluations int
+}
+
+/*
+startLineSearch checks the arguments of a line search and evaluates f and its gradient
+at x
+*/
+func startLineSearch(f Fn, gradient VectorFn, x, direction []float64) (*objective, float64, []float64, error) {
+	obj := newObjective(f, OptimizeOptions{Gradient: gradient})
+	if len(x) == 0 || len(x) != len(direction) {
+		return obj, 0, nil, &MathError{
+			code: errorDimensionMismatch,
+		}
+	}
+	value := obj.value(x)
+	g := obj.grad(x)
+	if math.IsNaN(value) || math.IsInf(value, 0) || !finiteVector(g) {
+		return obj, value, g, &MathError{
+			code: errorDiverged,
+		}
+	}
+	if dot(g, direction) >= 0 {
+		return obj, value, g, &MathError{
+			s: "The search direction is not a descent direction",
+		}
+	}
+	return obj, value, g, nil
+}
+
+/*
+BacktrackingLineSearch is a method to find a step along a descent direction with the
+Armijo rule: the step is halved until f decreases enough, f(x+a d) <= f(x) + c1 a g.d.
+It is cheap (the gradient is only needed at x) and is enough for Newton-like methods
+whose first step is usually accepted, but the step can be too short for a quasi-Newton
+update, see WolfeLineSearch.
+
+First parameter f is the function to minimize
+Second parameter gradient is the gradient of f, computed numerically when it is nil
+Third parameter x is the starting point
+Fourth parameter direction is the search direction d, g.d must be negative
+Fifth parameter step is the first step tried, 1 when it is 0
+Sixth parameter c1 is the sufficient decrease factor, 1e-4 when it is 0
+It returns the step found, and an error if f or its gradient are not finite at x, if
+the direction doesn't decrease f or if no step was found in 50 halvings
+*/
+func BacktrackingLineSearch(f Fn, gradient VectorFn, x, direction []float64, step, c1 float64) (LineSearchResult, error) {
+	const maxIterations = 50
+	if step <= 0 {
+		step = 1
+	}
+	if c1 <= 0 {
+		c1 = 1e-4
+	}
+	obj, value, g, err := startLineSearch(f, gradient, x, direction)
+	if err != nil {
+		return LineSearchResult{X: x, Value: value, Gradient: g, Evaluations: obj.evaluations}, err
+	}
+	slope := dot(g, direction)
+	for i := 0; i < maxIterations; i++ {
+		trial := axpy(step, direction, x)
+		if v := obj.value(trial); v <= value+c1*step*slope {
+			return LineSearchResult{Step: step, X: trial, Value: v, Evaluations: obj.evaluations}, nil
+		}
+		step /= 2
+	}
+	return LineSearchResult{X: x, Value: value, Evaluations: obj.evaluations}, &MathError{
+		code: errorNotConverged,
+	}
+}
+
+/*
+WolfeLineSearch is a method to find a step along a descent direction satisfying the
+strong Wolfe conditions: f decreases enough (f(x+a d) <= f(x) + c1 a g.d) and the
+slope is reduced enough (|g(x+a d).d| <= c2 |g.d|). The step is doubled until an
+interval containing an acceptable step is found, then reduced with cubic
+interpolation (algorithm 3.5 of Nocedal and Wright). It is the line search used by
+BFGS and LBFGS, the curvature condition keeping their updates positive definite.
+
+First parameter f is the function to minimize
+Second parameter gradient is the gradient of f, computed numerically when it is nil
+Third parameter x is the starting point
+Fourth parameter direction is the search direction d, g.d must be negative
+Fifth parameter step is the first step tried, 1 when it is 0
+Sixth parameter c1 is the sufficient decrease factor, 1e-4 when it is 0
+Seventh parameter c2 is the curvature factor, between c1 and 1, 0.9 when it is 0
+(0.1 is usual for nonlinear conjugate gradients)
+It returns the step found, and an error if f or its gradient are not finite at x, if
+the direction doesn't decrease f, if the factors are wrong or if no step was found
+*/
+func WolfeLineSearch(f Fn, gradient VectorFn, x, direction []float64, step, c1, c2 float64) (LineSearchResult, error) {
+	if step <= 0 {
+		step = 1
+	}
+	if c1 <= 0 {
+		c1 = 1e-4
+	}
+	if c2 <= 0 {
+		c2 = 0.9
+	}
+	if c1 >= c2 || c2 >= 1 {
+		return LineSearchResult{X: x}, &MathError{
+			s: "The factors of the Wolfe conditions must satisfy 0 < c1 < c2 < 1",
+		}
+	}
+	obj, value, g, err := startLineSearch(f, gradient, x, direction)
+	if err != nil {
+		return LineSearchResult{X: x, Value: value, Gradient: g, Evaluations: obj.evaluations}, err
+	}
+	p, err := wolfeLineSearch(obj, x, value, g, direction, step, c1, c2)
+	return LineSearchResult{Step: p.step, X: p.x, Value: p.value, Gradient: p.g, Evaluations: obj.evaluations}, err
+}
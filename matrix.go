@@ -1,5 +1,9 @@
 package advmath
 
+import (
+	"math"
+)
+
 /*
 Matrix is a standard mathematical array of numbers
 */
@@ -306,122 +310,289 @@ func (m Matrix) LUDecomposition() (*Matrix, *Matrix, error) {
 }
 
 /*
-Determinant is a method to compute the determinant of a square matrix. It uses the
-LU decomposition to compute the value
+PLUDecomposition is a method to create the LU decomposition of a square matrix
+with partial pivoting: at each step the row with the biggest value in the
+column is used as pivot. This makes the decomposition stable and possible for
+every square matrix, inversible or not.
+First return value is the permutation P of the rows so that P.ApplyRows(A) = L*U
+Second return value is the lower triangular matrix with ones on the diagonal
+Third return value is the upper triangular matrix, a zero on its diagonal means
+the matrix is singular
+Fourth return value is the error that can occur in the process (if non square matrix)
 */
-func (m Matrix) Determinant() (float64, error) {
+func (m Matrix) PLUDecomposition() (Permutation, *Matrix, *Matrix, error) {
 	if !m.IsSquare() {
-		return 0.0, &MathError{
+		return nil, nil, nil, &MathError{
 			code: errorNonSquareMatrix,
 		}
 	}
 
-	_, u, err := m.LUDecomposition()
-	if err != nil {
-		return 0.0, err
+	n := int(m.NumberOfRows)
+	p := NewPermutation(m.NumberOfRows)
+	a := make([]float64, len(m.M))
+	copy(a, m.M)
+
+	for k := 0; k < n; k++ {
+		pivot := k
+		for i := k + 1; i < n; i++ {
+			if math.Abs(a[i*n+k]) > math.Abs(a[pivot*n+k]) {
+				pivot = i
+			}
+		}
+		if pivot != k {
+			for j := 0; j < n; j++ {
+				a[k*n+j], a[pivot*n+j] = a[pivot*n+j], a[k*n+j]
+			}
+			p.Swap(uint(k), uint(pivot))
+		}
+		if a[k*n+k] == 0.0 {
+			//The whole column is zero, nothing to eliminate
+			continue
+		}
+		for i := k + 1; i < n; i++ {
+			a[i*n+k] /= a[k*n+k]
+			for j := k + 1; j < n; j++ {
+				a[i*n+j] -= a[i*n+k] * a[k*n+j]
+			}
+		}
 	}
 
-	//We just need to compute the determinant of the upper matrix and since it's a triangular matrix that's just
-	//mulitplying the elements on the diagonal
-	det := 1.0
-	var column uint
-	var row uint
-	for row = 0; row < m.NumberOfRows; row++ {
-		det *= u.Get(row, column)
-		column++
+	l := NewIdentity(m.NumberOfRows)
+	u := NewMatrix(m.NumberOfRows, m.NumberOfColumns)
+	for i := 0; i < n; i++ {
+		for j := 0; j < n; j++ {
+			if j < i {
+				l.M[i*n+j] = a[i*n+j]
+			} else {
+				u.M[i*n+j] = a[i*n+j]
+			}
+		}
 	}
 
-	return det, nil
+	return p, l, u, nil
 }
 
-func (m Matrix) determinantLU() (float64, *Matrix, *Matrix, error) {
+/*
+Cholesky is a method to create the Cholesky decomposition of a symmetric positive
+definite matrix: the lower triangular matrix L with a positive diagonal such that
+A = L*L'. It costs half of the LU decomposition and doubles as the cheapest test of
+positive definiteness. Only the lower half of the matrix is read.
+First return value is the lower triangular matrix L
+Second return value is the error that can occur in the process (if non square matrix
+or if the matrix is not positive definite)
+*/
+func (m Matrix) Cholesky() (*Matrix, error) {
 	if !m.IsSquare() {
-		return 0.0, nil, nil, &MathError{
+		return nil, &MathError{
 			code: errorNonSquareMatrix,
 		}
 	}
 
-	l, u, err := m.LUDecomposition()
+	n := int(m.NumberOfRows)
+	l := NewMatrix(m.NumberOfRows, m.NumberOfColumns)
+	for j := 0; j < n; j++ {
+		sum := m.M[j*n+j]
+		for k := 0; k < j; k++ {
+			sum -= l.M[j*n+k] * l.M[j*n+k]
+		}
+		if !(sum > 0) {
+			return nil, &MathError{
+				s: "The matrix is not positive definite",
+			}
+		}
+		l.M[j*n+j] = math.Sqrt(sum)
+		for i := j + 1; i < n; i++ {
+			sum := m.M[i*n+j]
+			for k := 0; k < j; k++ {
+				sum -= l.M[i*n+k] * l.M[j*n+k]
+			}
+			l.M[i*n+j] = sum / l.M[j*n+j]
+		}
+	}
+
+	return l, nil
+}
+
+/*
+Determinant is a method to compute the determinant of a square matrix. It uses the
+LU decomposition with partial pivoting to compute the value. Note that a small
+determinant doesn't mean that the matrix is close to singular, use RCond for that.
+*/
+func (m Matrix) Determinant() (float64, error) {
+	p, _, u, err := m.PLUDecomposition()
 	if err != nil {
-		return 0.0, nil, nil, err
+		return 0.0, err
 	}
 
-	//We just need to compute the determinant of the upper matrix
-	//and since it's a triangular matrix that's just
-	//mulitplying the elements on the diagonal
-	det := 1.0
-	var column uint
+	//We just need to compute the determinant of the upper matrix and since it's a triangular matrix that's just
+	//mulitplying the elements on the diagonal, the permutation only changes the sign
+	det := float64(p.Sign())
 	var row uint
 	for row = 0; row < m.NumberOfRows; row++ {
-		det *= u.Get(row, column)
-		column++
+		det *= u.Get(row, row)
+	}
+
+	return det, nil
+}
+
+/*
+Solve is a method to solve the system A*x = b for a square matrix A, using the LU
+decomposition with partial pivoting then a forward and a back substitution. It is
+cheaper and more accurate than multiplying b by the inverse.
+First parameter is the right hand side b
+It returns an error if the matrix is not square, if b doesn't have the right size or
+if the matrix is singular
+*/
+func (m Matrix) Solve(b []float64) ([]float64, error) {
+	if uint(len(b)) != m.NumberOfRows {
+		return nil, &MathError{
+			code: errorDimensionMismatch,
+		}
+	}
+	p, l, u, err := m.PLUDecomposition()
+	if err != nil {
+		return nil, err
 	}
+	x, _ := p.ApplyVector(b)
+	n := len(b)
 
-	return det, l, u, nil
+	//L*y = P*b, L has ones on its diagonal
+	for i := 0; i < n; i++ {
+		for j := 0; j < i; j++ {
+			x[i] -= l.M[i*n+j] * x[j]
+		}
+	}
+	//U*x = y
+	for i := n - 1; i >= 0; i-- {
+		if u.M[i*n+i] == 0 {
+			return nil, &MathError{
+				code: errorNotInversible,
+			}
+		}
+		for j := i + 1; j < n; j++ {
+			x[i] -= u.M[i*n+j] * x[j]
+		}
+		x[i] /= u.M[i*n+i]
+	}
+	return x, nil
 }
 
 /*
 Inverse is a method to compute the inverse of a square matrix. If this method is called on a
 non square matrix then an error will be returned.
-This method uses the LU decomposition to compute the inverse:
+This method uses the LU decomposition (with partial pivoting) to compute the inverse:
 
-A*A^-1 = I <=> (L*U)*[a1 a2 ... aN] = [e1 e2 ... eN]
+P*A*A^-1 = P <=> (L*U)*[a1 a2 ... aN] = [p1 p2 ... pN]
 
 This is like solving sets of equations for :
 
-L*y = en
+L*y = pn
 U*an = y
 
 That should be easy since we have triangular matrices. Once we've done that, all the an are simply
 the inverse of our A matrix.
+
+If the matrix is singular an error is returned. If it is so badly conditioned that the
+inverse is meaningless in double precision (see RCond), the computed inverse is returned
+along with an error telling that the result is unreliable.
 */
 func (m Matrix) Inverse() (*Matrix, error) {
-	//First get the LU decomposition and the determinant
-	det, l, u, error := m.determinantLU()
-	if error != nil {
-		return nil, error
+	x, rcond, err := m.inverseRCond()
+	if err != nil {
+		return nil, err
+	}
+	if rcond < machineEpsilon {
+		return x, &MathError{
+			code: errorIllConditioned,
+		}
 	}
+	return x, nil
+}
 
-	if det == 0.0 {
-		//Ok cannot find inverse
-		return nil, &MathError{
-			code: errorNotInversible,
+/*
+RCond is a method to compute the reciprocal of the condition number of a square
+matrix in the 1-norm: 1/(||A||*||A^-1||). It is 1 for the identity and 0 for a
+singular matrix. Roughly, a value of 10^-k means that k digits are lost when
+solving a system or computing the inverse of this matrix, so a value close to
+the machine epsilon (about 2.2e-16) means the results can't be trusted.
+*/
+func (m Matrix) RCond() (float64, error) {
+	_, rcond, err := m.inverseRCond()
+	if err != nil {
+		if e, ok := err.(*MathError); ok && e.code == errorNotInversible {
+			return 0.0, nil
 		}
+		return 0.0, err
 	}
+	return rcond, nil
+}
 
-	id := NewIdentity(m.NumberOfRows)
-	y := NewMatrix(m.NumberOfRows, m.NumberOfColumns)
+/*
+inverseRCond computes the inverse of the matrix and its reciprocal condition number
+*/
+func (m Matrix) inverseRCond() (*Matrix, float64, error) {
+	p, l, u, err := m.PLUDecomposition()
+	if err != nil {
+		return nil, 0.0, err
+	}
 
-	//Let solve L*Y = I
-	var i, j, k int
-	var sum float64
-	for k = 0; k < int(y.NumberOfColumns); k++ {
-		y.M[k] = id.GetColumn(uint(k))[0] / l.Get(0, 0)
-		for i = 1; i < int(l.NumberOfRows); i++ {
-			for j = 0; j < i; j++ {
-				sum += l.Get(uint(i), uint(j)) * y.M[uint(j)*y.NumberOfColumns+uint(k)]
+	n := int(m.NumberOfRows)
+	for i := 0; i < n; i++ {
+		if u.M[i*n+i] == 0.0 {
+			//Ok cannot find inverse
+			return nil, 0.0, &MathError{
+				code: errorNotInversible,
 			}
-			y.M[uint(i)*y.NumberOfColumns+uint(k)] = (id.Get(uint(i), uint(k)) - sum) / l.Get(uint(i), uint(i))
-			sum = 0.0
 		}
 	}
 
 	x := NewMatrix(m.NumberOfRows, m.NumberOfColumns)
-	var sum2 float64
-	//Now let solve U*X = Y
-	for n := 0; n < int(x.NumberOfColumns); n++ {
-		x.Set(x.NumberOfRows-1, x.NumberOfColumns-1-uint(n), y.GetColumn(x.NumberOfColumns - 1 - uint(n))[int(y.NumberOfRows)-1]/u.Get(x.NumberOfRows-1, x.NumberOfColumns-1))
-		for o := int(x.NumberOfColumns) - 2; o >= 0; o-- {
-			for p := o + 1; p < int(x.NumberOfRows); p++ {
-				sum2 += u.Get(uint(o), uint(p)) * x.Get(uint(p), x.NumberOfColumns-1-uint(n))
+	y := make([]float64, n)
+	for k := 0; k < n; k++ {
+		//Let solve L*y = P*ek
+		for i := 0; i < n; i++ {
+			sum := 0.0
+			if p[i] == uint(k) {
+				sum = 1.0
 			}
-
-			x.Set(uint(o), x.NumberOfColumns-1-uint(n), (y.Get(uint(o), x.NumberOfColumns-1-uint(n))-sum2)/u.Get(uint(o), uint(o)))
-			sum2 = 0.0
+			for j := 0; j < i; j++ {
+				sum -= l.M[i*n+j] * y[j]
+			}
+			y[i] = sum
+		}
+		//Now let solve U*xk = y
+		for i := n - 1; i >= 0; i-- {
+			sum := y[i]
+			for j := i + 1; j < n; j++ {
+				sum -= u.M[i*n+j] * x.M[j*n+k]
+			}
+			x.M[i*n+k] = sum / u.M[i*n+i]
 		}
 	}
 
-	return x, nil
+	norm := m.norm1() * x.norm1()
+	if norm == 0.0 || math.IsNaN(norm) || math.IsInf(norm, 0) {
+		return x, 0.0, nil
+	}
+	return x, 1.0 / norm, nil
+}
+
+/*
+norm1 computes the 1-norm of the matrix, i.e. the maximum absolute column sum
+*/
+func (m Matrix) norm1() float64 {
+	var max float64
+	var i, j uint
+	for j = 0; j < m.NumberOfColumns; j++ {
+		sum := 0.0
+		for i = 0; i < m.NumberOfRows; i++ {
+			sum += math.Abs(m.M[i*m.NumberOfColumns+j])
+		}
+		if sum > max || math.IsNaN(sum) {
+			max = sum
+		}
+	}
+	return max
 }
 
 /*
@@ -506,3 +677,70 @@ func (m Matrix) Cofactor() (*Matrix, error) {
 
 	return nil, nil
 }
+
+/*
+Diagonal is a method to retrieve the k-th diagonal of the matrix: k = 0 is the
+main diagonal, k > 0 a diagonal above it and k < 0 a diagonal below it. For a
+matrix:
+
+	[1 2 3]
+	[4 5 6]
+
+Diagonal(1) returns [2 6] and Diagonal(-1) returns [4].
+If there is no such diagonal, an empty slice is returned.
+First parameter is the index of the diagonal
+*/
+func (m Matrix) Diagonal(k int) []float64 {
+	row, col := diagonalStart(k)
+	diag := []float64{}
+	for ; row < m.NumberOfRows && col < m.NumberOfColumns; row, col = row+1, col+1 {
+		diag = append(diag, m.M[row*m.NumberOfColumns+col])
+	}
+	return diag
+}
+
+/*
+SetDiagonal is a method to set the values of the k-th diagonal (see Diagonal for
+the meaning of k). The number of values must match the length of the diagonal.
+First parameter is the index of the diagonal
+Second parameter are the values
+*/
+func (m *Matrix) SetDiagonal(k int, values []float64) error {
+	if len(values) != len(m.Diagonal(k)) {
+		return &MathError{
+			code: errorDimensionMismatch,
+		}
+	}
+	row, col := diagonalStart(k)
+	for _, v := range values {
+		m.M[row*m.NumberOfColumns+col] = v
+		row++
+		col++
+	}
+	return nil
+}
+
+/*
+AntiTrace is a method to compute the sum of the elements on the anti-diagonal
+of a square matrix, i.e. from the top right corner to the bottom left one.
+*/
+func (m Matrix) AntiTrace() (float64, error) {
+	if !m.IsSquare() {
+		return 0.0, &MathError{
+			code: errorNonSquareMatrix,
+		}
+	}
+	var trace float64
+	var row uint
+	for row = 0; row < m.NumberOfRows; row++ {
+		trace += m.Get(row, m.NumberOfColumns-1-row)
+	}
+	return trace, nil
+}
+
+func diagonalStart(k int) (uint, uint) {
+	if k >= 0 {
+		return 0, uint(k)
+	}
+	return uint(-k), 0
+}
@@ -0,0 +1,269 @@
+package advmath
+
+/*
+Matrix32 is the single precision counterpart of Matrix. It stores its values
+as float32 which halves the memory used and makes it easy to hand the data to
+libraries (GPU, graphics) that only work with single precision.
+The API is the same as Matrix, the heavy computations (LU decomposition,
+determinant, inverse) are done in double precision and rounded back to float32.
+*/
+type Matrix32 struct {
+	NumberOfRows    uint
+	NumberOfColumns uint
+	M               []float32
+}
+
+/*
+NewMatrix32 is a method to create a new single precision matrix. By default
+when created the matrix is filled with float32 default value (which is 0.0)
+First parameter is the number of rows
+Second parameter is the number of columns
+*/
+func NewMatrix32(rows, cols uint) *Matrix32 {
+	m := new(Matrix32)
+	m.NumberOfRows = rows
+	m.NumberOfColumns = cols
+	m.M = make([]float32, rows*cols)
+	return m
+}
+
+/*
+NewIdentity32 is a method to create a single precision identity square matrix.
+First parameter is the number of rows and columns
+*/
+func NewIdentity32(rows uint) *Matrix32 {
+	i := NewMatrix32(rows, rows)
+
+	var j uint
+	for j = 0; j < rows; j++ {
+		i.M[j*i.NumberOfColumns+j] = 1.0
+	}
+
+	return i
+}
+
+/*
+ToMatrix32 is a method to convert a matrix to its single precision version.
+Values are rounded to the nearest float32.
+*/
+func (m Matrix) ToMatrix32() *Matrix32 {
+	ret := NewMatrix32(m.NumberOfRows, m.NumberOfColumns)
+	for i, v := range m.M {
+		ret.M[i] = float32(v)
+	}
+	return ret
+}
+
+/*
+ToMatrix is a method to convert a single precision matrix to a double precision
+Matrix. The conversion is exact.
+*/
+func (m Matrix32) ToMatrix() *Matrix {
+	ret := NewMatrix(m.NumberOfRows, m.NumberOfColumns)
+	for i, v := range m.M {
+		ret.M[i] = float64(v)
+	}
+	return ret
+}
+
+/*
+IsSquare is a method to find if a matrix is a square matrix or not.
+*/
+func (m Matrix32) IsSquare() bool {
+	return m.NumberOfColumns == m.NumberOfRows
+}
+
+/*
+Get is a method to retrieve the content of a matrix at the given
+row and column.
+It returns the value found.
+*/
+func (m Matrix32) Get(row uint, column uint) float32 {
+	return m.M[row*m.NumberOfColumns+column]
+}
+
+/*
+GetRow is method used to return the specified row of a matrix. It takes the
+row number as an input. Note that rowNumber should start at 0.
+*/
+func (m Matrix32) GetRow(rowNumber uint) []float32 {
+	row := make([]float32, m.NumberOfColumns)
+	copy(row, m.M[rowNumber*m.NumberOfColumns:(rowNumber+1)*m.NumberOfColumns])
+	return row
+}
+
+/*
+GetColumn is a method used to retrieve a specific column of the matrix.
+First parameter is the column number
+*/
+func (m Matrix32) GetColumn(colNumber uint) []float32 {
+	col := make([]float32, m.NumberOfRows)
+
+	var rows uint
+	for rows = 0; rows < m.NumberOfRows; rows++ {
+		col[rows] = m.M[rows*m.NumberOfColumns+colNumber]
+	}
+
+	return col
+}
+
+/*
+Set is a method to set the value at the given row and column
+it doesn't return anything but changes the underlying matrix.
+*/
+func (m *Matrix32) Set(row uint, column uint, value float32) {
+	m.M[row*m.NumberOfColumns+column] = value
+}
+
+/*
+SetRow is a method to set the value at the given row, it changes the
+underlying matrix and returns it.
+*/
+func (m *Matrix32) SetRow(rowNumber uint, row []float32) *Matrix32 {
+	copy(m.M[rowNumber*m.NumberOfColumns:(rowNumber+1)*m.NumberOfColumns], row)
+	return m
+}
+
+/*
+Multiply is a method to multiply the matrix by the given matrix (A*B).
+The products are accumulated in double precision before being stored.
+First parameter is the matrix used for the multiplication
+*/
+func (m Matrix32) Multiply(in *Matrix32) (*Matrix32, error) {
+	if m.NumberOfColumns != in.NumberOfRows {
+		return nil, &MathError{
+			code: errorCannotMultiply,
+		}
+	}
+
+	result := NewMatrix32(m.NumberOfRows, in.NumberOfColumns)
+
+	var i, j, k uint
+	for i = 0; i < m.NumberOfRows; i++ {
+		for j = 0; j < in.NumberOfColumns; j++ {
+			sum := 0.0
+			for k = 0; k < m.NumberOfColumns; k++ {
+				sum += float64(m.M[i*m.NumberOfColumns+k]) * float64(in.M[k*in.NumberOfColumns+j])
+			}
+			result.M[i*result.NumberOfColumns+j] = float32(sum)
+		}
+	}
+	return result, nil
+}
+
+/*
+ScalarMultiply is a method to multiply a matrix by a scalar.
+First parameter is a scalar used to multiply
+*/
+func (m Matrix32) ScalarMultiply(scal float32) *Matrix32 {
+	result := NewMatrix32(m.NumberOfRows, m.NumberOfColumns)
+	for i, v := range m.M {
+		result.M[i] = v * scal
+	}
+	return result
+}
+
+/*
+Add is a method to add a matrix to another matrix
+First parameter is a matrix to add
+*/
+func (m Matrix32) Add(in *Matrix32) (*Matrix32, error) {
+	if in.NumberOfColumns != m.NumberOfColumns || in.NumberOfRows != m.NumberOfRows {
+		return nil, &MathError{
+			code: errorCannotAdd,
+		}
+	}
+
+	result := NewMatrix32(m.NumberOfRows, m.NumberOfColumns)
+	for i := range m.M {
+		result.M[i] = m.M[i] + in.M[i]
+	}
+
+	return result, nil
+}
+
+/*
+Subtract is a method to subtract a matrix with another one.
+First parameter is the matrix to subtract
+*/
+func (m Matrix32) Subtract(in *Matrix32) (*Matrix32, error) {
+	if in.NumberOfColumns != m.NumberOfColumns || in.NumberOfRows != m.NumberOfRows {
+		return nil, &MathError{
+			code: errorCannotAdd,
+		}
+	}
+	return m.Add(in.Neg())
+}
+
+/*
+Neg is a method to return the negative version of a matrix.
+*/
+func (m Matrix32) Neg() *Matrix32 {
+	return m.ScalarMultiply(-1.0)
+}
+
+/*
+Trace is a method to compute the trace of a square matrix. The sum is done in
+double precision. It returns an error for a non square matrix.
+*/
+func (m Matrix32) Trace() (float32, error) {
+	if !m.IsSquare() {
+		return 0.0, &MathError{
+			code: errorNonSquareMatrix,
+		}
+	}
+	var trace float64
+	var row uint
+	for row = 0; row < m.NumberOfRows; row++ {
+		trace += float64(m.Get(row, row))
+	}
+	return float32(trace), nil
+}
+
+/*
+Transpose is a method to compute the transposition of a matrix.
+*/
+func (m Matrix32) Transpose() (*Matrix32, error) {
+	ret := NewMatrix32(m.NumberOfColumns, m.NumberOfRows)
+
+	var i, j uint
+	for i = 0; i < m.NumberOfRows; i++ {
+		for j = 0; j < m.NumberOfColumns; j++ {
+			ret.M[j*ret.NumberOfColumns+i] = m.M[i*m.NumberOfColumns+j]
+		}
+	}
+	return ret, nil
+}
+
+/*
+LUDecomposition is a method to create the LU decomposition of a square matrix.
+See Matrix.LUDecomposition, the computation is done in double precision.
+*/
+func (m Matrix32) LUDecomposition() (*Matrix32, *Matrix32, error) {
+	l, u, err := m.ToMatrix().LUDecomposition()
+	if err != nil {
+		return nil, nil, err
+	}
+	return l.ToMatrix32(), u.ToMatrix32(), nil
+}
+
+/*
+Determinant is a method to compute the determinant of a square matrix.
+See Matrix.Determinant, the computation is done in double precision.
+*/
+func (m Matrix32) Determinant() (float32, error) {
+	det, err := m.ToMatrix().Determinant()
+	return float32(det), err
+}
+
+/*
+Inverse is a method to compute the inverse of a square matrix.
+See Matrix.Inverse, the computation is done in double precision.
+*/
+func (m Matrix32) Inverse() (*Matrix32, error) {
+	inv, err := m.ToMatrix().Inverse()
+	if err != nil {
+		return nil, err
+	}
+	return inv.ToMatrix32(), nil
+}
@@ -0,0 +1,251 @@
+package advmath
+
+import (
+	"math"
+)
+
+/*
+Minimize1DMethod is the method used by Minimize1D
+*/
+type Minimize1DMethod int
+
+const (
+	//GoldenSection shrinks the interval by the golden ratio at each iteration, it is
+	//slow (linear convergence) but only needs f to be unimodal
+	GoldenSection Minimize1DMethod = iota
+	//BrentMinimization combines parabolic interpolation, which converges much faster
+	//on smooth functions, with golden section steps when the parabola is not trusted
+	BrentMinimization
+)
+
+/*
+MinimumResult holds the outcome of a minimization
+*/
+type MinimumResult struct {
+	//X is the best point found
+	X float64
+	//Value is f(X)
+	Value float64
+	//Iterations is the number of iterations done
+	Iterations int
+	//Converged tells if the precision required was met
+	Converged bool
+}
+
+/*
+invPhi is 1/phi = (sqrt(5)-1)/2, phi being the golden ratio
+*/
+var invPhi = (math.Sqrt(5) - 1) / 2
+
+/*
+minimizeTolerance is the tolerance on x used when the one given is 0, about the
+square root of the machine epsilon since f is flat near a minimum
+*/
+const minimizeTolerance = 1e-8
+
+/*
+BracketMinimum looks for three points a < b < c with f(b) <= f(a) and f(b) <= f(c),
+so that a minimum is between a and c, starting from init and going downhill with steps
+growing by the golden ratio. The first step is 0.1*max(1, |init|).
+
+First param init is where the search starts
+Second param f is the function to minimize
+return the three points, and an error if f is not finite or keeps decreasing (e.g. it
+is not bounded below)
+*/
+func BracketMinimum(init float64, f F) (float64, float64, float64, error) {
+	const maxIterations = 200
+	growth := 1 / invPhi
+	finite := func(v float64) bool {
+		return !math.IsNaN(v) && !math.IsInf(v, 0)
+	}
+
+	a, b := init, init+0.1*math.Max(1, math.Abs(init))
+	fa, fb := f(a), f(b)
+	if fb > fa {
+		//Downhill is on the other side
+		a, b = b, a
+		fa, fb = fb, fa
+	}
+	c := b + growth*(b-a)
+	fc := f(c)
+	for i := 0; i < maxIterations; i++ {
+		if !finite(fa) || !finite(fb) || !finite(fc) {
+			return 0, 0, 0, &MathError{
+				s: "Cannot bracket a minimum of a function which is not finite",
+			}
+		}
+		if fc >= fb {
+			if a > c {
+				a, c = c, a
+			}
+			return a, b, c, nil
+		}
+		a, b, fb = b, c, fc
+		c = b + growth*(b-a)
+		fc = f(c)
+	}
+	return 0, 0, 0, &MathError{
+		code: errorDiverged,
+	}
+}
+
+/*
+Minimize1D is a method to find a minimum of f between a and b. If f has several local
+minima in the interval, any of them can be returned; if f decreases up to an end, the
+result is close to this end.
+
+First param a is the lower bound of the interval
+Second param b is the upper bound of the interval
+Third param f is the function to minimize
+Fourth param method is GoldenSection or BrentMinimization
+Fifth param tolerance is the precision on x, relative to max(1, |x|), 1e-8 when it is
+0 (smaller values are useless since f is flat near the minimum)
+return the minimum found, and an error if the precision was not met in 500 iterations
+*/
+func Minimize1D(a, b float64, f F, method Minimize1DMethod, tolerance float64) (MinimumResult, error) {
+	if a > b {
+		a, b = b, a
+	}
+	if tolerance <= 0 {
+		tolerance = minimizeTolerance
+	}
+	if method == BrentMinimization {
+		return brentMinimize(a, b, f, tolerance)
+	}
+	return goldenSection(a, b, f, tolerance)
+}
+
+/*
+Minimize1DFrom is the same as Minimize1D but the interval is found by BracketMinimum
+starting from init
+*/
+func Minimize1DFrom(init float64, f F, method Minimize1DMethod, tolerance float64) (MinimumResult, error) {
+	a, _, c, err := BracketMinimum(init, f)
+	if err != nil {
+		return MinimumResult{X: init, Value: f(init)}, err
+	}
+	return Minimize1D(a, c, f, method, tolerance)
+}
+
+func goldenSection(a, b float64, f F, tolerance float64) (MinimumResult, error) {
+	const maxIterations = 500
+	x1, x2 := b-invPhi*(b-a), a+invPhi*(b-a)
+	f1, f2 := f(x1), f(x2)
+	result := MinimumResult{}
+	for ; result.Iterations < maxIterations; result.Iterations++ {
+		if b-a <= 2*tolerance*math.Max(1, math.Abs(x1)) {
+			result.Converged = true
+			break
+		}
+		if f1 < f2 {
+			b, x2, f2 = x2, x1, f1
+			x1 = b - invPhi*(b-a)
+			f1 = f(x1)
+		} else {
+			a, x1, f1 = x1, x2, f2
+			x2 = a + invPhi*(b-a)
+			f2 = f(x2)
+		}
+	}
+	result.X, result.Value = x1, f1
+	if f2 < f1 {
+		result.X, result.Value = x2, f2
+	}
+	if !result.Converged {
+		return result, &MathError{
+			code: errorNotConverged,
+		}
+	}
+	return result, nil
+}
+
+/*
+brentMinimize is Brent's method from 'Algorithms for Minimization without Derivatives':
+x is the best point, w the second best and v the previous value of w, the parabola
+through them gives the next point when it falls in the interval and the step is less
+than half the step before the last one
+*/
+func brentMinimize(a, b float64, f F, tolerance float64) (MinimumResult, error) {
+	const maxIterations = 500
+	cgold := 1 - invPhi
+	x := a + cgold*(b-a)
+	w, v := x, x
+	fx := f(x)
+	fw, fv := fx, fx
+	//d is the last step, e the one before
+	var d, e float64
+	result := MinimumResult{}
+	for ; result.Iterations < maxIterations; result.Iterations++ {
+		xm := (a + b) / 2
+		tol1 := tolerance * math.Max(1, math.Abs(x))
+		tol2 := 2 * tol1
+		if math.Abs(x-xm) <= tol2-(b-a)/2 {
+			result.Converged = true
+			break
+		}
+
+		golden := true
+		if math.Abs(e) > tol1 {
+			r := (x - w) * (fx - fv)
+			q := (x - v) * (fx - fw)
+			p := (x-v)*q - (x-w)*r
+			q = 2 * (q - r)
+			if q > 0 {
+				p = -p
+			}
+			q = math.Abs(q)
+			if math.Abs(p) < math.Abs(q*e/2) && p > q*(a-x) && p < q*(b-x) {
+				//Parabolic step
+				e = d
+				d = p / q
+				golden = false
+				if u := x + d; u-a < tol2 || b-u < tol2 {
+					d = math.Copysign(tol1, xm-x)
+				}
+			}
+		}
+		if golden {
+			if x >= xm {
+				e = a - x
+			} else {
+				e = b - x
+			}
+			d = cgold * e
+		}
+
+		u := x + d
+		if math.Abs(d) < tol1 {
+			u = x + math.Copysign(tol1, d)
+		}
+		fu := f(u)
+		if fu <= fx {
+			if u >= x {
+				a = x
+			} else {
+				b = x
+			}
+			v, w, x = w, x, u
+			fv, fw, fx = fw, fx, fu
+			continue
+		}
+		if u < x {
+			a = u
+		} else {
+			b = u
+		}
+		if fu <= fw || w == x {
+			v, w = w, u
+			fv, fw = fw, fu
+		} else if fu <= fv || v == x || v == w {
+			v, fv = u, fu
+		}
+	}
+	result.X, result.Value = x, fx
+	if !result.Converged {
+		return result, &MathError{
+			code: errorNotConverged,
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,198 @@
+package advmath
+
+import (
+	"math"
+	"sort"
+)
+
+/*
+vertex is a point of the Nelder-Mead simplex with its value
+*/
+type vertex struct {
+	x     []float64
+	value float64
+}
+
+/*
+NelderMead is a method to find a local minimum of f with the Nelder-Mead simplex
+algorithm: a simplex of n+1 points is reflected, expanded, contracted or shrunk
+towards the best point according to the values of f at its vertices. It doesn't use
+derivatives, so it works on non-smooth or noisy functions, but it is slow in high
+dimension (more than about 10 variables). The simplex can collapse before reaching a
+minimum, so once it has converged the method is restarted from the best point with a
+new simplex, until a restart doesn't improve f (at most 5 restarts).
+
+First parameter f is the function to minimize, a NaN value is taken as +Inf so that f
+can be undefined out of its domain
+Second parameter x0 is the starting point
+Third parameter step is the size of the initial simplex, the vertex i being x0 with
+step*max(1, |x0[i]|) added to x0[i], 0.1 when it is 0
+Fourth parameter options are the stopping criteria and the callback: a run stops when
+the simplex is smaller than StepTolerance*(1+|x|) or, when ValueTolerance is set, when
+the values at the vertices differ by at most ValueTolerance*(1+|f|). Gradient and
+GradientTolerance are not used, MaxIterations is 1000*n by default for all the runs.
+The bounds and the equality constraints are used as by the other methods
+It returns the best point, and an error if the iterations were exhausted before
+convergence
+*/
+func NelderMead(f Fn, x0 []float64, step float64, options OptimizeOptions) (OptimizeResult, error) {
+	return minimizeConstrained(f, x0, options, 0, func(f Fn, x0 []float64, o OptimizeOptions) (OptimizeResult, error) {
+		return nelderMead(f, x0, step, o)
+	})
+}
+
+/*
+nelderMead is NelderMead without the equality constraints, the points of the simplex
+are projected on the bounds
+*/
+func nelderMead(f Fn, x0 []float64, step float64, options OptimizeOptions) (OptimizeResult, error) {
+	const maxRestarts = 5
+	n := len(x0)
+	if step <= 0 {
+		step = 0.1
+	}
+	o := options.withDefaults(1000 * n)
+	if o.StepTolerance <= 0 {
+		o.StepTolerance = 1e-8
+	}
+	obj := newObjective(func(x []float64) float64 {
+		if v := f(x); !math.IsNaN(v) {
+			return v
+		}
+		return math.Inf(1)
+	}, o)
+
+	result := OptimizeResult{}
+	best := vertex{x: append([]float64(nil), x0...)}
+	best.value = obj.value(best.x)
+	for restart := 0; restart <= maxRestarts; restart++ {
+		v, converged := nelderMeadRun(obj, best, step, o, &result)
+		improved := v.value < best.value
+		if improved || restart == 0 {
+			best = v
+		}
+		if !converged {
+			result.X, result.Value, result.Evaluations = best.x, best.value, obj.evaluations
+			return result, &MathError{
+				code: errorNotConverged,
+			}
+		}
+		if restart > 0 && !improved {
+			break
+		}
+	}
+	result.X, result.Value, result.Evaluations = best.x, best.value, obj.evaluations
+	result.Converged = true
+	return result, nil
+}
+
+/*
+nelderMeadRun runs the simplex algorithm from a simplex built around start, it returns
+the best vertex and tells if the simplex converged before the iterations of result
+reached the maximum
+*/
+func nelderMeadRun(obj *objective, start vertex, step float64, o OptimizeOptions, result *OptimizeResult) (vertex, bool) {
+	const (
+		reflection  = 1.0
+		expansion   = 2.0
+		contraction = 0.5
+		shrink      = 0.5
+	)
+	n := len(start.x)
+	simplex := make([]vertex, n+1)
+	simplex[0] = start
+	for i := 0; i < n; i++ {
+		x := append([]float64(nil), start.x...)
+		delta := step * math.Max(1, math.Abs(x[i]))
+		if o.Upper != nil && x[i]+delta > o.Upper[i] {
+			//Towards the inside of the bounds
+			delta = -delta
+		}
+		x[i] += delta
+		o.project(x)
+		simplex[i+1] = vertex{x: x, value: obj.value(x)}
+	}
+	//point returns centroid + t*(centroid - worst)
+	point := func(centroid []float64, t float64) vertex {
+		x := make([]float64, n)
+		for i := range x {
+			x[i] = centroid[i] + t*(centroid[i]-simplex[n].x[i])
+		}
+		o.project(x)
+		return vertex{x: x, value: obj.value(x)}
+	}
+
+	for result.Iterations < o.MaxIterations {
+		sort.SliceStable(simplex, func(a, b int) bool { return simplex[a].value < simplex[b].value })
+		if nelderMeadConverged(simplex, o) {
+			return simplex[0], true
+		}
+		result.Iterations++
+
+		//Centroid of all the vertices but the worst
+		centroid := make([]float64, n)
+		for _, v := range simplex[:n] {
+			for i := range centroid {
+				centroid[i] += v.x[i] / float64(n)
+			}
+		}
+		reflected := point(centroid, reflection)
+		switch {
+		case reflected.value < simplex[0].value:
+			if expanded := point(centroid, expansion); expanded.value < reflected.value {
+				simplex[n] = expanded
+			} else {
+				simplex[n] = reflected
+			}
+		case reflected.value < simplex[n-1].value:
+			simplex[n] = reflected
+		default:
+			//Outside contraction if the reflected point is better than the worst,
+			//inside contraction otherwise
+			var contracted vertex
+			if reflected.value < simplex[n].value {
+				contracted = point(centroid, contraction)
+			} else {
+				contracted = point(centroid, -contraction)
+			}
+			if contracted.value < math.Min(reflected.value, simplex[n].value) {
+				simplex[n] = contracted
+				break
+			}
+			for k := 1; k <= n; k++ {
+				for i := range simplex[k].x {
+					simplex[k].x[i] = simplex[0].x[i] + shrink*(simplex[k].x[i]-simplex[0].x[i])
+				}
+				simplex[k].value = obj.value(simplex[k].x)
+			}
+		}
+
+		if o.OnIteration != nil {
+			best := simplex[0]
+			for _, v := range simplex {
+				if v.value < best.value {
+					best = v
+				}
+			}
+			o.OnIteration(OptimizeState{Iteration: result.Iterations, X: best.x, Value: best.value})
+		}
+	}
+	sort.SliceStable(simplex, func(a, b int) bool { return simplex[a].value < simplex[b].value })
+	return simplex[0], false
+}
+
+/*
+nelderMeadConverged tells if the simplex, sorted by value, is small enough
+*/
+func nelderMeadConverged(simplex []vertex, o OptimizeOptions) bool {
+	best := simplex[0]
+	if o.ValueTolerance > 0 && simplex[len(simplex)-1].value-best.value <= o.ValueTolerance*(1+math.Abs(best.value)) {
+		return true
+	}
+	for _, v := range simplex[1:] {
+		if !o.stepConverged(best.x, v.x) {
+			return false
+		}
+	}
+	return true
+}
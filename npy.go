@@ -0,0 +1,198 @@
+package advmath
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// npyMagic is the prefix of every .npy file
+const npyMagic = "\x93NUMPY"
+
+// maxNpyElements is the largest number of elements LoadNpy accepts (2 GiB of data),
+// so that a corrupted header can't ask for an unbounded allocation
+const maxNpyElements = 1 << 28
+
+// maxNpyHeader is the largest header LoadNpy accepts (1 MiB), a real header describing
+// a 2-D array is less than a few hundred bytes
+const maxNpyHeader = 1 << 20
+
+/*
+LoadNpy is a method to read a matrix saved with numpy.save. Only 2-D arrays of
+float64 (dtype '<f8' or '>f8') stored in C order are supported, with at most 2^28
+elements.
+First parameter is the reader holding the .npy data
+*/
+func LoadNpy(r io.Reader) (*Matrix, error) {
+	prefix := make([]byte, 8)
+	if _, err := io.ReadFull(r, prefix); err != nil {
+		return nil, err
+	}
+	if string(prefix[:6]) != npyMagic {
+		return nil, &MathError{
+			s: "Invalid npy data, magic string not found",
+		}
+	}
+
+	//Version 1 uses 2 bytes for the header length, versions 2 and 3 use 4 bytes
+	var headerLength uint32
+	switch prefix[6] {
+	case 1:
+		var l uint16
+		if err := binary.Read(r, binary.LittleEndian, &l); err != nil {
+			return nil, err
+		}
+		headerLength = uint32(l)
+	case 2, 3:
+		if err := binary.Read(r, binary.LittleEndian, &headerLength); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, &MathError{
+			s: fmt.Sprintf("Unsupported npy version %d.%d", prefix[6], prefix[7]),
+		}
+	}
+
+	if headerLength > maxNpyHeader {
+		return nil, &MathError{
+			s: fmt.Sprintf("The npy header length %d is too large", headerLength),
+		}
+	}
+	header := make([]byte, headerLength)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, err
+	}
+	order, rows, cols, err := parseNpyHeader(string(header))
+	if err != nil {
+		return nil, err
+	}
+
+	if cols != 0 && rows > maxNpyElements/cols {
+		return nil, &MathError{
+			s: fmt.Sprintf("The npy shape (%d, %d) is too large", rows, cols),
+		}
+	}
+
+	//Read by chunks so that the memory grows with the data actually present
+	const chunk = 1 << 16
+	values := make([]float64, 0, minUint(rows*cols, chunk))
+	for remaining := rows * cols; remaining > 0; {
+		n := minUint(remaining, chunk)
+		block := make([]float64, n)
+		if err := binary.Read(r, order, block); err != nil {
+			return nil, err
+		}
+		values = append(values, block...)
+		remaining -= n
+	}
+	m := &Matrix{NumberOfRows: rows, NumberOfColumns: cols, M: values}
+	return m, nil
+}
+
+/*
+SaveNpy is a method to write the matrix in the .npy format (version 1.0) so it
+can be loaded with numpy.load. The data is written as little endian float64
+in C order.
+First parameter is the writer
+*/
+func (m *Matrix) SaveNpy(w io.Writer) error {
+	header := fmt.Sprintf("{'descr': '<f8', 'fortran_order': False, 'shape': (%d, %d), }", m.NumberOfRows, m.NumberOfColumns)
+	//The header is padded with spaces and ends with a new line so that the
+	//data starts on a 64 bytes boundary
+	total := len(npyMagic) + 4 + len(header) + 1
+	if total%64 != 0 {
+		header += strings.Repeat(" ", 64-total%64)
+	}
+	header += "\n"
+
+	var buf bytes.Buffer
+	buf.WriteString(npyMagic)
+	buf.Write([]byte{1, 0})
+	binary.Write(&buf, binary.LittleEndian, uint16(len(header)))
+	buf.WriteString(header)
+	if _, err := w.Write(buf.Bytes()); err != nil {
+		return err
+	}
+
+	data := make([]byte, 8*len(m.M))
+	for i, v := range m.M {
+		binary.LittleEndian.PutUint64(data[8*i:], math.Float64bits(v))
+	}
+	_, err := w.Write(data)
+	return err
+}
+
+/*
+parseNpyHeader reads the python dictionary describing the array and returns
+the byte order and the shape of the matrix.
+*/
+func parseNpyHeader(header string) (binary.ByteOrder, uint, uint, error) {
+	value := func(key string) string {
+		i := strings.Index(header, "'"+key+"'")
+		if i < 0 {
+			return ""
+		}
+		v := strings.TrimSpace(header[i+len(key)+2:])
+		return strings.TrimSpace(strings.TrimPrefix(v, ":"))
+	}
+
+	var order binary.ByteOrder
+	descr := value("descr")
+	switch {
+	case strings.HasPrefix(descr, "'<f8'"):
+		order = binary.LittleEndian
+	case strings.HasPrefix(descr, "'>f8'"):
+		order = binary.BigEndian
+	default:
+		return nil, 0, 0, &MathError{
+			s: "Unsupported npy dtype, only float64 is supported",
+		}
+	}
+
+	if !strings.HasPrefix(value("fortran_order"), "False") {
+		return nil, 0, 0, &MathError{
+			s: "Unsupported npy layout, only C order is supported",
+		}
+	}
+
+	shape := value("shape")
+	end := strings.Index(shape, ")")
+	if !strings.HasPrefix(shape, "(") || end < 0 {
+		return nil, 0, 0, &MathError{
+			s: "Invalid npy header, shape not found",
+		}
+	}
+	var dims []uint
+	for _, d := range strings.Split(shape[1:end], ",") {
+		d = strings.TrimSpace(d)
+		if d == "" {
+			continue
+		}
+		n, err := strconv.ParseUint(d, 10, 64)
+		if err != nil {
+			return nil, 0, 0, err
+		}
+		dims = append(dims, uint(n))
+	}
+	if len(dims) != 2 {
+		return nil, 0, 0, &MathError{
+			s: "Unsupported npy shape, only 2-D arrays are supported",
+		}
+	}
+
+	return order, dims[0], dims[1], nil
+}
+
+/*
+minUint returns the smallest of a and b
+*/
+func minUint(a, b uint) uint {
+	if a < b {
+		return a
+	}
+	return b
+}
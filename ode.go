@@ -0,0 +1,622 @@
+package advmath
+
+import (
+	"math"
+)
+
+/*
+stepper computes y(t+h) from y(t) with a one step method
+*/
+type stepper func(f ODE, t float64, h float64, y []float64) ([]float64, error)
+
+/*
+evaluateODE calls f and checks the length of the result
+*/
+func evaluateODE(f ODE, t float64, y []float64) ([]float64, error) {
+	dy := f(t, y)
+	if len(dy) != len(y) {
+		return nil, &MathError{
+			code: errorDimensionMismatch,
+		}
+	}
+	return dy, nil
+}
+
+/*
+axpy returns y + a*x
+*/
+func axpy(a float64, x []float64, y []float64) []float64 {
+	r := make([]float64, len(y))
+	for i := range y {
+		r[i] = y[i] + a*x[i]
+	}
+	return r
+}
+
+/*
+finiteVector tells if all the values are finite
+*/
+func finiteVector(v []float64) bool {
+	for _, x := range v {
+		if math.IsNaN(x) || math.IsInf(x, 0) {
+			return false
+		}
+	}
+	return true
+}
+
+func eulerStep(f ODE, t float64, h float64, y []float64) ([]float64, error) {
+	k, err := evaluateODE(f, t, y)
+	if err != nil {
+		return nil, err
+	}
+	return axpy(h, k, y), nil
+}
+
+func rk4Step(f ODE, t float64, h float64, y []float64) ([]float64, error) {
+	k1, err := evaluateODE(f, t, y)
+	if err != nil {
+		return nil, err
+	}
+	k2, err := evaluateODE(f, t+h/2, axpy(h/2, k1, y))
+	if err != nil {
+		return nil, err
+	}
+	k3, err := evaluateODE(f, t+h/2, axpy(h/2, k2, y))
+	if err != nil {
+		return nil, err
+	}
+	k4, err := evaluateODE(f, t+h, axpy(h, k3, y))
+	if err != nil {
+		return nil, err
+	}
+	next := make([]float64, len(y))
+	for i := range y {
+		next[i] = y[i] + h/6*(k1[i]+2*k2[i]+2*k3[i]+k4[i])
+	}
+	return next, nil
+}
+
+/*
+fixedStep integrates with n steps of the same size, the trajectory has n+1 points
+*/
+func fixedStep(f ODE, t0 float64, y0 []float64, t1 float64, n int, step stepper) (*ODEResult, error) {
+	if n <= 0 || len(y0) == 0 {
+		return nil, &MathError{
+			code: errorDimensionMismatch,
+		}
+	}
+	h := (t1 - t0) / float64(n)
+	r := newODEResult(f, n+1)
+	r.Times = append(r.Times, t0)
+	r.States = append(r.States, append([]float64(nil), y0...))
+	y := r.States[0]
+	for i := 1; i <= n; i++ {
+		next, err := step(f, r.Times[i-1], h, y)
+		if err != nil {
+			return r, err
+		}
+		if !finiteVector(next) {
+			return r, &MathError{
+				code: errorDiverged,
+			}
+		}
+		//Computed from i to avoid accumulating the round-off on t
+		t := t0 + float64(i)*h
+		if i == n {
+			t = t1
+		}
+		r.Times = append(r.Times, t)
+		r.States = append(r.States, next)
+		y = next
+	}
+	return r, nil
+}
+
+/*
+Euler is a method to solve the initial value problem y' = f(t, y), y(t0) = y0 with the
+explicit Euler method y(t+h) = y(t) + h f(t, y(t)). It is only first order, so it needs
+very small steps, and is mostly useful to check the other methods.
+
+First parameter f is the right hand side of the system
+Second parameter t0 is the initial time
+Third parameter y0 is the initial state
+Fourth parameter t1 is the final time, it can be lower than t0
+Fifth parameter n is the number of steps
+It returns the solution at the n+1 times, and an error if f doesn't
+return a slice of the length of y0 or if the solution is not finite anymore (the
+solution is then the part computed before)
+*/
+func Euler(f ODE, t0 float64, y0 []float64, t1 float64, n int) (*ODEResult, error) {
+	return fixedStep(f, t0, y0, t1, n, eulerStep)
+}
+
+/*
+RK4 is a method to solve the initial value problem y' = f(t, y), y(t0) = y0 with the
+classical fourth order Runge-Kutta method: the error is divided by 16 when the number
+of steps is doubled.
+
+First parameter f is the right hand side of the system
+Second parameter t0 is the initial time
+Third parameter y0 is the initial state
+Fourth parameter t1 is the final time, it can be lower than t0
+Fifth parameter n is the number of steps
+It returns the solution at the n+1 times, and an error if f doesn't
+return a slice of the length of y0 or if the solution is not finite anymore (the
+solution is then the part computed before)
+*/
+func RK4(f ODE, t0 float64, y0 []float64, t1 float64, n int) (*ODEResult, error) {
+	return fixedStep(f, t0, y0, t1, n, rk4Step)
+}
+
+/*
+ODEOptions holds the settings of the adaptive ODE solvers. The zero value uses the
+defaults given for each field.
+*/
+type ODEOptions struct {
+	//RelTol is the relative tolerance on each component, 1e-6 when it is 0
+	RelTol float64
+	//AbsTol is the absolute tolerance on each component, 1e-9 when it is 0
+	AbsTol float64
+	//InitialStep is the size of the first step tried, estimated from f when it is 0
+	InitialStep float64
+	//MinStep is the smallest step allowed, the solver fails if the tolerance needs a
+	//smaller one. When it is 0, only steps too small to change t are refused
+	MinStep float64
+	//MaxStep is the largest step allowed, |t1 - t0| when it is 0
+	MaxStep float64
+	//MaxSteps is the maximum number of steps (accepted or rejected), 100000 when it is 0
+	MaxSteps int
+	//Events are the events whose zeros are looked for at each step (see ODEEvent)
+	Events []ODEEvent
+}
+
+/*
+withDefaults returns the options with the defaults for the interval [t0, t1]
+*/
+func (o ODEOptions) withDefaults(t0, t1 float64) ODEOptions {
+	if o.RelTol <= 0 {
+		o.RelTol = 1e-6
+	}
+	if o.AbsTol <= 0 {
+		o.AbsTol = 1e-9
+	}
+	if o.MaxStep <= 0 {
+		o.MaxStep = math.Abs(t1 - t0)
+	}
+	if o.MaxSteps <= 0 {
+		o.MaxSteps = 100000
+	}
+	return o
+}
+
+/*
+errorNorm is the root mean square of the error scaled by the tolerance, the step is
+accepted when it is at most 1
+*/
+func (o ODEOptions) errorNorm(e, y, next []float64) float64 {
+	var sum float64
+	for i := range e {
+		scale := o.AbsTol + o.RelTol*math.Max(math.Abs(y[i]), math.Abs(next[i]))
+		sum += (e[i] / scale) * (e[i] / scale)
+	}
+	return math.Sqrt(sum / float64(len(e)))
+}
+
+/*
+initialStep estimates the first step as in Hairer, Norsett and Wanner: the step for
+which an Euler step changes y by 1% of its size
+*/
+func (o ODEOptions) initialStep(y0, f0 []float64) float64 {
+	if o.InitialStep > 0 {
+		return math.Min(o.InitialStep, o.MaxStep)
+	}
+	var d0, d1 float64
+	for i := range y0 {
+		scale := o.AbsTol + o.RelTol*math.Abs(y0[i])
+		d0 += (y0[i] / scale) * (y0[i] / scale)
+		d1 += (f0[i] / scale) * (f0[i] / scale)
+	}
+	h := 1e-6
+	if d0 > 1e-10 && d1 > 1e-10 {
+		h = 0.01 * math.Sqrt(d0/d1)
+	}
+	return math.Min(h, o.MaxStep)
+}
+
+/*
+Dormand-Prince coefficients, the last row of dpA being the fifth order solution
+*/
+var (
+	dpC = [7]float64{0, 1.0 / 5, 3.0 / 10, 4.0 / 5, 8.0 / 9, 1, 1}
+	dpA = [7][6]float64{
+		{},
+		{1.0 / 5},
+		{3.0 / 40, 9.0 / 40},
+		{44.0 / 45, -56.0 / 15, 32.0 / 9},
+		{19372.0 / 6561, -25360.0 / 2187, 64448.0 / 6561, -212.0 / 729},
+		{9017.0 / 3168, -355.0 / 33, 46732.0 / 5247, 49.0 / 176, -5103.0 / 18656},
+		{35.0 / 384, 0, 500.0 / 1113, 125.0 / 192, -2187.0 / 6784, 11.0 / 84},
+	}
+	//Difference between the fifth and the fourth order solutions
+	dpE = [7]float64{71.0 / 57600, 0, -71.0 / 16695, 71.0 / 1920, -17253.0 / 339200, 22.0 / 525, -1.0 / 40}
+)
+
+/*
+dormandPrinceStep does one step from (t, y) with k[0] = f(t, y) given, it fills k and
+returns the fifth order solution and the error estimate
+*/
+func dormandPrinceStep(f ODE, t float64, h float64, y []float64, k *[7][]float64) ([]float64, []float64, error) {
+	var next []float64
+	for s := 1; s < 7; s++ {
+		stage := append([]float64(nil), y...)
+		for j := 0; j < s; j++ {
+			if dpA[s][j] != 0 {
+				for i := range stage {
+					stage[i] += h * dpA[s][j] * k[j][i]
+				}
+			}
+		}
+		if s == 6 {
+			//The last stage is evaluated at the solution (FSAL)
+			next = stage
+		}
+		var err error
+		k[s], err = evaluateODE(f, t+dpC[s]*h, stage)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	e := make([]float64, len(y))
+	for s := 0; s < 7; s++ {
+		if dpE[s] != 0 {
+			for i := range e {
+				e[i] += h * dpE[s] * k[s][i]
+			}
+		}
+	}
+	return next, e, nil
+}
+
+/*
+RK45 is a method to solve the initial value problem y' = f(t, y), y(t0) = y0 with the
+adaptive Dormand-Prince 5(4) Runge-Kutta method: the difference between a fifth and a
+fourth order solution estimates the local error, a step whose error is larger than
+AbsTol + RelTol*|y| is rejected and retried with a smaller step, the next step size is
+chosen from the error of the current one. It uses 6 evaluations of f per step, the last
+one being reused by the next step.
+
+First parameter f is the right hand side of the system
+Second parameter t0 is the initial time
+Third parameter y0 is the initial state
+Fourth parameter t1 is the final time, it can be lower than t0
+Fifth parameter options are the tolerances, the step safeguards and the events
+It returns the solution at the accepted steps (the last time being t1, or the time of
+a terminal event), with the zeros of options.Events, and an error if f doesn't return a
+slice of the length of y0, if the step becomes smaller than MinStep (the problem is
+probably stiff, see the implicit solvers) or if MaxSteps is reached. The solution is
+then the part computed before.
+*/
+func RK45(f ODE, t0 float64, y0 []float64, t1 float64, options ODEOptions) (*ODEResult, error) {
+	const (
+		safety    = 0.9
+		minFactor = 0.2
+		maxFactor = 5
+	)
+	r := newODEResult(f, 0)
+	if len(y0) == 0 {
+		return r, &MathError{
+			code: errorDimensionMismatch,
+		}
+	}
+	o := options.withDefaults(t0, t1)
+	var k [7][]float64
+	var err error
+	k[0], err = evaluateODE(f, t0, y0)
+	if err != nil {
+		return r, err
+	}
+	r.Times = []float64{t0}
+	r.States = [][]float64{append([]float64(nil), y0...)}
+	r.derivatives = [][]float64{k[0]}
+	if t1 == t0 {
+		return r, nil
+	}
+	watcher := newEventWatcher(o.Events, t0, y0)
+	direction := math.Copysign(1, t1-t0)
+
+	t, y := t0, r.States[0]
+	h := o.initialStep(y0, k[0])
+	for steps := 0; ; steps++ {
+		if steps >= o.MaxSteps {
+			return r, &MathError{
+				code: errorBudgetExceeded,
+			}
+		}
+		last := false
+		if h >= math.Abs(t1-t) {
+			h = math.Abs(t1 - t)
+			last = true
+		}
+		if h < o.MinStep || t+direction*h == t {
+			return r, &MathError{
+				s: "Step size became smaller than the minimum step",
+			}
+		}
+
+		next, e, err := dormandPrinceStep(f, t, direction*h, y, &k)
+		if err != nil {
+			return r, err
+		}
+		norm := o.errorNorm(e, y, next)
+		if math.IsNaN(norm) {
+			//Overflow in the stages, try a much smaller step
+			h *= minFactor
+			continue
+		}
+		factor := float64(maxFactor)
+		if norm > 0 {
+			factor = math.Min(maxFactor, math.Max(minFactor, safety*math.Pow(norm, -0.2)))
+		}
+		if norm > 1 {
+			//Rejected
+			h *= math.Min(1, factor)
+			continue
+		}
+
+		if last {
+			t = t1
+		} else {
+			t += direction * h
+		}
+		y = next
+		k[0] = k[6]
+		r.Times = append(r.Times, t)
+		r.States = append(r.States, y)
+		r.derivatives = append(r.derivatives, k[0])
+		if watcher.check(f, r) || last {
+			return r, nil
+		}
+		h = math.Min(h*factor, o.MaxStep)
+	}
+}
+
+/*
+implicitSolve solves z = c + gamma*h*f(t, z) with Newton iterations starting from
+guess, the Jacobian of f being computed numerically at each iteration
+*/
+func implicitSolve(f ODE, t float64, gamma float64, h float64, c []float64, guess []float64) ([]float64, error) {
+	const maxIterations = 50
+	n := len(c)
+	g := func(z []float64) []float64 {
+		return f(t, z)
+	}
+
+	z := append([]float64(nil), guess...)
+	for iteration := 0; iteration < maxIterations; iteration++ {
+		fz, err := evaluateODE(f, t, z)
+		if err != nil {
+			return nil, err
+		}
+		//(I - gamma*h*J) dz = c + gamma*h*f(t, z) - z
+		jacobian, err := Jacobian(z, g, 0, 1)
+		if err != nil {
+			return nil, err
+		}
+		for i := range jacobian.M {
+			jacobian.M[i] *= -gamma * h
+		}
+		residual := make([]float64, n)
+		for i := range residual {
+			jacobian.M[i*n+i]++
+			residual[i] = c[i] + gamma*h*fz[i] - z[i]
+		}
+		dz, err := jacobian.Solve(residual)
+		if err != nil {
+			return nil, err
+		}
+		converged := true
+		for i := range z {
+			z[i] += dz[i]
+			if math.Abs(dz[i]) > 1e-12*(1+math.Abs(z[i])) {
+				converged = false
+			}
+		}
+		if !finiteVector(z) {
+			break
+		}
+		if converged {
+			return z, nil
+		}
+	}
+	return nil, &MathError{
+		code: errorNotConverged,
+	}
+}
+
+func backwardEulerStep(f ODE, t float64, h float64, y []float64) ([]float64, error) {
+	//Explicit Euler as first guess
+	guess, err := eulerStep(f, t, h, y)
+	if err != nil {
+		return nil, err
+	}
+	return implicitSolve(f, t+h, 1, h, y, guess)
+}
+
+/*
+BackwardEuler is a method to solve the initial value problem y' = f(t, y), y(t0) = y0
+with the implicit Euler method y(t+h) = y(t) + h f(t+h, y(t+h)). Each step solves this
+equation with Newton iterations using the numerical Jacobian of f and Matrix.Solve.
+It is only first order but stable for any step on a stiff problem (fast decaying
+components, e.g. chemical kinetics or circuits) where the explicit methods blow up
+unless the step is tiny.
+
+First parameter f is the right hand side of the system
+Second parameter t0 is the initial time
+Third parameter y0 is the initial state
+Fourth parameter t1 is the final time, it can be lower than t0
+Fifth parameter n is the number of steps
+It returns the solution at the n+1 times, and an error if f doesn't
+return a slice of the length of y0 or if the Newton iterations of a step failed (the
+solution is then the part computed before)
+*/
+func BackwardEuler(f ODE, t0 float64, y0 []float64, t1 float64, n int) (*ODEResult, error) {
+	return fixedStep(f, t0, y0, t1, n, backwardEulerStep)
+}
+
+/*
+BDF2 is a method to solve the initial value problem y' = f(t, y), y(t0) = y0 with the
+second order backward differentiation formula
+y(t+h) - 4/3 y(t) + 1/3 y(t-h) = 2/3 h f(t+h, y(t+h)), the first step being a backward
+Euler step. Like BackwardEuler it solves an equation at each step with Newton
+iterations, it is as stable on stiff problems but second order.
+
+First parameter f is the right hand side of the system
+Second parameter t0 is the initial time
+Third parameter y0 is the initial state
+Fourth parameter t1 is the final time, it can be lower than t0
+Fifth parameter n is the number of steps
+It returns the solution at the n+1 times, and an error if f doesn't
+return a slice of the length of y0 or if the Newton iterations of a step failed (the
+solution is then the part computed before)
+*/
+func BDF2(f ODE, t0 float64, y0 []float64, t1 float64, n int) (*ODEResult, error) {
+	if n <= 0 || len(y0) == 0 {
+		return nil, &MathError{
+			code: errorDimensionMismatch,
+		}
+	}
+	h := (t1 - t0) / float64(n)
+	r := newODEResult(f, n+1)
+	r.Times = append(r.Times, t0)
+	r.States = append(r.States, append([]float64(nil), y0...))
+	first, err := backwardEulerStep(f, t0, h, y0)
+	if err != nil {
+		return r, err
+	}
+	if n == 1 {
+		r.Times = append(r.Times, t1)
+		r.States = append(r.States, first)
+		return r, nil
+	}
+	r.Times = append(r.Times, t0+h)
+	r.States = append(r.States, first)
+
+	for i := 2; i <= n; i++ {
+		previous, y := r.States[i-2], r.States[i-1]
+		c := make([]float64, len(y))
+		guess := make([]float64, len(y))
+		for j := range y {
+			c[j] = 4.0/3*y[j] - 1.0/3*previous[j]
+			//Linear extrapolation
+			guess[j] = 2*y[j] - previous[j]
+		}
+		t := t0 + float64(i)*h
+		if i == n {
+			t = t1
+		}
+		next, err := implicitSolve(f, t, 2.0/3, h, c, guess)
+		if err != nil {
+			return r, err
+		}
+		r.Times = append(r.Times, t)
+		r.States = append(r.States, next)
+	}
+	return r, nil
+}
+
+/*
+adams integrates with the fourth order Adams-Bashforth formula, started with three RK4
+steps, and corrects each step with the Adams-Moulton formula when correct is true
+*/
+func adams(f ODE, t0 float64, y0 []float64, t1 float64, n int, correct bool) (*ODEResult, error) {
+	if n <= 3 {
+		return RK4(f, t0, y0, t1, n)
+	}
+	r, err := fixedStep(f, t0, y0, t0+3*(t1-t0)/float64(n), 3, rk4Step)
+	if err != nil {
+		return r, err
+	}
+	h := (t1 - t0) / float64(n)
+	//Derivatives at the last four points, the most recent last
+	fs := make([][]float64, 4)
+	for i := range fs {
+		if fs[i], err = evaluateODE(f, r.Times[i], r.States[i]); err != nil {
+			return r, err
+		}
+	}
+
+	for i := 4; i <= n; i++ {
+		y := r.States[i-1]
+		t := t0 + float64(i)*h
+		if i == n {
+			t = t1
+		}
+		next := make([]float64, len(y))
+		for c := range y {
+			next[c] = y[c] + h/24*(55*fs[3][c]-59*fs[2][c]+37*fs[1][c]-9*fs[0][c])
+		}
+		fnext, err := evaluateODE(f, t, next)
+		if err != nil {
+			return r, err
+		}
+		if correct {
+			for c := range y {
+				next[c] = y[c] + h/24*(9*fnext[c]+19*fs[3][c]-5*fs[2][c]+fs[1][c])
+			}
+			if fnext, err = evaluateODE(f, t, next); err != nil {
+				return r, err
+			}
+		}
+		if !finiteVector(next) {
+			return r, &MathError{
+				code: errorDiverged,
+			}
+		}
+		r.Times = append(r.Times, t)
+		r.States = append(r.States, next)
+		fs = append(fs[1:], fnext)
+	}
+	return r, nil
+}
+
+/*
+AdamsBashforth is a method to solve the initial value problem y' = f(t, y),
+y(t0) = y0 with the explicit fourth order Adams-Bashforth multistep method:
+y(t+h) = y(t) + h/24 (55 f(t) - 59 f(t-h) + 37 f(t-2h) - 9 f(t-3h)). The three first
+steps are RK4 steps. It only evaluates f once per step (RK4 does it four times), which
+makes it much cheaper when f is expensive, but it is less stable.
+
+First parameter f is the right hand side of the system
+Second parameter t0 is the initial time
+Third parameter y0 is the initial state
+Fourth parameter t1 is the final time, it can be lower than t0
+Fifth parameter n is the number of steps
+It returns the solution at the n+1 times, and an error if f doesn't
+return a slice of the length of y0 or if the solution is not finite anymore (the
+solution is then the part computed before)
+*/
+func AdamsBashforth(f ODE, t0 float64, y0 []float64, t1 float64, n int) (*ODEResult, error) {
+	return adams(f, t0, y0, t1, n, false)
+}
+
+/*
+AdamsBashforthMoulton is a method to solve the initial value problem y' = f(t, y),
+y(t0) = y0 with the fourth order Adams predictor-corrector: each step is predicted with
+the Adams-Bashforth formula then corrected once with the implicit Adams-Moulton formula
+y(t+h) = y(t) + h/24 (9 f(t+h) + 19 f(t) - 5 f(t-h) + f(t-2h)), f(t+h) being computed at
+the prediction. It evaluates f twice per step, is more accurate and more stable than
+AdamsBashforth. The three first steps are RK4 steps.
+
+First parameter f is the right hand side of the system
+Second parameter t0 is the initial time
+Third parameter y0 is the initial state
+Fourth parameter t1 is the final time, it can be lower than t0
+Fifth parameter n is the number of steps
+It returns the solution at the n+1 times, and an error if f doesn't
+return a slice of the length of y0 or if the solution is not finite anymore (the
+solution is then the part computed before)
+*/
+func AdamsBashforthMoulton(f ODE, t0 float64, y0 []float64, t1 float64, n int) (*ODEResult, error) {
+	return adams(f, t0, y0, t1, n, true)
+}
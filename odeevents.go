@@ -0,0 +1,265 @@
+package advmath
+
+import (
+	"math"
+	"sort"
+	"sync"
+)
+
+/*
+ODEEvent is a condition g(t, y) = 0 watched while solving an ODE, e.g. the height of a
+projectile to detect when it hits the ground. The zeros are found where g changes sign
+between two steps, so two zeros in the same step are missed: limit the step (MaxStep)
+if g can go back and forth quickly.
+*/
+type ODEEvent struct {
+	//G is the function whose zeros are the events
+	G func(t float64, y []float64) float64
+	//Direction only keeps the zeros where G increases (> 0) or decreases (< 0) in
+	//the direction of the integration, 0 keeps all of them
+	Direction int
+	//Terminal stops the integration at the first zero
+	Terminal bool
+}
+
+/*
+EventHit is a zero of an ODEEvent found while solving an ODE
+*/
+type EventHit struct {
+	//Event is the index of the event in the slice given to the solver
+	Event int `json:"event"`
+	//T is the time of the zero
+	T float64 `json:"t"`
+	//Y is the state at T
+	Y []float64 `json:"y"`
+}
+
+/*
+ODEResult is the solution of an ODE computed by a solver, with a continuous output
+between the steps: on each step the cubic Hermite interpolant of the states and of
+their derivatives f(t, y) (see NewCubicHermite), which is fourth order accurate.
+It can be converted to a Matrix (StatesMatrix), resampled on other times (Resample)
+and exported as CSV (WriteCSV) or JSON (MarshalJSON).
+*/
+type ODEResult struct {
+	//Times are the times of the steps, increasing or decreasing
+	Times []float64
+	//States are the states at Times
+	States [][]float64
+	//Events are the zeros of the events, in the order of the integration
+	Events []EventHit
+	//f is the right hand side of the system
+	f ODE
+	//derivatives are f(t, y) at Times, the fixed step solvers don't compute them
+	//all so they are only computed when the continuous output is first needed
+	derivatives [][]float64
+	lock        sync.Mutex
+}
+
+/*
+newODEResult creates an empty result with room for n points
+*/
+func newODEResult(f ODE, n int) *ODEResult {
+	return &ODEResult{
+		Times:  make([]float64, 0, n),
+		States: make([][]float64, 0, n),
+		f:      f,
+	}
+}
+
+/*
+NewODEResult is a method to create the continuous output of a trajectory computed by a
+fixed step solver (Euler, RK4, BackwardEuler, BDF2...) and to find the zeros of the
+events on it. A terminal event truncates the trajectory at its first zero.
+First parameter f is the right hand side of the system
+Second parameter times are the times returned by the solver
+Third parameter states are the states returned by the solver
+Fourth parameter events are the events to find, nil for none
+It returns an error if the sizes don't match
+*/
+func NewODEResult(f ODE, times []float64, states [][]float64, events []ODEEvent) (*ODEResult, error) {
+	if len(times) == 0 || len(times) != len(states) {
+		return nil, &MathError{
+			code: errorDimensionMismatch,
+		}
+	}
+	r := newODEResult(f, len(times))
+	watcher := newEventWatcher(events, times[0], states[0])
+	for i := range times {
+		d, err := evaluateODE(f, times[i], states[i])
+		if err != nil {
+			return nil, err
+		}
+		r.Times = append(r.Times, times[i])
+		r.States = append(r.States, append([]float64(nil), states[i]...))
+		r.derivatives = append(r.derivatives, d)
+		if i > 0 && watcher.check(f, r) {
+			break
+		}
+	}
+	return r, nil
+}
+
+/*
+computeDerivatives computes the derivatives at the times where the solver didn't
+*/
+func (r *ODEResult) computeDerivatives() {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	for i := len(r.derivatives); i < len(r.Times); i++ {
+		r.derivatives = append(r.derivatives, r.f(r.Times[i], r.States[i]))
+	}
+}
+
+/*
+hermite interpolates the step i (from Times[i] to Times[i+1]) at t
+*/
+func (r *ODEResult) hermite(i int, t float64) []float64 {
+	r.computeDerivatives()
+	h := r.Times[i+1] - r.Times[i]
+	theta := (t - r.Times[i]) / h
+	y := make([]float64, len(r.States[i]))
+	for c := range y {
+		y[c] = CubicHermite(r.States[i][c], r.States[i+1][c], h*r.derivatives[i][c], h*r.derivatives[i+1][c], theta)
+	}
+	return y
+}
+
+/*
+At is a method to compute the state at any time t between the first and the last
+times, nil is returned out of this interval
+*/
+func (r *ODEResult) At(t float64) []float64 {
+	n := len(r.Times)
+	if n == 0 {
+		return nil
+	}
+	first, last := r.Times[0], r.Times[n-1]
+	if t == first {
+		return append([]float64(nil), r.States[0]...)
+	}
+	if n == 1 || t < math.Min(first, last) || t > math.Max(first, last) {
+		return nil
+	}
+	//First step ending at or after t in the direction of the integration
+	var i int
+	if last > first {
+		i = sort.Search(n-1, func(k int) bool { return r.Times[k+1] >= t })
+	} else {
+		i = sort.Search(n-1, func(k int) bool { return r.Times[k+1] <= t })
+	}
+	return r.hermite(i, t)
+}
+
+/*
+StatesMatrix is a method to get the states as a matrix with one row per time and one
+column per component, e.g. to compute statistics on the trajectory
+*/
+func (r *ODEResult) StatesMatrix() *Matrix {
+	if len(r.States) == 0 {
+		return NewMatrix(0, 0)
+	}
+	cols := uint(len(r.States[0]))
+	m := NewMatrix(uint(len(r.States)), cols)
+	for i, y := range r.States {
+		copy(m.M[uint(i)*cols:], y)
+	}
+	return m
+}
+
+/*
+Resample is a method to compute the solution at other times with the continuous
+output, e.g. to get evenly spaced points from an adaptive solver or to compare two
+solutions at the same times. The events are kept.
+First parameter times are the new times, all of them between the first and the last
+times of the solution
+It returns an error if a time is out of the solution
+*/
+func (r *ODEResult) Resample(times []float64) (*ODEResult, error) {
+	s := newODEResult(r.f, len(times))
+	for _, t := range times {
+		y := r.At(t)
+		if y == nil {
+			return nil, &MathError{
+				s: "Cannot resample the ODE solution out of its interval",
+			}
+		}
+		s.Times = append(s.Times, t)
+		s.States = append(s.States, y)
+	}
+	s.Events = append([]EventHit(nil), r.Events...)
+	return s, nil
+}
+
+/*
+eventWatcher keeps the values of the events at the last step
+*/
+type eventWatcher struct {
+	events []ODEEvent
+	g      []float64
+}
+
+func newEventWatcher(events []ODEEvent, t float64, y []float64) *eventWatcher {
+	w := &eventWatcher{
+		events: events,
+		g:      make([]float64, len(events)),
+	}
+	for i, e := range events {
+		w.g[i] = e.G(t, y)
+	}
+	return w
+}
+
+/*
+check looks for the zeros of the events on the last step of r with Brent on the
+continuous output, records them in r.Events and, if one is terminal, replaces the last
+point of r by the first terminal zero. It returns true in that case.
+*/
+func (w *eventWatcher) check(f ODE, r *ODEResult) bool {
+	if len(w.events) == 0 {
+		return false
+	}
+	i := len(r.Times) - 2
+	ta, tb := r.Times[i], r.Times[i+1]
+	var hits []EventHit
+	for k, e := range w.events {
+		ga := w.g[k]
+		gb := e.G(tb, r.States[i+1])
+		w.g[k] = gb
+		if ga == 0 || (gb != 0 && math.Signbit(ga) == math.Signbit(gb)) {
+			continue
+		}
+		//Direction of the change of g, in the direction of the integration
+		increasing := gb > ga == (tb > ta)
+		if (e.Direction > 0 && !increasing) || (e.Direction < 0 && increasing) {
+			continue
+		}
+		g := func(t float64) float64 {
+			return e.G(t, r.hermite(i, t))
+		}
+		t := tb
+		if gb != 0 {
+			tol := 4 * machineEpsilon * math.Max(math.Abs(ta), math.Abs(tb))
+			root, err := Brent(ta, tb, g, tol)
+			if err != nil {
+				continue
+			}
+			t = root
+		}
+		hits = append(hits, EventHit{Event: k, T: t, Y: r.hermite(i, t)})
+	}
+	//In the order of the integration
+	sort.SliceStable(hits, func(a, b int) bool {
+		return (hits[a].T < hits[b].T) == (tb > ta)
+	})
+	for _, hit := range hits {
+		r.Events = append(r.Events, hit)
+		if w.events[hit.Event].Terminal {
+			r.Times[i+1] = hit.T
+			r.States[i+1] = hit.Y
+			r.derivatives[i+1] = f(hit.T, hit.Y)
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,261 @@
+package advmath
+
+import (
+	"math"
+)
+
+/*
+OptimizeResult holds the outcome of a multivariate minimization
+*/
+type OptimizeResult struct {
+	//X is the best point found
+	X []float64
+	//Value is f(X)
+	Value float64
+	//Gradient is the gradient at X, nil for the methods which don't use it
+	Gradient []float64
+	//Iterations is the number of iterations done
+	Iterations int
+	//Evaluations is the number of calls to f, including the ones made to compute
+	//numerical gradients
+	Evaluations int
+	//Converged tells if a stopping criterion was met
+	Converged bool
+}
+
+/*
+OptimizeState describes an iteration of a multivariate minimization, it is given to
+the OnIteration callback
+*/
+type OptimizeState struct {
+	//Iteration is the number of the iteration, starting at 1
+	Iteration int
+	//X is the current point, it must not be modified
+	X []float64
+	//Value is f(X)
+	Value float64
+	//GradientNorm is the norm of the gradient at X, 0 for the methods which don't
+	//use it
+	GradientNorm float64
+}
+
+/*
+OptimizeOptions holds the settings shared by the multivariate minimization methods.
+The zero value uses numerical gradients and the defaults given for each field.
+*/
+type OptimizeOptions struct {
+	//Gradient is the gradient of f, computed with Gradient (Ridders' method on each
+	//variable) when it is nil, which is accurate but costs many evaluations of f
+	Gradient VectorFn
+	//MaxIterations is the maximum number of iterations, the default depends on the
+	//method
+	MaxIterations int
+	//GradientTolerance stops the method when the norm of the gradient is at most
+	//this value, 1e-6 when it is 0
+	GradientTolerance float64
+	//ValueTolerance stops the method when f changes by at most
+	//ValueTolerance*(1+|f|) in an iteration, not used when it is 0
+	ValueTolerance float64
+	//StepTolerance stops the method when the points move by at most
+	//StepTolerance*(1+|x|) in an iteration, not used when it is 0 except by the
+	//derivative-free methods which use 1e-8
+	StepTolerance float64
+	//MaxEvaluations is the budget of calls to f of the global methods
+	//(SimulatedAnnealing, DifferentialEvolution), 10000*n when it is 0, the other
+	//methods don't use it
+	MaxEvaluations int
+	//OnIteration is called at the end of every iteration when it is not nil, e.g. to
+	//log the convergence history
+	OnIteration func(OptimizeState)
+	//Lower are the lower bounds of the variables, nil when there are none, -Inf for a
+	//variable without lower bound. The iterates are projected on the bounds
+	Lower []float64
+	//Upper are the upper bounds of the variables, as Lower
+	Upper []float64
+	//Equality are constraints h(x) = 0, handled with an augmented Lagrangian penalty:
+	//the method is run several times on f plus a penalty which increases until the
+	//constraints are met. The penalty makes f steeper, GradientDescent then needs a
+	//smaller learning rate
+	Equality []Fn
+	//ConstraintTolerance is the largest |h(x)| accepted for the equality
+	//constraints, 1e-6 when it is 0
+	ConstraintTolerance float64
+}
+
+/*
+withDefaults returns the options with the defaults of a method
+*/
+func (o OptimizeOptions) withDefaults(maxIterations int) OptimizeOptions {
+	if o.MaxIterations <= 0 {
+		o.MaxIterations = maxIterations
+	}
+	if o.GradientTolerance <= 0 {
+		o.GradientTolerance = 1e-6
+	}
+	return o
+}
+
+/*
+valueConverged tells if the change of f from previous to current meets ValueTolerance
+*/
+func (o OptimizeOptions) valueConverged(previous, current float64) bool {
+	return o.ValueTolerance > 0 && math.Abs(current-previous) <= o.ValueTolerance*(1+math.Abs(current))
+}
+
+/*
+stepConverged tells if the move from previous to x meets StepTolerance
+*/
+func (o OptimizeOptions) stepConverged(previous, x []float64) bool {
+	if o.StepTolerance <= 0 {
+		return false
+	}
+	scale := 1 + Norm(x)
+	for i := range x {
+		if math.Abs(x[i]-previous[i]) > o.StepTolerance*scale {
+			return false
+		}
+	}
+	return true
+}
+
+/*
+objective is the function being minimized, it counts the evaluations
+*/
+type objective struct {
+	f           Fn
+	gradient    VectorFn
+	evaluations int
+}
+
+func newObjective(f Fn, options OptimizeOptions) *objective {
+	return &objective{
+		f:        f,
+		gradient: options.Gradient,
+	}
+}
+
+func (o *objective) value(x []float64) float64 {
+	o.evaluations++
+	return o.f(x)
+}
+
+func (o *objective) grad(x []float64) []float64 {
+	if o.gradient != nil {
+		return o.gradient(x)
+	}
+	return Gradient(x, o.value, 1e-4)
+}
+
+/*
+DescentMethod is the update rule used by GradientDescent
+*/
+type DescentMethod int
+
+const (
+	//VanillaDescent moves against the gradient: x -= rate * g
+	VanillaDescent DescentMethod = iota
+	//MomentumDescent accumulates the past steps (heavy ball with a factor 0.9), which
+	//speeds up the progress along narrow valleys: v = 0.9 v - rate * g, x += v
+	MomentumDescent
+	//AdamDescent scales each component by a running estimate of the size of its
+	//gradient (Kingma and Ba, with beta1 = 0.9 and beta2 = 0.999), which makes the
+	//step size nearly independent of the scaling of f
+	AdamDescent
+)
+
+/*
+GradientDescent is a method to find a local minimum of f by moving against its
+gradient with a fixed learning rate. It is simple and cheap per iteration but needs
+many iterations, and the learning rate has to be tuned: too large the iterates
+diverge, too small they barely move. BFGS is usually much faster on smooth problems.
+
+First parameter f is the function to minimize
+Second parameter x0 is the starting point
+Third parameter method is the update rule
+Fourth parameter rate is the learning rate, 0.01 when it is 0
+Fifth parameter options are the gradient, the stopping criteria, the callback and the
+constraints, 10000 iterations by default
+It returns the last point, and an error if the iterates are not finite anymore or if
+no stopping criterion was met
+*/
+func GradientDescent(f Fn, x0 []float64, method DescentMethod, rate float64, options OptimizeOptions) (OptimizeResult, error) {
+	return minimizeConstrained(f, x0, options, 0, func(f Fn, x0 []float64, o OptimizeOptions) (OptimizeResult, error) {
+		return gradientDescent(f, x0, method, rate, o)
+	})
+}
+
+/*
+gradientDescent is GradientDescent without the equality constraints, the iterates are
+projected on the bounds after each step
+*/
+func gradientDescent(f Fn, x0 []float64, method DescentMethod, rate float64, options OptimizeOptions) (OptimizeResult, error) {
+	const (
+		momentum = 0.9
+		beta1    = 0.9
+		beta2    = 0.999
+		epsilon  = 1e-8
+	)
+	if rate <= 0 {
+		rate = 0.01
+	}
+	o := options.withDefaults(10000)
+	obj := newObjective(f, o)
+	x := append([]float64(nil), x0...)
+	value := obj.value(x)
+	g := obj.grad(x)
+	//First and second moments for Adam, velocity for the momentum
+	m := make([]float64, len(x))
+	v := make([]float64, len(x))
+
+	result := OptimizeResult{}
+	for result.Iterations < o.MaxIterations {
+		if Norm(o.projectedGradient(x, g)) <= o.GradientTolerance {
+			result.Converged = true
+			break
+		}
+		result.Iterations++
+		k := float64(result.Iterations)
+		previousX := append([]float64(nil), x...)
+		for i := range x {
+			switch method {
+			case MomentumDescent:
+				v[i] = momentum*v[i] - rate*g[i]
+				x[i] += v[i]
+			case AdamDescent:
+				m[i] = beta1*m[i] + (1-beta1)*g[i]
+				v[i] = beta2*v[i] + (1-beta2)*g[i]*g[i]
+				//Bias correction of the moments started at 0
+				mhat := m[i] / (1 - math.Pow(beta1, k))
+				vhat := v[i] / (1 - math.Pow(beta2, k))
+				x[i] -= rate * mhat / (math.Sqrt(vhat) + epsilon)
+			default:
+				x[i] -= rate * g[i]
+			}
+		}
+		o.project(x)
+		previous := value
+		value = obj.value(x)
+		if math.IsNaN(value) || math.IsInf(value, 0) || !finiteVector(x) {
+			result.X, result.Value, result.Evaluations = x, value, obj.evaluations
+			return result, &MathError{
+				code: errorDiverged,
+			}
+		}
+		g = obj.grad(x)
+		if o.OnIteration != nil {
+			o.OnIteration(OptimizeState{Iteration: result.Iterations, X: x, Value: value, GradientNorm: Norm(o.projectedGradient(x, g))})
+		}
+		if o.valueConverged(previous, value) || o.stepConverged(previousX, x) {
+			result.Converged = true
+			break
+		}
+	}
+
+	result.X, result.Value, result.Gradient, result.Evaluations = x, value, g, obj.evaluations
+	if !result.Converged {
+		return result, &MathError{
+			code: errorNotConverged,
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,330 @@
+package advmath
+
+import (
+	"math"
+)
+
+/*
+GivensRotation is a rotation in the plane of two coordinates I and K:
+
+	G = [ C  S]
+	    [-S  C]
+
+applied to the rows (or columns) I and K, every other row being unchanged.
+It is used to zero a single element of a matrix, which is handy to update a
+factorization (QR for instance) after a small change.
+*/
+type GivensRotation struct {
+	I uint
+	K uint
+	C float64
+	S float64
+}
+
+/*
+NewGivens is a method to create the rotation of rows i and k zeroing b in
+[a b]^T, i.e. G*[a b]^T = [r 0]^T.
+First parameter is the index of the first coordinate
+Second parameter is the index of the second coordinate
+Third parameter is the value a at the first coordinate
+Fourth parameter is the value b at the second coordinate (zeroed)
+It returns the rotation and r
+*/
+func NewGivens(i, k uint, a, b float64) (GivensRotation, float64) {
+	if b == 0 {
+		return GivensRotation{I: i, K: k, C: 1, S: 0}, a
+	}
+	r := math.Hypot(a, b)
+	return GivensRotation{I: i, K: k, C: a / r, S: b / r}, r
+}
+
+/*
+ApplyLeft is a method to compute G*A in place, only the rows I and K of the
+matrix are changed.
+First parameter is the matrix to rotate
+*/
+func (g GivensRotation) ApplyLeft(m *Matrix) error {
+	if g.I >= m.NumberOfRows || g.K >= m.NumberOfRows {
+		return &MathError{
+			code: errorDimensionMismatch,
+		}
+	}
+	var j uint
+	for j = 0; j < m.NumberOfColumns; j++ {
+		x := m.Get(g.I, j)
+		y := m.Get(g.K, j)
+		m.Set(g.I, j, g.C*x+g.S*y)
+		m.Set(g.K, j, -g.S*x+g.C*y)
+	}
+	return nil
+}
+
+/*
+ApplyRight is a method to compute A*G^T in place, only the columns I and K of
+the matrix are changed.
+First parameter is the matrix to rotate
+*/
+func (g GivensRotation) ApplyRight(m *Matrix) error {
+	if g.I >= m.NumberOfColumns || g.K >= m.NumberOfColumns {
+		return &MathError{
+			code: errorDimensionMismatch,
+		}
+	}
+	var j uint
+	for j = 0; j < m.NumberOfRows; j++ {
+		x := m.Get(j, g.I)
+		y := m.Get(j, g.K)
+		m.Set(j, g.I, g.C*x+g.S*y)
+		m.Set(j, g.K, -g.S*x+g.C*y)
+	}
+	return nil
+}
+
+/*
+ApplyVector is a method to compute G*v in place.
+First parameter is the vector to rotate
+*/
+func (g GivensRotation) ApplyVector(v []float64) error {
+	if g.I >= uint(len(v)) || g.K >= uint(len(v)) {
+		return &MathError{
+			code: errorDimensionMismatch,
+		}
+	}
+	x := v[g.I]
+	y := v[g.K]
+	v[g.I] = g.C*x + g.S*y
+	v[g.K] = -g.S*x + g.C*y
+	return nil
+}
+
+/*
+Matrix is a method returning the full nxn matrix of the rotation.
+First parameter is the size of the matrix
+*/
+func (g GivensRotation) Matrix(n uint) *Matrix {
+	m := NewIdentity(n)
+	m.Set(g.I, g.I, g.C)
+	m.Set(g.I, g.K, g.S)
+	m.Set(g.K, g.I, -g.S)
+	m.Set(g.K, g.K, g.C)
+	return m
+}
+
+/*
+HouseholderReflector is the reflection H = I - Beta*V*V^T. It acts on the
+coordinates Offset to Offset+len(V)-1, the other ones being unchanged, which
+allows to apply it to the trailing part of a matrix as done in a QR
+decomposition.
+*/
+type HouseholderReflector struct {
+	Offset uint
+	V      []float64
+	Beta   float64
+}
+
+/*
+NewHouseholder is a method to create the reflector sending x to alpha*e1,
+i.e. zeroing every coordinate of x but the first one. alpha has the opposite
+sign of x[0] to avoid cancellations.
+First parameter is the offset of the reflector
+Second parameter is the vector x
+It returns the reflector and alpha
+*/
+func NewHouseholder(offset uint, x []float64) (HouseholderReflector, float64) {
+	v := make([]float64, len(x))
+	copy(v, x)
+	h := HouseholderReflector{Offset: offset, V: v}
+	if len(x) == 0 {
+		return h, 0
+	}
+
+	norm := Norm(x)
+	if norm == 0 {
+		//Nothing to reflect, H is the identity
+		return h, 0
+	}
+	alpha := -math.Copysign(norm, x[0])
+	v[0] -= alpha
+	vv, _ := Dot(v, v)
+	h.Beta = 2 / vv
+	return h, alpha
+}
+
+/*
+ApplyLeft is a method to compute H*A in place.
+First parameter is the matrix to reflect
+*/
+func (h HouseholderReflector) ApplyLeft(m *Matrix) error {
+	n := uint(len(h.V))
+	if h.Offset+n > m.NumberOfRows {
+		return &MathError{
+			code: errorDimensionMismatch,
+		}
+	}
+	var i, j uint
+	for j = 0; j < m.NumberOfColumns; j++ {
+		//w = beta * v^T * A[:, j]
+		var w float64
+		for i = 0; i < n; i++ {
+			w += h.V[i] * m.Get(h.Offset+i, j)
+		}
+		w *= h.Beta
+		for i = 0; i < n; i++ {
+			m.Set(h.Offset+i, j, m.Get(h.Offset+i, j)-w*h.V[i])
+		}
+	}
+	return nil
+}
+
+/*
+ApplyRight is a method to compute A*H in place.
+First parameter is the matrix to reflect
+*/
+func (h HouseholderReflector) ApplyRight(m *Matrix) error {
+	n := uint(len(h.V))
+	if h.Offset+n > m.NumberOfColumns {
+		return &MathError{
+			code: errorDimensionMismatch,
+		}
+	}
+	var i, j uint
+	for i = 0; i < m.NumberOfRows; i++ {
+		var w float64
+		for j = 0; j < n; j++ {
+			w += m.Get(i, h.Offset+j) * h.V[j]
+		}
+		w *= h.Beta
+		for j = 0; j < n; j++ {
+			m.Set(i, h.Offset+j, m.Get(i, h.Offset+j)-w*h.V[j])
+		}
+	}
+	return nil
+}
+
+/*
+ApplyVector is a method to compute H*v in place.
+First parameter is the vector to reflect
+*/
+func (h HouseholderReflector) ApplyVector(v []float64) error {
+	n := uint(len(h.V))
+	if h.Offset+n > uint(len(v)) {
+		return &MathError{
+			code: errorDimensionMismatch,
+		}
+	}
+	w, _ := Dot(h.V, v[h.Offset:h.Offset+n])
+	w *= h.Beta
+	for i := range h.V {
+		v[h.Offset+uint(i)] -= w * h.V[i]
+	}
+	return nil
+}
+
+/*
+Matrix is a method returning the full nxn matrix of the reflector.
+First parameter is the size of the matrix
+*/
+func (h HouseholderReflector) Matrix(n uint) *Matrix {
+	m := NewIdentity(n)
+	h.ApplyLeft(m)
+	return m
+}
+
+/*
+Orthonormalize is a method to compute an orthonormal basis of the space spanned
+by the columns of the matrix using the modified Gram-Schmidt algorithm.
+A column that is (numerically) a linear combination of the previous ones is
+skipped, so the number of columns of the result is the rank of the matrix.
+First return value is the matrix whose columns are the orthonormal basis
+Second return value is the rank, smaller than the number of columns if the
+matrix is rank deficient
+*/
+func (m Matrix) Orthonormalize() (*Matrix, uint) {
+	rows := m.NumberOfRows
+	var basis [][]float64
+
+	var j uint
+	for j = 0; j < m.NumberOfColumns; j++ {
+		v := m.GetColumn(j)
+		original := Norm(v)
+		for _, q := range basis {
+			//Modified version: project the updated vector, not the original one
+			d, _ := Dot(q, v)
+			for i := range v {
+				v[i] -= d * q[i]
+			}
+		}
+		n := Norm(v)
+		if n == 0 || n <= 1e-10*original {
+			continue
+		}
+		for i := range v {
+			v[i] /= n
+		}
+		basis = append(basis, v)
+	}
+
+	rank := uint(len(basis))
+	q := NewMatrix(rows, rank)
+	var i uint
+	for k, v := range basis {
+		for i = 0; i < rows; i++ {
+			q.Set(i, uint(k), v[i])
+		}
+	}
+	return q, rank
+}
+
+/*
+leastSquares solves min ||A*X - B|| column by column with the Householder QR
+decomposition of A, which is more accurate than the normal equations. A must have at
+least as many rows as columns and full column rank, a diagonal element of R smaller
+than n*eps times the largest one gives an errorNotInversible.
+*/
+func leastSquares(a *Matrix, b *Matrix) (*Matrix, error) {
+	m, n := a.NumberOfRows, a.NumberOfColumns
+	if m < n || b.NumberOfRows != m {
+		return nil, &MathError{
+			code: errorDimensionMismatch,
+		}
+	}
+	r := NewMatrix(m, n)
+	copy(r.M, a.M)
+	qtb := NewMatrix(m, b.NumberOfColumns)
+	copy(qtb.M, b.M)
+
+	var i, j, k uint
+	largest := 0.0
+	for k = 0; k < n; k++ {
+		column := make([]float64, m-k)
+		for i = k; i < m; i++ {
+			column[i-k] = r.M[i*n+k]
+		}
+		h, _ := NewHouseholder(k, column)
+		h.ApplyLeft(r)
+		h.ApplyLeft(qtb)
+		largest = math.Max(largest, math.Abs(r.M[k*n+k]))
+	}
+
+	p := b.NumberOfColumns
+	x := NewMatrix(n, p)
+	for k = 0; k < n; k++ {
+		if math.Abs(r.M[k*n+k]) <= float64(n)*machineEpsilon*largest {
+			return nil, &MathError{
+				code: errorNotInversible,
+			}
+		}
+	}
+	//Back substitution R*X = Q^T*B
+	for j = 0; j < p; j++ {
+		for k = n; k > 0; k-- {
+			row := k - 1
+			sum := qtb.M[row*p+j]
+			for i = row + 1; i < n; i++ {
+				sum -= r.M[row*n+i] * x.M[i*p+j]
+			}
+			x.M[row*p+j] = sum / r.M[row*n+row]
+		}
+	}
+	return x, nil
+}
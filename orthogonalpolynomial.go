@@ -0,0 +1,174 @@
+package advmath
+
+import (
+	"math"
+)
+
+/*
+recurrence is a three-term recurrence p_(k+1) = (a x + b) p_k - c p_(k-1) starting
+from p_0 = 1 and p_1 = p1, coefficients gives a, b and c for a k >= 1
+*/
+type recurrence struct {
+	p1           Polynomial
+	coefficients func(k int) (float64, float64, float64)
+}
+
+/*
+polynomials returns p_0 to p_n
+*/
+func (r recurrence) polynomials(n int) []Polynomial {
+	if n < 0 {
+		return nil
+	}
+	result := []Polynomial{{1}}
+	if n >= 1 {
+		result = append(result, r.p1)
+	}
+	for k := 1; k < n; k++ {
+		a, b, c := r.coefficients(k)
+		pk, previous := result[k], result[k-1]
+		next := make(Polynomial, len(pk)+1)
+		for i := range pk {
+			next[i+1] += a * pk[i]
+			next[i] += b * pk[i]
+		}
+		for i := range previous {
+			next[i] -= c * previous[i]
+		}
+		result = append(result, next.trim())
+	}
+	return result
+}
+
+/*
+evaluate computes p_n(x) with the recurrence, which is stable for these families
+while the evaluation of the coefficients is not for large n
+*/
+func (r recurrence) evaluate(n int, x float64) float64 {
+	if n < 0 {
+		return math.NaN()
+	}
+	previous, current := 1.0, r.p1.Evaluate(x)
+	if n == 0 {
+		return previous
+	}
+	for k := 1; k < n; k++ {
+		a, b, c := r.coefficients(k)
+		previous, current = current, (a*x+b)*current-c*previous
+	}
+	return current
+}
+
+var (
+	legendre = recurrence{
+		p1: Polynomial{0, 1},
+		coefficients: func(k int) (float64, float64, float64) {
+			return float64(2*k+1) / float64(k+1), 0, float64(k) / float64(k+1)
+		},
+	}
+	chebyshevT = recurrence{
+		p1: Polynomial{0, 1},
+		coefficients: func(k int) (float64, float64, float64) {
+			return 2, 0, 1
+		},
+	}
+	chebyshevU = recurrence{
+		p1: Polynomial{0, 2},
+		coefficients: func(k int) (float64, float64, float64) {
+			return 2, 0, 1
+		},
+	}
+	hermite = recurrence{
+		p1: Polynomial{0, 2},
+		coefficients: func(k int) (float64, float64, float64) {
+			return 2, 0, float64(2 * k)
+		},
+	}
+	laguerre = recurrence{
+		p1: Polynomial{1, -1},
+		coefficients: func(k int) (float64, float64, float64) {
+			return -1 / float64(k+1), float64(2*k+1) / float64(k+1), float64(k) / float64(k+1)
+		},
+	}
+)
+
+/*
+LegendrePolynomials is a method to get the Legendre polynomials P_0 to P_n, orthogonal
+on [-1, 1] with the weight 1, (k+1) P_(k+1) = (2k+1) x P_k - k P_(k-1). The index in
+the slice is the degree, nil is returned for n < 0.
+*/
+func LegendrePolynomials(n int) []Polynomial {
+	return legendre.polynomials(n)
+}
+
+/*
+LegendreP is a method to compute P_n(x) with the recurrence, NaN for n < 0
+*/
+func LegendreP(n int, x float64) float64 {
+	return legendre.evaluate(n, x)
+}
+
+/*
+ChebyshevTPolynomials is a method to get the Chebyshev polynomials of the first kind
+T_0 to T_n, orthogonal on [-1, 1] with the weight 1/sqrt(1 - x^2),
+T_(k+1) = 2x T_k - T_(k-1) and T_k(cos t) = cos(kt). The index in the slice is the
+degree, nil is returned for n < 0.
+*/
+func ChebyshevTPolynomials(n int) []Polynomial {
+	return chebyshevT.polynomials(n)
+}
+
+/*
+ChebyshevT is a method to compute T_n(x) with the recurrence, NaN for n < 0
+*/
+func ChebyshevT(n int, x float64) float64 {
+	return chebyshevT.evaluate(n, x)
+}
+
+/*
+ChebyshevUPolynomials is a method to get the Chebyshev polynomials of the second kind
+U_0 to U_n, orthogonal on [-1, 1] with the weight sqrt(1 - x^2), same recurrence as T
+with U_1 = 2x. The index in the slice is the degree, nil is returned for n < 0.
+*/
+func ChebyshevUPolynomials(n int) []Polynomial {
+	return chebyshevU.polynomials(n)
+}
+
+/*
+ChebyshevU is a method to compute U_n(x) with the recurrence, NaN for n < 0
+*/
+func ChebyshevU(n int, x float64) float64 {
+	return chebyshevU.evaluate(n, x)
+}
+
+/*
+HermitePolynomials is a method to get the (physicists') Hermite polynomials H_0 to H_n,
+orthogonal on R with the weight exp(-x^2), H_(k+1) = 2x H_k - 2k H_(k-1). The index in
+the slice is the degree, nil is returned for n < 0.
+*/
+func HermitePolynomials(n int) []Polynomial {
+	return hermite.polynomials(n)
+}
+
+/*
+HermiteH is a method to compute H_n(x) with the recurrence, NaN for n < 0
+*/
+func HermiteH(n int, x float64) float64 {
+	return hermite.evaluate(n, x)
+}
+
+/*
+LaguerrePolynomials is a method to get the Laguerre polynomials L_0 to L_n, orthogonal
+on [0, +inf) with the weight exp(-x), (k+1) L_(k+1) = (2k+1-x) L_k - k L_(k-1). The
+index in the slice is the degree, nil is returned for n < 0.
+*/
+func LaguerrePolynomials(n int) []Polynomial {
+	return laguerre.polynomials(n)
+}
+
+/*
+LaguerreL is a method to compute L_n(x) with the recurrence, NaN for n < 0
+*/
+func LaguerreL(n int, x float64) float64 {
+	return laguerre.evaluate(n, x)
+}
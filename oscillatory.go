@@ -0,0 +1,111 @@
+package advmath
+
+import (
+	"math"
+)
+
+/*
+FilonSin computes the integral of f(x)*sin(omega*x) between inf and sup with the Filon
+method: f is interpolated by parabolas on pairs of intervals (as Simpson does) but the
+oscillating factor is integrated exactly. The number of intervals only depends on how
+smooth f is, not on omega, whereas the classic rules need several points per
+oscillation and become useless for large omega.
+
+First parameter inf is the lower boundary
+Second parameter sup is the upper boundary
+Third parameter f is the non oscillating part of the integrand
+Fourth parameter omega is the angular frequency
+Fifth parameter n is the number of intervals, it has to be even
+*/
+func FilonSin(inf float64, sup float64, f F, omega float64, n int) (float64, error) {
+	return filon(inf, sup, f, omega, n, true)
+}
+
+/*
+FilonCos computes the integral of f(x)*cos(omega*x) between inf and sup with the Filon
+method, see FilonSin.
+
+First parameter inf is the lower boundary
+Second parameter sup is the upper boundary
+Third parameter f is the non oscillating part of the integrand
+Fourth parameter omega is the angular frequency
+Fifth parameter n is the number of intervals, it has to be even
+*/
+func FilonCos(inf float64, sup float64, f F, omega float64, n int) (float64, error) {
+	return filon(inf, sup, f, omega, n, false)
+}
+
+/*
+filon implements the Filon formulas from Abramowitz and Stegun (25.4.47 and 25.4.49)
+*/
+func filon(inf float64, sup float64, f F, omega float64, n int, sine bool) (float64, error) {
+	if n <= 0 || n%2 != 0 {
+		return 0, &MathError{
+			s: "Invalid number of intervals, for filon, intervals number has to be even",
+		}
+	}
+
+	h := (sup - inf) / float64(n)
+	alpha, beta, gamma := filonCoefficients(omega * h)
+
+	trig := math.Cos
+	if sine {
+		trig = math.Sin
+	}
+	fa := f(inf)
+	fb := f(sup)
+
+	//Sums over the even and the odd points
+	even := -0.5 * (fa*trig(omega*inf) + fb*trig(omega*sup))
+	var odd float64
+	for i := 0; i <= n; i++ {
+		x := inf + float64(i)*h
+		var v float64
+		switch {
+		case i == 0:
+			v = fa * trig(omega*x)
+		case i == n:
+			v = fb * trig(omega*x)
+		default:
+			v = f(x) * trig(omega*x)
+		}
+		if i%2 == 0 {
+			even += v
+		} else {
+			odd += v
+		}
+	}
+
+	var boundary float64
+	if sine {
+		boundary = fa*math.Cos(omega*inf) - fb*math.Cos(omega*sup)
+	} else {
+		boundary = fb*math.Sin(omega*sup) - fa*math.Sin(omega*inf)
+	}
+	return h * (alpha*boundary + beta*even + gamma*odd), nil
+}
+
+/*
+filonCoefficients computes the alpha, beta and gamma coefficients, for small theta
+the series are used since the closed forms suffer from cancellations.
+*/
+func filonCoefficients(theta float64) (float64, float64, float64) {
+	if math.Abs(theta) < 1.0/6.0 {
+		t2 := theta * theta
+		t3 := t2 * theta
+		t4 := t2 * t2
+		t6 := t4 * t2
+		alpha := 2*t3/45 - 2*t3*t2/315 + 2*t3*t4/4725
+		beta := 2.0/3.0 + 2*t2/15 - 4*t4/105 + 2*t6/567
+		gamma := 4.0/3.0 - 2*t2/15 + t4/210 - t6/11340
+		return alpha, beta, gamma
+	}
+
+	sin, cos := math.Sincos(theta)
+	t2 := theta * theta
+	t3 := t2 * theta
+	alpha := 1/theta + sin*cos/t2 - 2*sin*sin/t3
+	beta := 2 * ((1+cos*cos)/t2 - 2*sin*cos/t3)
+	gamma := 4 * (sin/t3 - cos/t2)
+	return alpha, beta, gamma
+}
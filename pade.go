@@ -0,0 +1,93 @@
+package advmath
+
+/*
+PadeApproximant is a rational function Numerator(x)/Denominator(x), the constant term
+of the denominator being 1
+*/
+type PadeApproximant struct {
+	Numerator   Polynomial
+	Denominator Polynomial
+}
+
+/*
+Evaluate is a method to compute the value of the approximant at x
+*/
+func (r PadeApproximant) Evaluate(x float64) float64 {
+	return r.Numerator.Evaluate(x) / r.Denominator.Evaluate(x)
+}
+
+/*
+F is a method returning the approximant as a function
+*/
+func (r PadeApproximant) F() F {
+	return r.Evaluate
+}
+
+/*
+Pade is a method to compute the [m/n] Padé approximant of a Taylor series: the
+rational function P/Q with deg P <= m, deg Q <= n and Q(0) = 1 whose Taylor series
+matches the given one up to x^(m+n). It usually approximates the function much further
+than the truncated series, beyond its radius of convergence.
+
+The coefficients of Q are the solution of a Toeplitz system, solved with the Levinson
+recursion or with the inverse of the matrix if one of its leading submatrices is
+singular, then the coefficients of P are found by multiplying the series by Q.
+
+First parameter coefficients are the Taylor coefficients, the one of x^0 first, at
+least m+n+1 of them are needed
+Second parameter m is the degree of the numerator
+Third parameter n is the degree of the denominator
+It returns an error if there are not enough coefficients or if the system is singular,
+i.e. the [m/n] approximant doesn't exist
+*/
+func Pade(coefficients []float64, m int, n int) (PadeApproximant, error) {
+	if m < 0 || n < 0 || len(coefficients) < m+n+1 {
+		return PadeApproximant{}, &MathError{
+			code: errorDimensionMismatch,
+		}
+	}
+	c := func(k int) float64 {
+		if k < 0 {
+			return 0
+		}
+		return coefficients[k]
+	}
+
+	q := make(Polynomial, n+1)
+	q[0] = 1
+	if n > 0 {
+		//sum(j = 1..n) c(m+i-j) q_j = -c(m+i) for i = 1..n
+		firstRow := make([]float64, n)
+		firstCol := make([]float64, n)
+		b := make([]float64, n)
+		for i := 0; i < n; i++ {
+			firstRow[i] = c(m - i)
+			firstCol[i] = c(m + i)
+			b[i] = -c(m + i + 1)
+		}
+		x, err := SolveToeplitz(firstRow, firstCol, b)
+		if err != nil {
+			t, _ := NewToeplitz(firstRow, firstCol)
+			inverse, err := t.Inverse()
+			if err != nil {
+				return PadeApproximant{}, err
+			}
+			rhs := NewMatrix(uint(n), 1)
+			rhs.M = b
+			solution, _ := inverse.Multiply(rhs)
+			x = solution.M
+		}
+		copy(q[1:], x)
+	}
+
+	p := make(Polynomial, m+1)
+	for k := 0; k <= m; k++ {
+		for j := 0; j <= n && j <= k; j++ {
+			p[k] += q[j] * c(k-j)
+		}
+	}
+	return PadeApproximant{
+		Numerator:   p.trim(),
+		Denominator: q.trim(),
+	}, nil
+}
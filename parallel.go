@@ -0,0 +1,121 @@
+package advmath
+
+import (
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+/*
+parallelPanels runs integrate on each of the panels with a pool of workers, every
+worker takes the next panel not yet integrated until there is none left.
+*/
+func parallelPanels(panels int, workers int, integrate func(p int)) {
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	var next int64 = -1
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				p := int(atomic.AddInt64(&next, 1))
+				if p >= panels {
+					return
+				}
+				integrate(p)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+/*
+ParallelSimpson computes the same integral as Simpson but the interval is split in
+one panel per worker, each panel being integrated concurrently. This is useful when
+each evaluation of f is expensive (a simulation for instance), f must be safe to
+call from several goroutines.
+
+First parameter inf is the lower boundary
+Second parameter sup is the upper boundary
+Third parameter f is the function to integrate
+Fourth parameter n is the total number of intervals, when it is odd the last panel
+ends with the 3/8 rule as Simpson does
+Fifth parameter is the number of workers, the number of CPUs is used when it is 0
+*/
+func ParallelSimpson(inf float64, sup float64, f F, n int, workers int) (float64, error) {
+	if n < 2 {
+		return 0, &MathError{
+			s: "Invalid number of iterations, for simpson, at least 2 iterations are needed",
+		}
+	}
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	//Each panel gets an even number of intervals, the last one gets the odd one left
+	panels := workers
+	if panels > n/2 {
+		panels = n / 2
+	}
+	h := (sup - inf) / float64(n)
+	pairs := n / 2
+
+	//Values are stored by panel so that the sum doesn't depend on the scheduling
+	values := make([]float64, panels)
+	parallelPanels(panels, workers, func(p int) {
+		//Panels are defined by the pairs of intervals they hold
+		first := p * pairs / panels
+		last := (p + 1) * pairs / panels
+		end := inf + float64(2*last)*h
+		intervals := 2 * (last - first)
+		if last == pairs {
+			end = sup
+			intervals += n % 2
+		}
+		values[p], _ = Simpson(inf+float64(2*first)*h, end, f, intervals)
+	})
+
+	var sum float64
+	for _, v := range values {
+		sum += v
+	}
+	return sum, nil
+}
+
+/*
+ParallelGaussKronrod splits the interval in panels of the same width, integrates each
+of them concurrently with GaussKronrod and returns the sum of the values and of the
+error estimates. f must be safe to call from several goroutines.
+
+First parameter inf is the lower boundary
+Second parameter sup is the upper boundary
+Third parameter f is the function to integrate
+Fourth parameter is the number of panels
+Fifth parameter is the number of workers, the number of CPUs is used when it is 0
+First return value is the integral
+Second return value is the estimated absolute error
+*/
+func ParallelGaussKronrod(inf float64, sup float64, f F, panels int, workers int) (float64, float64) {
+	if panels <= 0 {
+		panels = 1
+	}
+	values := make([]float64, panels)
+	errs := make([]float64, panels)
+	width := (sup - inf) / float64(panels)
+	parallelPanels(panels, workers, func(p int) {
+		end := inf + float64(p+1)*width
+		if p == panels-1 {
+			end = sup
+		}
+		values[p], errs[p] = GaussKronrod(inf+float64(p)*width, end, f)
+	})
+
+	var value, err float64
+	for p := range values {
+		value += values[p]
+		err += errs[p]
+	}
+	return value, err
+}
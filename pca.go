@@ -0,0 +1,100 @@
+package advmath
+
+import (
+	"math"
+)
+
+/*
+PCAResult holds the principal components of a data matrix with p variables
+*/
+type PCAResult struct {
+	//Means are the means of the variables, subtracted before the projection
+	Means []float64
+	//Components is the p x p orthogonal matrix whose column k is the direction of the
+	//component k, by decreasing variance. The sign of each column is chosen so that
+	//its largest element is positive
+	Components *Matrix
+	//Variances are the variances of the data along the components (the eigenvalues
+	//of the sample covariance matrix)
+	Variances []float64
+	//ExplainedRatio are the parts of the total variance explained by each component,
+	//their sum is 1
+	ExplainedRatio []float64
+	//Scores is the n x p matrix of the centered data projected on the components,
+	//row i being the coordinates of the observation i
+	Scores *Matrix
+}
+
+/*
+PCA is a function to compute the principal component analysis of a data matrix where
+each row is an observation and each column is a variable: the components are the
+eigenvectors of the sample covariance matrix, the first one being the direction of
+largest variance. Keeping the first k columns of Scores reduces the data to k
+dimensions with the smallest loss. The variables are not scaled, use PCA on
+standardized data (or see Correlation) when their units differ.
+First parameter is the n x p data matrix, n being at least 2
+It returns the components, and an error if there are less than 2 observations or if
+the eigen solver failed
+*/
+func PCA(data *Matrix) (*PCAResult, error) {
+	if data == nil {
+		return nil, &MathError{
+			code: errorMatrixIsNil,
+		}
+	}
+	covariance, err := data.Covariance(true)
+	if err != nil {
+		return nil, err
+	}
+	variances, components, err := covariance.SymmetricEigen()
+	if err != nil {
+		return nil, err
+	}
+
+	p := int(data.NumberOfColumns)
+	var total float64
+	for k := range variances {
+		//Round-off can give tiny negative eigenvalues for rank deficient data
+		variances[k] = math.Max(variances[k], 0)
+		total += variances[k]
+	}
+	ratios := make([]float64, p)
+	for k := 0; k < p; k++ {
+		if total > 0 {
+			ratios[k] = variances[k] / total
+		}
+		largest := 0
+		for i := 0; i < p; i++ {
+			if math.Abs(components.M[i*p+k]) > math.Abs(components.M[largest*p+k]) {
+				largest = i
+			}
+		}
+		if components.M[largest*p+k] < 0 {
+			for i := 0; i < p; i++ {
+				components.M[i*p+k] = -components.M[i*p+k]
+			}
+		}
+	}
+
+	means := data.columnMeans()
+	n := int(data.NumberOfRows)
+	scores := NewMatrix(uint(n), uint(p))
+	centered := make([]float64, p)
+	for r := 0; r < n; r++ {
+		for i := range centered {
+			centered[i] = data.M[r*p+i] - means[i]
+		}
+		for k := 0; k < p; k++ {
+			for i := 0; i < p; i++ {
+				scores.M[r*p+k] += centered[i] * components.M[i*p+k]
+			}
+		}
+	}
+	return &PCAResult{
+		Means:          means,
+		Components:     components,
+		Variances:      variances,
+		ExplainedRatio: ratios,
+		Scores:         scores,
+	}, nil
+}
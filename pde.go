@@ -0,0 +1,235 @@
+package advmath
+
+/*
+HeatEquation is the 1-D heat (diffusion) equation u_t = D u_xx for x in [0, Length],
+with the initial temperature u(x, 0) and the temperatures at both ends (Dirichlet
+boundary conditions). The solvers work on a uniform grid of nx intervals in space
+and nt steps in time and return the solution as a matrix whose row k is u at
+t = k*duration/nt and column i is u at x = i*Length/nx.
+*/
+type HeatEquation struct {
+	//Diffusivity is the coefficient D
+	Diffusivity float64
+	//Length is the length of the interval
+	Length float64
+	//Initial is u(x, 0)
+	Initial F
+	//Left is u(0, t), 0 when it is nil
+	Left F
+	//Right is u(Length, t), 0 when it is nil
+	Right F
+}
+
+/*
+WaveEquation is the 1-D wave equation u_tt = c^2 u_xx for x in [0, Length], with the
+initial position u(x, 0), the initial velocity u_t(x, 0) and the positions of both
+ends (Dirichlet boundary conditions), e.g. a vibrating string. The solution is
+returned as for HeatEquation.
+*/
+type WaveEquation struct {
+	//Speed is the wave speed c
+	Speed float64
+	//Length is the length of the interval
+	Length float64
+	//Initial is u(x, 0)
+	Initial F
+	//Velocity is u_t(x, 0), 0 when it is nil
+	Velocity F
+	//Left is u(0, t), 0 when it is nil
+	Left F
+	//Right is u(Length, t), 0 when it is nil
+	Right F
+}
+
+/*
+pdeGrid is a uniform grid on [0, length] x [0, duration]
+*/
+type pdeGrid struct {
+	nx, nt int
+	dx, dt float64
+}
+
+func newPDEGrid(length, duration float64, nx, nt int, initial F) (*pdeGrid, error) {
+	if nx < 2 || nt < 1 || initial == nil {
+		return nil, &MathError{
+			code: errorDimensionMismatch,
+		}
+	}
+	if length <= 0 || duration <= 0 {
+		return nil, &MathError{
+			s: "The length and the duration of a PDE grid must be positive",
+		}
+	}
+	return &pdeGrid{
+		nx: nx,
+		nt: nt,
+		dx: length / float64(nx),
+		dt: duration / float64(nt),
+	}, nil
+}
+
+/*
+start creates the solution matrix with the initial condition on its first row
+*/
+func (g *pdeGrid) start(initial, left, right F) *Matrix {
+	cols := uint(g.nx + 1)
+	u := NewMatrix(uint(g.nt+1), cols)
+	for i := 0; i <= g.nx; i++ {
+		u.M[i] = initial(float64(i) * g.dx)
+	}
+	//The boundary conditions win over the initial condition at the corners
+	u.M[0] = valueOrZero(left, 0)
+	u.M[g.nx] = valueOrZero(right, 0)
+	return u
+}
+
+/*
+row returns the row k of the solution matrix, sharing its storage
+*/
+func (g *pdeGrid) row(u *Matrix, k int) []float64 {
+	cols := g.nx + 1
+	return u.M[k*cols : (k+1)*cols]
+}
+
+/*
+valueOrZero evaluates an optional condition, nil meaning 0
+*/
+func valueOrZero(f F, x float64) float64 {
+	if f == nil {
+		return 0
+	}
+	return f(x)
+}
+
+/*
+Explicit is a method to solve the heat equation with the explicit FTCS scheme (forward
+in time, centered in space): u[k+1][i] = u[k][i] + r (u[k][i-1] - 2u[k][i] + u[k][i+1])
+with r = D dt/dx^2. It is first order in time and second order in space, and only
+stable when r <= 1/2, so refining the grid in space needs four times more steps.
+First parameter nx is the number of intervals in space (at least 2)
+Second parameter nt is the number of steps in time
+Third parameter duration is the final time
+It returns an error if r > 1/2
+*/
+func (h HeatEquation) Explicit(nx, nt int, duration float64) (*Matrix, error) {
+	g, err := newPDEGrid(h.Length, duration, nx, nt, h.Initial)
+	if err != nil {
+		return nil, err
+	}
+	r := h.Diffusivity * g.dt / (g.dx * g.dx)
+	if r > 0.5 {
+		return nil, &MathError{
+			s: "Unstable explicit scheme, D dt/dx^2 must be at most 1/2",
+		}
+	}
+
+	u := g.start(h.Initial, h.Left, h.Right)
+	for k := 1; k <= nt; k++ {
+		previous, current := g.row(u, k-1), g.row(u, k)
+		for i := 1; i < nx; i++ {
+			current[i] = previous[i] + r*(previous[i-1]-2*previous[i]+previous[i+1])
+		}
+		t := float64(k) * g.dt
+		current[0] = valueOrZero(h.Left, t)
+		current[nx] = valueOrZero(h.Right, t)
+	}
+	return u, nil
+}
+
+/*
+CrankNicolson is a method to solve the heat equation with the Crank-Nicolson scheme,
+the average of the explicit and the implicit schemes:
+u[k+1] - r/2 A u[k+1] = u[k] + r/2 A u[k], A being the second difference. Each step
+solves a tridiagonal system (SolveTridiagonal). It is second order in time and in
+space and stable for any step, although large steps give oscillations when the
+initial condition is not smooth.
+First parameter nx is the number of intervals in space (at least 2)
+Second parameter nt is the number of steps in time
+Third parameter duration is the final time
+*/
+func (h HeatEquation) CrankNicolson(nx, nt int, duration float64) (*Matrix, error) {
+	g, err := newPDEGrid(h.Length, duration, nx, nt, h.Initial)
+	if err != nil {
+		return nil, err
+	}
+	r := h.Diffusivity * g.dt / (g.dx * g.dx)
+
+	//The matrix is the same at every step, only the right hand side changes
+	m := nx - 1
+	lower := make([]float64, m-1)
+	diagonal := make([]float64, m)
+	upper := make([]float64, m-1)
+	for i := range diagonal {
+		diagonal[i] = 1 + r
+		if i < m-1 {
+			lower[i] = -r / 2
+			upper[i] = -r / 2
+		}
+	}
+
+	u := g.start(h.Initial, h.Left, h.Right)
+	b := make([]float64, m)
+	for k := 1; k <= nt; k++ {
+		previous, current := g.row(u, k-1), g.row(u, k)
+		t := float64(k) * g.dt
+		current[0] = valueOrZero(h.Left, t)
+		current[nx] = valueOrZero(h.Right, t)
+		for i := 1; i < nx; i++ {
+			b[i-1] = r/2*previous[i-1] + (1-r)*previous[i] + r/2*previous[i+1]
+		}
+		//Known values of the new step at the ends
+		b[0] += r / 2 * current[0]
+		b[m-1] += r / 2 * current[nx]
+		interior, err := SolveTridiagonal(lower, diagonal, upper, b)
+		if err != nil {
+			return nil, err
+		}
+		copy(current[1:nx], interior)
+	}
+	return u, nil
+}
+
+/*
+Leapfrog is a method to solve the wave equation with the leapfrog scheme, centered in
+time and in space: u[k+1][i] = 2u[k][i] - u[k-1][i] + C^2 (u[k][i-1] - 2u[k][i] + u[k][i+1])
+with the Courant number C = c dt/dx. The first step uses a Taylor expansion with the
+initial velocity. It is second order in time and in space, and only stable when
+C <= 1 (the CFL condition); C = 1 gives the exact solution on the grid.
+First parameter nx is the number of intervals in space (at least 2)
+Second parameter nt is the number of steps in time
+Third parameter duration is the final time
+It returns an error if C > 1
+*/
+func (w WaveEquation) Leapfrog(nx, nt int, duration float64) (*Matrix, error) {
+	g, err := newPDEGrid(w.Length, duration, nx, nt, w.Initial)
+	if err != nil {
+		return nil, err
+	}
+	courant := w.Speed * g.dt / g.dx
+	//Small tolerance so that C = 1 computed with round-off is accepted
+	if courant > 1+1e-12 {
+		return nil, &MathError{
+			s: "Unstable leapfrog scheme, c dt/dx must be at most 1",
+		}
+	}
+	c2 := courant * courant
+
+	u := g.start(w.Initial, w.Left, w.Right)
+	for k := 1; k <= nt; k++ {
+		current := g.row(u, k)
+		previous := g.row(u, k-1)
+		for i := 1; i < nx; i++ {
+			laplacian := previous[i-1] - 2*previous[i] + previous[i+1]
+			if k == 1 {
+				//u(dt) = u(0) + dt u_t(0) + dt^2/2 u_tt(0)
+				current[i] = previous[i] + g.dt*valueOrZero(w.Velocity, float64(i)*g.dx) + c2/2*laplacian
+			} else {
+				current[i] = 2*previous[i] - g.row(u, k-2)[i] + c2*laplacian
+			}
+		}
+		t := float64(k) * g.dt
+		current[0] = valueOrZero(w.Left, t)
+		current[nx] = valueOrZero(w.Right, t)
+	}
+	return u, nil
+}
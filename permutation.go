@@ -0,0 +1,167 @@
+package advmath
+
+/*
+Permutation is a reordering of n indices stored as a slice: applied to the rows
+of a matrix, row i of the result is row p[i] of the original matrix.
+*/
+type Permutation []uint
+
+/*
+NewPermutation is a method to create the identity permutation of n indices.
+First parameter is the number of indices
+*/
+func NewPermutation(n uint) Permutation {
+	p := make(Permutation, n)
+	for i := range p {
+		p[i] = uint(i)
+	}
+	return p
+}
+
+/*
+IsValid is a method checking that every index from 0 to n-1 appears exactly once.
+*/
+func (p Permutation) IsValid() bool {
+	seen := make([]bool, len(p))
+	for _, v := range p {
+		if v >= uint(len(p)) || seen[v] {
+			return false
+		}
+		seen[v] = true
+	}
+	return true
+}
+
+/*
+Swap is a method to exchange two indices of the permutation, it changes the
+permutation in place. This is what a row swap during a pivoting does.
+*/
+func (p Permutation) Swap(i, j uint) {
+	p[i], p[j] = p[j], p[i]
+}
+
+/*
+Compose is a method returning the permutation equivalent to applying p and
+then q.
+First parameter is the permutation applied after p
+*/
+func (p Permutation) Compose(q Permutation) (Permutation, error) {
+	if len(p) != len(q) {
+		return nil, &MathError{
+			code: errorDimensionMismatch,
+		}
+	}
+	r := make(Permutation, len(p))
+	for i := range q {
+		r[i] = p[q[i]]
+	}
+	return r, nil
+}
+
+/*
+Inverse is a method returning the permutation undoing p.
+*/
+func (p Permutation) Inverse() Permutation {
+	inv := make(Permutation, len(p))
+	for i, v := range p {
+		inv[v] = uint(i)
+	}
+	return inv
+}
+
+/*
+Sign is a method returning the signature of the permutation: 1 if it is made
+of an even number of transpositions, -1 otherwise. It is the determinant of
+the permutation matrix.
+*/
+func (p Permutation) Sign() int {
+	visited := make([]bool, len(p))
+	sign := 1
+	for i := range p {
+		if visited[i] {
+			continue
+		}
+		//A cycle of length l is l-1 transpositions
+		length := 0
+		for j := uint(i); !visited[j]; j = p[j] {
+			visited[j] = true
+			length++
+		}
+		if length%2 == 0 {
+			sign = -sign
+		}
+	}
+	return sign
+}
+
+/*
+Matrix is a method returning the permutation matrix P so that P*A is the same
+as ApplyRows(A).
+*/
+func (p Permutation) Matrix() *Matrix {
+	n := uint(len(p))
+	m := NewMatrix(n, n)
+	for i, v := range p {
+		m.M[uint(i)*n+v] = 1.0
+	}
+	return m
+}
+
+/*
+ApplyRows is a method returning a copy of the matrix with its rows reordered:
+row i of the result is row p[i] of m.
+First parameter is the matrix to reorder
+*/
+func (p Permutation) ApplyRows(m *Matrix) (*Matrix, error) {
+	if uint(len(p)) != m.NumberOfRows {
+		return nil, &MathError{
+			code: errorDimensionMismatch,
+		}
+	}
+	ret := NewMatrix(m.NumberOfRows, m.NumberOfColumns)
+	c := m.NumberOfColumns
+	for i, v := range p {
+		copy(ret.M[uint(i)*c:uint(i+1)*c], m.M[v*c:(v+1)*c])
+	}
+	return ret, nil
+}
+
+/*
+ApplyColumns is a method returning a copy of the matrix with its columns
+reordered: column j of the result is column p[j] of m.
+First parameter is the matrix to reorder
+*/
+func (p Permutation) ApplyColumns(m *Matrix) (*Matrix, error) {
+	if uint(len(p)) != m.NumberOfColumns {
+		return nil, &MathError{
+			code: errorDimensionMismatch,
+		}
+	}
+	ret := NewMatrix(m.NumberOfRows, m.NumberOfColumns)
+	c := m.NumberOfColumns
+	var i uint
+	for i = 0; i < m.NumberOfRows; i++ {
+		for j, v := range p {
+			ret.M[i*c+uint(j)] = m.M[i*c+v]
+		}
+	}
+	return ret, nil
+}
+
+/*
+ApplyVector is a method returning a copy of the vector reordered the same way
+as ApplyRows, useful to permute the right hand side of a system.
+First parameter is the vector to reorder
+*/
+func (p Permutation) ApplyVector(v []float64) ([]float64, error) {
+	if len(p) != len(v) {
+		return nil, &MathError{
+			code: errorDimensionMismatch,
+		}
+	}
+	ret := make([]float64, len(v))
+	for i, idx := range p {
+		ret[i] = v[idx]
+	}
+	return ret, nil
+}
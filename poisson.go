@@ -0,0 +1,278 @@
+package advmath
+
+import (
+	"math"
+)
+
+/*
+PoissonMethod is the iterative method used to solve the linear system of a Poisson
+equation
+*/
+type PoissonMethod int
+
+const (
+	//ConjugateGradient converges in about n iterations for n points per side
+	ConjugateGradient PoissonMethod = iota
+	//SOR is the successive over-relaxation (Gauss-Seidel with a relaxation factor),
+	//simpler but it needs more iterations than ConjugateGradient
+	SOR
+)
+
+/*
+PoissonOptions holds the settings of PoissonEquation.Solve. The zero value uses the
+conjugate gradient with the defaults given for each field.
+*/
+type PoissonOptions struct {
+	//Method is ConjugateGradient or SOR
+	Method PoissonMethod
+	//Tolerance is the norm of the residual relative to the norm of the right hand
+	//side at which the iterations stop, 1e-10 when it is 0
+	Tolerance float64
+	//MaxIterations is the maximum number of iterations, 10 times the number of
+	//unknowns (at least 1000) when it is 0
+	MaxIterations int
+	//Omega is the relaxation factor of SOR, between 0 and 2, the optimal factor for
+	//the grid is used when it is 0
+	Omega float64
+}
+
+/*
+PoissonEquation is the 2-D Poisson equation u_xx + u_yy = f(x, y) on the rectangle
+[X0, X1] x [Y0, Y1] with the values of u on the border (Dirichlet boundary
+conditions), e.g. the electrostatic potential of a charge density or the steady state
+temperature of a heated plate.
+*/
+type PoissonEquation struct {
+	//Source is f, given [x, y], 0 when it is nil (Laplace equation)
+	Source Fn
+	//Boundary is u on the border, given [x, y], 0 when it is nil
+	Boundary Fn
+	//X0 and X1 are the bounds of the rectangle in x
+	X0, X1 float64
+	//Y0 and Y1 are the bounds of the rectangle in y
+	Y0, Y1 float64
+}
+
+/*
+sparseMatrix is a matrix in compressed sparse row format: the non zero values of the
+row i are values[starts[i]:starts[i+1]], in the columns columns[starts[i]:starts[i+1]]
+*/
+type sparseMatrix struct {
+	values  []float64
+	columns []int
+	starts  []int
+}
+
+func (s *sparseMatrix) rows() int {
+	return len(s.starts) - 1
+}
+
+/*
+multiply computes s*x in y
+*/
+func (s *sparseMatrix) multiply(x, y []float64) {
+	for i := range y {
+		var sum float64
+		for k := s.starts[i]; k < s.starts[i+1]; k++ {
+			sum += s.values[k] * x[s.columns[k]]
+		}
+		y[i] = sum
+	}
+}
+
+/*
+dot is the dot product of two vectors of the same length
+*/
+func dot(x, y []float64) float64 {
+	var sum float64
+	for i := range x {
+		sum += x[i] * y[i]
+	}
+	return sum
+}
+
+/*
+conjugateGradient solves a x = b for a symmetric positive definite, starting from x
+*/
+func (s *sparseMatrix) conjugateGradient(b, x []float64, tol float64, maxIterations int) error {
+	n := len(b)
+	r := make([]float64, n)
+	ap := make([]float64, n)
+	s.multiply(x, ap)
+	for i := range r {
+		r[i] = b[i] - ap[i]
+	}
+	p := append([]float64(nil), r...)
+	rr := dot(r, r)
+	target := tol * tol * dot(b, b)
+	for iteration := 0; iteration < maxIterations; iteration++ {
+		if rr <= target {
+			return nil
+		}
+		s.multiply(p, ap)
+		alpha := rr / dot(p, ap)
+		for i := range x {
+			x[i] += alpha * p[i]
+			r[i] -= alpha * ap[i]
+		}
+		next := dot(r, r)
+		for i := range p {
+			p[i] = r[i] + next/rr*p[i]
+		}
+		rr = next
+	}
+	if rr <= target {
+		return nil
+	}
+	return &MathError{
+		code: errorNotConverged,
+	}
+}
+
+/*
+sor solves a x = b with the successive over-relaxation, starting from x
+*/
+func (s *sparseMatrix) sor(b, x []float64, omega, tol float64, maxIterations int) error {
+	n := len(b)
+	r := make([]float64, n)
+	target := tol * tol * dot(b, b)
+	for iteration := 0; iteration < maxIterations; iteration++ {
+		s.multiply(x, r)
+		var rr float64
+		for i := range r {
+			rr += (b[i] - r[i]) * (b[i] - r[i])
+		}
+		if rr <= target {
+			return nil
+		}
+		for i := 0; i < n; i++ {
+			sum := b[i]
+			var diagonal float64
+			for k := s.starts[i]; k < s.starts[i+1]; k++ {
+				if s.columns[k] == i {
+					diagonal = s.values[k]
+				} else {
+					sum -= s.values[k] * x[s.columns[k]]
+				}
+			}
+			x[i] += omega * (sum/diagonal - x[i])
+		}
+	}
+	return &MathError{
+		code: errorNotConverged,
+	}
+}
+
+/*
+Solve is a method to solve the Poisson equation with the five-point finite difference
+stencil on a uniform grid: (u[i-1][j] - 2u[i][j] + u[i+1][j])/hx^2 +
+(u[i][j-1] - 2u[i][j] + u[i][j+1])/hy^2 = f(x[i], y[j]). The equations of the interior
+points are assembled as a sparse symmetric positive definite system solved with the
+conjugate gradient or SOR. The method is second order: the error is divided by 4 when
+the grid is refined by 2.
+First parameter nx is the number of intervals in x (at least 2)
+Second parameter ny is the number of intervals in y (at least 2)
+Third parameter options are the method and the stopping criteria
+It returns the solution as a matrix of nx+1 rows and ny+1 columns, the element (i, j)
+being u at x = X0 + i*hx, y = Y0 + j*hy (the layout of NewInterp2D), and an error if
+the grid is not valid or if the method didn't converge
+*/
+func (p PoissonEquation) Solve(nx, ny int, options PoissonOptions) (*Matrix, error) {
+	if nx < 2 || ny < 2 {
+		return nil, &MathError{
+			code: errorDimensionMismatch,
+		}
+	}
+	if p.X1 <= p.X0 || p.Y1 <= p.Y0 {
+		return nil, &MathError{
+			s: "The rectangle of a Poisson equation must have a positive area",
+		}
+	}
+	hx := (p.X1 - p.X0) / float64(nx)
+	hy := (p.Y1 - p.Y0) / float64(ny)
+	point := func(i, j int) []float64 {
+		return []float64{p.X0 + float64(i)*hx, p.Y0 + float64(j)*hy}
+	}
+
+	//Border
+	cols := uint(ny + 1)
+	u := NewMatrix(uint(nx+1), cols)
+	if p.Boundary != nil {
+		for i := 0; i <= nx; i++ {
+			for j := 0; j <= ny; j++ {
+				if i == 0 || i == nx || j == 0 || j == ny {
+					u.M[uint(i)*cols+uint(j)] = p.Boundary(point(i, j))
+				}
+			}
+		}
+	}
+
+	//Unknown (i, j) of the interior is number (i-1)*(ny-1) + j-1. The equations are
+	//multiplied by -1 so that the matrix is positive definite
+	m := ny - 1
+	n := (nx - 1) * m
+	index := func(i, j int) int {
+		return (i-1)*m + j - 1
+	}
+	cx, cy := 1/(hx*hx), 1/(hy*hy)
+	a := &sparseMatrix{
+		starts: make([]int, 1, n+1),
+	}
+	b := make([]float64, n)
+	for i := 1; i < nx; i++ {
+		for j := 1; j < ny; j++ {
+			k := index(i, j)
+			if p.Source != nil {
+				b[k] = -p.Source(point(i, j))
+			}
+			neighbours := [4]struct {
+				i, j int
+				c    float64
+			}{{i - 1, j, cx}, {i + 1, j, cx}, {i, j - 1, cy}, {i, j + 1, cy}}
+			a.values = append(a.values, 2*cx+2*cy)
+			a.columns = append(a.columns, k)
+			for _, nb := range neighbours {
+				if nb.i == 0 || nb.i == nx || nb.j == 0 || nb.j == ny {
+					//Known value on the border
+					b[k] += nb.c * u.M[uint(nb.i)*cols+uint(nb.j)]
+					continue
+				}
+				a.values = append(a.values, -nb.c)
+				a.columns = append(a.columns, index(nb.i, nb.j))
+			}
+			a.starts = append(a.starts, len(a.values))
+		}
+	}
+
+	tol := options.Tolerance
+	if tol <= 0 {
+		tol = 1e-10
+	}
+	maxIterations := options.MaxIterations
+	if maxIterations <= 0 {
+		maxIterations = int(math.Max(1000, float64(10*a.rows())))
+	}
+	x := make([]float64, n)
+	var err error
+	if options.Method == SOR {
+		omega := options.Omega
+		if omega <= 0 {
+			//Spectral radius of the Jacobi iteration on the grid
+			rho := (cx*math.Cos(math.Pi/float64(nx)) + cy*math.Cos(math.Pi/float64(ny))) / (cx + cy)
+			omega = 2 / (1 + math.Sqrt(1-rho*rho))
+		}
+		err = a.sor(b, x, omega, tol, maxIterations)
+	} else {
+		err = a.conjugateGradient(b, x, tol, maxIterations)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for i := 1; i < nx; i++ {
+		for j := 1; j < ny; j++ {
+			u.M[uint(i)*cols+uint(j)] = x[index(i, j)]
+		}
+	}
+	return u, nil
+}
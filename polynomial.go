@@ -0,0 +1,505 @@
+package advmath
+
+import (
+	"math"
+	"math/cmplx"
+	"sort"
+)
+
+/*
+Polynomial is a polynomial with real coefficients, p[i] being the coefficient of x^i,
+so that []float64{-1, 0, 1} is x^2 - 1
+*/
+type Polynomial []float64
+
+/*
+NewPolynomial is a method to create a polynomial from its coefficients, the one of
+the constant first. Leading zero coefficients are removed.
+*/
+func NewPolynomial(coefficients ...float64) Polynomial {
+	p := make(Polynomial, len(coefficients))
+	copy(p, coefficients)
+	return p.trim()
+}
+
+/*
+trim removes the leading zero coefficients
+*/
+func (p Polynomial) trim() Polynomial {
+	n := len(p)
+	for n > 0 && p[n-1] == 0 {
+		n--
+	}
+	return p[:n]
+}
+
+/*
+Degree is a method to get the degree of the polynomial, -1 for the zero polynomial
+*/
+func (p Polynomial) Degree() int {
+	return len(p.trim()) - 1
+}
+
+/*
+Evaluate is a method to compute p(x) with Horner's scheme
+*/
+func (p Polynomial) Evaluate(x float64) float64 {
+	var v float64
+	for i := len(p) - 1; i >= 0; i-- {
+		v = v*x + p[i]
+	}
+	return v
+}
+
+/*
+EvaluateComplex is a method to compute p(z) for a complex z with Horner's scheme
+*/
+func (p Polynomial) EvaluateComplex(z complex128) complex128 {
+	var v complex128
+	for i := len(p) - 1; i >= 0; i-- {
+		v = v*z + complex(p[i], 0)
+	}
+	return v
+}
+
+/*
+F is a method returning the polynomial as a function, to give it to the integration
+or solving methods
+*/
+func (p Polynomial) F() F {
+	return p.Evaluate
+}
+
+/*
+Derivative is a method to get the derivative of the polynomial, computed exactly from
+the coefficients
+*/
+func (p Polynomial) Derivative() Polynomial {
+	p = p.trim()
+	if len(p) <= 1 {
+		return Polynomial{}
+	}
+	d := make(Polynomial, len(p)-1)
+	for i := 1; i < len(p); i++ {
+		d[i-1] = float64(i) * p[i]
+	}
+	return d
+}
+
+/*
+Integral is a method to get the antiderivative of the polynomial whose value at 0 is
+constant
+*/
+func (p Polynomial) Integral(constant float64) Polynomial {
+	p = p.trim()
+	q := make(Polynomial, len(p)+1)
+	q[0] = constant
+	for i := range p {
+		q[i+1] = p[i] / float64(i+1)
+	}
+	return q.trim()
+}
+
+/*
+DefiniteIntegral is a method to compute the integral of the polynomial between a and b
+exactly from the coefficients, without a quadrature
+*/
+func (p Polynomial) DefiniteIntegral(a float64, b float64) float64 {
+	q := p.Integral(0)
+	return q.Evaluate(b) - q.Evaluate(a)
+}
+
+/*
+Roots is a method to compute all the roots, real and complex, of the polynomial with
+the Durand-Kerner (Weierstrass) method: all the roots are improved at the same time,
+each one with z = z - p(z)/prod(z - other roots), then each root is polished with a
+few Newton iterations on p. Zero roots are found exactly. Multiple roots can only be
+found with about 16/m correct digits, m being the multiplicity, as with any method.
+The roots are sorted by real part then imaginary part.
+
+It returns an error for the zero polynomial (every number is a root) or if the
+iterations did not converge, the roots are then the last estimates
+*/
+func (p Polynomial) Roots() ([]complex128, error) {
+	p = p.trim()
+	if len(p) == 0 {
+		return nil, &MathError{
+			s: "The zero polynomial has infinitely many roots",
+		}
+	}
+
+	//x^k factors give exact zero roots
+	var roots []complex128
+	for len(p) > 1 && p[0] == 0 {
+		roots = append(roots, 0)
+		p = p[1:]
+	}
+	n := len(p) - 1
+	if n == 0 {
+		return roots, nil
+	}
+
+	//Monic polynomial and Cauchy bound on the modulus of the roots
+	monic := make(Polynomial, n+1)
+	radius := 0.0
+	for i := range p {
+		monic[i] = p[i] / p[n]
+		if i < n {
+			radius = math.Max(radius, math.Abs(monic[i]))
+		}
+	}
+	radius++
+
+	//absolute bounds the round-off of the evaluation of monic
+	absolute := make(Polynomial, n+1)
+	for i := range monic {
+		absolute[i] = math.Abs(monic[i])
+	}
+
+	//Initial values on a circle, not symmetric with respect to the real axis
+	z := make([]complex128, n)
+	for k := range z {
+		z[k] = cmplx.Rect(radius, 2*math.Pi*float64(k)/float64(n)+0.4)
+	}
+
+	converged := false
+	for iteration := 0; iteration < 1000 && !converged; iteration++ {
+		converged = true
+		for k := range z {
+			denominator := complex(1, 0)
+			for j := range z {
+				if j != k {
+					denominator *= z[k] - z[j]
+				}
+			}
+			if denominator == 0 {
+				//Two estimates are equal, move one of them a little
+				z[k] += complex(radius*1e-8, radius*1e-8)
+				converged = false
+				continue
+			}
+			value := monic.EvaluateComplex(z[k])
+			step := value / denominator
+			z[k] -= step
+			//Converged when the step is tiny or when p(z) is only round-off
+			if cmplx.Abs(step) > 1e-14*math.Max(cmplx.Abs(z[k]), 1e-300) &&
+				cmplx.Abs(value) > 16*machineEpsilon*absolute.Evaluate(cmplx.Abs(z[k])) {
+				converged = false
+			}
+		}
+	}
+
+	//Newton polishing
+	derivative := make(Polynomial, n)
+	for i := 1; i <= n; i++ {
+		derivative[i-1] = float64(i) * monic[i]
+	}
+	for k := range z {
+		for i := 0; i < 3; i++ {
+			d := derivative.EvaluateComplex(z[k])
+			if d == 0 {
+				break
+			}
+			next := z[k] - monic.EvaluateComplex(z[k])/d
+			if cmplx.IsNaN(next) || cmplx.IsInf(next) {
+				break
+			}
+			z[k] = next
+		}
+	}
+
+	roots = append(roots, z...)
+	sort.Slice(roots, func(i, j int) bool {
+		if real(roots[i]) != real(roots[j]) {
+			return real(roots[i]) < real(roots[j])
+		}
+		return imag(roots[i]) < imag(roots[j])
+	})
+	if !converged {
+		return roots, &MathError{
+			code: errorNotConverged,
+		}
+	}
+	return roots, nil
+}
+
+/*
+RealRoots is a method to get the real roots of the polynomial, i.e. the roots found
+by Roots whose imaginary part is below tol, in increasing order
+*/
+func (p Polynomial) RealRoots(tol float64) ([]float64, error) {
+	roots, err := p.Roots()
+	var values []float64
+	for _, r := range roots {
+		if math.Abs(imag(r)) <= tol {
+			values = append(values, real(r))
+		}
+	}
+	return values, err
+}
+
+/*
+Divide is a method to compute the quotient and the remainder of the long division of
+p by q, so that p = quotient*q + remainder with the degree of remainder lower than the
+degree of q
+
+It returns an error if q is the zero polynomial
+*/
+func (p Polynomial) Divide(q Polynomial) (Polynomial, Polynomial, error) {
+	q = q.trim()
+	if len(q) == 0 {
+		return nil, nil, &MathError{
+			code: errorDivisionByZero,
+		}
+	}
+	remainder := make(Polynomial, len(p.trim()))
+	copy(remainder, p)
+	if len(remainder) < len(q) {
+		return Polynomial{}, remainder, nil
+	}
+
+	n := len(q) - 1
+	quotient := make(Polynomial, len(remainder)-n)
+	for i := len(quotient) - 1; i >= 0; i-- {
+		c := remainder[i+n] / q[n]
+		quotient[i] = c
+		for j := 0; j <= n; j++ {
+			remainder[i+j] -= c * q[j]
+		}
+		//The leading coefficient is exactly cancelled
+		remainder[i+n] = 0
+	}
+	return quotient.trim(), remainder.trim(), nil
+}
+
+/*
+GCD is a method to compute the greatest common divisor of p and q with the Euclidean
+algorithm. As the coefficients are floating point numbers, the coefficients of a
+remainder smaller than tol times the largest coefficient of the dividend are
+considered to be zero, tol <= 0 means 1e-10. The result is monic (leading coefficient
+1), or the zero polynomial if p and q are both zero.
+*/
+func (p Polynomial) GCD(q Polynomial, tol float64) Polynomial {
+	if tol <= 0 {
+		tol = 1e-10
+	}
+	a := NewPolynomial(p...)
+	b := NewPolynomial(q...)
+	if len(a) < len(b) {
+		a, b = b, a
+	}
+	for len(b) > 0 {
+		scale := 0.0
+		for _, c := range a {
+			scale = math.Max(scale, math.Abs(c))
+		}
+		_, r, _ := a.Divide(b)
+		for i := range r {
+			if math.Abs(r[i]) <= tol*scale {
+				r[i] = 0
+			}
+		}
+		a, b = b, r.trim()
+	}
+
+	if len(a) == 0 {
+		return a
+	}
+	monic := make(Polynomial, len(a))
+	for i := range a {
+		monic[i] = a[i] / a[len(a)-1]
+	}
+	return monic
+}
+
+/*
+Multiply is a method to compute the product of two polynomials
+*/
+func (p Polynomial) Multiply(q Polynomial) Polynomial {
+	p = p.trim()
+	q = q.trim()
+	if len(p) == 0 || len(q) == 0 {
+		return Polynomial{}
+	}
+	product := make(Polynomial, len(p)+len(q)-1)
+	for i := range p {
+		for j := range q {
+			product[i+j] += p[i] * q[j]
+		}
+	}
+	return product
+}
+
+/*
+FromRoots is a method to create the monic polynomial whose roots are the given ones,
+(x - roots[0])(x - roots[1])..., a root given k times having multiplicity k
+*/
+func FromRoots(roots []float64) Polynomial {
+	p := Polynomial{1}
+	for _, r := range roots {
+		p = p.Multiply(Polynomial{-r, 1})
+	}
+	return p
+}
+
+/*
+CharacteristicPolynomial is a method to compute det(xI - m) with the Faddeev-LeVerrier
+algorithm: with M_0 = 0 and c_n = 1, M_k = m*M_(k-1) + c_(n-k+1)*I and
+c_(n-k) = -trace(m*M_k)/k. Its roots are the eigenvalues of m, its constant term is
+(-1)^n det(m). The algorithm uses n matrix products, so it is meant for small matrices,
+it loses precision when n grows.
+
+It returns an error if the matrix is not square
+*/
+func (m Matrix) CharacteristicPolynomial() (Polynomial, error) {
+	if !m.IsSquare() {
+		return nil, &MathError{
+			code: errorNonSquareMatrix,
+		}
+	}
+	n := int(m.NumberOfRows)
+	p := make(Polynomial, n+1)
+	p[n] = 1
+	mk := NewMatrix(m.NumberOfRows, m.NumberOfColumns)
+	for k := 1; k <= n; k++ {
+		//M_k = m*M_(k-1) + c_(n-k+1)*I
+		product, _ := m.Multiply(mk)
+		for i := 0; i < n; i++ {
+			product.M[i*n+i] += p[n-k+1]
+		}
+		mk = product
+		am, _ := m.Multiply(mk)
+		trace, _ := am.Trace()
+		p[n-k] = -trace / float64(k)
+	}
+	return p, nil
+}
+
+/*
+SturmSequence is a method to compute the Sturm sequence of the polynomial: p, p' and
+then the opposite of the remainder of the division of the two previous ones, until
+the remainder is zero (coefficients smaller than 1e-10 times the largest coefficient
+of the dividend are considered to be zero). The number of sign changes of the sequence
+at x decreases by one each time x goes past a distinct real root.
+*/
+func (p Polynomial) SturmSequence() []Polynomial {
+	const tol = 1e-10
+	p = NewPolynomial(p...)
+	if len(p) == 0 {
+		return nil
+	}
+	sequence := []Polynomial{p}
+	next := p.Derivative()
+	for len(next) > 0 {
+		sequence = append(sequence, next)
+		a := sequence[len(sequence)-2]
+		scale := 0.0
+		for _, c := range a {
+			scale = math.Max(scale, math.Abs(c))
+		}
+		_, r, _ := a.Divide(next)
+		for i := range r {
+			if math.Abs(r[i]) <= tol*scale {
+				r[i] = 0
+			} else {
+				r[i] = -r[i]
+			}
+		}
+		next = r.trim()
+	}
+	return sequence
+}
+
+/*
+signChanges is the number of sign changes of the sequence at x, zeros being ignored
+*/
+func signChanges(sequence []Polynomial, x float64) int {
+	changes := 0
+	previous := 0.0
+	for _, p := range sequence {
+		v := p.Evaluate(x)
+		if v == 0 {
+			continue
+		}
+		if previous != 0 && math.Signbit(v) != math.Signbit(previous) {
+			changes++
+		}
+		previous = v
+	}
+	return changes
+}
+
+/*
+CountRootsIn is a method to count the distinct real roots in (a, b] with Sturm's
+theorem, a root of multiplicity k counting once. a must be lower than b.
+*/
+func (p Polynomial) CountRootsIn(a float64, b float64) int {
+	sequence := p.SturmSequence()
+	return signChanges(sequence, a) - signChanges(sequence, b)
+}
+
+/*
+IsolateRoots is a method to split (a, b] into intervals holding exactly one distinct
+real root each, by bisection with the Sturm sequence. The intervals are sorted. When
+the root has an odd multiplicity p changes sign on its interval, which can then be
+given to Bisect or Brent. Roots too close to be separated after 60 bisections are
+left in the same interval.
+*/
+func (p Polynomial) IsolateRoots(a float64, b float64) [][2]float64 {
+	const maxDepth = 60
+	sequence := p.SturmSequence()
+	var intervals [][2]float64
+	var isolate func(a, b float64, va, vb int, depth int)
+	isolate = func(a, b float64, va, vb int, depth int) {
+		count := va - vb
+		if count <= 0 {
+			return
+		}
+		m := a + (b-a)/2
+		if count == 1 || depth == maxDepth || m == a || m == b {
+			intervals = append(intervals, [2]float64{a, b})
+			return
+		}
+		vm := signChanges(sequence, m)
+		isolate(a, m, va, vm, depth+1)
+		isolate(m, b, vm, vb, depth+1)
+	}
+	if len(sequence) > 0 {
+		isolate(a, b, signChanges(sequence, a), signChanges(sequence, b), 0)
+	}
+	return intervals
+}
+
+/*
+PolynomialFit is a function to fit a polynomial of the given degree to points by
+least squares, with the QR decomposition of the Vandermonde matrix. Centering and
+scaling x around 0 improves the accuracy for high degrees.
+First parameter are the abscissas
+Second parameter are the ordinates, one per abscissa
+Third parameter is the degree
+It returns the polynomial, and an error if the sizes differ, if there are not more
+points than the degree or if the abscissas don't have degree+1 distinct values
+*/
+func PolynomialFit(x, y []float64, degree int) (Polynomial, error) {
+	if len(x) != len(y) || degree < 0 || len(x) <= degree {
+		return nil, &MathError{
+			code: errorDimensionMismatch,
+		}
+	}
+	k := degree + 1
+	vandermonde := NewMatrix(uint(len(x)), uint(k))
+	for i, v := range x {
+		power := 1.0
+		for j := 0; j < k; j++ {
+			vandermonde.M[i*k+j] = power
+			power *= v
+		}
+	}
+	b := NewMatrix(uint(len(y)), 1)
+	copy(b.M, y)
+	coefficients, err := leastSquares(vandermonde, b)
+	if err != nil {
+		return nil, err
+	}
+	return NewPolynomial(coefficients.M...), nil
+}
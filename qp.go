@@ -0,0 +1,283 @@
+package advmath
+
+import (
+	"math"
+)
+
+/*
+QuadraticProgram is the problem of minimizing 1/2 x'Qx + c'x subject to the linear
+constraints Ax <= b and Aeq x = beq, e.g. a mean-variance portfolio (Q being the
+covariance of the returns) or a step of a model predictive controller.
+*/
+type QuadraticProgram struct {
+	//Q is the n x n symmetric positive definite matrix of the quadratic term
+	Q *Matrix
+	//C is the linear term, zero when it is nil
+	C []float64
+	//A are the inequality constraints Ax <= B, one per row, nil when there are none
+	A *Matrix
+	//B is the right hand side of the inequality constraints
+	B []float64
+	//Aeq are the equality constraints Aeq x = Beq, one per row, nil when there are
+	//none
+	Aeq *Matrix
+	//Beq is the right hand side of the equality constraints
+	Beq []float64
+}
+
+/*
+qpConstraint is the constraint normal'x >= bound
+*/
+type qpConstraint struct {
+	normal   []float64
+	bound    float64
+	equality bool
+}
+
+/*
+constraints checks the sizes of the problem and returns its constraints in the form
+normal'x >= bound, the equalities first
+*/
+func (p QuadraticProgram) constraints() ([]qpConstraint, error) {
+	if p.Q == nil {
+		return nil, &MathError{
+			code: errorMatrixIsNil,
+		}
+	}
+	n := p.Q.NumberOfColumns
+	mismatch := &MathError{
+		code: errorDimensionMismatch,
+	}
+	if !p.Q.IsSquare() || n == 0 || (p.C != nil && uint(len(p.C)) != n) {
+		return nil, mismatch
+	}
+	var constraints []qpConstraint
+	if p.Aeq != nil {
+		if p.Aeq.NumberOfColumns != n || uint(len(p.Beq)) != p.Aeq.NumberOfRows {
+			return nil, mismatch
+		}
+		var i uint
+		for i = 0; i < p.Aeq.NumberOfRows; i++ {
+			constraints = append(constraints, qpConstraint{normal: p.Aeq.GetRow(i), bound: p.Beq[i], equality: true})
+		}
+	}
+	if p.A != nil {
+		if p.A.NumberOfColumns != n || uint(len(p.B)) != p.A.NumberOfRows {
+			return nil, mismatch
+		}
+		var i uint
+		for i = 0; i < p.A.NumberOfRows; i++ {
+			normal := p.A.GetRow(i)
+			for j := range normal {
+				normal[j] = -normal[j]
+			}
+			constraints = append(constraints, qpConstraint{normal: normal, bound: -p.B[i]})
+		}
+	}
+	return constraints, nil
+}
+
+/*
+Solve is a method to find the minimum of the quadratic program with the dual
+active-set method of Goldfarb and Idnani: it starts from the unconstrained minimum
+-Q^-1 c and adds the most violated constraint at each iteration, dropping the
+constraints whose multiplier would become negative, until every constraint holds.
+No feasible starting point is needed and the result is exact up to round-off. The
+factorization Q = LL' is computed once, the constraints being added and dropped by
+updating J = L^-T and a triangular matrix with Givens rotations, so each iteration
+costs O(n^2). It suits the small dense problems of portfolio optimization and MPC.
+
+It returns the minimum (Gradient being Qx + c, Converged being true), and an error if
+the sizes don't match, if Q is not positive definite, if the constraints are
+infeasible or if the iterations don't end because of round-off
+*/
+func (p QuadraticProgram) Solve() (OptimizeResult, error) {
+	constraints, err := p.constraints()
+	if err != nil {
+		return OptimizeResult{}, err
+	}
+	l, err := p.Q.Cholesky()
+	if err != nil {
+		return OptimizeResult{}, err
+	}
+	inverse, err := l.Inverse()
+	if err != nil {
+		return OptimizeResult{}, err
+	}
+	//The columns of J are a basis in which Q is the identity, the first q of them span
+	//the normals of the active constraints: J'N = [R 0]' with R upper triangular
+	j, _ := inverse.Transpose()
+	n := int(p.Q.NumberOfColumns)
+	r := NewMatrix(uint(n), uint(n))
+	c := p.C
+	if c == nil {
+		c = make([]float64, n)
+	}
+
+	//Unconstrained minimum x = -JJ'c
+	x := make([]float64, n)
+	jc := j.transposedVector(c)
+	for i := range x {
+		for k := 0; k < n; k++ {
+			x[i] -= j.M[i*n+k] * jc[k]
+		}
+	}
+
+	var active []int
+	var multipliers []float64
+	maxIterations := 10 * (n + len(constraints) + 10)
+	result := OptimizeResult{}
+	finish := func(err error) (OptimizeResult, error) {
+		result.X = x
+		result.Gradient = make([]float64, n)
+		for i := range x {
+			for k := range x {
+				result.Gradient[i] += p.Q.M[i*n+k] * x[k]
+			}
+			result.Value += x[i] * (result.Gradient[i]/2 + c[i])
+			result.Gradient[i] += c[i]
+		}
+		return result, err
+	}
+	isActive := func(k int) bool {
+		for _, a := range active {
+			if a == k {
+				return true
+			}
+		}
+		return false
+	}
+	//drop removes the active constraint at position k and restores R triangular
+	drop := func(k int) {
+		q := len(active)
+		active = append(active[:k], active[k+1:]...)
+		multipliers = append(multipliers[:k], multipliers[k+1:]...)
+		for row := 0; row < q; row++ {
+			copy(r.M[row*n+k:row*n+q-1], r.M[row*n+k+1:row*n+q])
+			r.M[row*n+q-1] = 0
+		}
+		for i := k; i < q-1; i++ {
+			g, _ := NewGivens(uint(i), uint(i+1), r.M[i*n+i], r.M[(i+1)*n+i])
+			g.ApplyLeft(r)
+			r.M[(i+1)*n+i] = 0
+			g.ApplyRight(j)
+		}
+	}
+
+	for {
+		//The equalities first, then the most violated inequality
+		chosen, slack := -1, 0.0
+		for k, con := range constraints {
+			if isActive(k) {
+				continue
+			}
+			s := dot(con.normal, x) - con.bound
+			if con.equality {
+				chosen, slack = k, s
+				break
+			}
+			if s < -1e-10*(1+math.Abs(con.bound)) && s < slack {
+				chosen, slack = k, s
+			}
+		}
+		if chosen < 0 {
+			result.Converged = true
+			return finish(nil)
+		}
+		con := constraints[chosen]
+		normal := con.normal
+		if con.equality && slack > 0 {
+			//An equality can be added from either side
+			normal = make([]float64, n)
+			for i := range normal {
+				normal[i] = -con.normal[i]
+			}
+			slack = -slack
+		}
+		bound := dot(normal, x) - slack
+		//The multiplier of the chosen constraint is the last one while it is added
+		multipliers = append(multipliers, 0)
+
+		for {
+			result.Iterations++
+			if result.Iterations > maxIterations {
+				return finish(&MathError{
+					code: errorNotConverged,
+				})
+			}
+			q := len(active)
+			d := j.transposedVector(normal)
+			//Step z = J2 J2' normal in the primal space, step -R^-1 d1 of the multipliers
+			z := make([]float64, n)
+			var remaining float64
+			for k := q; k < n; k++ {
+				remaining += d[k] * d[k]
+				for i := range z {
+					z[i] += j.M[i*n+k] * d[k]
+				}
+			}
+			dual := make([]float64, q)
+			for i := q - 1; i >= 0; i-- {
+				sum := d[i]
+				for k := i + 1; k < q; k++ {
+					sum -= r.M[i*n+k] * dual[k]
+				}
+				dual[i] = sum / r.M[i*n+i]
+			}
+			//Partial step: the first multiplier of an inequality reaching 0
+			partial, blocking := math.Inf(1), -1
+			for i := 0; i < q; i++ {
+				if !constraints[active[i]].equality && dual[i] > 0 && multipliers[i]/dual[i] < partial {
+					partial, blocking = multipliers[i]/dual[i], i
+				}
+			}
+			//Full step: the chosen constraint becomes active
+			full := math.Inf(1)
+			if remaining > 1e-20*dot(d, d) {
+				full = -(dot(normal, x) - bound) / remaining
+			}
+			step := math.Min(partial, full)
+			if math.IsInf(step, 1) {
+				return finish(&MathError{
+					s: "The constraints of the quadratic program are infeasible",
+				})
+			}
+			for i := 0; i < q; i++ {
+				multipliers[i] -= step * dual[i]
+			}
+			multipliers[q] += step
+			if !math.IsInf(full, 1) {
+				x = axpy(step, z, x)
+			}
+			if full <= partial {
+				//Add the constraint: rotate d so that only its first q+1 components remain
+				for k := n - 1; k > q; k-- {
+					g, h := NewGivens(uint(k-1), uint(k), d[k-1], d[k])
+					d[k-1], d[k] = h, 0
+					g.ApplyRight(j)
+				}
+				for i := 0; i <= q; i++ {
+					r.M[i*n+q] = d[i]
+				}
+				active = append(active, chosen)
+				break
+			}
+			drop(blocking)
+		}
+	}
+}
+
+/*
+transposedVector returns m'v
+*/
+func (m Matrix) transposedVector(v []float64) []float64 {
+	out := make([]float64, m.NumberOfColumns)
+	n := m.NumberOfColumns
+	var i, k uint
+	for i = 0; i < m.NumberOfRows; i++ {
+		for k = 0; k < n; k++ {
+			out[k] += m.M[i*n+k] * v[i]
+		}
+	}
+	return out
+}
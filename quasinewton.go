@@ -0,0 +1,263 @@
+package advmath
+
+import (
+	"math"
+)
+
+/*
+quasiNewton is the approximation of the inverse of the Hessian used by a quasi-Newton
+method
+*/
+type quasiNewton interface {
+	//direction returns -H g
+	direction(g []float64) []float64
+	//update takes the step s and the change y of the gradient into account
+	update(s, y []float64)
+	//reset forgets the approximation, H becomes the identity
+	reset()
+}
+
+/*
+bfgsInverse is the dense approximation of the inverse of the Hessian
+*/
+type bfgsInverse struct {
+	h *Matrix
+	//scaled tells if the identity was already scaled after the first step
+	scaled bool
+}
+
+func (b *bfgsInverse) direction(g []float64) []float64 {
+	n := uint(len(g))
+	d := make([]float64, n)
+	var i, j uint
+	for i = 0; i < n; i++ {
+		for j = 0; j < n; j++ {
+			d[i] -= b.h.M[i*n+j] * g[j]
+		}
+	}
+	return d
+}
+
+/*
+update is H = (I - rho s y') H (I - rho y s') + rho s s' with rho = 1/(y's), written
+H - rho (Hy s' + s y'H) + (rho^2 y'Hy + rho) s s' since H is symmetric
+*/
+func (b *bfgsInverse) update(s, y []float64) {
+	n := uint(len(s))
+	sy := dot(s, y)
+	if !b.scaled {
+		//Scaling of the identity by s'y/y'y (Nocedal and Wright 6.20), so that the
+		//first steps have the right size
+		scale := sy / dot(y, y)
+		for i := range b.h.M {
+			b.h.M[i] *= scale
+		}
+		b.scaled = true
+	}
+	rho := 1 / sy
+	hy := make([]float64, n)
+	var i, j uint
+	for i = 0; i < n; i++ {
+		for j = 0; j < n; j++ {
+			hy[i] += b.h.M[i*n+j] * y[j]
+		}
+	}
+	c := rho*rho*dot(y, hy) + rho
+	for i = 0; i < n; i++ {
+		for j = 0; j < n; j++ {
+			b.h.M[i*n+j] += -rho*(hy[i]*s[j]+s[i]*hy[j]) + c*s[i]*s[j]
+		}
+	}
+}
+
+func (b *bfgsInverse) reset() {
+	b.h = NewIdentity(b.h.NumberOfRows)
+	b.scaled = false
+}
+
+/*
+lbfgsMemory keeps the last steps and changes of the gradient, the inverse of the
+Hessian is applied with the two-loop recursion without being stored
+*/
+type lbfgsMemory struct {
+	size int
+	s    [][]float64
+	y    [][]float64
+	rho  []float64
+}
+
+func (l *lbfgsMemory) direction(g []float64) []float64 {
+	q := make([]float64, len(g))
+	for i := range q {
+		q[i] = -g[i]
+	}
+	alpha := make([]float64, len(l.s))
+	for k := len(l.s) - 1; k >= 0; k-- {
+		alpha[k] = l.rho[k] * dot(l.s[k], q)
+		for i := range q {
+			q[i] -= alpha[k] * l.y[k][i]
+		}
+	}
+	if k := len(l.s) - 1; k >= 0 {
+		//Initial Hessian gamma*I with gamma = s'y/y'y of the last step
+		gamma := 1 / (l.rho[k] * dot(l.y[k], l.y[k]))
+		for i := range q {
+			q[i] *= gamma
+		}
+	}
+	for k := range l.s {
+		beta := l.rho[k] * dot(l.y[k], q)
+		for i := range q {
+			q[i] += (alpha[k] - beta) * l.s[k][i]
+		}
+	}
+	return q
+}
+
+func (l *lbfgsMemory) update(s, y []float64) {
+	if len(l.s) == l.size {
+		l.s, l.y, l.rho = l.s[1:], l.y[1:], l.rho[1:]
+	}
+	l.s = append(l.s, s)
+	l.y = append(l.y, y)
+	l.rho = append(l.rho, 1/dot(s, y))
+}
+
+func (l *lbfgsMemory) reset() {
+	l.s, l.y, l.rho = nil, nil, nil
+}
+
+/*
+minimizeQuasiNewton is the loop shared by BFGS and LBFGS, without the equality
+constraints. With bounds, the variables blocked on a bound are left out of the
+direction and the step is found by backtracking on the projected path instead of the
+Wolfe line search.
+*/
+func minimizeQuasiNewton(f Fn, x0 []float64, approximation quasiNewton, options OptimizeOptions) (OptimizeResult, error) {
+	o := options.withDefaults(1000)
+	obj := newObjective(f, o)
+	x := append([]float64(nil), x0...)
+	value := obj.value(x)
+	g := obj.grad(x)
+	result := OptimizeResult{}
+	finish := func(err error) (OptimizeResult, error) {
+		result.X, result.Value, result.Gradient, result.Evaluations = x, value, g, obj.evaluations
+		return result, err
+	}
+	if math.IsNaN(value) || math.IsInf(value, 0) || !finiteVector(g) {
+		return finish(&MathError{
+			code: errorDiverged,
+		})
+	}
+
+	for result.Iterations < o.MaxIterations {
+		if Norm(o.projectedGradient(x, g)) <= o.GradientTolerance {
+			result.Converged = true
+			return finish(nil)
+		}
+		result.Iterations++
+		d := approximation.direction(g)
+		//The variables blocked by the bounds don't move
+		for i := range d {
+			if o.atBound(x, g, i) {
+				d[i] = 0
+			}
+		}
+		steepest := result.Iterations == 1
+		if dot(d, g) >= 0 {
+			//The approximation was spoiled by round-off, start again with the gradient
+			approximation.reset()
+			d = o.projectedGradient(x, g)
+			for i := range d {
+				d[i] = -d[i]
+			}
+			steepest = true
+		}
+		step := 1.0
+		if steepest {
+			//The gradient has no natural scale, the first step is at most 1 long
+			step = math.Min(1, 1/Norm(g))
+		}
+		var p linePoint
+		var err error
+		if o.bounded() {
+			p, err = projectedLineSearch(obj, o, x, value, g, d, step)
+		} else {
+			p, err = wolfeLineSearch(obj, x, value, g, d, step, 1e-4, 0.9)
+		}
+		if err != nil {
+			return finish(err)
+		}
+		s := make([]float64, len(x))
+		y := make([]float64, len(x))
+		for i := range s {
+			s[i] = p.x[i] - x[i]
+			y[i] = p.g[i] - g[i]
+		}
+		//The Wolfe conditions make s'y > 0 unless round-off dominates
+		if dot(s, y) > 0 {
+			approximation.update(s, y)
+		}
+		previous := value
+		previousX := x
+		x, value, g = p.x, p.value, p.g
+		if o.OnIteration != nil {
+			o.OnIteration(OptimizeState{Iteration: result.Iterations, X: x, Value: value, GradientNorm: Norm(o.projectedGradient(x, g))})
+		}
+		if o.valueConverged(previous, value) || o.stepConverged(previousX, x) {
+			result.Converged = true
+			return finish(nil)
+		}
+	}
+	if Norm(o.projectedGradient(x, g)) <= o.GradientTolerance {
+		result.Converged = true
+		return finish(nil)
+	}
+	return finish(&MathError{
+		code: errorNotConverged,
+	})
+}
+
+/*
+BFGS is a method to find a local minimum of a smooth function f with the
+Broyden-Fletcher-Goldfarb-Shanno quasi-Newton method: the inverse of the Hessian is
+approximated by a matrix updated from the changes of the gradient at each step, and
+the step along the quasi-Newton direction satisfies the strong Wolfe conditions. It
+converges superlinearly near the minimum without computing second derivatives. The
+matrix has n^2 elements, see LBFGS for many variables.
+
+First parameter f is the function to minimize
+Second parameter x0 is the starting point
+Third parameter options are the gradient, the stopping criteria, the callback and the
+constraints, 1000 iterations by default
+It returns the last point, and an error if the line search failed (e.g. the gradient
+is wrong or too inaccurate for the tolerance) or if no stopping criterion was met
+*/
+func BFGS(f Fn, x0 []float64, options OptimizeOptions) (OptimizeResult, error) {
+	return minimizeConstrained(f, x0, options, 0, func(f Fn, x0 []float64, o OptimizeOptions) (OptimizeResult, error) {
+		return minimizeQuasiNewton(f, x0, &bfgsInverse{h: NewIdentity(uint(len(x0)))}, o)
+	})
+}
+
+/*
+LBFGS is a method to find a local minimum of a smooth function f with the limited
+memory BFGS method: instead of a matrix, only the last steps and changes of the
+gradient are kept and applied with the two-loop recursion, so each iteration costs
+O(memory*n). It is the method of choice with thousands of variables.
+
+First parameter f is the function to minimize
+Second parameter x0 is the starting point
+Third parameter memory is the number of steps kept, 10 when it is 0 (3 to 20 is usual)
+Fourth parameter options are the gradient, the stopping criteria, the callback and the
+constraints, 1000 iterations by default
+It returns the last point, and an error if the line search failed or if no stopping
+criterion was met
+*/
+func LBFGS(f Fn, x0 []float64, memory int, options OptimizeOptions) (OptimizeResult, error) {
+	if memory <= 0 {
+		memory = 10
+	}
+	return minimizeConstrained(f, x0, options, 0, func(f Fn, x0 []float64, o OptimizeOptions) (OptimizeResult, error) {
+		return minimizeQuasiNewton(f, x0, &lbfgsMemory{size: memory}, o)
+	})
+}
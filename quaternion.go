@@ -0,0 +1,200 @@
+package advmath
+
+import (
+	"math"
+)
+
+/*
+Quaternion is a quaternion W + X*i + Y*j + Z*k. Unit quaternions represent 3-D
+rotations, they are more compact than rotation matrices and can be
+interpolated smoothly with Slerp.
+*/
+type Quaternion struct {
+	W float64
+	X float64
+	Y float64
+	Z float64
+}
+
+/*
+NewQuaternionFromAxisAngle is a method to create the unit quaternion of the
+rotation around the given axis. The axis doesn't need to be normalized.
+First parameter is the axis (3-D vector)
+Second parameter is the angle in radians
+*/
+func NewQuaternionFromAxisAngle(axis []float64, theta float64) (Quaternion, error) {
+	if len(axis) != 3 {
+		return Quaternion{}, &MathError{
+			code: errorDimensionMismatch,
+		}
+	}
+	n := Norm(axis)
+	if n == 0 {
+		return Quaternion{}, &MathError{
+			code: errorDivisionByZero,
+		}
+	}
+	s, c := math.Sincos(theta / 2)
+	return Quaternion{W: c, X: s * axis[0] / n, Y: s * axis[1] / n, Z: s * axis[2] / n}, nil
+}
+
+/*
+Multiply is a method to compute the Hamilton product q*p. When both are
+rotations, the result is the rotation p followed by q.
+First parameter is the quaternion to multiply with
+*/
+func (q Quaternion) Multiply(p Quaternion) Quaternion {
+	return Quaternion{
+		W: q.W*p.W - q.X*p.X - q.Y*p.Y - q.Z*p.Z,
+		X: q.W*p.X + q.X*p.W + q.Y*p.Z - q.Z*p.Y,
+		Y: q.W*p.Y - q.X*p.Z + q.Y*p.W + q.Z*p.X,
+		Z: q.W*p.Z + q.X*p.Y - q.Y*p.X + q.Z*p.W,
+	}
+}
+
+/*
+Conjugate is a method to compute the conjugate of the quaternion, which is its
+inverse for a unit quaternion.
+*/
+func (q Quaternion) Conjugate() Quaternion {
+	return Quaternion{W: q.W, X: -q.X, Y: -q.Y, Z: -q.Z}
+}
+
+/*
+Norm is a method to compute the norm of the quaternion.
+*/
+func (q Quaternion) Norm() float64 {
+	return Norm([]float64{q.W, q.X, q.Y, q.Z})
+}
+
+/*
+Normalize is a method returning the unit quaternion with the same direction.
+An error is returned for the zero quaternion.
+*/
+func (q Quaternion) Normalize() (Quaternion, error) {
+	n := q.Norm()
+	if n == 0 {
+		return Quaternion{}, &MathError{
+			code: errorDivisionByZero,
+		}
+	}
+	return Quaternion{W: q.W / n, X: q.X / n, Y: q.Y / n, Z: q.Z / n}, nil
+}
+
+/*
+Rotate is a method to rotate a 3-D vector with a unit quaternion (q*v*q^-1).
+First parameter is the vector to rotate
+*/
+func (q Quaternion) Rotate(v []float64) ([]float64, error) {
+	if len(v) != 3 {
+		return nil, &MathError{
+			code: errorDimensionMismatch,
+		}
+	}
+	r := q.Multiply(Quaternion{X: v[0], Y: v[1], Z: v[2]}).Multiply(q.Conjugate())
+	return []float64{r.X, r.Y, r.Z}, nil
+}
+
+/*
+Slerp is a method to interpolate between two unit quaternions along the
+shortest arc at constant angular speed.
+First parameter is the starting rotation (returned when t is 0)
+Second parameter is the ending rotation (returned when t is 1)
+Third parameter is the interpolation parameter t between 0 and 1
+*/
+func Slerp(a, b Quaternion, t float64) Quaternion {
+	cos := a.W*b.W + a.X*b.X + a.Y*b.Y + a.Z*b.Z
+	//q and -q are the same rotation, take the shortest path
+	if cos < 0 {
+		b = Quaternion{W: -b.W, X: -b.X, Y: -b.Y, Z: -b.Z}
+		cos = -cos
+	}
+
+	var s0, s1 float64
+	if cos > 0.9995 {
+		//Very close, a linear interpolation avoids dividing by sin(~0)
+		s0 = 1 - t
+		s1 = t
+	} else {
+		theta := math.Acos(cos)
+		sin := math.Sin(theta)
+		s0 = math.Sin((1-t)*theta) / sin
+		s1 = math.Sin(t*theta) / sin
+	}
+
+	r := Quaternion{
+		W: s0*a.W + s1*b.W,
+		X: s0*a.X + s1*b.X,
+		Y: s0*a.Y + s1*b.Y,
+		Z: s0*a.Z + s1*b.Z,
+	}
+	if n, err := r.Normalize(); err == nil {
+		return n
+	}
+	return r
+}
+
+/*
+ToRotationMatrix is a method to convert a unit quaternion to the equivalent
+3x3 rotation matrix, see NewRotation3DX for the conventions used.
+*/
+func (q Quaternion) ToRotationMatrix() *Matrix {
+	w, x, y, z := q.W, q.X, q.Y, q.Z
+	r := NewMatrix(3, 3)
+	r.SetRow(0, []float64{1 - 2*(y*y+z*z), 2 * (x*y - w*z), 2 * (x*z + w*y)})
+	r.SetRow(1, []float64{2 * (x*y + w*z), 1 - 2*(x*x+z*z), 2 * (y*z - w*x)})
+	r.SetRow(2, []float64{2 * (x*z - w*y), 2 * (y*z + w*x), 1 - 2*(x*x+y*y)})
+	return r
+}
+
+/*
+FromRotationMatrix is a method to convert a 3x3 rotation matrix to a unit
+quaternion. It uses Shepperd's method which picks the biggest of the four
+components first to stay accurate for every rotation.
+First parameter is the rotation matrix
+*/
+func FromRotationMatrix(m *Matrix) (Quaternion, error) {
+	if m.NumberOfRows != 3 || m.NumberOfColumns != 3 {
+		return Quaternion{}, &MathError{
+			code: errorDimensionMismatch,
+		}
+	}
+
+	trace := m.Get(0, 0) + m.Get(1, 1) + m.Get(2, 2)
+	var q Quaternion
+	switch {
+	case trace > 0:
+		s := 2 * math.Sqrt(trace+1)
+		q = Quaternion{
+			W: s / 4,
+			X: (m.Get(2, 1) - m.Get(1, 2)) / s,
+			Y: (m.Get(0, 2) - m.Get(2, 0)) / s,
+			Z: (m.Get(1, 0) - m.Get(0, 1)) / s,
+		}
+	case m.Get(0, 0) > m.Get(1, 1) && m.Get(0, 0) > m.Get(2, 2):
+		s := 2 * math.Sqrt(1+m.Get(0, 0)-m.Get(1, 1)-m.Get(2, 2))
+		q = Quaternion{
+			W: (m.Get(2, 1) - m.Get(1, 2)) / s,
+			X: s / 4,
+			Y: (m.Get(0, 1) + m.Get(1, 0)) / s,
+			Z: (m.Get(0, 2) + m.Get(2, 0)) / s,
+		}
+	case m.Get(1, 1) > m.Get(2, 2):
+		s := 2 * math.Sqrt(1+m.Get(1, 1)-m.Get(0, 0)-m.Get(2, 2))
+		q = Quaternion{
+			W: (m.Get(0, 2) - m.Get(2, 0)) / s,
+			X: (m.Get(0, 1) + m.Get(1, 0)) / s,
+			Y: s / 4,
+			Z: (m.Get(1, 2) + m.Get(2, 1)) / s,
+		}
+	default:
+		s := 2 * math.Sqrt(1+m.Get(2, 2)-m.Get(0, 0)-m.Get(1, 1))
+		q = Quaternion{
+			W: (m.Get(1, 0) - m.Get(0, 1)) / s,
+			X: (m.Get(0, 2) + m.Get(2, 0)) / s,
+			Y: (m.Get(1, 2) + m.Get(2, 1)) / s,
+			Z: s / 4,
+		}
+	}
+	return q.Normalize()
+}
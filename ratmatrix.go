@@ -0,0 +1,285 @@
+package advmath
+
+import (
+	"math/big"
+)
+
+/*
+RatMatrix is a matrix of rational numbers. All the computations are exact,
+there is no rounding at all, which makes it a good fit for teaching or when
+an exact answer is required. It is of course much slower than Matrix.
+*/
+type RatMatrix struct {
+	NumberOfRows    uint
+	NumberOfColumns uint
+	M               []*big.Rat
+}
+
+/*
+NewRatMatrix is a method to create a new rational matrix filled with zeros.
+First parameter is the number of rows
+Second parameter is the number of columns
+*/
+func NewRatMatrix(rows, cols uint) *RatMatrix {
+	m := new(RatMatrix)
+	m.NumberOfRows = rows
+	m.NumberOfColumns = cols
+	m.M = make([]*big.Rat, rows*cols)
+	for i := range m.M {
+		m.M[i] = new(big.Rat)
+	}
+	return m
+}
+
+/*
+NewRatIdentity is a method to create a rational identity square matrix.
+First parameter is the number of rows and columns
+*/
+func NewRatIdentity(rows uint) *RatMatrix {
+	i := NewRatMatrix(rows, rows)
+
+	var j uint
+	for j = 0; j < rows; j++ {
+		i.M[j*i.NumberOfColumns+j].SetInt64(1)
+	}
+
+	return i
+}
+
+/*
+ToRatMatrix is a method to convert a matrix to a rational matrix. The conversion
+is exact: every finite float64 is a rational number. An error is returned if the
+matrix holds an infinity or a NaN.
+*/
+func (m Matrix) ToRatMatrix() (*RatMatrix, error) {
+	ret := NewRatMatrix(m.NumberOfRows, m.NumberOfColumns)
+	for i, v := range m.M {
+		if ret.M[i].SetFloat64(v) == nil {
+			return nil, &MathError{
+				s: "Cannot convert an infinity or a NaN to a rational number",
+			}
+		}
+	}
+	return ret, nil
+}
+
+/*
+ToMatrix is a method to round a rational matrix to a float64 Matrix.
+*/
+func (m RatMatrix) ToMatrix() *Matrix {
+	ret := NewMatrix(m.NumberOfRows, m.NumberOfColumns)
+	for i, v := range m.M {
+		ret.M[i], _ = v.Float64()
+	}
+	return ret
+}
+
+/*
+IsSquare is a method to find if a matrix is a square matrix or not.
+*/
+func (m RatMatrix) IsSquare() bool {
+	return m.NumberOfColumns == m.NumberOfRows
+}
+
+/*
+Get is a method to retrieve the content of a matrix at the given
+row and column. The value returned is a copy.
+*/
+func (m RatMatrix) Get(row uint, column uint) *big.Rat {
+	return new(big.Rat).Set(m.M[row*m.NumberOfColumns+column])
+}
+
+/*
+Set is a method to set the value at the given row and column.
+*/
+func (m *RatMatrix) Set(row uint, column uint, value *big.Rat) {
+	m.M[row*m.NumberOfColumns+column].Set(value)
+}
+
+/*
+SetRow is a method to set the value at the given row from integers, it
+changes the underlying matrix and returns it.
+*/
+func (m *RatMatrix) SetRow(rowNumber uint, row []int64) *RatMatrix {
+	var cols uint
+	for cols = 0; cols < m.NumberOfColumns; cols++ {
+		m.M[rowNumber*m.NumberOfColumns+cols].SetInt64(row[cols])
+	}
+	return m
+}
+
+/*
+Multiply is a method to multiply the matrix by the given matrix (A*B).
+First parameter is the matrix used for the multiplication
+*/
+func (m RatMatrix) Multiply(in *RatMatrix) (*RatMatrix, error) {
+	if m.NumberOfColumns != in.NumberOfRows {
+		return nil, &MathError{
+			code: errorCannotMultiply,
+		}
+	}
+
+	result := NewRatMatrix(m.NumberOfRows, in.NumberOfColumns)
+	tmp := new(big.Rat)
+
+	var i, j, k uint
+	for i = 0; i < m.NumberOfRows; i++ {
+		for j = 0; j < in.NumberOfColumns; j++ {
+			sum := result.M[i*result.NumberOfColumns+j]
+			for k = 0; k < m.NumberOfColumns; k++ {
+				tmp.Mul(m.M[i*m.NumberOfColumns+k], in.M[k*in.NumberOfColumns+j])
+				sum.Add(sum, tmp)
+			}
+		}
+	}
+	return result, nil
+}
+
+/*
+RREF is a method to compute the reduced row echelon form of the matrix using
+a Gauss-Jordan elimination. It works on any matrix, square or not.
+First return value is the reduced matrix
+Second return value is the rank of the matrix
+*/
+func (m RatMatrix) RREF() (*RatMatrix, uint) {
+	r := m.copy()
+	rows := r.NumberOfRows
+	cols := r.NumberOfColumns
+	tmp := new(big.Rat)
+
+	var rank, col, i, j uint
+	for col = 0; col < cols && rank < rows; col++ {
+		//Any non zero pivot is fine since there is no rounding
+		p := rank
+		for p < rows && r.M[p*cols+col].Sign() == 0 {
+			p++
+		}
+		if p == rows {
+			continue
+		}
+		r.swapRows(p, rank)
+
+		pivot := new(big.Rat).Set(r.M[rank*cols+col])
+		for j = 0; j < cols; j++ {
+			r.M[rank*cols+j].Quo(r.M[rank*cols+j], pivot)
+		}
+
+		for i = 0; i < rows; i++ {
+			if i == rank || r.M[i*cols+col].Sign() == 0 {
+				continue
+			}
+			factor := new(big.Rat).Set(r.M[i*cols+col])
+			for j = 0; j < cols; j++ {
+				tmp.Mul(factor, r.M[rank*cols+j])
+				r.M[i*cols+j].Sub(r.M[i*cols+j], tmp)
+			}
+		}
+		rank++
+	}
+
+	return r, rank
+}
+
+/*
+Determinant is a method to compute the exact determinant of a square matrix
+using a gaussian elimination.
+*/
+func (m RatMatrix) Determinant() (*big.Rat, error) {
+	if !m.IsSquare() {
+		return nil, &MathError{
+			code: errorNonSquareMatrix,
+		}
+	}
+
+	a := m.copy()
+	n := a.NumberOfRows
+	det := big.NewRat(1, 1)
+	factor := new(big.Rat)
+	tmp := new(big.Rat)
+
+	var i, j, k uint
+	for k = 0; k < n; k++ {
+		p := k
+		for p < n && a.M[p*n+k].Sign() == 0 {
+			p++
+		}
+		if p == n {
+			return new(big.Rat), nil
+		}
+		if p != k {
+			a.swapRows(p, k)
+			det.Neg(det)
+		}
+		det.Mul(det, a.M[k*n+k])
+		for i = k + 1; i < n; i++ {
+			if a.M[i*n+k].Sign() == 0 {
+				continue
+			}
+			factor.Quo(a.M[i*n+k], a.M[k*n+k])
+			for j = k; j < n; j++ {
+				tmp.Mul(factor, a.M[k*n+j])
+				a.M[i*n+j].Sub(a.M[i*n+j], tmp)
+			}
+		}
+	}
+
+	return det, nil
+}
+
+/*
+Inverse is a method to compute the exact inverse of a square matrix. It
+computes the reduced row echelon form of [A | I], the right part being the
+inverse if A has full rank.
+*/
+func (m RatMatrix) Inverse() (*RatMatrix, error) {
+	if !m.IsSquare() {
+		return nil, &MathError{
+			code: errorNonSquareMatrix,
+		}
+	}
+
+	n := m.NumberOfRows
+	augmented := NewRatMatrix(n, 2*n)
+	var i, j uint
+	for i = 0; i < n; i++ {
+		for j = 0; j < n; j++ {
+			augmented.M[i*2*n+j].Set(m.M[i*n+j])
+		}
+		augmented.M[i*2*n+n+i].SetInt64(1)
+	}
+
+	reduced, _ := augmented.RREF()
+
+	inv := NewRatMatrix(n, n)
+	for i = 0; i < n; i++ {
+		//Left part must be the identity
+		if reduced.M[i*2*n+i].Cmp(big.NewRat(1, 1)) != 0 {
+			return nil, &MathError{
+				code: errorNotInversible,
+			}
+		}
+		for j = 0; j < n; j++ {
+			inv.M[i*n+j].Set(reduced.M[i*2*n+n+j])
+		}
+	}
+
+	return inv, nil
+}
+
+func (m RatMatrix) copy() *RatMatrix {
+	ret := NewRatMatrix(m.NumberOfRows, m.NumberOfColumns)
+	for i, v := range m.M {
+		ret.M[i].Set(v)
+	}
+	return ret
+}
+
+func (m *RatMatrix) swapRows(a, b uint) {
+	if a == b {
+		return
+	}
+	var j uint
+	for j = 0; j < m.NumberOfColumns; j++ {
+		m.M[a*m.NumberOfColumns+j], m.M[b*m.NumberOfColumns+j] = m.M[b*m.NumberOfColumns+j], m.M[a*m.NumberOfColumns+j]
+	}
+}
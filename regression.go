@@ -0,0 +1,250 @@
+package advmath
+
+import (
+	"math"
+)
+
+/*
+RegressionResult holds the fit of a linear model y = b0 + b1*x1 + ... + bp*xp. For a
+weighted fit, RSS and TSS are the weighted sums of squares.
+*/
+type RegressionResult struct {
+	//Coefficients are b0 (the intercept) then b1 to bp
+	Coefficients []float64
+	//StandardErrors are the standard errors of the coefficients, in the same order
+	StandardErrors []float64
+	//Residuals are the observed minus the fitted values, one per observation
+	Residuals []float64
+	//RSquared is the coefficient of determination 1 - RSS/TSS, the part of the
+	//variance of y explained by the model
+	RSquared float64
+	//AdjustedRSquared is R^2 corrected for the number of variables,
+	//1 - (1-R^2)(n-1)/(n-p-1)
+	AdjustedRSquared float64
+	//ResidualStdError is the estimate sqrt(RSS/(n-p-1)) of the standard deviation of
+	//the noise
+	ResidualStdError float64
+	//Weights are the weights of the observations in the fit, nil for the ordinary
+	//least squares
+	Weights []float64
+}
+
+/*
+LinearRegression is a function to fit the line y = b0 + b1*x by ordinary least
+squares.
+First parameter is the explanatory variable x
+Second parameter is the response y, one value per x
+It returns Coefficients = [b0, b1], and an error if the sizes don't match, if there
+are less than 3 observations or if x is constant
+*/
+func LinearRegression(x, y []float64) (RegressionResult, error) {
+	m := NewMatrix(uint(len(x)), 1)
+	copy(m.M, x)
+	return MultipleRegression(m, y)
+}
+
+/*
+MultipleRegression is a function to fit the linear model y = b0 + b1*x1 + ... + bp*xp
+by ordinary least squares, the intercept b0 being added to the variables. The
+coefficients are found with the Householder QR decomposition of the design matrix,
+which is more accurate than the normal equations when the variables are nearly
+collinear. The standard errors are sqrt(s^2 (X'X)^-1) with s^2 = RSS/(n-p-1), which
+assumes independent errors of the same variance.
+First parameter is the n x p matrix of the variables, one row per observation
+Second parameter is the response y, one value per observation
+It returns the fit, and an error if the sizes don't match, if there are not more
+observations than coefficients (n > p+1) or if the variables are collinear
+*/
+func MultipleRegression(x *Matrix, y []float64) (RegressionResult, error) {
+	return weightedRegression(x, y, nil)
+}
+
+/*
+WeightedRegression is a function to fit the linear model y = b0 + b1*x1 + ... + bp*xp
+by weighted least squares, minimizing sum(w_i r_i^2): observations of different
+precisions are given weights inversely proportional to the variance of their noise.
+R^2 and the standard errors are weighted the same way.
+First parameter is the n x p matrix of the variables, one row per observation
+Second parameter is the response y, one value per observation
+Third parameter are the positive weights, one per observation
+It returns the fit, and an error if the sizes don't match, if a weight is not
+positive, if there are not more observations than coefficients or if the variables
+are collinear
+*/
+func WeightedRegression(x *Matrix, y []float64, weights []float64) (RegressionResult, error) {
+	if len(weights) != len(y) {
+		return RegressionResult{}, &MathError{
+			code: errorDimensionMismatch,
+		}
+	}
+	for _, w := range weights {
+		if !(w > 0) || math.IsInf(w, 1) {
+			return RegressionResult{}, &MathError{
+				s: "The weights must be positive",
+			}
+		}
+	}
+	return weightedRegression(x, y, append([]float64(nil), weights...))
+}
+
+/*
+RobustRegression is a function to fit the linear model y = b0 + b1*x1 + ... + bp*xp
+with the Huber M-estimator, which gives less influence to the outliers: the residuals
+larger than k times the scale of the noise are penalized linearly instead of
+quadratically. It is computed by iteratively reweighted least squares, starting from
+the ordinary fit, each observation getting the weight min(1, k s/|r|) where the scale
+s is the median absolute deviation of the residuals divided by 0.6745. The standard
+errors are those of the last weighted fit, an approximation.
+First parameter is the n x p matrix of the variables, one row per observation
+Second parameter is the response y, one value per observation
+Third parameter is the tuning constant k, 1.345 when it is 0 (95% efficiency for
+normal noise), a smaller k resists the outliers more
+It returns the fit with the final weights (small for the outliers), and an error if
+the sizes don't match, if there are not more observations than coefficients, if the
+variables are collinear or if the iterations didn't converge
+*/
+func RobustRegression(x *Matrix, y []float64, k float64) (RegressionResult, error) {
+	const (
+		maxIterations = 100
+		tolerance     = 1e-10
+	)
+	if k <= 0 {
+		k = 1.345
+	}
+	result, err := weightedRegression(x, y, nil)
+	if err != nil {
+		return result, err
+	}
+	weights := make([]float64, len(y))
+	for iteration := 0; iteration < maxIterations; iteration++ {
+		absolute := make([]float64, len(y))
+		for i, r := range result.Residuals {
+			absolute[i] = math.Abs(r)
+		}
+		mad, _ := Median(absolute, PropagateNaN)
+		scale := mad / 0.6745
+		if scale == 0 {
+			//More than half of the points are fitted exactly, the others are outliers
+			scale = machineEpsilon * (1 + Norm(y))
+		}
+		for i, r := range absolute {
+			weights[i] = math.Min(1, k*scale/r)
+		}
+		next, err := weightedRegression(x, y, append([]float64(nil), weights...))
+		if err != nil {
+			return result, err
+		}
+		var change, size float64
+		for j := range next.Coefficients {
+			change = math.Max(change, math.Abs(next.Coefficients[j]-result.Coefficients[j]))
+			size = math.Max(size, math.Abs(next.Coefficients[j]))
+		}
+		result = next
+		if change <= tolerance*(1+size) {
+			return result, nil
+		}
+	}
+	return result, &MathError{
+		code: errorNotConverged,
+	}
+}
+
+/*
+weightedRegression is the weighted least squares fit, weights being nil for the
+ordinary least squares: the rows of the design matrix and y are scaled by sqrt(w_i)
+*/
+func weightedRegression(x *Matrix, y []float64, weights []float64) (RegressionResult, error) {
+	if x == nil {
+		return RegressionResult{}, &MathError{
+			code: errorMatrixIsNil,
+		}
+	}
+	n, p := int(x.NumberOfRows), int(x.NumberOfColumns)
+	if len(y) != n {
+		return RegressionResult{}, &MathError{
+			code: errorDimensionMismatch,
+		}
+	}
+	k := p + 1
+	if n <= k {
+		return RegressionResult{}, &MathError{
+			s: "The regression needs more observations than coefficients",
+		}
+	}
+
+	weight := func(i int) float64 {
+		if weights == nil {
+			return 1
+		}
+		return weights[i]
+	}
+
+	//Design matrix with a column of ones for the intercept, the rows scaled by
+	//sqrt(w_i)
+	design := NewMatrix(uint(n), uint(k))
+	b := NewMatrix(uint(n), 1)
+	for i := 0; i < n; i++ {
+		scale := math.Sqrt(weight(i))
+		design.M[i*k] = scale
+		for j := 0; j < p; j++ {
+			design.M[i*k+1+j] = scale * x.M[i*p+j]
+		}
+		b.M[i] = scale * y[i]
+	}
+	coefficients, err := leastSquares(design, b)
+	if err != nil {
+		return RegressionResult{}, err
+	}
+
+	result := RegressionResult{
+		Coefficients: coefficients.M,
+		Residuals:    make([]float64, n),
+		Weights:      weights,
+	}
+	var average, total float64
+	for i := 0; i < n; i++ {
+		average += weight(i) * y[i]
+		total += weight(i)
+	}
+	average /= total
+	var rss, tss float64
+	for i := 0; i < n; i++ {
+		fitted := result.Coefficients[0] + dot(x.M[i*p:(i+1)*p], result.Coefficients[1:])
+		result.Residuals[i] = y[i] - fitted
+		rss += weight(i) * result.Residuals[i] * result.Residuals[i]
+		tss += weight(i) * (y[i] - average) * (y[i] - average)
+	}
+	dof := float64(n - k)
+	result.RSquared = 1 - rss/tss
+	result.AdjustedRSquared = 1 - (1-result.RSquared)*float64(n-1)/dof
+	result.ResidualStdError = math.Sqrt(rss / dof)
+
+	//The diagonal of (X'WX)^-1 = L^-T L^-1 with X'WX = LL'
+	xtx := NewMatrix(uint(k), uint(k))
+	for i := 0; i < k; i++ {
+		for j := 0; j <= i; j++ {
+			var sum float64
+			for r := 0; r < n; r++ {
+				sum += design.M[r*k+i] * design.M[r*k+j]
+			}
+			xtx.M[i*k+j], xtx.M[j*k+i] = sum, sum
+		}
+	}
+	l, err := xtx.Cholesky()
+	if err != nil {
+		return RegressionResult{}, err
+	}
+	inverse, err := l.Inverse()
+	if err != nil {
+		return RegressionResult{}, err
+	}
+	result.StandardErrors = make([]float64, k)
+	for j := 0; j < k; j++ {
+		var sum float64
+		for i := j; i < k; i++ {
+			sum += inverse.M[i*k+j] * inverse.M[i*k+j]
+		}
+		result.StandardErrors[j] = result.ResidualStdError * math.Sqrt(sum)
+	}
+	return result, nil
+}
@@ -0,0 +1,217 @@
+package advmath
+
+import (
+	"context"
+)
+
+/*
+RootSolver is implemented by the solving methods so that they can be swapped without
+changing the call sites. The starting point (initial value or bracket) is part of the
+solver, the settings shared by all methods are given as options:
+
+	solver := BrentSolver{A: 0, B: 2}
+	r, err := solver.Solve(f, Tolerance(1e-12), OnIteration(func(s IterationState) {
+		log.Println(s.Iteration, s.X, s.Value)
+	}))
+*/
+type RootSolver interface {
+	//Solve looks for a zero of f with the given options
+	Solve(f F, options ...SolverOption) (RootResult, error)
+}
+
+/*
+SolverOption is a setting given to RootSolver.Solve
+*/
+type SolverOption func(*solverSettings)
+
+/*
+solverSettings holds the settings shared by the solving methods
+*/
+type solverSettings struct {
+	maxIterations int
+	tolerance     float64
+	onIteration   func(IterationState)
+	ctx           context.Context
+}
+
+/*
+newSolverSettings returns the settings with the defaults of a method, changed by the
+options
+*/
+func newSolverSettings(maxIterations int, tolerance float64, options []SolverOption) *solverSettings {
+	s := &solverSettings{
+		maxIterations: maxIterations,
+		tolerance:     tolerance,
+	}
+	for _, option := range options {
+		option(s)
+	}
+	return s
+}
+
+/*
+iterate gives the state of an iteration to the callback if there is one, and returns
+the error of the context if the method has to stop
+*/
+func (s *solverSettings) iterate(state IterationState) error {
+	if s.onIteration != nil {
+		s.onIteration(state)
+	}
+	if s.ctx != nil {
+		return s.ctx.Err()
+	}
+	return nil
+}
+
+/*
+MaxIterations sets the maximum number of iterations, values <= 0 keep the default of
+the method
+*/
+func MaxIterations(n int) SolverOption {
+	return func(s *solverSettings) {
+		if n > 0 {
+			s.maxIterations = n
+		}
+	}
+}
+
+/*
+Tolerance sets the precision required on the zero, values <= 0 keep the default of 1e-10
+*/
+func Tolerance(tol float64) SolverOption {
+	return func(s *solverSettings) {
+		if tol > 0 {
+			s.tolerance = tol
+		}
+	}
+}
+
+/*
+OnIteration sets a function called at every iteration with the current estimate of
+the zero, e.g. to log the convergence history
+*/
+func OnIteration(callback func(IterationState)) SolverOption {
+	return func(s *solverSettings) {
+		s.onIteration = callback
+	}
+}
+
+/*
+Context makes the method stop at the next iteration once ctx is done, the error
+returned is then ctx.Err() and the result holds the last iterate
+*/
+func Context(ctx context.Context) SolverOption {
+	return func(s *solverSettings) {
+		s.ctx = ctx
+	}
+}
+
+/*
+defaultTolerance is the precision required when no Tolerance option is given
+*/
+const defaultTolerance = 1e-10
+
+/*
+NewtonSolver uses NewtonWithResult starting from Init, 1000 iterations by default
+*/
+type NewtonSolver struct {
+	Init float64
+}
+
+/*
+Solve looks for a zero of f with the Newton method
+*/
+func (solver NewtonSolver) Solve(f F, options ...SolverOption) (RootResult, error) {
+	return newton(solver.Init, f, newSolverSettings(1000, defaultTolerance, options))
+}
+
+/*
+DampedNewtonSolver uses DampedNewton starting from Init with steps clamped to MaxStep
+(0 for no clamping), 1000 iterations by default
+*/
+type DampedNewtonSolver struct {
+	Init    float64
+	MaxStep float64
+}
+
+/*
+Solve looks for a zero of f with the damped Newton method
+*/
+func (solver DampedNewtonSolver) Solve(f F, options ...SolverOption) (RootResult, error) {
+	return dampedNewton(solver.Init, f, solver.MaxStep, newSolverSettings(1000, defaultTolerance, options))
+}
+
+/*
+SteffensenSolver uses SteffensenWithResult starting from Init, 1000 iterations by default
+*/
+type SteffensenSolver struct {
+	Init float64
+}
+
+/*
+Solve looks for a zero of f with the Steffensen method
+*/
+func (solver SteffensenSolver) Solve(f F, options ...SolverOption) (RootResult, error) {
+	return steffensen(solver.Init, f, newSolverSettings(1000, defaultTolerance, options))
+}
+
+/*
+SecantSolver uses Secant starting from X0 and X1, 1000 iterations by default
+*/
+type SecantSolver struct {
+	X0 float64
+	X1 float64
+}
+
+/*
+Solve looks for a zero of f with the secant method
+*/
+func (solver SecantSolver) Solve(f F, options ...SolverOption) (RootResult, error) {
+	return secant(solver.X0, solver.X1, f, newSolverSettings(1000, defaultTolerance, options))
+}
+
+/*
+BisectSolver uses Bisect on [A, B], 5000 iterations by default which is more than
+what it takes to reach two consecutive float64
+*/
+type BisectSolver struct {
+	A float64
+	B float64
+}
+
+/*
+Solve looks for a zero of f with the bisection method
+*/
+func (solver BisectSolver) Solve(f F, options ...SolverOption) (RootResult, error) {
+	return bisect(solver.A, solver.B, f, newSolverSettings(5000, defaultTolerance, options))
+}
+
+/*
+BrentSolver uses Brent on [A, B], 500 iterations by default
+*/
+type BrentSolver struct {
+	A float64
+	B float64
+}
+
+/*
+Solve looks for a zero of f with the Brent method
+*/
+func (solver BrentSolver) Solve(f F, options ...SolverOption) (RootResult, error) {
+	return brent(solver.A, solver.B, f, newSolverSettings(500, defaultTolerance, options))
+}
+
+/*
+IllinoisSolver uses Illinois on [A, B], 1000 iterations by default
+*/
+type IllinoisSolver struct {
+	A float64
+	B float64
+}
+
+/*
+Solve looks for a zero of f with the Illinois method
+*/
+func (solver IllinoisSolver) Solve(f F, options ...SolverOption) (RootResult, error) {
+	return illinois(solver.A, solver.B, f, newSolverSettings(1000, defaultTolerance, options))
+}
@@ -0,0 +1,144 @@
+package advmath
+
+import (
+	"math/rand"
+)
+
+/*
+randomGenerator returns a generator drawing from source, rand.NewSource(1) when it is
+nil so that the results are reproducible
+*/
+func randomGenerator(source rand.Source) *rand.Rand {
+	if source == nil {
+		source = rand.NewSource(1)
+	}
+	return rand.New(source)
+}
+
+/*
+NormalSample is a function to draw independent values from the normal distribution,
+with the Ziggurat algorithm of the standard library.
+First parameter is the number of values
+Second parameter is the mean
+Third parameter is the standard deviation
+Fourth parameter is the random generator, rand.NewSource(1) when it is nil
+*/
+func NormalSample(n int, mean, stddev float64, source rand.Source) []float64 {
+	random := randomGenerator(source)
+	sample := make([]float64, n)
+	for i := range sample {
+		sample[i] = mean + stddev*random.NormFloat64()
+	}
+	return sample
+}
+
+/*
+MultivariateNormalSample is a function to draw independent vectors from the
+multivariate normal distribution N(mean, covariance): with covariance = LL' (Cholesky
+decomposition), mean + Lz has the right distribution when z is standard normal.
+First parameter is the number of vectors
+Second parameter is the mean, of size d
+Third parameter is the d x d covariance matrix, symmetric positive definite
+Fourth parameter is the random generator, rand.NewSource(1) when it is nil
+It returns an n x d matrix with one vector per row, the layout used by Covariance, and
+an error if the sizes don't match or if the covariance is not positive definite
+*/
+func MultivariateNormalSample(n int, mean []float64, covariance *Matrix, source rand.Source) (*Matrix, error) {
+	if covariance == nil {
+		return nil, &MathError{
+			code: errorMatrixIsNil,
+		}
+	}
+	d := len(mean)
+	if n < 0 || covariance.NumberOfRows != uint(d) {
+		return nil, &MathError{
+			code: errorDimensionMismatch,
+		}
+	}
+	l, err := covariance.Cholesky()
+	if err != nil {
+		return nil, err
+	}
+	random := randomGenerator(source)
+	sample := NewMatrix(uint(n), uint(d))
+	z := make([]float64, d)
+	for k := 0; k < n; k++ {
+		for i := range z {
+			z[i] = random.NormFloat64()
+		}
+		for i := 0; i < d; i++ {
+			sample.M[k*d+i] = mean[i] + dot(l.M[i*d:i*d+i+1], z[:i+1])
+		}
+	}
+	return sample, nil
+}
+
+/*
+NewRandomPermutation is a method to draw a permutation of n indices uniformly with the
+Fisher-Yates shuffle.
+First parameter is the number of indices
+Second parameter is the random generator, rand.NewSource(1) when it is nil
+*/
+func NewRandomPermutation(n uint, source rand.Source) Permutation {
+	random := randomGenerator(source)
+	p := NewPermutation(n)
+	for i := len(p) - 1; i > 0; i-- {
+		p.Swap(uint(i), uint(random.Intn(i+1)))
+	}
+	return p
+}
+
+/*
+Shuffle is a function returning a copy of data in a uniformly random order, the data
+being unchanged. Use NewRandomPermutation to shuffle the rows of a matrix.
+First parameter is the data
+Second parameter is the random generator, rand.NewSource(1) when it is nil
+*/
+func Shuffle(data []float64, source rand.Source) []float64 {
+	shuffled, _ := NewRandomPermutation(uint(len(data)), source).ApplyVector(data)
+	return shuffled
+}
+
+/*
+Resample is a function drawing len(data) values from data with replacement, the
+bootstrap sample.
+First parameter is the data
+Second parameter is the random generator, rand.NewSource(1) when it is nil
+*/
+func Resample(data []float64, source rand.Source) []float64 {
+	return resample(data, randomGenerator(source))
+}
+
+func resample(data []float64, random *rand.Rand) []float64 {
+	sample := make([]float64, len(data))
+	for i := range sample {
+		sample[i] = data[random.Intn(len(data))]
+	}
+	return sample
+}
+
+/*
+Bootstrap is a function to estimate the distribution of a statistic of data by
+computing it on resamples drawn with replacement: the standard deviation of the
+results estimates its standard error, and their quantiles (see Quantile) give a
+percentile confidence interval.
+First parameter is the data
+Second parameter is the number of resamples, 1000 to 10000 is usual
+Third parameter is the statistic, e.g. a closure around Median
+Fourth parameter is the random generator, rand.NewSource(1) when it is nil
+It returns the statistic of each resample, and an error if data is empty or if the
+number of resamples is not positive
+*/
+func Bootstrap(data []float64, resamples int, statistic func([]float64) float64, source rand.Source) ([]float64, error) {
+	if len(data) == 0 || resamples <= 0 {
+		return nil, &MathError{
+			code: errorDimensionMismatch,
+		}
+	}
+	random := randomGenerator(source)
+	results := make([]float64, resamples)
+	for k := range results {
+		results[k] = statistic(resample(data, random))
+	}
+	return results, nil
+}
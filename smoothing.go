@@ -0,0 +1,176 @@
+package advmath
+
+import (
+	"math"
+	"sort"
+)
+
+/*
+checkWindow returns an error if window is not between 1 and the size of the data
+*/
+func checkWindow(data []float64, window int) error {
+	if window < 1 || window > len(data) {
+		return &MathError{
+			s: "The window must hold between 1 and len(data) values",
+		}
+	}
+	return nil
+}
+
+/*
+RollingMean is a function to compute the moving average of a series over a sliding
+window, with a running sum so that the cost doesn't depend on the window. The mean
+of a window holding a NaN is NaN.
+First parameter is the series
+Second parameter is the number of values in the window
+It returns len(data)-window+1 values, value k being the mean of data[k:k+window], and
+an error if the window is not between 1 and len(data)
+*/
+func RollingMean(data []float64, window int) ([]float64, error) {
+	if err := checkWindow(data, window); err != nil {
+		return nil, err
+	}
+	means := make([]float64, len(data)-window+1)
+	var sum float64
+	for i := 0; i < window; i++ {
+		sum += data[i]
+	}
+	means[0] = sum / float64(window)
+	for k := 1; k < len(means); k++ {
+		sum += data[k+window-1] - data[k-1]
+		if k%window == 0 || math.IsNaN(sum) || math.IsInf(sum, 0) {
+			//Recomputed from time to time, the round-off of the running sum grows, and
+			//after a NaN or an infinity which the difference can't remove
+			sum = 0
+			for _, v := range data[k : k+window] {
+				sum += v
+			}
+		}
+		means[k] = sum / float64(window)
+	}
+	return means, nil
+}
+
+/*
+RollingMedian is a function to compute the moving median of a series over a sliding
+window. Unlike the moving average it removes isolated spikes entirely and keeps the
+steps sharp. The median of a window holding a NaN is NaN, as for RollingMean.
+First parameter is the series
+Second parameter is the number of values in the window
+It returns len(data)-window+1 values, value k being the median of data[k:k+window],
+and an error if the window is not between 1 and len(data)
+*/
+func RollingMedian(data []float64, window int) ([]float64, error) {
+	if err := checkWindow(data, window); err != nil {
+		return nil, err
+	}
+	medians := make([]float64, len(data)-window+1)
+	//The window is kept sorted without its NaN values, which are counted, the oldest
+	//value being replaced by the new one
+	sorted := make([]float64, 0, window)
+	nans := 0
+	add := func(v float64) {
+		if math.IsNaN(v) {
+			nans++
+			return
+		}
+		i := sort.SearchFloat64s(sorted, v)
+		sorted = append(sorted, 0)
+		copy(sorted[i+1:], sorted[i:])
+		sorted[i] = v
+	}
+	remove := func(v float64) {
+		if math.IsNaN(v) {
+			nans--
+			return
+		}
+		i := sort.SearchFloat64s(sorted, v)
+		sorted = append(sorted[:i], sorted[i+1:]...)
+	}
+	middle := func() float64 {
+		if nans > 0 {
+			return math.NaN()
+		}
+		if window%2 == 1 {
+			return sorted[window/2]
+		}
+		return (sorted[window/2-1] + sorted[window/2]) / 2
+	}
+	for _, v := range data[:window] {
+		add(v)
+	}
+	medians[0] = middle()
+	for k := 1; k < len(medians); k++ {
+		remove(data[k-1])
+		add(data[k+window-1])
+		medians[k] = middle()
+	}
+	return medians, nil
+}
+
+/*
+SavitzkyGolay is a function to smooth a series of equally spaced values with the
+Savitzky-Golay filter: each value is replaced by the value at the center of the
+polynomial fitted by least squares to the window around it. Since the fit is the same
+linear combination for every window, it is computed once as convolution weights. The
+filter keeps the height and the width of the peaks much better than a moving average
+of the same window. The first and last window/2 values are taken from the polynomials
+fitted to the first and the last windows.
+First parameter is the series
+Second parameter is the number of values in the window, odd
+Third parameter is the degree of the polynomials, less than the window (2 to 4 is
+usual, 0 and 1 give a moving average)
+It returns the smoothed series, of the same size, and an error if the window is not
+odd and between degree+1 and len(data)
+*/
+func SavitzkyGolay(data []float64, window, degree int) ([]float64, error) {
+	if err := checkWindow(data, window); err != nil {
+		return nil, err
+	}
+	if window%2 == 0 || degree < 0 || degree >= window {
+		return nil, &MathError{
+			s: "The window must be odd and larger than the degree",
+		}
+	}
+	half := window / 2
+	offsets := make([]float64, window)
+	for i := range offsets {
+		offsets[i] = float64(i - half)
+	}
+
+	//The value of the fitted polynomial at 0 is its constant coefficient, the first
+	//row of the pseudo-inverse of the Vandermonde matrix
+	k := degree + 1
+	vandermonde := NewMatrix(uint(window), uint(k))
+	for i, x := range offsets {
+		power := 1.0
+		for j := 0; j < k; j++ {
+			vandermonde.M[i*k+j] = power
+			power *= x
+		}
+	}
+	pseudoInverse, err := leastSquares(vandermonde, NewIdentity(uint(window)))
+	if err != nil {
+		return nil, err
+	}
+	weights := pseudoInverse.M[:window]
+
+	n := len(data)
+	smoothed := make([]float64, n)
+	for i := half; i < n-half; i++ {
+		smoothed[i] = dot(weights, data[i-half:i+half+1])
+	}
+	first, err := PolynomialFit(offsets, data[:window], degree)
+	if err != nil {
+		return nil, err
+	}
+	last, err := PolynomialFit(offsets, data[n-window:], degree)
+	if err != nil {
+		return nil, err
+	}
+	for i := 0; i < half; i++ {
+		smoothed[i] = first.Evaluate(offsets[i])
+		smoothed[n-1-i] = last.Evaluate(offsets[window-1-i])
+	}
+	return smoothed, nil
+}
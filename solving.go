@@ -1,8 +1,9 @@
 package advmath
 
 import (
-	"fmt"
+	"context"
 	"math"
+	"math/cmplx"
 )
 
 /*
@@ -11,47 +12,239 @@ algorithm. Note that depending on the sign of the init value the
 algorithm will return a positive solution or a negative solution.
 It doesn't find all the solutions but only the closest to init.
 
-If there is a division by zero, the process will stop and return -1 in the err return
-value. Note that it doesn't mean that there is no solution but that the algorithm didn't
-manage to find one.
+If the derivative is zero (or so small that the step would be huge), or if an iterate
+is not finite, the process will stop and return -1 in the err return value. Note that it
+doesn't mean that there is no solution but that the algorithm didn't manage to find one.
 
 First param init is an initial estimated value of the zero
 Second param f is the function to solve
 Third param is the number of iteration, it is optional and set to 1000 by default
 Fourth param precision is the precision required, used to have an end condition
-return the zero and zero in the error field or a -1 in the error field if it failed
+return the zero and zero in the error field or a -1 in the error field if it failed,
+NewtonWithResult tells more about the failure
 */
 func Newton(init float64, f F, n int, precision float64) (float64, int) {
-	//This is in case of a zero division
-	defer func() {
-		if err := recover(); err != nil {
-			fmt.Print("Error in solving.go: %T", err)
-		}
-	}()
+	result, err := NewtonWithResult(init, f, n, precision)
+	if err != nil {
+		//Very likely we didn't find what we were looking for
+		return 0.0, -1
+	}
 
+	return result.Root, 0
+}
+
+/*
+RootResult is what the ...WithResult versions of the solving methods return, so that
+the caller can check how much the root can be trusted
+*/
+type RootResult struct {
+	//Root is the last iterate
+	Root float64
+	//Iterations is the number of iterations done
+	Iterations int
+	//Residual is |f(Root)|
+	Residual float64
+	//Converged tells if the precision required was met
+	Converged bool
+}
+
+/*
+NewtonWithResult is the same as Newton but returns a RootResult and an error instead
+of a -1 code. The error is a *MathError whose State tells where the method was when
+it failed: a derivative that is zero or so small that the step would be more than
+1e8 times max(1, |x|) stops it with a division by zero error, a value or an iterate
+that is not finite with a divergence error.
+
+First param init is an initial estimated value of the zero
+Second param f is the function to solve
+Third param is the number of iteration, it is optional and set to 1000 by default
+Fourth param precision is the precision required, used to have an end condition
+*/
+func NewtonWithResult(init float64, f F, n int, precision float64) (RootResult, error) {
 	if n == 0 {
 		//This should be enough for pretty much every precision
 		n = 1000
 	}
+	return newton(init, f, &solverSettings{maxIterations: n, tolerance: precision})
+}
+
+/*
+NewtonContext is the same as NewtonWithResult but stops when ctx is done, so that a
+server can cancel a method running on an adversarial input. The error is then
+ctx.Err() and the result holds the last iterate.
+*/
+func NewtonContext(ctx context.Context, init float64, f F, n int, precision float64) (RootResult, error) {
+	if n == 0 {
+		n = 1000
+	}
+	return newton(init, f, &solverSettings{maxIterations: n, tolerance: precision, ctx: ctx})
+}
 
-	var previous float64
+func newton(init float64, f F, s *solverSettings) (RootResult, error) {
 	x := init
-	var i int
-	for i = 0; i < n; i++ {
-		previous = x
-		x = x - f(x)/Standard(x, f, precision)
+	result := RootResult{}
+	for result.Iterations < s.maxIterations {
+		result.Iterations++
+		fx := f(x)
+		if fx == 0 {
+			result.Converged = true
+			break
+		}
+		d := Standard(x, f, s.tolerance)
+		state := &IterationState{
+			Iteration:  result.Iterations,
+			X:          x,
+			Value:      fx,
+			Derivative: d,
+		}
+		if err := s.iterate(*state); err != nil {
+			result.Root = x
+			result.Residual = math.Abs(fx)
+			return result, err
+		}
+		if math.IsNaN(fx) || math.IsInf(fx, 0) || math.IsNaN(d) || math.IsInf(d, 0) {
+			result.Root = x
+			result.Residual = math.Abs(fx)
+			return result, &MathError{
+				code:  errorDiverged,
+				State: state,
+			}
+		}
+		step := fx / d
+		if d == 0 || math.Abs(step) > 1e8*math.Max(1, math.Abs(x)) {
+			result.Root = x
+			result.Residual = math.Abs(fx)
+			return result, &MathError{
+				code:  errorDivisionByZero,
+				State: state,
+			}
+		}
 
-		if math.Abs(x-previous) <= precision {
+		x -= step
+		if math.Abs(step) <= s.tolerance {
+			result.Converged = true
 			break
 		}
 	}
 
-	if i == (n - 1) {
-		//Very likely we didn't find what we were looking for
-		return 0.0, -1
+	result.Root = x
+	result.Residual = math.Abs(f(x))
+	if !result.Converged {
+		return result, &MathError{
+			code: errorNotConverged,
+		}
 	}
+	return result, nil
+}
+
+/*
+DampedNewton is a globalized version of Newton that converges from initial values
+where the plain method overshoots and diverges. The Newton step is first clamped to
+maxStep, then halved until it decreases |f| enough (Armijo condition
+|f(x - l*s)| <= (1 - 1e-4*l)|f(x)|). If no step decreases |f|, the iterate is at a
+local minimum of |f| which is not a zero, and the method stops with an error
+holding the iteration state.
+
+First param init is an initial estimated value of the zero
+Second param f is the function to solve
+Third param is the number of iteration, it is optional and set to 1000 by default
+Fourth param precision is the precision required, used to have an end condition
+Fifth param maxStep is the largest step allowed, 0 means no clamping
+*/
+func DampedNewton(init float64, f F, n int, precision float64, maxStep float64) (RootResult, error) {
+	if n == 0 {
+		n = 1000
+	}
+	return dampedNewton(init, f, maxStep, &solverSettings{maxIterations: n, tolerance: precision})
+}
+
+func dampedNewton(init float64, f F, maxStep float64, s *solverSettings) (RootResult, error) {
+	const (
+		armijo    = 1e-4
+		minLambda = 1e-10
+	)
+	x := init
+	fx := f(x)
+	result := RootResult{}
+	for result.Iterations < s.maxIterations {
+		result.Iterations++
+		if fx == 0 {
+			result.Converged = true
+			break
+		}
+		d := Standard(x, f, s.tolerance)
+		state := &IterationState{
+			Iteration:  result.Iterations,
+			X:          x,
+			Value:      fx,
+			Derivative: d,
+		}
+		if err := s.iterate(*state); err != nil {
+			result.Root = x
+			result.Residual = math.Abs(fx)
+			return result, err
+		}
+		if math.IsNaN(fx) || math.IsInf(fx, 0) || math.IsNaN(d) || math.IsInf(d, 0) {
+			result.Root = x
+			result.Residual = math.Abs(fx)
+			return result, &MathError{
+				code:  errorDiverged,
+				State: state,
+			}
+		}
+		if d == 0 {
+			result.Root = x
+			result.Residual = math.Abs(fx)
+			return result, &MathError{
+				code:  errorDivisionByZero,
+				State: state,
+			}
+		}
+		step := fx / d
+		if maxStep > 0 && math.Abs(step) > maxStep {
+			step = math.Copysign(maxStep, step)
+		}
+		if math.Abs(step) <= s.tolerance {
+			//Close enough, |f| may not decrease anymore because of the round-off
+			x -= step
+			fx = f(x)
+			result.Converged = true
+			break
+		}
 
-	return x, 0
+		//Backtracking, a non finite value is rejected like a too large one
+		lambda := 1.0
+		next := x - step
+		fnext := f(next)
+		for !(math.Abs(fnext) <= (1-armijo*lambda)*math.Abs(fx)) {
+			lambda /= 2
+			if lambda < minLambda {
+				result.Root = x
+				result.Residual = math.Abs(fx)
+				return result, &MathError{
+					code:  errorNotConverged,
+					State: state,
+				}
+			}
+			next = x - lambda*step
+			fnext = f(next)
+		}
+
+		x, fx = next, fnext
+		if math.Abs(lambda*step) <= s.tolerance {
+			result.Converged = true
+			break
+		}
+	}
+
+	result.Root = x
+	result.Residual = math.Abs(fx)
+	if !result.Converged {
+		return result, &MathError{
+			code: errorNotConverged,
+		}
+	}
+	return result, nil
 }
 
 /*
@@ -68,28 +261,786 @@ First param init is an initial estimated value of the zero
 Second param f is the function to solve
 Third param is the number of iteration, it is optional and set to 1000 by default
 Fourth param precision is the precision required, used to have an end condition
-return the zero and zero in the error field or a -1 in the error field if it failed
+return the zero and zero in the error field or a -1 in the error field if it failed,
+SteffensenWithResult tells more about the failure
 */
 func Steffensen(init float64, f F, n int, precision float64) (float64, int) {
+	result, err := SteffensenWithResult(init, f, n, precision)
+	if err != nil {
+		return result.Root, -1
+	}
+	return result.Root, 0
+}
+
+/*
+SteffensenWithResult is the same as Steffensen but returns a RootResult and an error
+instead of a -1 code when the precision is not reached.
+
+First param init is an initial estimated value of the zero
+Second param f is the function to solve
+Third param is the number of iteration, it is optional and set to 1000 by default
+Fourth param precision is the precision required, used to have an end condition
+*/
+func SteffensenWithResult(init float64, f F, n int, precision float64) (RootResult, error) {
 	if n == 0 {
 		//ok let's try 1000
 		n = 1000
 	}
+	return steffensen(init, f, &solverSettings{maxIterations: n, tolerance: precision})
+}
+
+/*
+SteffensenContext is the same as SteffensenWithResult but stops when ctx is done, the
+error is then ctx.Err() and the result holds the last iterate.
+*/
+func SteffensenContext(ctx context.Context, init float64, f F, n int, precision float64) (RootResult, error) {
+	if n == 0 {
+		n = 1000
+	}
+	return steffensen(init, f, &solverSettings{maxIterations: n, tolerance: precision, ctx: ctx})
+}
+
+func steffensen(init float64, f F, s *solverSettings) (RootResult, error) {
 	p0 := init
 	var p1, p2, p float64
-	for i := 1; i < n; i++ {
-		p1 = p0 + f(p0)
+	result := RootResult{}
+	for i := 1; i <= s.maxIterations; i++ {
+		result.Iterations = i
+		f0 := f(p0)
+		if err := s.iterate(IterationState{Iteration: i, X: p0, Value: f0}); err != nil {
+			result.Root = p0
+			result.Residual = math.Abs(f0)
+			return result, err
+		}
+		p1 = p0 + f0
 		p2 = p1 + f(p1)
 		p = p2 - math.Pow(p2-p1, 2.0)/(p2-2*p1+p0)
 
-		if math.Abs(p-p0) < precision {
-			return p, 0
+		if math.Abs(p-p0) < s.tolerance {
+			result.Converged = true
+			break
 		}
 		if math.IsNaN(p) {
 			//Ok so we have the exact value
-			return p0, 0
+			p = p0
+			result.Converged = true
+			break
 		}
 		p0 = p
 	}
-	return p, -1
+
+	result.Root = p
+	result.Residual = math.Abs(f(p))
+	if !result.Converged {
+		return result, &MathError{
+			code: errorNotConverged,
+		}
+	}
+	return result, nil
+}
+
+/*
+Bisect finds a zero of f between a and b with the bisection method: the interval is
+halved keeping the half where f changes sign. It is slow (one bit per iteration) but
+it always converges, which makes it a safe fallback when Newton or Steffensen
+diverge. f must be continuous and f(a), f(b) must have opposite signs.
+
+First param a is a boundary of the interval
+Second param b is the other boundary
+Third param f is the function to solve
+Fourth param tol is the absolute precision required on the zero
+return the zero, and an error if f doesn't change sign between a and b
+*/
+func Bisect(a float64, b float64, f F, tol float64) (float64, error) {
+	result, err := bisect(a, b, f, &solverSettings{maxIterations: 5000, tolerance: tol})
+	return result.Root, err
+}
+
+func bisect(a float64, b float64, f F, s *solverSettings) (RootResult, error) {
+	fa := f(a)
+	fb := f(b)
+	if fa == 0 {
+		return RootResult{Root: a, Converged: true}, nil
+	}
+	if fb == 0 {
+		return RootResult{Root: b, Converged: true}, nil
+	}
+	if math.Signbit(fa) == math.Signbit(fb) || math.IsNaN(fa) || math.IsNaN(fb) {
+		return RootResult{}, &MathError{
+			code: errorNoSignChange,
+		}
+	}
+
+	result := RootResult{}
+	for result.Iterations < s.maxIterations {
+		result.Iterations++
+		m := a + (b-a)/2
+		if math.Abs(b-a)/2 <= s.tolerance || m == a || m == b {
+			result.Root = m
+			result.Residual = math.Abs(f(m))
+			result.Converged = true
+			return result, nil
+		}
+		fm := f(m)
+		if err := s.iterate(IterationState{Iteration: result.Iterations, X: m, Value: fm}); err != nil {
+			result.Root = m
+			result.Residual = math.Abs(fm)
+			return result, err
+		}
+		if fm == 0 {
+			result.Root = m
+			result.Converged = true
+			return result, nil
+		}
+		if math.Signbit(fm) == math.Signbit(fa) {
+			a, fa = m, fm
+		} else {
+			b = m
+		}
+	}
+	result.Root = a + (b-a)/2
+	result.Residual = math.Abs(f(result.Root))
+	return result, &MathError{
+		code: errorNotConverged,
+	}
+}
+
+/*
+BracketRoot looks for an interval where f changes sign, starting from init and moving
+outward on both sides with steps growing by a factor 1.6, so that the result can be
+given directly to Bisect, Brent or Illinois. The first step is 0.1*max(1, |init|).
+When f is not finite at a point (e.g. log on negative values) the step on that side
+is divided by 4 instead, so that the scan stops at the boundary of the domain of f.
+Note that it finds a sign change, not every zero: zeros of even multiplicity or two
+zeros between consecutive points are missed.
+
+First param init is where the search starts
+Second param f is the function to solve
+return the boundaries a < b of an interval where f changes sign, and an error if
+no sign change was found
+*/
+func BracketRoot(init float64, f F) (float64, float64, error) {
+	const (
+		growth        = 1.6
+		maxIterations = 200
+	)
+	finite := func(v float64) bool {
+		return !math.IsNaN(v) && !math.IsInf(v, 0)
+	}
+
+	f0 := f(init)
+	if f0 == 0 {
+		return init, init, nil
+	}
+	if !finite(f0) {
+		return 0, 0, &MathError{
+			code: errorNoSignChange,
+		}
+	}
+
+	left, fleft := init, f0
+	right, fright := init, f0
+	hleft := 0.1 * math.Max(1, math.Abs(init))
+	hright := hleft
+	for i := 0; i < maxIterations; i++ {
+		//Left side, then right side
+		x := left - hleft
+		if x != left {
+			fx := f(x)
+			switch {
+			case !finite(fx):
+				hleft /= 4
+			case fx == 0 || math.Signbit(fx) != math.Signbit(fleft):
+				return x, left, nil
+			default:
+				left, fleft = x, fx
+				hleft *= growth
+			}
+		}
+
+		x = right + hright
+		if x != right {
+			fx := f(x)
+			switch {
+			case !finite(fx):
+				hright /= 4
+			case fx == 0 || math.Signbit(fx) != math.Signbit(fright):
+				return right, x, nil
+			default:
+				right, fright = x, fx
+				hright *= growth
+			}
+		}
+	}
+	return left, right, &MathError{
+		code: errorNoSignChange,
+	}
+}
+
+/*
+Brent finds a zero of f between a and b with Brent's method (zbrent in 'Numerical
+Recipes'): it uses inverse quadratic interpolation or the secant method when they
+make good progress and falls back to bisection otherwise. It converges as surely as
+Bisect but superlinearly on smooth functions, without needing a derivative, which
+makes it the recommended solver when a bracket is known.
+
+First param a is a boundary of the interval
+Second param b is the other boundary
+Third param f is the function to solve
+Fourth param tol is the absolute precision required on the zero
+return the zero, and an error if f doesn't change sign between a and b or if the
+precision wasn't reached in 500 iterations (flat functions need more than the 100 of
+'Numerical Recipes')
+*/
+func Brent(a float64, b float64, f F, tol float64) (float64, error) {
+	result, err := brent(a, b, f, &solverSettings{maxIterations: 500, tolerance: tol})
+	return result.Root, err
+}
+
+func brent(a float64, b float64, f F, s *solverSettings) (RootResult, error) {
+	fa := f(a)
+	fb := f(b)
+	if fa == 0 {
+		return RootResult{Root: a, Converged: true}, nil
+	}
+	if fb == 0 {
+		return RootResult{Root: b, Converged: true}, nil
+	}
+	if math.Signbit(fa) == math.Signbit(fb) || math.IsNaN(fa) || math.IsNaN(fb) {
+		return RootResult{}, &MathError{
+			code: errorNoSignChange,
+		}
+	}
+
+	c, fc := b, fb
+	var d, e float64
+	result := RootResult{}
+	for result.Iterations < s.maxIterations {
+		result.Iterations++
+		if math.Signbit(fb) == math.Signbit(fc) {
+			//c is always on the other side of the zero
+			c, fc = a, fa
+			d = b - a
+			e = d
+		}
+		if math.Abs(fc) < math.Abs(fb) {
+			//b is always the best estimate
+			a, b, c = b, c, b
+			fa, fb, fc = fb, fc, fb
+		}
+
+		if err := s.iterate(IterationState{Iteration: result.Iterations, X: b, Value: fb}); err != nil {
+			result.Root = b
+			result.Residual = math.Abs(fb)
+			return result, err
+		}
+
+		tol1 := 2*machineEpsilon*math.Abs(b) + 0.5*s.tolerance
+		xm := 0.5 * (c - b)
+		if math.Abs(xm) <= tol1 || fb == 0 {
+			result.Root = b
+			result.Residual = math.Abs(fb)
+			result.Converged = true
+			return result, nil
+		}
+
+		if math.Abs(e) >= tol1 && math.Abs(fa) > math.Abs(fb) {
+			var p, q float64
+			s := fb / fa
+			if a == c {
+				//Secant
+				p = 2 * xm * s
+				q = 1 - s
+			} else {
+				//Inverse quadratic interpolation
+				q = fa / fc
+				r := fb / fc
+				p = s * (2*xm*q*(q-r) - (b-a)*(r-1))
+				q = (q - 1) * (r - 1) * (s - 1)
+			}
+			if p > 0 {
+				q = -q
+			}
+			p = math.Abs(p)
+			if 2*p < math.Min(3*xm*q-math.Abs(tol1*q), math.Abs(e*q)) {
+				//Accept the interpolation
+				e = d
+				d = p / q
+			} else {
+				//Interpolation failed, use bisection
+				d = xm
+				e = d
+			}
+		} else {
+			//Bounds decreasing too slowly, use bisection
+			d = xm
+			e = d
+		}
+
+		a, fa = b, fb
+		if math.Abs(d) > tol1 {
+			b += d
+		} else {
+			b += math.Copysign(tol1, xm)
+		}
+		fb = f(b)
+	}
+
+	result.Root = b
+	result.Residual = math.Abs(fb)
+	return result, &MathError{
+		code: errorNotConverged,
+	}
+}
+
+/*
+Secant finds a zero of f with the secant method: like Newton but the derivative is
+replaced by the slope between the last two iterates, so that only one evaluation of
+f is needed per iteration. It converges superlinearly (order 1.618) near a simple
+zero but, as Newton, it can diverge from bad initial values.
+
+First param x0 is a first initial estimated value of the zero
+Second param x1 is a second initial estimated value, close to x0
+Third param f is the function to solve
+Fourth param is the number of iteration, it is optional and set to 1000 by default
+Fifth param precision is the precision required, used to have an end condition
+return the zero, and an error if two iterates have the same value of f or if the
+precision wasn't reached
+*/
+func Secant(x0 float64, x1 float64, f F, n int, precision float64) (float64, error) {
+	if n == 0 {
+		n = 1000
+	}
+	result, err := secant(x0, x1, f, &solverSettings{maxIterations: n, tolerance: precision})
+	return result.Root, err
+}
+
+func secant(x0 float64, x1 float64, f F, s *solverSettings) (RootResult, error) {
+	f0 := f(x0)
+	f1 := f(x1)
+	result := RootResult{}
+	for result.Iterations < s.maxIterations {
+		result.Iterations++
+		if err := s.iterate(IterationState{Iteration: result.Iterations, X: x1, Value: f1}); err != nil {
+			result.Root = x1
+			result.Residual = math.Abs(f1)
+			return result, err
+		}
+		if f1 == 0 {
+			result.Converged = true
+			break
+		}
+		if f1 == f0 {
+			result.Root = x1
+			result.Residual = math.Abs(f1)
+			return result, &MathError{
+				code: errorDivisionByZero,
+			}
+		}
+		x := x1 - f1*(x1-x0)/(f1-f0)
+		if math.IsNaN(x) || math.IsInf(x, 0) {
+			break
+		}
+		x0, f0 = x1, f1
+		x1, f1 = x, f(x)
+		if math.Abs(x1-x0) <= s.tolerance {
+			result.Converged = true
+			break
+		}
+	}
+	result.Root = x1
+	result.Residual = math.Abs(f1)
+	if !result.Converged {
+		return result, &MathError{
+			code: errorNotConverged,
+		}
+	}
+	return result, nil
+}
+
+/*
+Illinois finds a zero of f between a and b with the Illinois variant of the false
+position (regula falsi) method: the next estimate is where the line through the two
+boundaries crosses zero, and the value of f at a boundary that is kept twice in a
+row is halved so that it cannot get stuck as the plain method does. As Bisect it
+always converges, usually much faster.
+
+First param a is a boundary of the interval
+Second param b is the other boundary
+Third param f is the function to solve
+Fourth param tol is the absolute precision required on the zero
+return the zero, and an error if f doesn't change sign between a and b or if the
+precision wasn't reached in 1000 iterations
+*/
+func Illinois(a float64, b float64, f F, tol float64) (float64, error) {
+	result, err := illinois(a, b, f, &solverSettings{maxIterations: 1000, tolerance: tol})
+	return result.Root, err
+}
+
+func illinois(a float64, b float64, f F, s *solverSettings) (RootResult, error) {
+	fa := f(a)
+	fb := f(b)
+	if fa == 0 {
+		return RootResult{Root: a, Converged: true}, nil
+	}
+	if fb == 0 {
+		return RootResult{Root: b, Converged: true}, nil
+	}
+	if math.Signbit(fa) == math.Signbit(fb) || math.IsNaN(fa) || math.IsNaN(fb) {
+		return RootResult{}, &MathError{
+			code: errorNoSignChange,
+		}
+	}
+
+	//side tells which boundary was kept at the previous iteration
+	side := 0
+	c := a
+	result := RootResult{}
+	for result.Iterations < s.maxIterations {
+		result.Iterations++
+		c = (a*fb - b*fa) / (fb - fa)
+		if math.Abs(b-a) <= s.tolerance {
+			result.Converged = true
+			break
+		}
+		fc := f(c)
+		if err := s.iterate(IterationState{Iteration: result.Iterations, X: c, Value: fc}); err != nil {
+			result.Root = c
+			result.Residual = math.Abs(fc)
+			return result, err
+		}
+		if fc == 0 {
+			result.Converged = true
+			break
+		}
+		if math.Signbit(fc) == math.Signbit(fb) {
+			b, fb = c, fc
+			if side == -1 {
+				fa /= 2
+			}
+			side = -1
+		} else {
+			a, fa = c, fc
+			if side == 1 {
+				fb /= 2
+			}
+			side = 1
+		}
+		if math.Abs(b-a) <= s.tolerance {
+			result.Converged = true
+			break
+		}
+	}
+	result.Root = c
+	result.Residual = math.Abs(f(c))
+	if !result.Converged {
+		return result, &MathError{
+			code: errorNotConverged,
+		}
+	}
+	return result, nil
+}
+
+/*
+NewtonWithDerivative is the same as Newton but uses the derivative given by the
+caller instead of a numerical one, which is both faster and more precise when the
+derivative is known analytically.
+
+First param init is an initial estimated value of the zero
+Second param f is the function to solve
+Third param fprime is the derivative of f
+Fourth param is the number of iteration, it is optional and set to 1000 by default
+Fifth param precision is the precision required, used to have an end condition
+return the zero, and an error if the derivative is zero at an iterate or if the
+precision wasn't reached
+*/
+func NewtonWithDerivative(init float64, f F, fprime F, n int, precision float64) (float64, error) {
+	if n == 0 {
+		n = 1000
+	}
+	x := init
+	for i := 0; i < n; i++ {
+		fx := f(x)
+		if fx == 0 {
+			return x, nil
+		}
+		d := fprime(x)
+		if d == 0 {
+			return x, &MathError{
+				code: errorDivisionByZero,
+			}
+		}
+		previous := x
+		x -= fx / d
+		if math.IsNaN(x) || math.IsInf(x, 0) {
+			break
+		}
+		if math.Abs(x-previous) <= precision {
+			return x, nil
+		}
+	}
+	return x, &MathError{
+		code: errorNotConverged,
+	}
+}
+
+/*
+Halley finds a zero of f with Halley's method, which also uses the second derivative
+and converges cubically near a simple zero (the number of correct digits triples at
+each iteration):
+
+	x = x - 2 f f' / (2 f'^2 - f f'')
+
+First param init is an initial estimated value of the zero
+Second param f is the function to solve
+Third param fprime is the derivative of f
+Fourth param fsecond is the second derivative of f
+Fifth param is the number of iteration, it is optional and set to 1000 by default
+Sixth param precision is the precision required, used to have an end condition
+return the zero, and an error if the denominator is zero at an iterate or if the
+precision wasn't reached
+*/
+func Halley(init float64, f F, fprime F, fsecond F, n int, precision float64) (float64, error) {
+	if n == 0 {
+		n = 1000
+	}
+	x := init
+	for i := 0; i < n; i++ {
+		fx := f(x)
+		if fx == 0 {
+			return x, nil
+		}
+		d := fprime(x)
+		denominator := 2*d*d - fx*fsecond(x)
+		if denominator == 0 {
+			return x, &MathError{
+				code: errorDivisionByZero,
+			}
+		}
+		previous := x
+		x -= 2 * fx * d / denominator
+		if math.IsNaN(x) || math.IsInf(x, 0) {
+			break
+		}
+		if math.Abs(x-previous) <= precision {
+			return x, nil
+		}
+	}
+	return x, &MathError{
+		code: errorNotConverged,
+	}
+}
+
+/*
+FindRoots finds all the zeros of f between a and b where f changes sign: the interval
+is split in subdivisions pieces and each piece where f changes sign is solved with
+Brent. Zeros closer than the width of a piece can be missed when f changes sign an
+even number of times in between, and zeros where f doesn't change sign (a double
+root, f touching the axis) are only found by FindRootsWithTangents.
+
+First param a is the lower boundary of the interval
+Second param b is the upper boundary of the interval
+Third param f is the function to solve
+Fourth param is the number of pieces, it is optional and set to 1000 by default
+Fifth param tol is the absolute precision required on the zeros
+return the zeros in increasing order
+*/
+func FindRoots(a float64, b float64, f F, subdivisions int, tol float64) []float64 {
+	return findRoots(a, b, f, subdivisions, tol, false)
+}
+
+/*
+FindRootsWithTangents is the same as FindRoots but also finds the zeros where f
+touches the axis without changing sign: in each piece where f doesn't change sign but
+its (numerical) derivative does, the extremum is found with Brent on the derivative
+and kept when |f| is below tol there.
+*/
+func FindRootsWithTangents(a float64, b float64, f F, subdivisions int, tol float64) []float64 {
+	return findRoots(a, b, f, subdivisions, tol, true)
+}
+
+func findRoots(a float64, b float64, f F, subdivisions int, tol float64, tangents bool) []float64 {
+	if subdivisions <= 0 {
+		subdivisions = 1000
+	}
+	if a > b {
+		a, b = b, a
+	}
+	derivative := func(x float64) float64 {
+		return Standard(x, f, 0)
+	}
+
+	var roots []float64
+	add := func(x float64) {
+		if len(roots) == 0 || x-roots[len(roots)-1] > tol {
+			roots = append(roots, x)
+		}
+	}
+
+	h := (b - a) / float64(subdivisions)
+	x0 := a
+	f0 := f(x0)
+	var d0 float64
+	if tangents {
+		d0 = derivative(x0)
+	}
+	for i := 1; i <= subdivisions; i++ {
+		x1 := a + float64(i)*h
+		if i == subdivisions {
+			x1 = b
+		}
+		f1 := f(x1)
+
+		switch {
+		case f0 == 0:
+			add(x0)
+		case f1 != 0 && math.Signbit(f0) != math.Signbit(f1):
+			if root, err := Brent(x0, x1, f, tol); err == nil {
+				add(root)
+			}
+		}
+		var d1 float64
+		if tangents {
+			d1 = derivative(x1)
+			if f1 != 0 && math.Signbit(f0) == math.Signbit(f1) && d0 != 0 && d1 != 0 && math.Signbit(d0) != math.Signbit(d1) {
+				if extremum, err := Brent(x0, x1, derivative, tol); err == nil && math.Abs(f(extremum)) <= tol {
+					add(extremum)
+				}
+			}
+		}
+		x0, f0, d0 = x1, f1, d1
+	}
+	if f0 == 0 {
+		add(x0)
+	}
+	return roots
+}
+
+/*
+Muller finds a zero of a complex function with Muller's method: the parabola through
+the last three iterates is computed and its root closest to the last iterate becomes
+the next iterate. As the square root of the discriminant can be complex, it finds
+complex zeros even from a real initial value, which Newton on the real line cannot
+do. It converges with order about 1.84 and doesn't need a derivative.
+
+First param init is an initial estimated value of the zero
+Second param f is the function to solve
+Third param is the number of iteration, it is optional and set to 1000 by default
+Fourth param precision is the precision required, used to have an end condition
+return the zero, and an error if the parabola degenerates or if the precision
+wasn't reached
+*/
+func Muller(init complex128, f func(complex128) complex128, n int, precision float64) (complex128, error) {
+	if n == 0 {
+		n = 1000
+	}
+	h := complex(0.1*math.Max(1, cmplx.Abs(init)), 0)
+	x0, x1, x2 := init-h, init+h, init
+	f0, f1, f2 := f(x0), f(x1), f(x2)
+
+	for i := 0; i < n; i++ {
+		if f2 == 0 {
+			return x2, nil
+		}
+		h1 := x1 - x0
+		h2 := x2 - x1
+		d1 := (f1 - f0) / h1
+		d2 := (f2 - f1) / h2
+		a := (d2 - d1) / (h2 + h1)
+		b := a*h2 + d2
+		disc := cmplx.Sqrt(b*b - 4*a*f2)
+		//The sign giving the biggest denominator gives the closest root
+		den := b + disc
+		if cmplx.Abs(b-disc) > cmplx.Abs(den) {
+			den = b - disc
+		}
+		if den == 0 {
+			return x2, &MathError{
+				code: errorDivisionByZero,
+			}
+		}
+		dx := -2 * f2 / den
+		x0, x1, x2 = x1, x2, x2+dx
+		f0, f1, f2 = f1, f2, f(x2)
+		if cmplx.IsNaN(x2) || cmplx.IsInf(x2) {
+			break
+		}
+		if cmplx.Abs(dx) <= precision {
+			return x2, nil
+		}
+	}
+	return x2, &MathError{
+		code: errorNotConverged,
+	}
+}
+
+/*
+FixedPoint finds a fixed point of g, i.e. a solution of x = g(x), by iterating
+x = g(x) from init. It converges when |g'| < 1 near the fixed point, linearly with
+the ratio |g'|. The iteration is stopped as diverging when the steps grow 5 times in
+a row or when an iterate is not finite.
+
+First param init is an initial estimated value of the fixed point
+Second param g is the function
+Third param tol is the precision required, used to have an end condition
+return the fixed point, and an error if the iterates diverge or if the precision
+wasn't reached in 1000 iterations
+*/
+func FixedPoint(init float64, g F, tol float64) (float64, error) {
+	return fixedPoint(init, g, tol, false)
+}
+
+/*
+FixedPointAitken is the same as FixedPoint with Aitken's delta-squared acceleration:
+from x0, x1 = g(x0) and x2 = g(x1), the next iterate is
+x0 - (x1 - x0)^2 / (x2 - 2 x1 + x0), which converges quadratically (it is the method
+of Steffensen applied to x - g(x)) and can even converge when |g'| > 1.
+*/
+func FixedPointAitken(init float64, g F, tol float64) (float64, error) {
+	return fixedPoint(init, g, tol, true)
+}
+
+func fixedPoint(init float64, g F, tol float64, aitken bool) (float64, error) {
+	const maxIterations = 1000
+	const maxGrowth = 5
+	x := init
+	previousStep := math.Inf(1)
+	growth := 0
+	for i := 0; i < maxIterations; i++ {
+		next := g(x)
+		if aitken {
+			x2 := g(next)
+			denominator := x2 - 2*next + x
+			if denominator != 0 {
+				next = x - (next-x)*(next-x)/denominator
+			} else {
+				next = x2
+			}
+		}
+		if math.IsNaN(next) || math.IsInf(next, 0) {
+			return x, &MathError{
+				code: errorDiverged,
+			}
+		}
+
+		step := math.Abs(next - x)
+		x = next
+		if step <= tol {
+			return x, nil
+		}
+		if step > previousStep {
+			growth++
+			if growth >= maxGrowth {
+				return x, &MathError{
+					code: errorDiverged,
+				}
+			}
+		} else {
+			growth = 0
+		}
+		previousStep = step
+	}
+	return x, &MathError{
+		code: errorNotConverged,
+	}
 }
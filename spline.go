@@ -0,0 +1,354 @@
+package advmath
+
+import (
+	"math"
+	"sort"
+)
+
+/*
+SplineCondition is the condition used at the ends of a cubic spline
+*/
+type SplineCondition int
+
+const (
+	//NaturalSpline has a zero second derivative at both ends
+	NaturalSpline SplineCondition = iota
+	//ClampedSpline has the first derivatives given at both ends
+	ClampedSpline
+	//NotAKnotSpline has a continuous third derivative at the second and the next to
+	//last points, so the two first and the two last intervals are the same cubic
+	NotAKnotSpline
+)
+
+/*
+CubicSpline is a piecewise cubic function, on [X[i], X[i+1]] it is
+Y[i] + b[i] t + c[i] t^2 + d[i] t^3 with t = x - X[i]. Out of [X[0], X[n-1]] the
+polynomials of the first and last intervals are extrapolated.
+*/
+type CubicSpline struct {
+	X []float64
+	Y []float64
+	b []float64
+	c []float64
+	d []float64
+}
+
+/*
+checkSamples checks that there are at least two samples with x strictly increasing
+*/
+func checkSamples(x, y []float64) error {
+	if len(x) < 2 || len(x) != len(y) {
+		return &MathError{
+			code: errorDimensionMismatch,
+		}
+	}
+	for i := 1; i < len(x); i++ {
+		if !(x[i] > x[i-1]) {
+			return &MathError{
+				s: "The abscissas must be strictly increasing",
+			}
+		}
+	}
+	return nil
+}
+
+/*
+newHermiteSpline creates the piecewise cubic with the values y and the first
+derivatives slopes at the points x
+*/
+func newHermiteSpline(x, y, slopes []float64) *CubicSpline {
+	n := len(x)
+	s := &CubicSpline{
+		X: append([]float64(nil), x...),
+		Y: append([]float64(nil), y...),
+		b: append([]float64(nil), slopes[:n-1]...),
+		c: make([]float64, n-1),
+		d: make([]float64, n-1),
+	}
+	for i := 0; i < n-1; i++ {
+		h := x[i+1] - x[i]
+		delta := (y[i+1] - y[i]) / h
+		s.c[i] = (3*delta - 2*slopes[i] - slopes[i+1]) / h
+		s.d[i] = (slopes[i] + slopes[i+1] - 2*delta) / (h * h)
+	}
+	return s
+}
+
+/*
+NewCubicSpline is a method to create the cubic spline interpolating the samples
+(x[i], y[i]): it has continuous first and second derivatives. The slopes at the points
+are the solution of a tridiagonal system (see SolveTridiagonal).
+First parameter x are the abscissas, strictly increasing
+Second parameter y are the values
+Third parameter condition is the condition at the ends
+Fourth and fifth parameters are the derivatives at x[0] and x[n-1], only used by
+ClampedSpline
+It returns an error if there are less than 2 samples or if x is not increasing
+*/
+func NewCubicSpline(x, y []float64, condition SplineCondition, startSlope, endSlope float64) (*CubicSpline, error) {
+	if err := checkSamples(x, y); err != nil {
+		return nil, err
+	}
+	n := len(x)
+	h, delta := differences(x, y)
+
+	slopes := make([]float64, n)
+	switch {
+	case condition == NotAKnotSpline && n == 2:
+		//A line
+		slopes[0], slopes[1] = delta[0], delta[0]
+	case condition == NotAKnotSpline && n == 3:
+		//The parabola through the three points
+		c := (delta[1] - delta[0]) / (x[2] - x[0])
+		for i := range slopes {
+			slopes[i] = delta[0] + c*(2*x[i]-x[0]-x[1])
+		}
+	default:
+		lower := make([]float64, n-1)
+		diagonal := make([]float64, n)
+		upper := make([]float64, n-1)
+		b := make([]float64, n)
+		for i := 1; i < n-1; i++ {
+			lower[i-1] = h[i]
+			diagonal[i] = 2 * (h[i-1] + h[i])
+			upper[i] = h[i-1]
+			b[i] = 3 * (h[i]*delta[i-1] + h[i-1]*delta[i])
+		}
+		switch condition {
+		case ClampedSpline:
+			diagonal[0], b[0] = 1, startSlope
+			diagonal[n-1], b[n-1] = 1, endSlope
+		case NotAKnotSpline:
+			diagonal[0] = h[1]
+			upper[0] = h[0] + h[1]
+			b[0] = ((h[0]+2*(h[0]+h[1]))*h[1]*delta[0] + h[0]*h[0]*delta[1]) / (h[0] + h[1])
+			diagonal[n-1] = h[n-3]
+			lower[n-2] = h[n-2] + h[n-3]
+			b[n-1] = (h[n-2]*h[n-2]*delta[n-3] + (2*(h[n-3]+h[n-2])+h[n-2])*h[n-3]*delta[n-2]) / (h[n-3] + h[n-2])
+		default:
+			diagonal[0], upper[0], b[0] = 2, 1, 3*delta[0]
+			lower[n-2], diagonal[n-1], b[n-1] = 1, 2, 3*delta[n-2]
+		}
+		var err error
+		slopes, err = SolveTridiagonal(lower, diagonal, upper, b)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return newHermiteSpline(x, y, slopes), nil
+}
+
+/*
+interval returns the index of the polynomial used at x
+*/
+func (s *CubicSpline) interval(x float64) int {
+	i := sort.SearchFloat64s(s.X, x) - 1
+	if i < 0 {
+		return 0
+	}
+	if i > len(s.b)-1 {
+		return len(s.b) - 1
+	}
+	return i
+}
+
+/*
+Evaluate is a method to compute the value of the spline at x
+*/
+func (s *CubicSpline) Evaluate(x float64) float64 {
+	i := s.interval(x)
+	t := x - s.X[i]
+	return s.Y[i] + t*(s.b[i]+t*(s.c[i]+t*s.d[i]))
+}
+
+/*
+Derivative is a method to compute the first derivative of the spline at x
+*/
+func (s *CubicSpline) Derivative(x float64) float64 {
+	i := s.interval(x)
+	t := x - s.X[i]
+	return s.b[i] + t*(2*s.c[i]+3*t*s.d[i])
+}
+
+/*
+SecondDerivative is a method to compute the second derivative of the spline at x
+*/
+func (s *CubicSpline) SecondDerivative(x float64) float64 {
+	i := s.interval(x)
+	t := x - s.X[i]
+	return 2*s.c[i] + 6*t*s.d[i]
+}
+
+/*
+primitive is the integral of the polynomial of interval i from X[i] to x
+*/
+func (s *CubicSpline) primitive(i int, x float64) float64 {
+	t := x - s.X[i]
+	return t * (s.Y[i] + t*(s.b[i]/2+t*(s.c[i]/3+t*s.d[i]/4)))
+}
+
+/*
+Integrate is a method to compute exactly the integral of the spline between a and b
+*/
+func (s *CubicSpline) Integrate(a float64, b float64) float64 {
+	if a > b {
+		return -s.Integrate(b, a)
+	}
+	i, j := s.interval(a), s.interval(b)
+	if i == j {
+		return s.primitive(i, b) - s.primitive(i, a)
+	}
+	sum := s.primitive(i, s.X[i+1]) - s.primitive(i, a)
+	for k := i + 1; k < j; k++ {
+		sum += s.primitive(k, s.X[k+1])
+	}
+	return sum + s.primitive(j, b)
+}
+
+/*
+F is a method returning the spline as a function, to give it to the integration or
+solving methods
+*/
+func (s *CubicSpline) F() F {
+	return s.Evaluate
+}
+
+/*
+NewPCHIP is a method to create the piecewise cubic Hermite interpolant of Fritsch and
+Carlson (PCHIP): the slopes are chosen so that the interpolant is monotone wherever
+the data is, and has its extrema at the samples, so it doesn't overshoot. A quantity
+that is never negative in the samples is never negative in the interpolant. It only
+has a continuous first derivative.
+First parameter x are the abscissas, strictly increasing
+Second parameter y are the values
+It returns an error if there are less than 2 samples or if x is not increasing
+*/
+func NewPCHIP(x, y []float64) (*CubicSpline, error) {
+	if err := checkSamples(x, y); err != nil {
+		return nil, err
+	}
+	n := len(x)
+	h, delta := differences(x, y)
+	slopes := make([]float64, n)
+	if n == 2 {
+		slopes[0], slopes[1] = delta[0], delta[0]
+		return newHermiteSpline(x, y, slopes), nil
+	}
+
+	for k := 1; k < n-1; k++ {
+		if delta[k-1] == 0 || delta[k] == 0 || math.Signbit(delta[k-1]) != math.Signbit(delta[k]) {
+			//Local extremum
+			continue
+		}
+		//Weighted harmonic mean
+		w1 := 2*h[k] + h[k-1]
+		w2 := h[k] + 2*h[k-1]
+		slopes[k] = (w1 + w2) / (w1/delta[k-1] + w2/delta[k])
+	}
+	slopes[0] = pchipEnd(h[0], h[1], delta[0], delta[1])
+	slopes[n-1] = pchipEnd(h[n-2], h[n-3], delta[n-2], delta[n-3])
+	return newHermiteSpline(x, y, slopes), nil
+}
+
+/*
+pchipEnd is the shape preserving three points slope at an end, h0 and delta0 being
+the interval at the end and h1, delta1 its neighbour
+*/
+func pchipEnd(h0, h1, delta0, delta1 float64) float64 {
+	d := ((2*h0+h1)*delta0 - h0*delta1) / (h0 + h1)
+	switch {
+	case d == 0 || math.Signbit(d) != math.Signbit(delta0):
+		return 0
+	case math.Signbit(delta0) != math.Signbit(delta1) && math.Abs(d) > 3*math.Abs(delta0):
+		return 3 * delta0
+	}
+	return d
+}
+
+/*
+NewAkima is a method to create the Akima spline: the slope at a sample is a mean of
+the slopes of the two neighbouring intervals, weighted by how much the slopes change on
+the other side, so that an outlier only changes the interpolant near it and the
+interpolant doesn't wiggle like a cubic spline on noisy data. The two missing slopes at
+each end are extrapolated linearly. It only has a continuous first derivative.
+First parameter x are the abscissas, strictly increasing
+Second parameter y are the values
+It returns an error if there are less than 2 samples or if x is not increasing
+*/
+func NewAkima(x, y []float64) (*CubicSpline, error) {
+	if err := checkSamples(x, y); err != nil {
+		return nil, err
+	}
+	n := len(x)
+	_, delta := differences(x, y)
+	slopes := make([]float64, n)
+	if n == 2 {
+		slopes[0], slopes[1] = delta[0], delta[0]
+		return newHermiteSpline(x, y, slopes), nil
+	}
+
+	//m[k+2] is the slope of the interval k, from -2 to n
+	m := make([]float64, n+3)
+	copy(m[2:], delta)
+	m[1] = 2*m[2] - m[3]
+	m[0] = 2*m[1] - m[2]
+	m[n+1] = 2*m[n] - m[n-1]
+	m[n+2] = 2*m[n+1] - m[n]
+	for i := 0; i < n; i++ {
+		//Slopes of the intervals i-2, i-1, i and i+1
+		w1 := math.Abs(m[i+3] - m[i+2])
+		w2 := math.Abs(m[i+1] - m[i])
+		if w1+w2 == 0 {
+			slopes[i] = (m[i+1] + m[i+2]) / 2
+		} else {
+			slopes[i] = (w1*m[i+1] + w2*m[i+2]) / (w1 + w2)
+		}
+	}
+	return newHermiteSpline(x, y, slopes), nil
+}
+
+/*
+differences returns the lengths of the intervals and the slopes of the chords
+*/
+func differences(x, y []float64) ([]float64, []float64) {
+	h := make([]float64, len(x)-1)
+	delta := make([]float64, len(x)-1)
+	for i := range h {
+		h[i] = x[i+1] - x[i]
+		delta[i] = (y[i+1] - y[i]) / h[i]
+	}
+	return h, delta
+}
+
+/*
+NewCubicHermite is a method to create the piecewise cubic Hermite interpolant: on each
+interval the cubic with the given values and first derivatives at both ends. It has a
+continuous first derivative, and is as accurate as the derivatives given, which makes
+it the natural dense output of an ODE solver that knows f(t, y) = y' at its steps.
+First parameter x are the abscissas, strictly increasing
+Second parameter y are the values
+Third parameter dydx are the first derivatives
+It returns an error if the sizes don't match, if there are less than 2 samples or if
+x is not increasing
+*/
+func NewCubicHermite(x, y, dydx []float64) (*CubicSpline, error) {
+	if err := checkSamples(x, y); err != nil {
+		return nil, err
+	}
+	if len(dydx) != len(x) {
+		return nil, &MathError{
+			code: errorDimensionMismatch,
+		}
+	}
+	return newHermiteSpline(x, y, dydx), nil
+}
+
+/*
+CubicHermite is a method to compute at t in [0, 1] the cubic going from y0 with the
+derivative d0 at 0 to y1 with the derivative d1 at 1, e.g. an easing curve for an
+animation with d0 = d1 = 0
+*/
+func CubicHermite(y0, y1, d0, d1, t float64) float64 {
+	h00, h10, h01, h11 := hermiteBasis(t)
+	return h00*y0 + h10*d0 + h01*y1 + h11*d1
+}
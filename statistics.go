@@ -0,0 +1,485 @@
+package advmath
+
+import (
+	"math"
+	"sort"
+)
+
+/*
+NaNPolicy tells how the statistics of a sample handle the NaN values, which usually
+stand for missing data
+*/
+type NaNPolicy int
+
+const (
+	//PropagateNaN returns NaN when the sample holds a NaN, as the arithmetic does
+	PropagateNaN NaNPolicy = iota
+	//OmitNaN ignores the NaN values, the statistic is computed on the others
+	OmitNaN
+	//RejectNaN returns an error when the sample holds a NaN
+	RejectNaN
+)
+
+/*
+applyNaNPolicy applies the NaN policy to data. It returns the values to use, which
+are data itself when it has no NaN, and tells if the statistic is NaN.
+*/
+func applyNaNPolicy(data []float64, policy NaNPolicy) ([]float64, bool, error) {
+	nans := 0
+	for _, v := range data {
+		if math.IsNaN(v) {
+			nans++
+		}
+	}
+	if nans == 0 {
+		return data, false, nil
+	}
+	switch policy {
+	case OmitNaN:
+		values := make([]float64, 0, len(data)-nans)
+		for _, v := range data {
+			if !math.IsNaN(v) {
+				values = append(values, v)
+			}
+		}
+		return values, false, nil
+	case RejectNaN:
+		return nil, false, &MathError{
+			s: "The sample holds a NaN value",
+		}
+	}
+	return nil, true, nil
+}
+
+/*
+mean is the mean of a non empty sample, refined by the mean of the deviations to
+cancel most of the round-off
+*/
+func mean(data []float64) float64 {
+	var sum float64
+	for _, v := range data {
+		sum += v
+	}
+	m := sum / float64(len(data))
+	var deviation float64
+	for _, v := range data {
+		deviation += v - m
+	}
+	return m + deviation/float64(len(data))
+}
+
+/*
+centralMoments returns the mean and the central moments of order 2, 3 and 4 (divided
+by n) of a non empty sample
+*/
+func centralMoments(data []float64) (m, m2, m3, m4 float64) {
+	m = mean(data)
+	for _, v := range data {
+		d := v - m
+		m2 += d * d
+		m3 += d * d * d
+		m4 += d * d * d * d
+	}
+	n := float64(len(data))
+	return m, m2 / n, m3 / n, m4 / n
+}
+
+/*
+Mean is a function to compute the arithmetic mean of a sample.
+First parameter is the sample
+Second parameter tells how the NaN values are handled
+It returns an error if the sample is empty
+*/
+func Mean(data []float64, policy NaNPolicy) (float64, error) {
+	values, nan, err := applyNaNPolicy(data, policy)
+	if err != nil || nan {
+		return math.NaN(), err
+	}
+	if len(values) == 0 {
+		return math.NaN(), &MathError{
+			code: errorDivisionByZero,
+		}
+	}
+	return mean(values), nil
+}
+
+/*
+Variance is a function to compute the variance of a sample with the two-pass
+algorithm, which is accurate even when the mean is large compared to the spread.
+First parameter is the sample
+Second parameter tells if the bias correction (dividing by n-1 instead of n, a.k.a.
+the sample variance) has to be applied
+Third parameter tells how the NaN values are handled
+It returns an error if the sample is empty, or has a single value with the bias
+correction
+*/
+func Variance(data []float64, unbiased bool, policy NaNPolicy) (float64, error) {
+	values, nan, err := applyNaNPolicy(data, policy)
+	if err != nil || nan {
+		return math.NaN(), err
+	}
+	n := len(values)
+	if n == 0 || (unbiased && n < 2) {
+		return math.NaN(), &MathError{
+			code: errorDivisionByZero,
+		}
+	}
+	_, m2, _, _ := centralMoments(values)
+	if unbiased {
+		return m2 * float64(n) / float64(n-1), nil
+	}
+	return m2, nil
+}
+
+/*
+StdDev is a function to compute the standard deviation of a sample, the square root
+of its Variance.
+First parameter is the sample
+Second parameter tells if the bias correction of the variance has to be applied
+Third parameter tells how the NaN values are handled
+*/
+func StdDev(data []float64, unbiased bool, policy NaNPolicy) (float64, error) {
+	v, err := Variance(data, unbiased, policy)
+	return math.Sqrt(v), err
+}
+
+/*
+Median is a function to compute the median of a sample, the mean of the two middle
+values when their number is even.
+First parameter is the sample
+Second parameter tells how the NaN values are handled
+It returns an error if the sample is empty
+*/
+func Median(data []float64, policy NaNPolicy) (float64, error) {
+	return Quantile(data, 0.5, policy)
+}
+
+/*
+Quantile is a function to compute the quantile of order p of a sample, interpolating
+linearly between the sorted values: the value at the position p*(n-1), counted from
+0. It is the default definition of R and NumPy (type 7 of Hyndman and Fan). The
+sample is not modified.
+First parameter is the sample
+Second parameter is the order p, between 0 (the minimum) and 1 (the maximum)
+Third parameter tells how the NaN values are handled
+It returns an error if the sample is empty or if p is out of [0, 1]
+*/
+func Quantile(data []float64, p float64, policy NaNPolicy) (float64, error) {
+	if !(p >= 0 && p <= 1) {
+		return math.NaN(), &MathError{
+			s: "The order of a quantile must be between 0 and 1",
+		}
+	}
+	values, nan, err := applyNaNPolicy(data, policy)
+	if err != nil || nan {
+		return math.NaN(), err
+	}
+	if len(values) == 0 {
+		return math.NaN(), &MathError{
+			code: errorDivisionByZero,
+		}
+	}
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	position := p * float64(len(sorted)-1)
+	i := int(position)
+	if i == len(sorted)-1 {
+		return sorted[i], nil
+	}
+	fraction := position - float64(i)
+	return sorted[i] + fraction*(sorted[i+1]-sorted[i]), nil
+}
+
+/*
+Skewness is a function to compute the skewness of a sample, the third standardized
+moment m3/m2^1.5: it is 0 for a symmetric distribution and positive when the right
+tail is longer.
+First parameter is the sample
+Second parameter tells if the bias correction G1 = g1*sqrt(n(n-1))/(n-2) has to be
+applied, as Excel and SAS do
+Third parameter tells how the NaN values are handled
+It returns an error if the sample is constant or too small (3 values with the bias
+correction)
+*/
+func Skewness(data []float64, unbiased bool, policy NaNPolicy) (float64, error) {
+	values, nan, err := applyNaNPolicy(data, policy)
+	if err != nil || nan {
+		return math.NaN(), err
+	}
+	n := float64(len(values))
+	if n == 0 || (unbiased && n < 3) {
+		return math.NaN(), &MathError{
+			code: errorDivisionByZero,
+		}
+	}
+	_, m2, m3, _ := centralMoments(values)
+	if m2 == 0 {
+		return math.NaN(), &MathError{
+			s: "Cannot compute the skewness of a constant sample",
+		}
+	}
+	g1 := m3 / math.Pow(m2, 1.5)
+	if unbiased {
+		return g1 * math.Sqrt(n*(n-1)) / (n - 2), nil
+	}
+	return g1, nil
+}
+
+/*
+Kurtosis is a function to compute the excess kurtosis of a sample, m4/m2^2 - 3: it is
+0 for a normal distribution and positive when the tails are heavier.
+First parameter is the sample
+Second parameter tells if the bias correction
+G2 = ((n+1)g2 + 6)(n-1)/((n-2)(n-3)) has to be applied, as Excel and SAS do
+Third parameter tells how the NaN values are handled
+It returns an error if the sample is constant or too small (4 values with the bias
+correction)
+*/
+func Kurtosis(data []float64, unbiased bool, policy NaNPolicy) (float64, error) {
+	values, nan, err := applyNaNPolicy(data, policy)
+	if err != nil || nan {
+		return math.NaN(), err
+	}
+	n := float64(len(values))
+	if n == 0 || (unbiased && n < 4) {
+		return math.NaN(), &MathError{
+			code: errorDivisionByZero,
+		}
+	}
+	_, m2, _, m4 := centralMoments(values)
+	if m2 == 0 {
+		return math.NaN(), &MathError{
+			s: "Cannot compute the kurtosis of a constant sample",
+		}
+	}
+	g2 := m4/(m2*m2) - 3
+	if unbiased {
+		return ((n+1)*g2 + 6) * (n - 1) / ((n - 2) * (n - 3)), nil
+	}
+	return g2, nil
+}
+
+/*
+Covariance is a method to compute the covariance matrix of a data matrix where
+each row is an observation and each column is a variable. The result is a
+square matrix with one row and one column per variable.
+First parameter tells if the bias correction (dividing by n-1 instead of n,
+a.k.a. the sample covariance) has to be applied
+*/
+func (m Matrix) Covariance(unbiased bool) (*Matrix, error) {
+	n := m.NumberOfRows
+	if n == 0 || (unbiased && n < 2) {
+		return nil, &MathError{
+			code: errorDivisionByZero,
+		}
+	}
+
+	p := m.NumberOfColumns
+	means := m.columnMeans()
+	divisor := float64(n)
+	if unbiased {
+		divisor = float64(n - 1)
+	}
+
+	cov := NewMatrix(p, p)
+	var i, j, k uint
+	for i = 0; i < p; i++ {
+		for j = i; j < p; j++ {
+			var sum float64
+			for k = 0; k < n; k++ {
+				sum += (m.M[k*p+i] - means[i]) * (m.M[k*p+j] - means[j])
+			}
+			cov.M[i*p+j] = sum / divisor
+			cov.M[j*p+i] = cov.M[i*p+j]
+		}
+	}
+	return cov, nil
+}
+
+/*
+Correlation is a method to compute the Pearson correlation matrix of a data
+matrix where each row is an observation and each column is a variable. The
+diagonal is made of ones. An error is returned if a variable is constant since
+its correlation is not defined.
+*/
+func (m Matrix) Correlation() (*Matrix, error) {
+	cov, err := m.Covariance(false)
+	if err != nil {
+		return nil, err
+	}
+
+	p := m.NumberOfColumns
+	std := make([]float64, p)
+	for i := range std {
+		std[i] = math.Sqrt(cov.M[uint(i)*p+uint(i)])
+		if std[i] == 0 {
+			return nil, &MathError{
+				s: "Cannot compute the correlation of a constant variable",
+			}
+		}
+	}
+
+	var i, j uint
+	for i = 0; i < p; i++ {
+		for j = 0; j < p; j++ {
+			if i == j {
+				cov.M[i*p+j] = 1.0
+				continue
+			}
+			cov.M[i*p+j] /= std[i] * std[j]
+		}
+	}
+	return cov, nil
+}
+
+/*
+columnMeans returns the mean of each column of the matrix, refined as mean does so
+that a constant column gets its value exactly and a variance of 0
+*/
+func (m Matrix) columnMeans() []float64 {
+	means := make([]float64, m.NumberOfColumns)
+	if m.NumberOfRows == 0 {
+		return means
+	}
+	var j uint
+	for j = 0; j < m.NumberOfColumns; j++ {
+		means[j] = mean(m.GetColumn(j))
+	}
+	return means
+}
+
+/*
+checkPair returns an error if the series x and y don't have the same size or are
+empty
+*/
+func checkPair(x, y []float64) error {
+	if len(x) != len(y) || len(x) == 0 {
+		return &MathError{
+			code: errorDimensionMismatch,
+		}
+	}
+	return nil
+}
+
+/*
+Covariance is a function to compute the covariance of two series of observations.
+First parameter is the first series
+Second parameter is the second series, of the same size
+Third parameter tells if the bias correction (dividing by n-1 instead of n, a.k.a.
+the sample covariance) has to be applied
+It returns an error if the sizes differ, if the series are empty or have a single
+value with the bias correction
+*/
+func Covariance(x, y []float64, unbiased bool) (float64, error) {
+	if err := checkPair(x, y); err != nil {
+		return math.NaN(), err
+	}
+	n := len(x)
+	if unbiased && n < 2 {
+		return math.NaN(), &MathError{
+			code: errorDivisionByZero,
+		}
+	}
+	mx, my := mean(x), mean(y)
+	var sum float64
+	for i := range x {
+		sum += (x[i] - mx) * (y[i] - my)
+	}
+	if unbiased {
+		return sum / float64(n-1), nil
+	}
+	return sum / float64(n), nil
+}
+
+/*
+Correlation is a function to compute the Pearson correlation coefficient of two
+series, between -1 and 1: it measures how close the relation between them is to a
+line.
+First parameter is the first series
+Second parameter is the second series, of the same size
+It returns an error if the sizes differ, if the series are empty or if one of them is
+constant since its correlation is not defined
+*/
+func Correlation(x, y []float64) (float64, error) {
+	if err := checkPair(x, y); err != nil {
+		return math.NaN(), err
+	}
+	mx, my := mean(x), mean(y)
+	var sxy, sxx, syy float64
+	for i := range x {
+		dx, dy := x[i]-mx, y[i]-my
+		sxy += dx * dy
+		sxx += dx * dx
+		syy += dy * dy
+	}
+	if sxx == 0 || syy == 0 {
+		return math.NaN(), &MathError{
+			s: "Cannot compute the correlation of a constant variable",
+		}
+	}
+	//Round-off can give a value slightly out of [-1, 1]
+	return math.Max(-1, math.Min(1, sxy/math.Sqrt(sxx*syy))), nil
+}
+
+/*
+ranks returns the rank of each value of x, from 1 to n, the tied values getting the
+mean of their ranks
+*/
+func ranks(x []float64) []float64 {
+	order := make([]int, len(x))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return x[order[i]] < x[order[j]]
+	})
+	r := make([]float64, len(x))
+	for i := 0; i < len(order); {
+		j := i
+		for j+1 < len(order) && x[order[j+1]] == x[order[i]] {
+			j++
+		}
+		//Positions i to j are tied
+		rank := float64(i+j)/2 + 1
+		for k := i; k <= j; k++ {
+			r[order[k]] = rank
+		}
+		i = j + 1
+	}
+	return r
+}
+
+/*
+SpearmanCorrelation is a function to compute the Spearman rank correlation of two
+series, the Pearson correlation of their ranks (tied values getting the mean of their
+ranks): it is 1 when y increases with x, even if the relation is not linear, and it
+is robust to outliers.
+First parameter is the first series
+Second parameter is the second series, of the same size
+It returns an error if the sizes differ, if the series are empty or if one of them is
+constant
+*/
+func SpearmanCorrelation(x, y []float64) (float64, error) {
+	if err := checkPair(x, y); err != nil {
+		return math.NaN(), err
+	}
+	return Correlation(ranks(x), ranks(y))
+}
+
+/*
+SpearmanCorrelation is a method to compute the Spearman rank correlation matrix of a
+data matrix where each row is an observation and each column is a variable: it is
+the Pearson correlation matrix (see Correlation) of the ranks of each column. An error
+is returned if a variable is constant.
+*/
+func (m Matrix) SpearmanCorrelation() (*Matrix, error) {
+	ranked := NewMatrix(m.NumberOfRows, m.NumberOfColumns)
+	var j uint
+	for j = 0; j < m.NumberOfColumns; j++ {
+		r := ranks(m.GetColumn(j))
+		for i := range r {
+			ranked.M[uint(i)*m.NumberOfColumns+j] = r[i]
+		}
+	}
+	return ranked.Correlation()
+}
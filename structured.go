@@ -0,0 +1,315 @@
+package advmath
+
+import (
+	"math"
+	"math/cmplx"
+)
+
+/*
+NewToeplitz is a method to create a Toeplitz matrix, i.e. a matrix where every
+diagonal is constant: T[i][j] only depends on i-j. It is fully defined by its
+first row and its first column, which must share their first element.
+First parameter is the first row
+Second parameter is the first column
+*/
+func NewToeplitz(firstRow, firstCol []float64) (*Matrix, error) {
+	if len(firstRow) == 0 || len(firstCol) == 0 || firstRow[0] != firstCol[0] {
+		return nil, &MathError{
+			code: errorDimensionMismatch,
+		}
+	}
+
+	rows := uint(len(firstCol))
+	cols := uint(len(firstRow))
+	t := NewMatrix(rows, cols)
+	var i, j uint
+	for i = 0; i < rows; i++ {
+		for j = 0; j < cols; j++ {
+			if j >= i {
+				t.M[i*cols+j] = firstRow[j-i]
+			} else {
+				t.M[i*cols+j] = firstCol[i-j]
+			}
+		}
+	}
+	return t, nil
+}
+
+/*
+NewCirculant is a method to create a circulant matrix, i.e. a square matrix
+where each column is the previous one rotated down by one element:
+C[i][j] = c[(i-j) mod n].
+First parameter is the first column
+*/
+func NewCirculant(c []float64) *Matrix {
+	n := uint(len(c))
+	m := NewMatrix(n, n)
+	var i, j uint
+	for i = 0; i < n; i++ {
+		for j = 0; j < n; j++ {
+			m.M[i*n+j] = c[(i+n-j)%n]
+		}
+	}
+	return m
+}
+
+/*
+SolveToeplitz is a method to solve T*x = b where T is a square Toeplitz matrix,
+using the Levinson recursion from 'Numerical Recipes'. It only needs O(n^2)
+operations instead of O(n^3) for a LU decomposition and doesn't build the
+matrix. The method fails (division by zero error) if one of the leading
+principal submatrices of T is singular, in that case use the LU decomposition.
+First parameter is the first row of T
+Second parameter is the first column of T
+Third parameter is the right hand side b
+*/
+func SolveToeplitz(firstRow, firstCol, b []float64) ([]float64, error) {
+	n := len(b)
+	if n == 0 || len(firstRow) != n || len(firstCol) != n || firstRow[0] != firstCol[0] {
+		return nil, &MathError{
+			code: errorDimensionMismatch,
+		}
+	}
+
+	//r is 1-based as in the book: T[i][j] = r[n+i-j]
+	r := make([]float64, 2*n)
+	for k := 0; k < n; k++ {
+		r[n+k] = firstCol[k]
+		r[n-k] = firstRow[k]
+	}
+	y := make([]float64, n+1)
+	copy(y[1:], b)
+	x := make([]float64, n+1)
+	g := make([]float64, n+1)
+	h := make([]float64, n+1)
+
+	if r[n] == 0.0 {
+		return nil, &MathError{
+			code: errorDivisionByZero,
+		}
+	}
+	x[1] = y[1] / r[n]
+	if n == 1 {
+		return x[1:], nil
+	}
+	g[1] = r[n-1] / r[n]
+	h[1] = r[n+1] / r[n]
+
+	for m := 1; m <= n; m++ {
+		m1 := m + 1
+		//Solution of the system of size m+1
+		sxn := -y[m1]
+		sd := -r[n]
+		for j := 1; j <= m; j++ {
+			sxn += r[n+m1-j] * x[j]
+			sd += r[n+m1-j] * g[m-j+1]
+		}
+		if sd == 0.0 {
+			return nil, &MathError{
+				code: errorDivisionByZero,
+			}
+		}
+		x[m1] = sxn / sd
+		for j := 1; j <= m; j++ {
+			x[j] -= x[m1] * g[m-j+1]
+		}
+		if m1 == n {
+			return x[1:], nil
+		}
+
+		//Update of the auxiliary vectors g and h
+		sgn := -r[n-m1]
+		shn := -r[n+m1]
+		sgd := -r[n]
+		for j := 1; j <= m; j++ {
+			sgn += r[n+j-m1] * g[j]
+			shn += r[n+m1-j] * h[j]
+			sgd += r[n+j-m1] * h[m-j+1]
+		}
+		if sgd == 0.0 {
+			return nil, &MathError{
+				code: errorDivisionByZero,
+			}
+		}
+		g[m1] = sgn / sgd
+		h[m1] = shn / sd
+		k := m
+		pp := g[m1]
+		qq := h[m1]
+		for j := 1; j <= (m+1)/2; j++ {
+			pt1, pt2 := g[j], g[k]
+			qt1, qt2 := h[j], h[k]
+			g[j] = pt1 - pp*qt2
+			g[k] = pt2 - pp*qt1
+			h[j] = qt1 - qq*pt2
+			h[k] = qt2 - qq*pt1
+			k--
+		}
+	}
+
+	return x[1:], nil
+}
+
+/*
+SolveCirculant is a method to solve C*x = b where C is the circulant matrix
+created by NewCirculant(c). The discrete Fourier transform diagonalizes every
+circulant matrix: C*x is the circular convolution of c and x, so x is the
+inverse transform of DFT(b)/DFT(c). Unlike SolveToeplitz it works for every
+invertible circulant matrix, including the ones with singular leading blocks
+such as the cyclic shifts. The transforms are computed directly in O(n^2).
+First parameter is the first column of C
+Second parameter is the right hand side b
+It returns an error if the sizes differ or if C is singular (an eigenvalue
+DFT(c)[k] is zero up to round-off)
+*/
+func SolveCirculant(c, b []float64) ([]float64, error) {
+	n := len(c)
+	if n == 0 || len(b) != n {
+		return nil, &MathError{
+			code: errorDimensionMismatch,
+		}
+	}
+	eigenvalues := dft(c)
+	largest := 0.0
+	for _, l := range eigenvalues {
+		largest = math.Max(largest, cmplx.Abs(l))
+	}
+	transformed := dft(b)
+	for k, l := range eigenvalues {
+		if cmplx.Abs(l) <= float64(n)*machineEpsilon*largest {
+			return nil, &MathError{
+				code: errorNotInversible,
+			}
+		}
+		transformed[k] /= l
+	}
+	x := make([]float64, n)
+	for k, v := range dftComplex(transformed, true) {
+		x[k] = real(v) / float64(n)
+	}
+	return x, nil
+}
+
+/*
+dft returns the discrete Fourier transform of a real vector
+*/
+func dft(x []float64) []complex128 {
+	values := make([]complex128, len(x))
+	for i, v := range x {
+		values[i] = complex(v, 0)
+	}
+	return dftComplex(values, false)
+}
+
+/*
+dftComplex returns sum x[j] exp(-+2 i pi jk/n) for each k, the sign being + for
+the inverse transform (without the 1/n factor)
+*/
+func dftComplex(x []complex128, inverse bool) []complex128 {
+	n := len(x)
+	sign := -1.0
+	if inverse {
+		sign = 1.0
+	}
+	//The powers of the root of unity, with an exact reduction of jk modulo n
+	roots := make([]complex128, n)
+	for k := range roots {
+		roots[k] = cmplx.Rect(1, sign*2*math.Pi*float64(k)/float64(n))
+	}
+	out := make([]complex128, n)
+	for k := range out {
+		for j, v := range x {
+			out[k] += v * roots[(j*k)%n]
+		}
+	}
+	return out
+}
+
+/*
+NewVandermonde is a method to create the Vandermonde matrix of the points x:
+row i is [1 x[i] x[i]^2 ... x[i]^degree]. Multiplying it by the coefficients
+of a polynomial (lowest degree first) evaluates the polynomial at every point,
+which is what is needed for polynomial fitting.
+First parameter are the points
+Second parameter is the highest power
+*/
+func NewVandermonde(x []float64, degree uint) *Matrix {
+	cols := degree + 1
+	v := NewMatrix(uint(len(x)), cols)
+	var j uint
+	for i, xi := range x {
+		p := 1.0
+		for j = 0; j < cols; j++ {
+			v.M[uint(i)*cols+j] = p
+			p *= xi
+		}
+	}
+	return v
+}
+
+/*
+NewHilbert is a method to create the Hilbert matrix of order n: H[i][j] = 1/(i+j+1)
+(indices starting at 0). It is the classic example of an ill-conditioned
+matrix, its condition number grows like e^(3.5n), which makes it a good test
+for the numerical routines.
+First parameter is the number of rows and columns
+*/
+func NewHilbert(n uint) *Matrix {
+	h := NewMatrix(n, n)
+	var i, j uint
+	for i = 0; i < n; i++ {
+		for j = 0; j < n; j++ {
+			h.M[i*n+j] = 1.0 / float64(i+j+1)
+		}
+	}
+	return h
+}
+
+/*
+SolveTridiagonal is a method to solve T*x = b where T is a tridiagonal matrix given by
+its three diagonals, with the Thomas algorithm in O(n) operations. There is no
+pivoting, which is fine for the diagonally dominant matrices met with splines or
+finite differences, a zero pivot gives an error.
+First parameter is the subdiagonal, lower[i] being T[i+1][i] (n-1 values)
+Second parameter is the diagonal (n values)
+Third parameter is the superdiagonal, upper[i] being T[i][i+1] (n-1 values)
+Fourth parameter is the right hand side b
+*/
+func SolveTridiagonal(lower, diagonal, upper, b []float64) ([]float64, error) {
+	n := len(diagonal)
+	if n == 0 || len(b) != n || len(lower) != n-1 || len(upper) != n-1 {
+		return nil, &MathError{
+			code: errorDimensionMismatch,
+		}
+	}
+
+	//Forward elimination, c and d are the modified upper diagonal and right hand side
+	c := make([]float64, n)
+	d := make([]float64, n)
+	pivot := diagonal[0]
+	for i := 0; i < n; i++ {
+		if i > 0 {
+			pivot = diagonal[i] - lower[i-1]*c[i-1]
+		}
+		if pivot == 0 {
+			return nil, &MathError{
+				code: errorZeroPivot,
+			}
+		}
+		if i < n-1 {
+			c[i] = upper[i] / pivot
+		}
+		d[i] = b[i]
+		if i > 0 {
+			d[i] -= lower[i-1] * d[i-1]
+		}
+		d[i] /= pivot
+	}
+
+	//Back substitution
+	x := d
+	for i := n - 2; i >= 0; i-- {
+		x[i] -= c[i] * x[i+1]
+	}
+	return x, nil
+}
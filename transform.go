@@ -0,0 +1,115 @@
+package advmath
+
+import (
+	"math"
+)
+
+/*
+NewRotation2D is a method to create the 2x2 matrix rotating a point of the
+plane counterclockwise around the origin.
+First parameter is the angle in radians
+*/
+func NewRotation2D(theta float64) *Matrix {
+	s, c := math.Sincos(theta)
+	r := NewMatrix(2, 2)
+	r.SetRow(0, []float64{c, -s})
+	r.SetRow(1, []float64{s, c})
+	return r
+}
+
+/*
+NewRotation3DX is a method to create the 3x3 matrix rotating a point around
+the x axis (right handed, counterclockwise when looking from positive x).
+First parameter is the angle in radians
+*/
+func NewRotation3DX(theta float64) *Matrix {
+	s, c := math.Sincos(theta)
+	r := NewMatrix(3, 3)
+	r.SetRow(0, []float64{1, 0, 0})
+	r.SetRow(1, []float64{0, c, -s})
+	r.SetRow(2, []float64{0, s, c})
+	return r
+}
+
+/*
+NewRotation3DY is a method to create the 3x3 matrix rotating a point around
+the y axis.
+First parameter is the angle in radians
+*/
+func NewRotation3DY(theta float64) *Matrix {
+	s, c := math.Sincos(theta)
+	r := NewMatrix(3, 3)
+	r.SetRow(0, []float64{c, 0, s})
+	r.SetRow(1, []float64{0, 1, 0})
+	r.SetRow(2, []float64{-s, 0, c})
+	return r
+}
+
+/*
+NewRotation3DZ is a method to create the 3x3 matrix rotating a point around
+the z axis.
+First parameter is the angle in radians
+*/
+func NewRotation3DZ(theta float64) *Matrix {
+	s, c := math.Sincos(theta)
+	r := NewMatrix(3, 3)
+	r.SetRow(0, []float64{c, -s, 0})
+	r.SetRow(1, []float64{s, c, 0})
+	r.SetRow(2, []float64{0, 0, 1})
+	return r
+}
+
+/*
+NewScaling is a method to create a diagonal scaling matrix, there is one
+factor per dimension: NewScaling(2, 3) scales x by 2 and y by 3.
+Parameters are the factors for each axis
+*/
+func NewScaling(factors ...float64) *Matrix {
+	n := uint(len(factors))
+	s := NewMatrix(n, n)
+	for i, f := range factors {
+		s.M[uint(i)*n+uint(i)] = f
+	}
+	return s
+}
+
+/*
+NewTranslation is a method to create a translation in homogeneous coordinates,
+for a 2-D translation the matrix is 3x3 and for a 3-D one it is 4x4:
+
+	[1 0 tx]
+	[0 1 ty]
+	[0 0  1]
+
+Parameters are the offsets for each axis
+*/
+func NewTranslation(offsets ...float64) *Matrix {
+	n := uint(len(offsets))
+	t := NewIdentity(n + 1)
+	for i, o := range offsets {
+		t.M[uint(i)*(n+1)+n] = o
+	}
+	return t
+}
+
+/*
+Homogeneous is a method to embed a square linear transformation (a rotation
+or a scaling for instance) in homogeneous coordinates, so that it can be
+multiplied with a translation created by NewTranslation. The matrix
+gets one more row and one more column, the new diagonal element being 1.
+*/
+func (m Matrix) Homogeneous() (*Matrix, error) {
+	if !m.IsSquare() {
+		return nil, &MathError{
+			code: errorNonSquareMatrix,
+		}
+	}
+
+	n := m.NumberOfRows
+	h := NewIdentity(n + 1)
+	var i uint
+	for i = 0; i < n; i++ {
+		copy(h.M[i*(n+1):i*(n+1)+n], m.M[i*n:(i+1)*n])
+	}
+	return h, nil
+}
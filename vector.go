@@ -0,0 +1,77 @@
+package advmath
+
+import (
+	"math"
+)
+
+/*
+Dot computes the dot product of two vectors of the same size.
+First parameter is the first vector
+Second parameter is the second vector
+*/
+func Dot(u, v []float64) (float64, error) {
+	if len(u) != len(v) {
+		return 0.0, &MathError{
+			code: errorDimensionMismatch,
+		}
+	}
+	var sum float64
+	for i := range u {
+		sum += u[i] * v[i]
+	}
+	return sum, nil
+}
+
+/*
+Norm computes the euclidean norm of a vector.
+First parameter is the vector
+*/
+func Norm(u []float64) float64 {
+	//Scale to avoid overflows and underflows in the squares
+	var scale float64
+	for _, x := range u {
+		scale = math.Max(scale, math.Abs(x))
+	}
+	if scale == 0 || math.IsInf(scale, 0) {
+		return scale
+	}
+	var sum float64
+	for _, x := range u {
+		sum += (x / scale) * (x / scale)
+	}
+	return scale * math.Sqrt(sum)
+}
+
+/*
+Cross computes the cross product u x v of two 3-D vectors.
+First parameter is the first vector
+Second parameter is the second vector
+*/
+func Cross(u, v []float64) ([]float64, error) {
+	if len(u) != 3 || len(v) != 3 {
+		return nil, &MathError{
+			code: errorDimensionMismatch,
+		}
+	}
+	return []float64{
+		u[1]*v[2] - u[2]*v[1],
+		u[2]*v[0] - u[0]*v[2],
+		u[0]*v[1] - u[1]*v[0],
+	}, nil
+}
+
+/*
+Outer computes the outer product of two vectors, i.e. the matrix u*v^T which
+has len(u) rows and len(v) columns.
+First parameter is the first vector
+Second parameter is the second vector
+*/
+func Outer(u, v []float64) *Matrix {
+	m := NewMatrix(uint(len(u)), uint(len(v)))
+	for i := range u {
+		for j := range v {
+			m.M[i*len(v)+j] = u[i] * v[j]
+		}
+	}
+	return m
+}
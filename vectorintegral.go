@@ -0,0 +1,198 @@
+package advmath
+
+import (
+	"container/heap"
+)
+
+/*
+replay records the values of a vector function at the points where a scalar rule
+evaluates it, so that the rule can then be applied to each component without
+evaluating the function again. It relies on the rules evaluating their function at
+the same points in the same order every time they are called with the same
+parameters, which is the case of Simpson and GaussKronrod.
+*/
+type replay struct {
+	values [][]float64
+	next   int
+}
+
+func (r *replay) record(f VectorF) F {
+	return func(x float64) float64 {
+		//Copied since f may reuse the slice it returns
+		r.values = append(r.values, append([]float64(nil), f(x)...))
+		return 0
+	}
+}
+
+func (r *replay) component(k int) F {
+	r.next = 0
+	return func(float64) float64 {
+		v := r.values[r.next][k]
+		r.next++
+		return v
+	}
+}
+
+func (r *replay) dimension() int {
+	if len(r.values) == 0 {
+		return 0
+	}
+	return len(r.values[0])
+}
+
+/*
+SimpsonVector is the same as Simpson for a function with values in R^n, each
+component is integrated with the same n+1 evaluations of f.
+
+First parameter inf is the lower boundary
+Second parameter sup is the upper boundary
+Third parameter f is the function to integrate
+Fourth parameter n is the number of intervals
+The method returns the integral of each component
+*/
+func SimpsonVector(inf float64, sup float64, f VectorF, n int) ([]float64, error) {
+	var r replay
+	if _, err := Simpson(inf, sup, r.record(f), n); err != nil {
+		return nil, err
+	}
+	result := make([]float64, r.dimension())
+	for k := range result {
+		result[k], _ = Simpson(inf, sup, r.component(k), n)
+	}
+	return result, nil
+}
+
+/*
+SimpsonComplex is the same as Simpson for a function with complex values, the real
+and imaginary parts being integrated with the same evaluations of f.
+*/
+func SimpsonComplex(inf float64, sup float64, f ComplexF, n int) (complex128, error) {
+	v, err := SimpsonVector(inf, sup, complexToVector(f), n)
+	if err != nil {
+		return 0, err
+	}
+	return complex(v[0], v[1]), nil
+}
+
+/*
+GaussKronrodVector is the same as GaussKronrod for a function with values in R^n,
+each component is integrated with the same 15 evaluations of f. The error returned
+is the biggest of the error estimates of the components.
+*/
+func GaussKronrodVector(inf float64, sup float64, f VectorF) ([]float64, float64) {
+	var r replay
+	GaussKronrod(inf, sup, r.record(f))
+	result := make([]float64, r.dimension())
+	var err float64
+	for k := range result {
+		var e float64
+		result[k], e = GaussKronrod(inf, sup, r.component(k))
+		if e > err {
+			err = e
+		}
+	}
+	return result, err
+}
+
+/*
+AdaptiveGaussKronrodVector is the same as AdaptiveGaussKronrod for a function with
+values in R^n. The intervals are shared by all the components and the one split is
+the one where the biggest error of the components is the biggest, so that every
+evaluation of f is used for all the components.
+
+First parameter inf is the lower boundary
+Second parameter sup is the upper boundary
+Third parameter f is the function to integrate
+Fourth parameter tol is the absolute tolerance required for each component
+Fifth parameter is the maximum number of intervals, it is optional and set to 1000 by default
+First return value is the integral of each component
+Second return value is the estimated absolute error, the biggest of the components
+Third return value is an error if the tolerance could not be reached
+*/
+func AdaptiveGaussKronrodVector(inf float64, sup float64, f VectorF, tol float64, maxIntervals int) ([]float64, float64, error) {
+	if maxIntervals <= 0 {
+		maxIntervals = 1000
+	}
+
+	value, err := GaussKronrodVector(inf, sup, f)
+	intervals := &gkVectorHeap{{inf, sup, value, err}}
+
+	for err > tol && intervals.Len() < maxIntervals {
+		worst := heap.Pop(intervals).(gkVectorInterval)
+		m := (worst.a + worst.b) / 2
+		if m == worst.a || m == worst.b {
+			//Cannot split anymore
+			heap.Push(intervals, worst)
+			break
+		}
+		lv, le := GaussKronrodVector(worst.a, m, f)
+		rv, re := GaussKronrodVector(m, worst.b, f)
+		heap.Push(intervals, gkVectorInterval{worst.a, m, lv, le})
+		heap.Push(intervals, gkVectorInterval{m, worst.b, rv, re})
+
+		value, err = intervals.sum()
+	}
+
+	if err > tol {
+		return value, err, &MathError{
+			code: errorNotConverged,
+		}
+	}
+	return value, err, nil
+}
+
+/*
+AdaptiveGaussKronrodComplex is the same as AdaptiveGaussKronrod for a function with
+complex values, the real and imaginary parts being integrated with the same
+evaluations of f. The tolerance applies to both parts.
+*/
+func AdaptiveGaussKronrodComplex(inf float64, sup float64, f ComplexF, tol float64, maxIntervals int) (complex128, float64, error) {
+	v, err, e := AdaptiveGaussKronrodVector(inf, sup, complexToVector(f), tol, maxIntervals)
+	return complex(v[0], v[1]), err, e
+}
+
+func complexToVector(f ComplexF) VectorF {
+	return func(x float64) []float64 {
+		z := f(x)
+		return []float64{real(z), imag(z)}
+	}
+}
+
+type gkVectorInterval struct {
+	a, b  float64
+	value []float64
+	err   float64
+}
+
+/*
+gkVectorHeap is a max heap of intervals ordered by their error estimate
+*/
+type gkVectorHeap []gkVectorInterval
+
+func (h gkVectorHeap) Len() int            { return len(h) }
+func (h gkVectorHeap) Less(i, j int) bool  { return h[i].err > h[j].err }
+func (h gkVectorHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *gkVectorHeap) Push(x interface{}) { *h = append(*h, x.(gkVectorInterval)) }
+func (h *gkVectorHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	x := old[n-1]
+	*h = old[:n-1]
+	return x
+}
+
+/*
+sum returns the integral of each component and the sum of the errors of the
+intervals, which bounds the error of every component
+*/
+func (h gkVectorHeap) sum() ([]float64, float64) {
+	value := make([]float64, len(h[0].value))
+	var err float64
+	for _, i := range h {
+		for k, v := range i.value {
+			value[k] += v
+		}
+		err += i.err
+	}
+	return value, err
+}